@@ -0,0 +1,167 @@
+// Package apperr is the error model of the blog. A failure the caller can act
+// on is an *Error of a Kind that names the entity and the field it is about
+// and keeps its cause. The repositories return errors of a kind, the services
+// add the entity, and every frontend maps the kinds to its own terms: the cli
+// to messages and exit codes, the HTTP server to statuses.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Kind int
+
+const (
+	// Internal is every failure that is none of the kinds below
+	Internal Kind = iota
+	NotFound
+	Conflict
+	Validation
+	Forbidden
+	Unavailable
+	// RateLimited means the caller has to slow down, RetryAfter tells for
+	// how long
+	RateLimited
+)
+
+var kindNames = [...]string{
+	Internal:    "internal",
+	NotFound:    "not_found",
+	Conflict:    "conflict",
+	Validation:  "validation",
+	Forbidden:   "forbidden",
+	Unavailable: "unavailable",
+	RateLimited: "rate_limited",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+type Error struct {
+	Kind Kind
+	// Code tells the failures of a kind apart for machines, like
+	// "email_taken", it defaults to the name of the kind
+	Code string
+	// Entity is the kind of record the error is about, like "post"
+	Entity string
+	// Field is the field of the entity at fault, empty when the error is
+	// about the whole record
+	Field string
+	// Message is shown to the user, a message is made up from the kind and
+	// the entity when it is empty
+	Message string
+	// RetryAfter is how long a RateLimited caller should wait
+	RetryAfter time.Duration
+	Err        error
+}
+
+// New returns an error of kind about entity
+func New(kind Kind, entity, message string) *Error {
+	return &Error{Kind: kind, Entity: entity, Message: message}
+}
+
+// Invalid returns a Validation error about field of entity
+func Invalid(entity, field, message string) *Error {
+	return &Error{Kind: Validation, Entity: entity, Field: field, Message: message}
+}
+
+// Error returns the message. The cause is added unless it is an *Error
+// itself, whose message the outer one replaces. Internal and Unavailable
+// errors without a message of their own show the cause, its details are
+// what tells them apart.
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = e.defaultMessage()
+	}
+	if message == "" {
+		if e.Err != nil {
+			return e.Err.Error()
+		}
+		return e.Kind.String()
+	}
+	var cause *Error
+	if e.Err == nil || errors.As(e.Err, &cause) {
+		return message
+	}
+	return message + ": " + e.Err.Error()
+}
+
+func (e *Error) defaultMessage() string {
+	entity := e.Entity
+	if entity == "" {
+		entity = "record"
+	}
+	switch e.Kind {
+	case NotFound:
+		return entity + " not found"
+	case Conflict:
+		return entity + " already exists"
+	case Validation:
+		if e.Field != "" {
+			return "invalid " + e.Field + " of " + entity
+		}
+		return "invalid " + entity
+	case Forbidden:
+		return "permission denied"
+	case RateLimited:
+		return "too many requests"
+	default:
+		return ""
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOrKind returns the code, or the name of the kind when there is none
+func (e *Error) CodeOrKind() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return e.Kind.String()
+}
+
+// As returns the outermost *Error in the chain of err
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// KindOf returns the kind of err, Internal when it has none
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return Internal
+}
+
+// Is reports whether err is an error of kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// Wrap returns err as an error about entity that keeps its kind, code, field
+// and retry delay. An error about an entity already is returned as it is,
+// an error without a kind becomes an Internal one. Wrap returns nil for nil.
+func Wrap(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	e, ok := As(err)
+	if !ok {
+		return &Error{Kind: Internal, Entity: entity, Err: err}
+	}
+	if e.Entity != "" {
+		return err
+	}
+	return &Error{Kind: e.Kind, Code: e.Code, Entity: entity, Field: e.Field, RetryAfter: e.RetryAfter, Err: err}
+}
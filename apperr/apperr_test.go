@@ -0,0 +1,97 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"nil", nil, Internal},
+		{"plain", errors.New("boom"), Internal},
+		{"error", New(NotFound, "post", ""), NotFound},
+		{"wrapped by fmt", fmt.Errorf("loading: %w", New(Conflict, "user", "")), Conflict},
+		{"outermost wins", &Error{Kind: Forbidden, Err: New(NotFound, "post", "")}, Forbidden},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.err); got != tt.want {
+			t.Errorf("%s: KindOf = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIs(t *testing.T) {
+	if Is(nil, Internal) {
+		t.Error("nil is an Internal error")
+	}
+	if !Is(errors.New("boom"), Internal) {
+		t.Error("an error without a kind is no Internal error")
+	}
+	err := fmt.Errorf("saving: %w", Invalid("post", "title", "the title is empty"))
+	if !Is(err, Validation) || Is(err, NotFound) {
+		t.Errorf("got the kind %v, want Validation only", KindOf(err))
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil, "post") != nil {
+		t.Fatal("Wrap(nil) is not nil")
+	}
+
+	// an error without a kind becomes an Internal one
+	cause := errors.New("connection reset")
+	wrapped := Wrap(cause, "post")
+	e, ok := As(wrapped)
+	if !ok || e.Kind != Internal || e.Entity != "post" || !errors.Is(wrapped, cause) {
+		t.Fatalf("Wrap of a plain error returned %#v", wrapped)
+	}
+	if wrapped.Error() != "connection reset" {
+		t.Fatalf("the Internal error says %q, want the cause", wrapped.Error())
+	}
+
+	// the kind, code, field and retry delay are kept
+	limited := &Error{Kind: RateLimited, Code: "locked_out", Field: "password", RetryAfter: time.Minute}
+	wrapped = Wrap(limited, "user")
+	e, _ = As(wrapped)
+	if e.Kind != RateLimited || e.Code != "locked_out" || e.Field != "password" || e.RetryAfter != time.Minute || e.Entity != "user" {
+		t.Fatalf("Wrap returned %#v", e)
+	}
+	if !errors.Is(wrapped, limited) {
+		t.Fatal("the wrapped error lost its cause")
+	}
+
+	// the entity named first stays
+	comment := New(NotFound, "comment", "")
+	if got := Wrap(comment, "post"); got != error(comment) {
+		t.Fatalf("Wrap of an error about an entity returned %#v, want it as it was", got)
+	}
+	if got := Wrap(New(NotFound, "", ""), "post").Error(); got != "post not found" {
+		t.Fatalf("the wrapped error says %q, want %q", got, "post not found")
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  *Error
+		want string
+	}{
+		{&Error{Kind: NotFound}, "record not found"},
+		{&Error{Kind: Conflict, Entity: "user"}, "user already exists"},
+		{&Error{Kind: Validation, Entity: "post", Field: "title"}, "invalid title of post"},
+		{&Error{Kind: Forbidden, Message: "not yours"}, "not yours"},
+		{&Error{Kind: Forbidden, Message: "not yours", Err: errors.New("actor 2")}, "not yours: actor 2"},
+		{&Error{Kind: NotFound, Entity: "post", Err: New(NotFound, "", "gone")}, "post not found"},
+		{&Error{Kind: Unavailable}, "unavailable"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("%#v says %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
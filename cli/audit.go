@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+func (cmd *command) auditService() (service.Audit, error) {
+	services, err := cmd.services()
+	if err != nil {
+		return nil, err
+	}
+	return services.Audit, nil
+}
+
+func auditTable(events ...models.AuditEvent) *table {
+	t := newTable("id", "time", "actor_id", "action", "entity", "entity_id", "changes", "request_id")
+	for _, event := range events {
+		actor := ""
+		if event.ActorID != nil {
+			actor = strconv.FormatInt(*event.ActorID, 10)
+		}
+		t.add(event.ID, event.CreatedAt, actor, event.Action, event.EntityType, event.EntityID, event.Changes, event.RequestID)
+	}
+	return t
+}
+
+func (cmd *command) audit(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("audit " + verb)
+
+	switch verb {
+	case "list":
+		actor := fs.Int64("actor", 0, "only the changes of the user with this id")
+		entity := fs.String("entity", "", "only the changes of this entity: user, post, comment, reaction, profile, avatar, two_factor or identity")
+		id := fs.Int64("id", 0, "only the changes of the entity with this id, needs --entity")
+		since := fs.String("since", "", "only the changes from this time on, RFC 3339 time or duration before now like 24h")
+		until := fs.String("until", "", "only the changes before this time, RFC 3339 time or duration before now")
+		limit := fs.Int("limit", 100, fmt.Sprintf("most changes to list, at most %d", service.MaxAuditEvents))
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *id != 0 && *entity == "" {
+			return fmt.Errorf("%w: --id needs --entity", errUsage)
+		}
+		filter := repository.AuditFilter{EntityType: *entity, EntityID: *id, Limit: *limit}
+		if *actor != 0 {
+			filter.ActorID = actor
+		}
+		var err error
+		if filter.Since, err = parseAgo("since", *since); err != nil {
+			return err
+		}
+		if filter.Until, err = parseAgo("until", *until); err != nil {
+			return err
+		}
+		auditService, err := cmd.auditService()
+		if err != nil {
+			return err
+		}
+		events, err := auditService.GetAuditEvents(ctx, filter)
+		if err != nil {
+			return err
+		}
+		return cmd.print(auditTable(events...))
+
+	default:
+		return fmt.Errorf("%w: unknown audit command %q", errUsage, verb)
+	}
+}
+
+// parseAgo reads a time in the past, a time or a duration before now, empty
+// is the zero time
+func parseAgo(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: --%s %q is neither an RFC 3339 time nor a duration", errUsage, name, value)
+}
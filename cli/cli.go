@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/database"
+	"postgresql-blog/logging"
+	"postgresql-blog/mail"
+	"postgresql-blog/ratelimit"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+	"postgresql-blog/service"
+	"postgresql-blog/tracing"
+)
+
+// exit codes returned by Run
+const (
+	ExitOK        = 0
+	ExitError     = 1
+	ExitUsage     = 2
+	ExitNotExist  = 3
+	ExitDuplicate = 4
+	ExitForbidden = 5
+	ExitInvalid   = 6
+	// ExitUnavailable means the database could not be reached, running
+	// the command again later may work
+	ExitUnavailable = 7
+	// ExitRateLimited means the command was refused for now, the message
+	// tells when to try again
+	ExitRateLimited = 8
+)
+
+var errUsage = errors.New("invalid usage")
+
+const usage = `Usage: blog [options] <resource> <command> [arguments]
+
+Resources and commands:
+  users     list | get <id> | get --email EMAIL | create | update <id>
+            delete <id> | verify --token TOKEN | resend-verification
+            reset-password --email EMAIL | --token TOKEN --new-password PASS
+            2fa-enroll | 2fa-confirm --code CODE | 2fa-disable --code CODE
+            sso-login | sso-link | sso-unlink [--provider NAME] | identities
+            deactivate | export --out PATH | erase <id> [--content keep|remove]
+            2fa-reset <id> | suspend <id> --until TIME | ban <id>
+            reactivate <id>
+  posts     list [--mine] | get <id> | get --title TITLE | create | update <id>
+            delete <id> | revisions <id> | diff <id> --from N --to N [--words]
+            restore <id> --revision N
+  drafts    list | get | save | discard | apply [--force] [--publish]
+  comments  list [--post ID [--sort SORT]] [--mine] | get <id>
+            create --post ID | update <id> | delete <id> | vote <id>
+  reactions toggle --post ID|--comment ID [--kind KIND] | kinds
+            list --post ID|--comment ID [--kind KIND] [--after ID] [--limit N]
+  profiles  show <username> [--posts] | get | update
+            avatar --file PATH | --remove
+  audit     list [--actor ID] [--entity TYPE [--id ID]] [--since TIME]
+            [--until TIME] [--limit N]
+
+Options (accepted before or after the command):
+  --output FORMAT     table, json, yaml or csv (default table)
+  --driver NAME       postgres, pgx or sqlite (env BLOG_DB_DRIVER)
+  --dsn DSN           connection string or sqlite file (env BLOG_DSN)
+  --username NAME     login used for posts and comments (env BLOG_USERNAME)
+  --password PASS     password for --username (env BLOG_PASSWORD)
+  --token-file PATH   file containing "username:password" (env BLOG_TOKEN_FILE)
+  --otp CODE          authenticator or recovery code of the login (env BLOG_OTP)
+  --demo              use an in-memory blog with sample data, not a database
+
+Run "blog <resource> <command> --help" for the details of a command, "blog"
+without arguments for the interactive prompt, "blog tui" for the full screen
+interface or "blog serve" for the HTTP server.
+`
+
+// commandHelp explains the commands whose flags do not say it all, --help
+// shows it above the flags
+var commandHelp = map[string]string{
+	"users list":       "Email addresses are only shown to their users, give the login options to see\nyour own.",
+	"users get":        "Email addresses are only shown to their users, give the login options to see\nyour own.",
+	"users update":     "Changing the password takes the current one in --password. A new email address\nreplaces a confirmed one once the mailed token confirms it.",
+	"users sso-login":  "Signs in with the provider in BLOG_OIDC_ISSUER, BLOG_OIDC_CLIENT_ID and\nBLOG_OIDC_CLIENT_SECRET, the first sign-in creates the user. Users with\ntwo-factor authentication pass --otp.",
+	"users sso-link":   "Links the account at the provider in BLOG_OIDC_ISSUER to the login.",
+	"users 2fa-reset":  "Removes the second factor of a user who lost it. Only the operators, the users\nwith their IDs in BLOG_OPERATORS, may.",
+	"users suspend":    "Only the operators, the users with their IDs in BLOG_OPERATORS, may.",
+	"users ban":        "Only the operators, the users with their IDs in BLOG_OPERATORS, may.",
+	"users reactivate": "Only the operators, the users with their IDs in BLOG_OPERATORS, may.",
+	"users erase":      "Users erase themselves, the operators, the users with their IDs in\nBLOG_OPERATORS, anybody.",
+	"posts revisions":  "Every post keeps its last BLOG_POST_REVISIONS revisions (default 50, 0 keeps\nall). The revisions of an unpublished post are shown to its author, give the\nlogin options.",
+	"posts diff":       "The revisions of an unpublished post are shown to its author, give the login\noptions.",
+	"comments list":    "top ranks the comments by the lower bound of their share of upvotes,\ncontroversial ones have many votes split evenly between up and down.",
+	"reactions toggle": "Besides a like the users react with the comma separated emoji in\nBLOG_REACTIONS, \"none\" allows likes only.",
+	"reactions kinds":  "Besides a like the users react with the comma separated emoji in\nBLOG_REACTIONS, \"none\" allows likes only.",
+	"profiles show":    "The email address is only shown when its user made it public.",
+	"profiles update":  "Email addresses are only shown to their users, --email-public shows yours to\neverybody.",
+}
+
+type options struct {
+	output    string
+	driver    string
+	dsn       string
+	username  string
+	password  string
+	tokenFile string
+	otp       string
+	demo      bool
+}
+
+// defaults come from the environment, flags override them
+func defaultOptions() *options {
+	return &options{
+		output:    envOr("BLOG_OUTPUT", OutputTable),
+		driver:    os.Getenv("BLOG_DB_DRIVER"),
+		dsn:       os.Getenv("BLOG_DSN"),
+		username:  os.Getenv("BLOG_USERNAME"),
+		password:  os.Getenv("BLOG_PASSWORD"),
+		tokenFile: os.Getenv("BLOG_TOKEN_FILE"),
+		otp:       os.Getenv("BLOG_OTP"),
+		demo:      os.Getenv("BLOG_DEMO") != "",
+	}
+}
+
+type command struct {
+	// ctx is the operation of the command in the logs
+	ctx    context.Context
+	opts   *options
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	store  repository.Store
+	// limits keeps the rate limits of store
+	limits ratelimit.Store
+	// closeStore closes the connections of store
+	closeStore func()
+}
+
+// Run executes the command line in args (without the program name) and
+// returns the process exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cmd := &command{
+		ctx:    logging.WithOperation(context.Background()),
+		opts:   defaultOptions(),
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	defer cmd.close()
+	return cmd.exec(args)
+}
+
+// exec runs the command line in args, tells stderr what went wrong and
+// returns the exit code
+func (cmd *command) exec(args []string) int {
+	err := cmd.run(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(cmd.stderr, "error:", Message(err))
+	}
+	return ExitCode(err)
+}
+
+// ExitCode maps an error returned by a command to the process exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, errUsage):
+		return ExitUsage
+	}
+	switch apperr.KindOf(err) {
+	case apperr.NotFound:
+		return ExitNotExist
+	case apperr.Conflict:
+		return ExitDuplicate
+	case apperr.Forbidden:
+		return ExitForbidden
+	case apperr.Validation:
+		return ExitInvalid
+	case apperr.Unavailable:
+		return ExitUnavailable
+	case apperr.RateLimited:
+		return ExitRateLimited
+	default:
+		return ExitError
+	}
+}
+
+// Message returns what the user is told about an error of a command
+func Message(err error) string {
+	if apperr.Is(err, apperr.Unavailable) {
+		return fmt.Sprintf("%v (is the database running? see --driver and --dsn)", err)
+	}
+	return err.Error()
+}
+
+func (cmd *command) run(args []string) error {
+	// only the options before the resource are parsed here, the ones after
+	// the command are parsed together with the command flags
+	fs := cmd.flagSet("blog")
+	fs.Usage = func() { fmt.Fprint(cmd.stderr, usage) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	rest := fs.Args()
+	if len(rest) < 2 {
+		fmt.Fprint(cmd.stderr, usage)
+		return fmt.Errorf("%w: missing resource or command", errUsage)
+	}
+
+	resource, verb, rest := rest[0], rest[1], rest[2:]
+	switch resource {
+	case "users", "user":
+		return cmd.users(verb, rest)
+	case "posts", "post":
+		return cmd.posts(verb, rest)
+	case "comments", "comment":
+		return cmd.comments(verb, rest)
+	case "profiles", "profile":
+		return cmd.profiles(verb, rest)
+	case "drafts", "draft":
+		return cmd.drafts(verb, rest)
+	case "reactions", "reaction":
+		return cmd.reactions(verb, rest)
+	case "audit":
+		return cmd.audit(verb, rest)
+	default:
+		fmt.Fprint(cmd.stderr, usage)
+		return fmt.Errorf("%w: unknown resource %q", errUsage, resource)
+	}
+}
+
+// flagSet returns a flag set that already knows the global options, so they
+// can be given either before the resource or after the command.
+func (cmd *command) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(cmd.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(cmd.stderr, "Usage of %s:\n", name)
+		if help, ok := commandHelp[name]; ok {
+			fmt.Fprintf(cmd.stderr, "%s\n\n", help)
+		}
+		fs.PrintDefaults()
+	}
+
+	opts := cmd.opts
+	fs.StringVar(&opts.output, "output", opts.output, "output format: table, json, yaml or csv")
+	fs.StringVar(&opts.output, "o", opts.output, "shorthand for --output")
+	fs.StringVar(&opts.driver, "driver", opts.driver, "storage driver: postgres, pgx or sqlite")
+	fs.StringVar(&opts.dsn, "dsn", opts.dsn, "connection string, or the file of a sqlite database")
+	fs.StringVar(&opts.username, "username", opts.username, "username to log in with")
+	fs.StringVar(&opts.password, "password", opts.password, "password to log in with")
+	fs.StringVar(&opts.tokenFile, "token-file", opts.tokenFile, `file containing "username:password"`)
+	fs.StringVar(&opts.otp, "otp", opts.otp, "authenticator app or recovery code, for users with two-factor authentication")
+	fs.BoolVar(&opts.demo, "demo", opts.demo, "use an in-memory blog with sample data")
+	return fs
+}
+
+// parseFlags parses flags that may appear anywhere between the positional
+// arguments and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseCommand parses the flags of a command and validates the global
+// options that may have been given with it.
+func (cmd *command) parseCommand(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if !validOutput(cmd.opts.output) {
+		return nil, fmt.Errorf("%w: unknown output format %q", errUsage, cmd.opts.output)
+	}
+	return positional, nil
+}
+
+// backend returns the store the command works on, the database is only
+// opened by the first command that needs it
+func (cmd *command) backend() (repository.Store, error) {
+	if cmd.store != nil {
+		return cmd.store, nil
+	}
+	if cmd.opts.demo {
+		cmd.store, cmd.limits = memory.NewDemo(), ratelimit.NewMemory()
+		return cmd.store, nil
+	}
+
+	backend, err := database.OpenBackend(database.NewConfig(cmd.opts.driver, cmd.opts.dsn))
+	if err != nil {
+		return nil, err
+	}
+	cmd.store, cmd.limits, cmd.closeStore = backend.Store, backend.RateLimits, backend.Close
+	return cmd.store, nil
+}
+
+// services returns the services of the backend, their writes and logins are
+// limited and their calls traced. The log mailer writes to stderr of the
+// command.
+func (cmd *command) services() (service.Services, error) {
+	store, err := cmd.backend()
+	if err != nil {
+		return service.Services{}, err
+	}
+	mailCfg := mail.ConfigFromEnv()
+	mailCfg.Output = cmd.stderr
+	mailer, err := mail.New(mailCfg)
+	if err != nil {
+		return service.Services{}, err
+	}
+	limiter := ratelimit.New(cmd.limits, ratelimit.ConfigFromEnv())
+	services := service.New(store, service.Options{
+		Mailer:       mailer,
+		MaxRevisions: service.MaxRevisionsFromEnv(),
+		Reactions:    service.ReactionsFromEnv(),
+		Operators:    service.OperatorsFromEnv(),
+	})
+	return service.Intercept(limiter.Services(services), tracing.Intercept), nil
+}
+
+func (cmd *command) close() {
+	if cmd.closeStore != nil {
+		cmd.closeStore()
+	}
+}
+
+func (cmd *command) print(t *table) error {
+	return render(cmd.stdout, cmd.opts.output, t)
+}
+
+func parseID(args []string, name string) (int64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: expected exactly one %s id", errUsage, name)
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: invalid %s id %q", errUsage, name, args[0])
+	}
+	return id, nil
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/ratelimit"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+// execute runs the command line in args on store with the table output,
+// stdin reads input
+func execute(store repository.Store, input string, args ...string) (stdout, stderr string, code int) {
+	var out, errOut strings.Builder
+	cmd := &command{
+		ctx:    context.Background(),
+		opts:   &options{output: OutputTable},
+		stdin:  strings.NewReader(input),
+		stdout: &out,
+		stderr: &errOut,
+		store:  store,
+		limits: ratelimit.NewMemory(),
+	}
+	code = cmd.exec(args)
+	return out.String(), errOut.String(), code
+}
+
+func TestRevisionsOfUnpublishedPosts(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewDemo()
+	repos := store.Repositories()
+	// alice is writing a post that is not published yet
+	post, err := repos.Posts.CreatePost(ctx, models.Post{UserID: 1, Title: "secret", Content: "not yet", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, title := range []string{"secret", "still secret"} {
+		if _, err := repos.Revisions.CreateRevision(ctx, models.PostRevision{PostID: post.ID, Title: title, Content: "not yet"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	id := strconv.FormatInt(post.ID, 10)
+
+	for _, args := range [][]string{
+		{"posts", "revisions", id},
+		{"posts", "diff", id, "--from", "1", "--to", "2"},
+		{"posts", "revisions", id, "--username", "bob", "--password", "demo"},
+	} {
+		stdout, _, code := execute(store, "", args...)
+		if code != ExitNotExist || strings.Contains(stdout, "secret") {
+			t.Fatalf("%v exited with %d and printed %q, want %d and nothing", args, code, stdout, ExitNotExist)
+		}
+	}
+
+	stdout, _, code := execute(store, "", "posts", "diff", id, "--from", "1", "--to", "2", "--username", "alice", "--password", "demo")
+	if code != ExitOK || !strings.Contains(stdout, "still secret") {
+		t.Fatalf("the author got exit code %d and %q, want the diff", code, stdout)
+	}
+	if _, _, code := execute(store, "", "posts", "revisions", "1"); code != ExitOK {
+		t.Fatalf("the revisions of a published post exited with %d", code)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"ok", []string{"posts", "get", "1"}, ExitOK},
+		{"help", []string{"--help"}, ExitOK},
+		{"missing post", []string{"posts", "get", "99"}, ExitNotExist},
+		{"missing user", []string{"users", "get", "99"}, ExitNotExist},
+		{"taken username", []string{"users", "create", "--name", "Alice", "--email", "alice@elsewhere", "--username", "alice", "--password", "x"}, ExitDuplicate},
+		{"unknown resource", []string{"frobnicate"}, ExitUsage},
+		{"unknown command", []string{"posts", "frobnicate"}, ExitUsage},
+		{"unknown flag", []string{"posts", "list", "--frobnicate"}, ExitUsage},
+		{"missing id", []string{"posts", "get"}, ExitUsage},
+		{"missing credentials", []string{"posts", "delete", "1"}, ExitUsage},
+		{"post of another user", []string{"posts", "delete", "3", "--username", "alice", "--password", "demo"}, ExitForbidden},
+		{"wrong password", []string{"posts", "delete", "1", "--username", "alice", "--password", "wrong"}, ExitForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, code := execute(memory.NewDemo(), "", tt.args...)
+			if code != tt.want {
+				t.Fatalf("%v exited with %d, want %d, stderr:\n%s", tt.args, code, tt.want, stderr)
+			}
+			if code != ExitOK && stderr == "" {
+				t.Fatalf("%v failed without telling why, stderr:\n%s", tt.args, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+func (cmd *command) commentService() (service.Comments, error) {
+	services, err := cmd.services()
+	if err != nil {
+		return nil, err
+	}
+	return services.Comments, nil
+}
+
+// score prints the ranking of a comment rounded, the JSON output keeps all
+// of it
+type score float64
+
+func (s score) String() string {
+	return strconv.FormatFloat(float64(s), 'f', 3, 64)
+}
+
+func commentTable(comments ...models.Comment) *table {
+	t := newTable("id", "user_id", "post_id", "content", "published", "created_at", "up", "down", "score", "reactions")
+	for _, comment := range comments {
+		t.add(comment.ID, comment.UserID, comment.PostID, comment.Content, comment.IsPublished, comment.CreatedAt,
+			comment.Upvotes, comment.Downvotes, score(comment.Score), reactionCounts(comment.Reactions))
+	}
+	return t
+}
+
+func (cmd *command) comments(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("comments " + verb)
+
+	switch verb {
+	case "list":
+		postID := fs.Int64("post", 0, "only list the comments of this post")
+		mine := fs.Bool("mine", false, "only list the comments of the logged in user")
+		sort := fs.String("sort", "", "order of the comments of --post: old, new, top or controversial (default old)")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *sort != "" && *postID == 0 {
+			return fmt.Errorf("%w: --sort needs --post", errUsage)
+		}
+		var userID int64
+		if *mine {
+			_, user, err := cmd.login(ctx)
+			if err != nil {
+				return err
+			}
+			userID = user.ID
+		}
+		commentService, err := cmd.commentService()
+		if err != nil {
+			return err
+		}
+
+		var all []models.Comment
+		switch {
+		case *postID != 0:
+			all, err = commentService.GetCommentByPostID(ctx, *postID, repository.CommentSort(*sort))
+		case *mine:
+			all, err = commentService.GetCommentByUserID(ctx, userID)
+		default:
+			all, err = commentService.GetAllComments(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		if *postID != 0 && *mine {
+			all = filterComments(all, userID)
+		}
+		return cmd.print(commentTable(all...))
+
+	case "get":
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "comment")
+		if err != nil {
+			return err
+		}
+		commentService, err := cmd.commentService()
+		if err != nil {
+			return err
+		}
+		comment, err := commentService.GetCommentByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return cmd.print(commentTable(*comment))
+
+	case "create":
+		postID := fs.Int64("post", 0, "id of the post to comment on")
+		content := fs.String("content", "", "content of the comment")
+		contentFile := fs.String("content-file", "", `read the content from a file, "-" for stdin`)
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *postID <= 0 {
+			return fmt.Errorf("%w: --post is required", errUsage)
+		}
+		body, err := cmd.readContent(*content, *contentFile)
+		if err != nil {
+			return err
+		}
+		if body == "" {
+			return fmt.Errorf("%w: --content or --content-file is required", errUsage)
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		if _, err := postService.GetPostByID(ctx, *postID); err != nil {
+			return err
+		}
+		commentService, err := cmd.commentService()
+		if err != nil {
+			return err
+		}
+		created, err := commentService.CreateComment(ctx, models.Comment{
+			UserID:  uint64(user.ID),
+			PostID:  uint64(*postID),
+			Content: body,
+		})
+		if err != nil {
+			return err
+		}
+		return cmd.print(commentTable(*created))
+
+	case "update":
+		content := fs.String("content", "", "new content")
+		contentFile := fs.String("content-file", "", `read the new content from a file, "-" for stdin`)
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "comment")
+		if err != nil {
+			return err
+		}
+		body, err := cmd.readContent(*content, *contentFile)
+		if err != nil {
+			return err
+		}
+		ctx, comment, commentService, err := cmd.ownComment(ctx, id)
+		if err != nil {
+			return err
+		}
+		updated := *comment
+		setIfGiven(&updated.Content, body)
+		updated.UpdatedAt = time.Now()
+		if _, err := commentService.UpdateCommentByID(ctx, updated); err != nil {
+			return err
+		}
+		return cmd.print(commentTable(updated))
+
+	case "delete":
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "comment")
+		if err != nil {
+			return err
+		}
+		ctx, _, commentService, err := cmd.ownComment(ctx, id)
+		if err != nil {
+			return err
+		}
+		return commentService.DeleteCommentByID(ctx, id)
+
+	case "vote":
+		down := fs.Bool("down", false, "vote the comment down instead of up")
+		unvote := fs.Bool("clear", false, "take the vote back")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "comment")
+		if err != nil {
+			return err
+		}
+		if *down && *unvote {
+			return fmt.Errorf("%w: give either --down or --clear", errUsage)
+		}
+		value := models.VoteUp
+		switch {
+		case *down:
+			value = models.VoteDown
+		case *unvote:
+			value = 0
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		commentService, err := cmd.commentService()
+		if err != nil {
+			return err
+		}
+		comment, err := commentService.VoteComment(ctx, id, user.ID, value)
+		if err != nil {
+			return err
+		}
+		return cmd.print(commentTable(*comment))
+
+	default:
+		return fmt.Errorf("%w: unknown comments command %q", errUsage, verb)
+	}
+}
+
+// ownComment returns the comment with the given id if it belongs to the
+// logged in user, and the context acting as that user
+func (cmd *command) ownComment(ctx context.Context, id int64) (context.Context, *models.Comment, service.Comments, error) {
+	ctx, user, err := cmd.login(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	commentService, err := cmd.commentService()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	comment, err := commentService.GetCommentByID(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if comment.UserID != uint64(user.ID) {
+		return nil, nil, nil, apperr.New(apperr.Forbidden, "comment", fmt.Sprintf("comment %d belongs to another user", id))
+	}
+	return ctx, comment, commentService, nil
+}
+
+func filterComments(comments []models.Comment, userID int64) []models.Comment {
+	var result []models.Comment
+	for _, comment := range comments {
+		if comment.UserID == uint64(userID) {
+			result = append(result, comment)
+		}
+	}
+	return result
+}
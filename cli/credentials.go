@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+var errNoCredentials = errors.New("this command needs --username/--password, BLOG_USERNAME/BLOG_PASSWORD or --token-file")
+
+// credentials returns the username and password to log in with. Flags and
+// environment variables win over the token file.
+func (cmd *command) credentials() (string, string, error) {
+	username, password := cmd.opts.username, cmd.opts.password
+	if username != "" && password != "" {
+		return username, password, nil
+	}
+	if cmd.opts.tokenFile == "" {
+		return "", "", fmt.Errorf("%w: %v", errUsage, errNoCredentials)
+	}
+
+	data, err := os.ReadFile(cmd.opts.tokenFile)
+	if err != nil {
+		return "", "", fmt.Errorf("reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	fileUser, filePassword, ok := strings.Cut(token, ":")
+	if !ok || fileUser == "" || filePassword == "" {
+		return "", "", fmt.Errorf("%w: token file %s must contain \"username:password\"", errUsage, cmd.opts.tokenFile)
+	}
+	if username != "" && username != fileUser {
+		return "", "", fmt.Errorf("%w: --username %q does not match the token file", errUsage, username)
+	}
+	return fileUser, filePassword, nil
+}
+
+// login returns the user the command runs as, and ctx acting as that user
+// so the audit log knows who made the changes
+func (cmd *command) login(ctx context.Context) (context.Context, *models.User, error) {
+	username, password, err := cmd.credentials()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userService, err := cmd.userService()
+	if err != nil {
+		return nil, nil, err
+	}
+	var user *models.User
+	if cmd.opts.otp != "" {
+		user, err = userService.LoginWithCode(ctx, username, password, cmd.opts.otp)
+	} else {
+		user, err = userService.GetUserByUsernameAndPassword(ctx, username, password)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotExist) {
+			return nil, nil, &apperr.Error{Kind: apperr.Forbidden, Code: "bad_credentials", Entity: "user", Message: "wrong username or password"}
+		}
+		return nil, nil, otpError(err)
+	}
+	return repository.WithActor(ctx, user.ID), user, nil
+}
+
+// otpError tells how to pass the code when a login needs one
+func otpError(err error) error {
+	if errors.Is(err, service.ErrTOTPRequired) {
+		return &apperr.Error{Kind: apperr.Forbidden, Code: "totp_required", Entity: "user", Message: "this user has two-factor authentication, pass --otp or set BLOG_OTP", Err: err}
+	}
+	return err
+}
+
+// readContent returns the inline content or the content of file, where "-"
+// reads standard input
+func (cmd *command) readContent(inline, file string) (string, error) {
+	if inline != "" && file != "" {
+		return "", fmt.Errorf("%w: use either --content or --content-file", errUsage)
+	}
+	if file == "" {
+		return inline, nil
+	}
+
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(cmd.stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading content: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
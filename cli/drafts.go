@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+func draftTable(drafts ...models.Draft) *table {
+	t := newTable("post_id", "title", "content", "thumbnail", "base_revision", "updated_at")
+	for _, draft := range drafts {
+		t.add(draft.PostID, draft.Title, draft.Content, draft.Thumbnail, draft.BaseRevision, draft.UpdatedAt)
+	}
+	return t
+}
+
+// warn prints the warnings of a draft to stderr, the output stays the draft
+func (cmd *command) warn(state *service.DraftState) {
+	for _, warning := range state.Warnings() {
+		fmt.Fprintln(cmd.stderr, "warning:", warning)
+	}
+}
+
+func (cmd *command) drafts(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("drafts " + verb)
+
+	switch verb {
+	case "list":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		drafts, err := postService.GetDrafts(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		return cmd.print(draftTable(drafts...))
+
+	case "get":
+		postID := fs.Int64("post", 0, "id of the post, leave out for the draft of a new post")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		state, err := postService.GetDraft(ctx, user.ID, *postID)
+		if err != nil {
+			return err
+		}
+		cmd.warn(state)
+		return cmd.print(draftTable(state.Draft))
+
+	case "save":
+		postID := fs.Int64("post", 0, "id of the post, leave out for the draft of a new post")
+		title := fs.String("title", "", "title of the post")
+		content := fs.String("content", "", "content of the post")
+		contentFile := fs.String("content-file", "", `read the content from a file, "-" for stdin`)
+		thumbnail := fs.String("thumbnail", "", "thumbnail url")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		body, err := cmd.readContent(*content, *contentFile)
+		if err != nil {
+			return err
+		}
+		ctx, draft, postService, err := cmd.startDraft(ctx, *postID)
+		if err != nil {
+			return err
+		}
+		setIfGiven(&draft.Title, *title)
+		setIfGiven(&draft.Content, body)
+		setIfGiven(&draft.Thumbnail, *thumbnail)
+		state, err := postService.SaveDraft(ctx, *draft)
+		if err != nil {
+			return err
+		}
+		cmd.warn(state)
+		return cmd.print(draftTable(state.Draft))
+
+	case "discard":
+		postID := fs.Int64("post", 0, "id of the post, leave out for the draft of a new post")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		return postService.DiscardDraft(ctx, user.ID, *postID)
+
+	case "apply":
+		postID := fs.Int64("post", 0, "id of the post, leave out to create the post of a new draft")
+		force := fs.Bool("force", false, "overwrite the changes made to the post since the draft was started")
+		publish := fs.Bool("publish", false, "publish the post")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		post, err := postService.ApplyDraft(ctx, user.ID, *postID, service.ApplyDraftOptions{Force: *force, Publish: *publish})
+		if err != nil {
+			return err
+		}
+		return cmd.print(postTable(*post))
+
+	default:
+		return fmt.Errorf("%w: unknown drafts command %q", errUsage, verb)
+	}
+}
+
+// startDraft returns the saved draft of the logged in user for a post, or a
+// new one with the post as it is. Only the own posts get drafts.
+func (cmd *command) startDraft(ctx context.Context, postID int64) (context.Context, *models.Draft, service.Posts, error) {
+	draft := &models.Draft{PostID: postID}
+	var postService service.Posts
+	var err error
+	if postID != 0 {
+		var post *models.Post
+		if ctx, post, postService, err = cmd.ownPost(ctx, postID); err != nil {
+			return nil, nil, nil, err
+		}
+		// the post is of the logged in user
+		draft.UserID = int64(post.UserID)
+		draft.Title, draft.Content, draft.Thumbnail = post.Title, post.Content, post.Thumbnail
+	} else {
+		var user *models.User
+		if ctx, user, err = cmd.login(ctx); err != nil {
+			return nil, nil, nil, err
+		}
+		draft.UserID = user.ID
+		if postService, err = cmd.postService(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	state, err := postService.GetDraft(ctx, draft.UserID, postID)
+	switch {
+	case err == nil:
+		return ctx, &state.Draft, postService, nil
+	case errors.Is(err, repository.ErrNotExist):
+		return ctx, draft, postService, nil
+	default:
+		return nil, nil, nil, err
+	}
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+)
+
+// table is the format independent result of a command, columns are kept in
+// order so every output format prints the fields the same way
+type table struct {
+	columns []string
+	rows    [][]any
+}
+
+func newTable(columns ...string) *table {
+	return &table{columns: columns}
+}
+
+func (t *table) add(values ...any) {
+	t.rows = append(t.rows, values)
+}
+
+func validOutput(format string) bool {
+	switch format {
+	case OutputTable, OutputJSON, OutputYAML, OutputCSV:
+		return true
+	}
+	return false
+}
+
+func render(w io.Writer, format string, t *table) error {
+	switch format {
+	case OutputJSON:
+		return renderJSON(w, t)
+	case OutputYAML:
+		return renderYAML(w, t)
+	case OutputCSV:
+		return renderCSV(w, t)
+	default:
+		return renderTable(w, t)
+	}
+}
+
+func renderTable(w io.Writer, t *table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.columns, "\t")))
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			// keep long content on one line so the columns stay aligned
+			cells[i] = strings.ReplaceAll(formatValue(value), "\n", " ")
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func renderCSV(w io.Writer, t *table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.columns); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cells[i] = formatValue(value)
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func renderJSON(w io.Writer, t *table) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, row := range t.rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, value := range row {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			key, _ := json.Marshal(t.columns[j])
+			val, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "\n    %s: %s", key, val)
+		}
+		b.WriteString("\n  }")
+	}
+	if len(t.rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderYAML(w io.Writer, t *table) error {
+	if len(t.rows) == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+
+	var b strings.Builder
+	for _, row := range t.rows {
+		for j, value := range row {
+			prefix := "  "
+			if j == 0 {
+				prefix = "- "
+			}
+			fmt.Fprintf(&b, "%s%s: %s\n", prefix, t.columns[j], yamlValue(value))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// strings are always quoted so values like "yes", "123" or "a: b" keep
+// their type when the output is parsed back
+func yamlValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case int, int64, uint64:
+		return fmt.Sprint(v)
+	default:
+		return strconv.Quote(formatValue(v))
+	}
+}
@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/diff"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+func (cmd *command) postService() (service.Posts, error) {
+	services, err := cmd.services()
+	if err != nil {
+		return nil, err
+	}
+	return services.Posts, nil
+}
+
+func postTable(posts ...models.Post) *table {
+	t := newTable("id", "user_id", "title", "content", "thumbnail", "published", "created_at", "reactions")
+	for _, post := range posts {
+		t.add(post.ID, post.UserID, post.Title, post.Content, post.Thumbnail, post.IsPublished, post.CreatedAt, reactionCounts(post.Reactions))
+	}
+	return t
+}
+
+func revisionTable(revisions ...models.PostRevision) *table {
+	t := newTable("revision", "post_id", "title", "thumbnail", "editor_id", "restored_from", "created_at")
+	for _, revision := range revisions {
+		var editor any
+		if revision.EditorID != nil {
+			editor = *revision.EditorID
+		}
+		var restoredFrom any
+		if revision.RestoredFrom != 0 {
+			restoredFrom = revision.RestoredFrom
+		}
+		t.add(revision.Number, revision.PostID, revision.Title, revision.Thumbnail, editor, restoredFrom, revision.CreatedAt)
+	}
+	return t
+}
+
+// diffTable lists the edits of a diff for the formats other than table,
+// which prints the diff as text
+func diffTable(d *service.RevisionDiff) *table {
+	t := newTable("field", "op", "text")
+	for _, field := range []struct {
+		name  string
+		edits []diff.Edit
+	}{{"title", d.Title}, {"thumbnail", d.Thumbnail}, {"content", d.Content}} {
+		for _, edit := range field.edits {
+			op := "equal"
+			switch edit.Op {
+			case diff.Insert:
+				op = "insert"
+			case diff.Delete:
+				op = "delete"
+			}
+			t.add(field.name, op, strings.TrimSuffix(edit.Text, "\n"))
+		}
+	}
+	return t
+}
+
+func (cmd *command) posts(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("posts " + verb)
+
+	switch verb {
+	case "list":
+		mine := fs.Bool("mine", false, "only list the posts of the logged in user")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		var all []models.Post
+		if *mine {
+			ctx, user, err := cmd.login(ctx)
+			if err != nil {
+				return err
+			}
+			all, err = postService.GetPostByUserID(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+		} else {
+			all, err = postService.GetAllPosts(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return cmd.print(postTable(all...))
+
+	case "get":
+		title := fs.String("title", "", "find the post by title instead of id")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		var id int64
+		if *title == "" {
+			if id, err = parseID(rest, "post"); err != nil {
+				return err
+			}
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		var post *models.Post
+		if *title != "" {
+			post, err = postService.GetPostByTitle(ctx, *title)
+		} else {
+			post, err = postService.GetPostByID(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		return cmd.print(postTable(*post))
+
+	case "create":
+		title := fs.String("title", "", "title of the post")
+		content := fs.String("content", "", "content of the post")
+		contentFile := fs.String("content-file", "", `read the content from a file, "-" for stdin`)
+		thumbnail := fs.String("thumbnail", "", "thumbnail url")
+		publish := fs.Bool("publish", false, "publish the post right away")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *title == "" {
+			return fmt.Errorf("%w: --title is required", errUsage)
+		}
+		body, err := cmd.readContent(*content, *contentFile)
+		if err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		postService, err := cmd.postService()
+		if err != nil {
+			return err
+		}
+		newPost := models.Post{
+			UserID:      uint64(user.ID),
+			Title:       *title,
+			Content:     body,
+			Thumbnail:   *thumbnail,
+			IsPublished: *publish,
+		}
+		if *publish {
+			newPost.PublishedAt = time.Now()
+		}
+		created, err := postService.CreatePost(ctx, newPost)
+		if err != nil {
+			return err
+		}
+		return cmd.print(postTable(*created))
+
+	case "update":
+		title := fs.String("title", "", "new title")
+		content := fs.String("content", "", "new content")
+		contentFile := fs.String("content-file", "", `read the new content from a file, "-" for stdin`)
+		thumbnail := fs.String("thumbnail", "", "new thumbnail url")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "post")
+		if err != nil {
+			return err
+		}
+		body, err := cmd.readContent(*content, *contentFile)
+		if err != nil {
+			return err
+		}
+		ctx, post, postService, err := cmd.ownPost(ctx, id)
+		if err != nil {
+			return err
+		}
+		updated := *post
+		setIfGiven(&updated.Title, *title)
+		setIfGiven(&updated.Content, body)
+		setIfGiven(&updated.Thumbnail, *thumbnail)
+		updated.UpdatedAt = time.Now()
+		if _, err := postService.UpdatePostByID(ctx, updated); err != nil {
+			return err
+		}
+		return cmd.print(postTable(updated))
+
+	case "delete":
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "post")
+		if err != nil {
+			return err
+		}
+		ctx, _, postService, err := cmd.ownPost(ctx, id)
+		if err != nil {
+			return err
+		}
+		return postService.DeletePostByID(ctx, id)
+
+	case "revisions":
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "post")
+		if err != nil {
+			return err
+		}
+		ctx, postService, err := cmd.readablePost(ctx, id)
+		if err != nil {
+			return err
+		}
+		revisions, err := postService.GetPostRevisions(ctx, id)
+		if err != nil {
+			return err
+		}
+		return cmd.print(revisionTable(revisions...))
+
+	case "diff":
+		from := fs.Int("from", 0, "number of the older revision")
+		to := fs.Int("to", 0, "number of the newer revision")
+		words := fs.Bool("words", false, "compare word by word instead of line by line")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "post")
+		if err != nil {
+			return err
+		}
+		if *from == 0 || *to == 0 {
+			return fmt.Errorf("%w: --from and --to are required", errUsage)
+		}
+		ctx, postService, err := cmd.readablePost(ctx, id)
+		if err != nil {
+			return err
+		}
+		d, err := postService.DiffPostRevisions(ctx, id, *from, *to, *words)
+		if err != nil {
+			return err
+		}
+		if cmd.opts.output == OutputTable {
+			_, err := fmt.Fprint(cmd.stdout, d)
+			return err
+		}
+		return cmd.print(diffTable(d))
+
+	case "restore":
+		number := fs.Int("revision", 0, "number of the revision to restore")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "post")
+		if err != nil {
+			return err
+		}
+		if *number == 0 {
+			return fmt.Errorf("%w: --revision is required", errUsage)
+		}
+		ctx, _, postService, err := cmd.ownPost(ctx, id)
+		if err != nil {
+			return err
+		}
+		restored, err := postService.RestorePostRevision(ctx, id, *number)
+		if err != nil {
+			return err
+		}
+		return cmd.print(postTable(*restored))
+
+	default:
+		return fmt.Errorf("%w: unknown posts command %q", errUsage, verb)
+	}
+}
+
+// ownPost returns the post with the given id if it belongs to the logged in
+// user, and the context acting as that user
+func (cmd *command) ownPost(ctx context.Context, id int64) (context.Context, *models.Post, service.Posts, error) {
+	ctx, user, err := cmd.login(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	postService, err := cmd.postService()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	post, err := postService.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if post.UserID != uint64(user.ID) {
+		return nil, nil, nil, apperr.New(apperr.Forbidden, "post", fmt.Sprintf("post %d belongs to another user", id))
+	}
+	return ctx, post, postService, nil
+}
+
+// readablePost returns ctx on behalf of the viewer and the post service when
+// the revisions of post id may be read. Like the API hides the drafts, the
+// revisions of an unpublished post are for its author only.
+func (cmd *command) readablePost(ctx context.Context, id int64) (context.Context, service.Posts, error) {
+	ctx, err := cmd.viewer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	postService, err := cmd.postService()
+	if err != nil {
+		return nil, nil, err
+	}
+	post, err := postService.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actor, ok := repository.ActorFromContext(ctx); !post.IsPublished && (!ok || uint64(actor) != post.UserID) {
+		return nil, nil, apperr.New(apperr.NotFound, "post", "")
+	}
+	return ctx, postService, nil
+}
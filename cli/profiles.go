@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"postgresql-blog/models"
+	"postgresql-blog/service"
+)
+
+func (cmd *command) profileService() (service.Profiles, error) {
+	services, err := cmd.services()
+	if err != nil {
+		return nil, err
+	}
+	return services.Profiles, nil
+}
+
+// viewer returns ctx on behalf of the logged in user when credentials are
+// given, users see their own email address then
+func (cmd *command) viewer(ctx context.Context) (context.Context, error) {
+	if cmd.opts.username == "" && cmd.opts.tokenFile == "" {
+		return ctx, nil
+	}
+	ctx, _, err := cmd.login(ctx)
+	return ctx, err
+}
+
+func formatLinks(links []models.Link) string {
+	parts := make([]string, len(links))
+	for i, link := range links {
+		parts[i] = link.Label + "=" + link.URL
+	}
+	return strings.Join(parts, ", ")
+}
+
+func profileTable(profile models.Profile) *table {
+	t := newTable("user_id", "display_name", "bio", "website", "links", "timezone", "locale", "email_public")
+	t.add(profile.UserID, profile.DisplayName, profile.Bio, profile.Website, formatLinks(profile.Links),
+		profile.Timezone, profile.Locale, profile.EmailPublic)
+	return t
+}
+
+func publicProfileTable(profile service.PublicProfile) *table {
+	t := newTable("user_id", "username", "display_name", "email", "bio", "website", "links", "timezone", "locale",
+		"avatar", "posts", "comments")
+	t.add(profile.UserID, profile.Username, profile.DisplayName, profile.Email, profile.Bio, profile.Website,
+		formatLinks(profile.Links), profile.Timezone, profile.Locale, profile.HasAvatar, len(profile.Posts), profile.CommentCount)
+	return t
+}
+
+// linksFlag collects "label=url" links, an empty value removes all links
+type linksFlag struct {
+	links []models.Link
+}
+
+func (f *linksFlag) String() string {
+	return formatLinks(f.links)
+}
+
+func (f *linksFlag) Set(value string) error {
+	if value == "" {
+		f.links = []models.Link{}
+		return nil
+	}
+	label, url, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected label=url, got %q", value)
+	}
+	f.links = append(f.links, models.Link{Label: label, URL: url})
+	return nil
+}
+
+func (cmd *command) profiles(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("profiles " + verb)
+
+	switch verb {
+	case "show":
+		posts := fs.Bool("posts", false, "list the published posts of the user instead")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(rest) != 1 {
+			return fmt.Errorf("%w: expected exactly one username", errUsage)
+		}
+		ctx, err := cmd.viewer(ctx)
+		if err != nil {
+			return err
+		}
+		profileService, err := cmd.profileService()
+		if err != nil {
+			return err
+		}
+		profile, err := profileService.GetPublicProfile(ctx, rest[0])
+		if err != nil {
+			return err
+		}
+		if *posts {
+			return cmd.print(postTable(profile.Posts...))
+		}
+		return cmd.print(publicProfileTable(*profile))
+
+	case "get":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		profileService, err := cmd.profileService()
+		if err != nil {
+			return err
+		}
+		profile, err := profileService.GetProfile(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		return cmd.print(profileTable(*profile))
+
+	case "update":
+		displayName := fs.String("display-name", "", "name shown instead of the username")
+		bio := fs.String("bio", "", "about you, in Markdown")
+		bioFile := fs.String("bio-file", "", `file to read the bio from, "-" for standard input`)
+		website := fs.String("website", "", "URL of your website")
+		var links linksFlag
+		fs.Var(&links, "link", `link as label=url, repeat for more links, an empty value removes them`)
+		timezone := fs.String("timezone", "", "IANA timezone like Europe/Berlin")
+		locale := fs.String("locale", "", "language tag like en-US")
+		emailPublic := fs.Bool("email-public", false, "show your email address to everybody")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		profileService, err := cmd.profileService()
+		if err != nil {
+			return err
+		}
+		profile, err := profileService.GetProfile(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		// only the flags that are given change the profile, so an empty
+		// value clears a field
+		var readErr error
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "display-name":
+				profile.DisplayName = *displayName
+			case "bio", "bio-file":
+				profile.Bio, readErr = cmd.readBio(*bio, *bioFile)
+			case "website":
+				profile.Website = *website
+			case "link":
+				profile.Links = links.links
+			case "timezone":
+				profile.Timezone = *timezone
+			case "locale":
+				profile.Locale = *locale
+			case "email-public":
+				profile.EmailPublic = *emailPublic
+			}
+		})
+		if readErr != nil {
+			return readErr
+		}
+		updated, err := profileService.UpdateProfile(ctx, *profile)
+		if err != nil {
+			return err
+		}
+		return cmd.print(profileTable(*updated))
+
+	case "avatar":
+		file := fs.String("file", "", `PNG, JPEG, GIF or WebP image, "-" for standard input`)
+		remove := fs.Bool("remove", false, "remove the avatar")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if (*file == "") == !*remove {
+			return fmt.Errorf("%w: use either --file or --remove", errUsage)
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		profileService, err := cmd.profileService()
+		if err != nil {
+			return err
+		}
+		if *remove {
+			return profileService.DeleteAvatar(ctx, user.ID)
+		}
+		var data []byte
+		if *file == "-" {
+			data, err = io.ReadAll(io.LimitReader(cmd.stdin, service.MaxAvatarSize+1))
+		} else {
+			data, err = os.ReadFile(*file)
+		}
+		if err != nil {
+			return fmt.Errorf("reading avatar: %w", err)
+		}
+		return profileService.SetAvatar(ctx, user.ID, data)
+
+	default:
+		return fmt.Errorf("%w: unknown profiles command %q", errUsage, verb)
+	}
+}
+
+// readBio is readContent for the bio flags
+func (cmd *command) readBio(inline, file string) (string, error) {
+	if inline != "" && file != "" {
+		return "", fmt.Errorf("%w: use either --bio or --bio-file", errUsage)
+	}
+	return cmd.readContent(inline, file)
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+func (cmd *command) reactionService() (service.Reactions, error) {
+	services, err := cmd.services()
+	if err != nil {
+		return nil, err
+	}
+	return services.Reactions, nil
+}
+
+// reactionCounts prints the counts of a post or comment as "like 2, 👍 1",
+// the JSON output keeps them an object
+type reactionCounts map[string]int
+
+func (c reactionCounts) String() string {
+	kinds := make([]string, 0, len(c))
+	for kind := range c {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s %d", kind, c[kind])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func reactorTable(reactors ...repository.Reactor) *table {
+	t := newTable("id", "user_id", "username", "kind", "created_at")
+	for _, reactor := range reactors {
+		t.add(reactor.ID, reactor.UserID, reactor.Username, reactor.Kind, reactor.CreatedAt)
+	}
+	return t
+}
+
+// reactionTarget reads --post and --comment, exactly one of them is given
+func reactionTarget(postID, commentID int64) (string, int64, error) {
+	switch {
+	case postID != 0 && commentID != 0:
+		return "", 0, fmt.Errorf("%w: give either --post or --comment", errUsage)
+	case postID != 0:
+		return models.TargetPost, postID, nil
+	case commentID != 0:
+		return models.TargetComment, commentID, nil
+	}
+	return "", 0, fmt.Errorf("%w: --post or --comment is required", errUsage)
+}
+
+func (cmd *command) reactions(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("reactions " + verb)
+
+	switch verb {
+	case "toggle":
+		postID := fs.Int64("post", 0, "id of the post to react to")
+		commentID := fs.Int64("comment", 0, "id of the comment to react to")
+		kind := fs.String("kind", models.ReactionLike, `the reaction, "like" or one of "reactions kinds"`)
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		targetType, targetID, err := reactionTarget(*postID, *commentID)
+		if err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		reactionService, err := cmd.reactionService()
+		if err != nil {
+			return err
+		}
+		toggle, err := reactionService.ToggleReaction(ctx, models.Reaction{
+			UserID:     user.ID,
+			TargetType: targetType,
+			TargetID:   targetID,
+			Kind:       *kind,
+		})
+		if err != nil {
+			return err
+		}
+		t := newTable("target_type", "target_id", "kind", "added", "reactions")
+		t.add(targetType, targetID, toggle.Reaction.Kind, toggle.Added, reactionCounts(toggle.Counts))
+		return cmd.print(t)
+
+	case "list":
+		postID := fs.Int64("post", 0, "list the reactions to this post")
+		commentID := fs.Int64("comment", 0, "list the reactions to this comment")
+		kind := fs.String("kind", "", "only list this reaction")
+		after := fs.Int64("after", 0, "start after the reaction with this id, the next page printed by the page before")
+		limit := fs.Int("limit", service.DefaultReactionPage, fmt.Sprintf("most reactions to list, at most %d", service.MaxReactionPage))
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		targetType, targetID, err := reactionTarget(*postID, *commentID)
+		if err != nil {
+			return err
+		}
+		reactionService, err := cmd.reactionService()
+		if err != nil {
+			return err
+		}
+		page, err := reactionService.GetReactions(ctx, repository.ReactionFilter{
+			TargetType: targetType,
+			TargetID:   targetID,
+			Kind:       *kind,
+			After:      *after,
+			Limit:      *limit,
+		})
+		if err != nil {
+			return err
+		}
+		if err := cmd.print(reactorTable(page.Reactors...)); err != nil {
+			return err
+		}
+		if page.Next != 0 {
+			// stderr keeps the output a clean list
+			fmt.Fprintf(cmd.stderr, "more with --after %d\n", page.Next)
+		}
+		return nil
+
+	case "kinds":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		reactionService, err := cmd.reactionService()
+		if err != nil {
+			return err
+		}
+		t := newTable("kind")
+		for _, kind := range reactionService.ReactionKinds() {
+			t.add(kind)
+		}
+		return cmd.print(t)
+
+	default:
+		return fmt.Errorf("%w: unknown reactions command %q", errUsage, verb)
+	}
+}
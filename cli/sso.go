@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"postgresql-blog/models"
+	"postgresql-blog/oidc"
+	"postgresql-blog/service"
+)
+
+// signIn runs the single sign-on of the provider in the BLOG_OIDC_*
+// variables, the user opens the printed URL in a browser
+func (cmd *command) signIn(ctx context.Context) (service.ExternalIdentity, error) {
+	cfg, err := oidc.ConfigFromEnv()
+	if err != nil {
+		return service.ExternalIdentity{}, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	client := oidc.New(cfg, nil)
+	claims, err := client.Login(ctx, func(url string) error {
+		fmt.Fprintf(cmd.stderr, "Open this URL in a browser to sign in:\n\n  %s\n\nWaiting for %s ...\n", url, client.RedirectURL())
+		return nil
+	})
+	if err != nil {
+		return service.ExternalIdentity{}, err
+	}
+	return service.ExternalIdentity{
+		Provider:      client.Provider(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
+
+// provider is the provider the identity commands are about by default
+func provider() string {
+	if name := os.Getenv("BLOG_OIDC_PROVIDER"); name != "" {
+		return name
+	}
+	return oidc.DefaultProvider
+}
+
+func identityTable(identities ...models.Identity) *table {
+	t := newTable("id", "provider", "subject", "email", "created_at", "last_used_at")
+	for _, identity := range identities {
+		var lastUsed any
+		if identity.LastUsedAt != nil {
+			lastUsed = *identity.LastUsedAt
+		}
+		t.add(identity.ID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, lastUsed)
+	}
+	return t
+}
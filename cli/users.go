@@ -0,0 +1,556 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/service"
+)
+
+func (cmd *command) userService() (service.Users, error) {
+	services, err := cmd.services()
+	if err != nil {
+		return nil, err
+	}
+	return services.Users, nil
+}
+
+func userTable(users ...models.User) *table {
+	t := newTable("id", "name", "email", "username", "verified", "status")
+	now := time.Now()
+	for _, user := range users {
+		t.add(user.ID, user.Name, user.Email, user.Username, user.EmailVerifiedAt != nil, user.State(now))
+	}
+	return t
+}
+
+func (cmd *command) users(verb string, args []string) error {
+	ctx := cmd.ctx
+	fs := cmd.flagSet("users " + verb)
+
+	switch verb {
+	case "list":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, err := cmd.viewer(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		all, err := userService.GetAllUsers(ctx)
+		if err != nil {
+			return err
+		}
+		return cmd.print(userTable(all...))
+
+	case "get":
+		email := fs.String("email", "", "find the user by email instead of id")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		var id int64
+		if *email == "" {
+			if id, err = parseID(rest, "user"); err != nil {
+				return err
+			}
+		}
+		ctx, err := cmd.viewer(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		var user *models.User
+		if *email != "" {
+			user, err = userService.GetUserByEmail(ctx, *email)
+		} else {
+			user, err = userService.GetUserByID(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		return cmd.print(userTable(*user))
+
+	case "create":
+		name := fs.String("name", "", "name of the new user")
+		email := fs.String("email", "", "email of the new user")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		// the login options are the credentials of the new user
+		newUser := models.User{
+			Name:     *name,
+			Email:    *email,
+			Username: cmd.opts.username,
+			Password: cmd.opts.password,
+		}
+		if newUser.Name == "" || newUser.Email == "" || newUser.Username == "" || newUser.Password == "" {
+			return fmt.Errorf("%w: --name, --email, --username and --password are required", errUsage)
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		created, err := userService.CreateUser(ctx, newUser)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.stderr, "A verification mail was sent to %s\n", created.Email)
+		return cmd.print(userTable(*created))
+
+	case "update":
+		name := fs.String("name", "", "new name")
+		email := fs.String("email", "", "new email")
+		username := fs.String("new-username", "", "new username")
+		password := fs.String("new-password", "", "new password, --password is the current one")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "user")
+		if err != nil {
+			return err
+		}
+		// users change themselves and the operators anybody, the service
+		// checks the login
+		ctx, _, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		user, err := userService.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		// fields that are not given keep their current value
+		updated := *user
+		setIfGiven(&updated.Name, *name)
+		setIfGiven(&updated.Email, *email)
+		setIfGiven(&updated.Username, *username)
+		// the password of the login is the current one
+		if *password != "" {
+			_, current, err := cmd.credentials()
+			if err != nil {
+				return err
+			}
+			if err := userService.ChangePassword(ctx, id, current, *password); err != nil {
+				return err
+			}
+		}
+		if _, err := userService.UpdateUserByID(ctx, updated); err != nil {
+			return err
+		}
+		if *email != "" && *email != user.Email {
+			fmt.Fprintf(cmd.stderr, "A verification mail was sent to %s\n", *email)
+		}
+		if user, err = userService.GetUserByID(ctx, id); err != nil {
+			return err
+		}
+		return cmd.print(userTable(*user))
+
+	case "delete":
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "user")
+		if err != nil {
+			return err
+		}
+		ctx, _, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		if _, err := userService.GetUserByID(ctx, id); err != nil {
+			return err
+		}
+		return userService.DeleteUserByID(ctx, id)
+
+	case "verify":
+		token := fs.String("token", "", "token from the verification mail")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *token == "" {
+			return fmt.Errorf("%w: --token is required", errUsage)
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		user, err := userService.VerifyEmail(ctx, *token)
+		if err != nil {
+			return err
+		}
+		return cmd.print(userTable(*user))
+
+	case "resend-verification":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		if err := userService.RequestEmailVerification(ctx, user.ID); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.stderr, "A verification mail was sent to %s\n", user.Email)
+		return nil
+
+	case "reset-password":
+		// with --email the token is mailed, with --token it sets the password
+		email := fs.String("email", "", "email address to mail the reset token to")
+		token := fs.String("token", "", "token from the password reset mail")
+		password := fs.String("new-password", "", "new password, --password is the current one")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if (*email == "") == (*token == "") || (*token != "" && *password == "") {
+			return fmt.Errorf("%w: use either --email, or --token with --new-password", errUsage)
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		if *email != "" {
+			if err := userService.RequestPasswordReset(ctx, *email); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.stderr, "If %s belongs to a user, a password reset mail was sent to it\n", *email)
+			return nil
+		}
+		return userService.ResetPassword(ctx, *token, *password)
+
+	case "2fa-enroll":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		setup, err := userService.EnrollTOTP(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.stderr, "Add the secret or the URI (as a QR code) to your authenticator app, then run \"users 2fa-confirm --code CODE\"")
+		t := newTable("secret", "uri")
+		t.add(setup.Secret, setup.URI)
+		return cmd.print(t)
+
+	case "2fa-confirm":
+		code := fs.String("code", "", "current code of the authenticator app")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *code == "" {
+			return fmt.Errorf("%w: --code is required", errUsage)
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		codes, err := userService.ConfirmTOTP(ctx, user.ID, *code)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.stderr, "Two-factor authentication is enabled. Keep these recovery codes safe, each works once and they are not shown again:")
+		t := newTable("recovery_code")
+		for _, c := range codes {
+			t.add(c)
+		}
+		return cmd.print(t)
+
+	case "2fa-disable":
+		// --otp logs in, so --code has to be another code: the next one of
+		// the app or a recovery code
+		code := fs.String("code", "", "another code of the authenticator app, or a recovery code")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *code == "" {
+			return fmt.Errorf("%w: --code is required", errUsage)
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		return userService.DisableTOTP(ctx, user.ID, *code)
+
+	case "2fa-reset":
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "user")
+		if err != nil {
+			return err
+		}
+		// only operators may, the service checks the login
+		ctx, _, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		return userService.ResetTOTP(ctx, id)
+
+	case "sso-login":
+		// signs in through the provider, the first sign-in creates the user
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		identity, err := cmd.signIn(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		user, err := userService.LoginWithIdentity(ctx, identity, cmd.opts.otp)
+		if err != nil {
+			return otpError(err)
+		}
+		return cmd.print(userTable(*user))
+
+	case "sso-link":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		identity, err := cmd.signIn(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		linked, err := userService.LinkIdentity(ctx, user.ID, identity)
+		if err != nil {
+			return err
+		}
+		return cmd.print(identityTable(*linked))
+
+	case "sso-unlink":
+		name := fs.String("provider", provider(), "provider to unlink")
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		return userService.UnlinkIdentity(ctx, user.ID, *name)
+
+	case "identities":
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		identities, err := userService.GetUserIdentities(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		return cmd.print(identityTable(identities...))
+
+	case "deactivate":
+		// closes the account of the login, logging in again opens it
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		if err := userService.DeactivateUser(ctx, user.ID); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.stderr, "The account is deactivated, log in again to open it")
+		return nil
+
+	case "suspend", "ban", "reactivate":
+		until := fs.String("until", "", "end of the suspension, RFC 3339 time or duration like 72h")
+		reason := fs.String("reason", "", "reason shown to the user")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "user")
+		if err != nil {
+			return err
+		}
+		var end time.Time
+		if verb == "suspend" {
+			if end, err = parseUntil(*until); err != nil {
+				return err
+			}
+		}
+		// only operators may, the service checks the login
+		ctx, _, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		switch verb {
+		case "suspend":
+			err = userService.SuspendUser(ctx, id, end, *reason)
+		case "ban":
+			err = userService.BanUser(ctx, id, *reason)
+		default:
+			err = userService.ReactivateUser(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		user, err := userService.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return cmd.print(userTable(*user))
+
+	case "erase":
+		content := fs.String("content", string(service.KeepContent), "keep or remove the posts and comments of the user")
+		rest, err := cmd.parseCommand(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := parseID(rest, "user")
+		if err != nil {
+			return err
+		}
+		policy, err := service.ParseContentPolicy(*content)
+		if err != nil {
+			return err
+		}
+		// users erase themselves, operators anybody
+		ctx, _, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		return userService.EraseUser(ctx, id, policy)
+
+	case "export":
+		out := fs.String("out", "", `zip archive to write, "-" for standard output`)
+		if _, err := cmd.parseCommand(fs, args); err != nil {
+			return err
+		}
+		if *out == "" {
+			return fmt.Errorf("%w: --out is required", errUsage)
+		}
+		ctx, user, err := cmd.login(ctx)
+		if err != nil {
+			return err
+		}
+		userService, err := cmd.userService()
+		if err != nil {
+			return err
+		}
+		export, err := userService.ExportUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if *out == "-" {
+			return export.WriteZip(cmd.stdout)
+		}
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		if err := export.WriteZip(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.stderr, "Wrote the data of %s to %s\n", user.Username, *out)
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown users command %q", errUsage, verb)
+	}
+}
+
+func setIfGiven(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// parseUntil reads the end of a suspension, a time or a duration from now
+func parseUntil(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: --until is required", errUsage)
+	}
+	if until, err := time.Parse(time.RFC3339, value); err == nil {
+		return until, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: --until %q is neither an RFC 3339 time nor a duration", errUsage, value)
+}
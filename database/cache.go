@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"postgresql-blog/repository/cache"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cache backends
+const (
+	CacheLRU   = "lru"
+	CacheRedis = "redis"
+)
+
+const (
+	DefaultCacheSize = 10000
+	DefaultCacheTTL  = time.Minute
+	DefaultRedisAddr = "localhost:6379"
+)
+
+// CacheConfig selects the cache in front of the post and comment reads, an
+// empty Kind reads straight from the database
+type CacheConfig struct {
+	Kind string
+	// Size is the capacity of the lru cache
+	Size      int
+	TTL       time.Duration
+	RedisAddr string
+}
+
+// cacheConfigFromEnv reads BLOG_CACHE, BLOG_CACHE_SIZE, BLOG_CACHE_TTL and
+// BLOG_REDIS_ADDR, values that do not parse keep the defaults
+func cacheConfigFromEnv() CacheConfig {
+	cfg := CacheConfig{
+		Kind:      os.Getenv("BLOG_CACHE"),
+		Size:      DefaultCacheSize,
+		TTL:       DefaultCacheTTL,
+		RedisAddr: DefaultRedisAddr,
+	}
+	if size, err := strconv.Atoi(os.Getenv("BLOG_CACHE_SIZE")); err == nil && size > 0 {
+		cfg.Size = size
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("BLOG_CACHE_TTL")); err == nil && ttl > 0 {
+		cfg.TTL = ttl
+	}
+	if addr := os.Getenv("BLOG_REDIS_ADDR"); addr != "" {
+		cfg.RedisAddr = addr
+	}
+	return cfg
+}
+
+// newCacheLayer returns the configured cache and a function closing it, the
+// layer is nil when no cache is configured
+func newCacheLayer(cfg CacheConfig) (*cache.Layer, func(), error) {
+	switch cfg.Kind {
+	case "":
+		return nil, func() {}, nil
+	case CacheLRU:
+		return cache.NewLayer(cache.NewLRU(cfg.Size), cfg.TTL), func() {}, nil
+	case CacheRedis:
+		redisCache := cache.NewRedis(redis.NewClient(&redis.Options{Addr: cfg.RedisAddr}), "blog:")
+		return cache.NewLayer(redisCache, cfg.TTL), func() { redisCache.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown cache %q", cfg.Kind)
+	}
+}
@@ -0,0 +1,11 @@
+package database_test
+
+import (
+	"testing"
+
+	"postgresql-blog/repository/repotest"
+)
+
+func TestSQLite(t *testing.T) {
+	repotest.Run(t, repotest.SQLite())
+}
@@ -2,10 +2,13 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"postgresql-blog/models"
+	"postgresql-blog/repository"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostgreSQLGORMRepository struct {
@@ -21,7 +24,7 @@ func NewPostgreSQLGORMRepository(db *gorm.DB) *PostgreSQLGORMRepository {
 // migrate database
 func (r *PostgreSQLGORMRepository) Migrate(ctx context.Context) error {
 	// table user
-	err := r.db.WithContext(ctx).AutoMigrate(&models.GormUser{})
+	err := repository.NewUserRepository(r.db).MigrateUser(ctx)
 	if err != nil {
 		return err
 	}
@@ -38,5 +41,82 @@ func (r *PostgreSQLGORMRepository) Migrate(ctx context.Context) error {
 		return err
 	}
 
-	return nil
+	// table user_tokens
+	err = r.db.WithContext(ctx).AutoMigrate(&models.UserToken{})
+	if err != nil {
+		return err
+	}
+
+	// tables user_two_factors and recovery_codes
+	err = repository.NewTwoFactorRepository(r.db).MigrateTwoFactor(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table identities
+	err = r.db.WithContext(ctx).AutoMigrate(&models.Identity{})
+	if err != nil {
+		return err
+	}
+
+	// tables profiles and avatars
+	err = repository.NewProfileRepository(r.db).MigrateProfile(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table audit_events
+	err = repository.NewAuditRepository(r.db).MigrateAudit(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table post_revisions
+	err = repository.NewRevisionRepository(r.db).MigrateRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table drafts
+	err = repository.NewDraftRepository(r.db).MigrateDraft(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table reactions
+	err = repository.NewReactionRepository(r.db).MigrateReaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table comment_votes
+	err = repository.NewVoteRepository(r.db).MigrateVote(ctx)
+	if err != nil {
+		return err
+	}
+
+	// table rate_limits
+	err = r.db.WithContext(ctx).AutoMigrate(&models.RateLimit{})
+	if err != nil {
+		return err
+	}
+
+	// record the version last, a failed migration leaves the old one
+	err = r.db.WithContext(ctx).AutoMigrate(&models.SchemaMigration{})
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.SchemaMigration{Version: repository.SchemaVersion, AppliedAt: time.Now()}).Error
+}
+
+// SchemaVersion returns the newest version the database was migrated to,
+// zero when it was never migrated
+func (r *PostgreSQLGORMRepository) SchemaVersion(ctx context.Context) (int64, error) {
+	if !r.db.Migrator().HasTable(&models.SchemaMigration{}) {
+		return 0, nil
+	}
+	var version int64
+	err := r.db.WithContext(ctx).Model(&models.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
 }
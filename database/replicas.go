@@ -0,0 +1,274 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"postgresql-blog/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// DefaultStickyWindow is how long the reads of a user go to the primary
+	// after their own write
+	DefaultStickyWindow = 5 * time.Second
+	// DefaultHealthInterval is how often the replicas are pinged
+	DefaultHealthInterval = 10 * time.Second
+)
+
+const resolverName = "blog:replicas"
+
+type replica struct {
+	dialector gorm.Dialector
+	// db is nil until the replica could be opened
+	db      atomic.Pointer[sql.DB]
+	healthy atomic.Bool
+}
+
+// Resolver is a gorm plugin sending the reads made outside of a transaction
+// to the healthy replicas, writes and transactions stay on the primary. A
+// user that just wrote reads from the primary for the sticky window, so
+// replication lag cannot hide their own write from them. Users are told
+// apart by repository.WithActor.
+type Resolver struct {
+	replicas []*replica
+	window   time.Duration
+	interval time.Duration
+	next     atomic.Uint64
+
+	mu     sync.Mutex
+	writes map[int64]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewResolver opens the replicas and starts pinging them, a replica that
+// cannot be opened or reached is left out until a health check reaches it.
+func NewResolver(replicas []gorm.Dialector, stickyWindow, healthInterval time.Duration) *Resolver {
+	r := &Resolver{
+		window:   stickyWindow,
+		interval: healthInterval,
+		writes:   map[int64]time.Time{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, dialector := range replicas {
+		r.replicas = append(r.replicas, &replica{dialector: dialector})
+	}
+
+	r.checkHealth()
+	go r.healthLoop()
+	return r
+}
+
+func (r *Resolver) Name() string {
+	return resolverName
+}
+
+// Initialize registers the routing callbacks
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("replicas:route", r.route); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("replicas:route", r.route); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("replicas:check", r.checkError); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register("replicas:check", r.checkError); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("replicas:record", r.recordWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("replicas:record", r.recordWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("replicas:record", r.recordWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("gorm:raw").Register("replicas:record", r.recordWrite); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Healthy returns how many of the replicas currently serve reads
+func (r *Resolver) Healthy() (healthy, total int) {
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy, len(r.replicas)
+}
+
+// Close stops the health checks and closes the replicas
+func (r *Resolver) Close() error {
+	close(r.stop)
+	<-r.done
+	return r.closeReplicas()
+}
+
+func (r *Resolver) closeReplicas() error {
+	var errs []error
+	for _, rep := range r.replicas {
+		if db := rep.db.Load(); db != nil {
+			errs = append(errs, db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Resolver) route(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	// anything but the primary pool is a transaction or a prepared
+	// statement session, those keep their connection
+	if _, ok := db.Statement.ConnPool.(*sql.DB); !ok {
+		return
+	}
+	if userID, ok := repository.ActorFromContext(db.Statement.Context); ok && r.wroteRecently(userID) {
+		return
+	}
+	if replicaDB := r.pick(); replicaDB != nil {
+		db.Statement.ConnPool = replicaDB
+	}
+}
+
+// pick returns the next healthy replica in turn, nil when none is healthy
+func (r *Resolver) pick() *sql.DB {
+	n := len(r.replicas)
+	start := r.next.Add(1)
+	for i := 0; i < n; i++ {
+		rep := r.replicas[(start+uint64(i))%uint64(n)]
+		if rep.healthy.Load() {
+			return rep.db.Load()
+		}
+	}
+	return nil
+}
+
+// checkError drops a replica as soon as a read on it fails with a
+// connection error instead of waiting for the next health check
+func (r *Resolver) checkError(db *gorm.DB) {
+	if db.Error == nil || !isConnectionError(db.Error) {
+		return
+	}
+	for _, rep := range r.replicas {
+		if replicaDB := rep.db.Load(); replicaDB != nil && db.Statement.ConnPool == gorm.ConnPool(replicaDB) {
+			rep.healthy.Store(false)
+		}
+	}
+}
+
+func isConnectionError(err error) bool {
+	var netError net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netError)
+}
+
+func (r *Resolver) recordWrite(db *gorm.DB) {
+	userID, ok := repository.ActorFromContext(db.Statement.Context)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	r.writes[userID] = time.Now()
+	r.mu.Unlock()
+}
+
+func (r *Resolver) wroteRecently(userID int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	written, ok := r.writes[userID]
+	return ok && time.Since(written) < r.window
+}
+
+func (r *Resolver) healthLoop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.checkHealth()
+			r.forgetWrites()
+		}
+	}
+}
+
+// checkHealth pings every replica, the ones that answer serve reads until
+// the next check
+func (r *Resolver) checkHealth() {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+			defer cancel()
+			rep.healthy.Store(rep.ping(ctx) == nil)
+		}(rep)
+	}
+	wg.Wait()
+}
+
+// ping opens the replica when that did not work yet and pings it
+func (rep *replica) ping(ctx context.Context) error {
+	replicaDB := rep.db.Load()
+	if replicaDB == nil {
+		// failures are reported by the health state, not by the logger
+		opened, err := gorm.Open(rep.dialector, &gorm.Config{
+			DisableAutomaticPing: true,
+			Logger:               logger.Default.LogMode(logger.Silent),
+		})
+		if err != nil {
+			return err
+		}
+		if replicaDB, err = opened.DB(); err != nil {
+			return err
+		}
+		rep.db.Store(replicaDB)
+	}
+	return replicaDB.PingContext(ctx)
+}
+
+// forgetWrites drops the writes that are older than the sticky window
+func (r *Resolver) forgetWrites() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for userID, written := range r.writes {
+		if time.Since(written) >= r.window {
+			delete(r.writes, userID)
+		}
+	}
+}
+
+// Close closes the connections of a database opened by Open, including the
+// replicas
+func Close(db *gorm.DB) error {
+	var errs []error
+	if plugin, ok := db.Config.Plugins[resolverName]; ok {
+		errs = append(errs, plugin.(*Resolver).Close())
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	errs = append(errs, sqlDB.Close())
+	return errors.Join(errs...)
+}
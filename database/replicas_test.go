@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"postgresql-blog/repository"
+
+	"gorm.io/gorm"
+)
+
+// the sqlite files stand in for the primary and the replicas, each knows
+// its own name so a read tells where it was served
+func seedServer(t *testing.T, path, name string) {
+	t.Helper()
+	db, err := gorm.Open(openSQLite(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if err := db.Exec("CREATE TABLE servers (name text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO servers (name) VALUES (?)", name).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openWithReplicas(t *testing.T, replicas ...string) (*gorm.DB, *Resolver) {
+	t.Helper()
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.db")
+	seedServer(t, primary, "primary")
+	db, err := Open(Config{Driver: DriverSQLite, DSN: primary, Replicas: replicas, HealthInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close(db) })
+	return db, db.Config.Plugins[resolverName].(*Resolver)
+}
+
+// servedBy reads the name of the server answering a query
+func servedBy(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	var names []string
+	if err := db.Table("servers").Order("name").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	return names[0]
+}
+
+func twoReplicas(t *testing.T) []string {
+	dir := t.TempDir()
+	replicas := []string{filepath.Join(dir, "replica1.db"), filepath.Join(dir, "replica2.db")}
+	seedServer(t, replicas[0], "replica1")
+	seedServer(t, replicas[1], "replica2")
+	return replicas
+}
+
+func TestReadsGoToReplicas(t *testing.T) {
+	db, resolver := openWithReplicas(t, twoReplicas(t)...)
+	if healthy, total := resolver.Healthy(); healthy != 2 || total != 2 {
+		t.Fatalf("%d of %d replicas are healthy, want 2 of 2", healthy, total)
+	}
+
+	served := map[string]int{}
+	for i := 0; i < 4; i++ {
+		served[servedBy(t, db)]++
+	}
+	if served["replica1"] != 2 || served["replica2"] != 2 {
+		t.Fatalf("reads were served by %v, want both replicas in turn", served)
+	}
+}
+
+func TestWritesAndTransactionsUsePrimary(t *testing.T) {
+	db, _ := openWithReplicas(t, twoReplicas(t)...)
+
+	if err := db.Exec("INSERT INTO servers (name) VALUES ('a write')").Error; err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if got := servedBy(t, tx); got != "a write" {
+			t.Errorf("a read in a transaction was served by %s, want the primary", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := servedBy(t, db); got == "a write" {
+		t.Fatal("a read outside of a transaction went to the primary")
+	}
+}
+
+func TestReadsAfterOwnWriteUsePrimary(t *testing.T) {
+	db, _ := openWithReplicas(t, twoReplicas(t)...)
+	writer := repository.WithActor(context.Background(), 1)
+	other := repository.WithActor(context.Background(), 2)
+
+	if err := db.WithContext(writer).Exec("INSERT INTO servers (name) VALUES ('a write')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := servedBy(t, db.WithContext(writer)); got != "a write" {
+		t.Fatalf("the read after the own write was served by %s, want the primary", got)
+	}
+	if got := servedBy(t, db.WithContext(other)); got == "a write" {
+		t.Fatal("the read of another user went to the primary")
+	}
+}
+
+func TestUnhealthyReplicas(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "replica1.db")
+	seedServer(t, good, "replica1")
+	// the directory of the second replica does not exist yet, so it
+	// cannot be opened
+	missing := filepath.Join(dir, "later", "replica2.db")
+	db, resolver := openWithReplicas(t, good, missing)
+
+	if healthy, _ := resolver.Healthy(); healthy != 1 {
+		t.Fatalf("%d replicas are healthy, want 1", healthy)
+	}
+	for i := 0; i < 3; i++ {
+		if got := servedBy(t, db); got != "replica1" {
+			t.Fatalf("a read was served by %s, want the healthy replica", got)
+		}
+	}
+
+	// the replica comes back with the next health check
+	if err := os.Mkdir(filepath.Dir(missing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	seedServer(t, missing, "replica2")
+	resolver.checkHealth()
+	if healthy, _ := resolver.Healthy(); healthy != 2 {
+		t.Fatalf("%d replicas are healthy after the check, want 2", healthy)
+	}
+
+	// and is dropped again when it goes away
+	resolver.replicas[0].db.Load().Close()
+	resolver.checkHealth()
+	if healthy, _ := resolver.Healthy(); healthy != 1 {
+		t.Fatalf("%d replicas are healthy after one closed, want 1", healthy)
+	}
+	for i := 0; i < 3; i++ {
+		if got := servedBy(t, db); got != "replica2" {
+			t.Fatalf("a read was served by %s, want the remaining replica", got)
+		}
+	}
+
+	// no healthy replica leaves the reads on the primary
+	resolver.replicas[1].db.Load().Close()
+	resolver.checkHealth()
+	if got := servedBy(t, db); got != "primary" {
+		t.Fatalf("a read without replicas was served by %s, want the primary", got)
+	}
+}
@@ -0,0 +1,234 @@
+// Package diff compares texts line by line or word by word with the
+// algorithm of Myers, "An O(ND) Difference Algorithm and Its Variations".
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Op is what an Edit does
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Edit is a token that is in both texts, only in the new one or only in the
+// old one
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// Lines compares a and b line by line, the lines keep their line breaks
+func Lines(a, b string) []Edit {
+	return Tokens(splitLines(a), splitLines(b))
+}
+
+// Words compares a and b word by word, the runs of whitespace between the
+// words are tokens of their own
+func Words(a, b string) []Edit {
+	return Tokens(splitWords(a), splitWords(b))
+}
+
+// MaxDistance is the most deletions and insertions Tokens looks for a
+// shortest edit script with, texts further apart are shown as the old
+// tokens deleted and the new ones inserted
+const MaxDistance = 2000
+
+// Tokens returns the shortest edit script from a to b, the deletions come
+// before the insertions they replace
+func Tokens(a, b []string) []Edit {
+	// the tokens both start and end with are not part of the search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	for _, token := range a[:prefix] {
+		edits = append(edits, Edit{Op: Equal, Text: token})
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if middle, ok := shortest(middleA, middleB); ok {
+		edits = append(edits, middle...)
+	} else {
+		for _, token := range middleA {
+			edits = append(edits, Edit{Op: Delete, Text: token})
+		}
+		for _, token := range middleB {
+			edits = append(edits, Edit{Op: Insert, Text: token})
+		}
+	}
+	for _, token := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Op: Equal, Text: token})
+	}
+	return edits
+}
+
+// shortest searches the shortest edit script, false when it is longer than
+// MaxDistance
+func shortest(a, b []string) ([]Edit, bool) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > MaxDistance {
+		max = MaxDistance
+	}
+	offset := max + 1
+	// v[offset+k] is the furthest x reached on diagonal k, trace keeps the
+	// part of v round d reads, v[offset-d-1:offset+d+2], to walk back
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(trace [][]int, a, b []string) []Edit {
+	var edits []Edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d][i] is v[offset+i-d-1]
+		v := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			prevK = k + 1
+		}
+		prevX := v(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			edits = append(edits, Edit{Op: Equal, Text: a[x]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			edits = append(edits, Edit{Op: Insert, Text: b[prevY]})
+		} else {
+			edits = append(edits, Edit{Op: Delete, Text: a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// Changed reports whether the edits change anything
+func Changed(edits []Edit) bool {
+	for _, edit := range edits {
+		if edit.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatLines prints line edits like a unified diff without hunks: every
+// line starts with " ", "+" or "-"
+func FormatLines(edits []Edit) string {
+	var sb strings.Builder
+	for _, edit := range edits {
+		switch edit.Op {
+		case Insert:
+			sb.WriteByte('+')
+		case Delete:
+			sb.WriteByte('-')
+		default:
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(edit.Text)
+		if !strings.HasSuffix(edit.Text, "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// FormatWords prints word edits inline like git diff --word-diff, removed
+// words as [-word-] and added ones as {+word+}
+func FormatWords(edits []Edit) string {
+	var sb strings.Builder
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			sb.WriteString(edits[i].Text)
+			i++
+			continue
+		}
+		// a run of changes is printed as all its deletions, then all its
+		// insertions
+		var deleted, inserted strings.Builder
+		for ; i < len(edits) && edits[i].Op != Equal; i++ {
+			if edits[i].Op == Delete {
+				deleted.WriteString(edits[i].Text)
+			} else {
+				inserted.WriteString(edits[i].Text)
+			}
+		}
+		if deleted.Len() > 0 {
+			sb.WriteString("[-" + deleted.String() + "-]")
+		}
+		if inserted.Len() > 0 {
+			sb.WriteString("{+" + inserted.String() + "+}")
+		}
+	}
+	return sb.String()
+}
+
+// splitLines ends every line with a line break, a missing one at the end
+// of the text is no change
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
+
+func splitWords(s string) []string {
+	var tokens []string
+	start, space := 0, false
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
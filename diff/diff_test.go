@@ -0,0 +1,88 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		want    []Edit
+		changed bool
+	}{
+		{"empty", "", "", nil, false},
+		{"from empty", "", "a\nb\n", []Edit{{Insert, "a\n"}, {Insert, "b\n"}}, true},
+		{"to empty", "a\nb\n", "", []Edit{{Delete, "a\n"}, {Delete, "b\n"}}, true},
+		{"equal", "a\nb\n", "a\nb\n", []Edit{{Equal, "a\n"}, {Equal, "b\n"}}, false},
+		{"insert", "a\nc\n", "a\nb\nc\n", []Edit{{Equal, "a\n"}, {Insert, "b\n"}, {Equal, "c\n"}}, true},
+		{"delete", "a\nb\nc\n", "a\nc\n", []Edit{{Equal, "a\n"}, {Delete, "b\n"}, {Equal, "c\n"}}, true},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", []Edit{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "x\n"}, {Equal, "c\n"}}, true},
+		{"no trailing newline", "a\nb", "a\nb\n", []Edit{{Equal, "a\n"}, {Equal, "b\n"}}, false},
+		{"line added without trailing newline", "a", "a\nb", []Edit{{Equal, "a\n"}, {Insert, "b\n"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if Changed(got) != tt.changed {
+				t.Fatalf("Changed is %v, want %v", Changed(got), tt.changed)
+			}
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"the quick fox", "the quick fox", "the quick fox"},
+		{"the quick fox", "the slow fox", "the [-quick-]{+slow+} fox"},
+		{"the fox", "the quick fox", "the {+quick +}fox"},
+		{"the quick fox", "the fox", "the [-quick -]fox"},
+		{"", "new words", "{+new words+}"},
+	}
+	for _, tt := range tests {
+		if got := FormatWords(Words(tt.a, tt.b)); got != tt.want {
+			t.Errorf("FormatWords(Words(%q, %q)) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLines(t *testing.T) {
+	got := FormatLines(Lines("a\nb\nc", "a\nx\nc"))
+	if want := " a\n-b\n+x\n c\n"; got != want {
+		t.Fatalf("FormatLines returned %q, want %q", got, want)
+	}
+}
+
+func TestTokensBeyondMaxDistance(t *testing.T) {
+	var a, b []string
+	for i := 0; i < MaxDistance; i++ {
+		a, b = append(a, fmt.Sprint("a", i)), append(b, fmt.Sprint("b", i))
+	}
+	a, b = append(a, "end"), append(b, "end")
+	edits := Tokens(a, b)
+	if len(edits) != 2*MaxDistance+1 {
+		t.Fatalf("got %d edits, want %d", len(edits), 2*MaxDistance+1)
+	}
+	// the old tokens are deleted, then the new ones inserted
+	for i, edit := range edits[:len(edits)-1] {
+		want := Edit{Insert, b[i%MaxDistance]}
+		if i < MaxDistance {
+			want = Edit{Delete, a[i]}
+		}
+		if edit != want {
+			t.Fatalf("edit %d is %v, want %v", i, edit, want)
+		}
+	}
+	if last := edits[len(edits)-1]; last != (Edit{Equal, "end"}) {
+		t.Fatalf("the last edit is %v, want the common end", last)
+	}
+}
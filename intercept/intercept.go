@@ -0,0 +1,82 @@
+// Package intercept runs the calls of the repositories and services through
+// interceptors, which is how metrics and tracing see them without the
+// repositories and services knowing about either.
+package intercept
+
+import "context"
+
+// layers of the application that are intercepted
+const (
+	LayerRepository = "repository"
+	LayerService    = "service"
+)
+
+// Op is a call passing through the interceptors
+type Op struct {
+	Layer string
+	// Entity names the repository or service, like "posts"
+	Entity string
+	// Type is the type the method belongs to, like "PostService"
+	Type   string
+	Method string
+	// ID is the id the call works on, zero when it has none
+	ID int64
+	// Rows is how many records the call returned, it is set once the call
+	// returned
+	Rows int
+}
+
+// Name returns the entity and method of op, like "posts.GetPostByID"
+func (op *Op) Name() string {
+	return op.Entity + "." + op.Method
+}
+
+// Interceptor runs call, it may look at op and the context before and after
+// and pass call a context of its own
+type Interceptor func(ctx context.Context, op *Op, call func(ctx context.Context) error) error
+
+// Chain returns an interceptor running interceptors in turn, the first one
+// is the outermost
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, op *Op, call func(ctx context.Context) error) error {
+		next := call
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context) error {
+				return interceptor(ctx, op, inner)
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// One intercepts a call returning a single record
+func One[T any](ctx context.Context, interceptor Interceptor, op Op, call func(ctx context.Context) (*T, error)) (*T, error) {
+	var result *T
+	err := interceptor(ctx, &op, func(ctx context.Context) error {
+		var err error
+		result, err = call(ctx)
+		if result != nil {
+			op.Rows = 1
+		}
+		return err
+	})
+	return result, err
+}
+
+// Many intercepts a call returning a list of records
+func Many[T any](ctx context.Context, interceptor Interceptor, op Op, call func(ctx context.Context) ([]T, error)) ([]T, error) {
+	var result []T
+	err := interceptor(ctx, &op, func(ctx context.Context) error {
+		var err error
+		result, err = call(ctx)
+		op.Rows = len(result)
+		return err
+	})
+	return result, err
+}
+
+// Exec intercepts a call returning only an error
+func Exec(ctx context.Context, interceptor Interceptor, op Op, call func(ctx context.Context) error) error {
+	return interceptor(ctx, &op, call)
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// DefaultSlowQuery is the duration after which a query is logged as slow
+const DefaultSlowQuery = 200 * time.Millisecond
+
+// GormLogger sends the logs of gorm to a slog.Logger. Statements are logged
+// at debug level, slow ones as warnings and failed ones as errors. The
+// statements are logged without their parameters, so the values of a query
+// such as passwords cannot leak.
+type GormLogger struct {
+	logger *slog.Logger
+	slow   time.Duration
+	// silent is set by LogMode(gormlogger.Silent), the migrator uses it
+	silent bool
+}
+
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{logger: logger, slow: slowThreshold}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.silent = level == gormlogger.Silent
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...any) {
+	l.log(ctx, slog.LevelInfo, msg, data...)
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...any) {
+	l.log(ctx, slog.LevelWarn, msg, data...)
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...any) {
+	l.log(ctx, slog.LevelError, msg, data...)
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, msg string, data ...any) {
+	if l.silent {
+		return
+	}
+	l.logger.Log(ctx, level, fmt.Sprintf(msg, data...), slog.String("component", "gorm"))
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.silent {
+		return
+	}
+	elapsed := time.Since(begin)
+
+	level, msg := slog.LevelDebug, "query"
+	switch {
+	// a missing row is an answer, the repositories turn it into ErrNotExist
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case l.slow > 0 && elapsed > l.slow:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("component", "gorm"),
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("elapsed", elapsed),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops the parameters of the statements before they are
+// logged
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, _ ...any) (string, []any) {
+	return sql, nil
+}
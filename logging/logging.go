@@ -0,0 +1,140 @@
+// Package logging sets up the structured logger of the blog. Records logged
+// with a context carry the operation id and the acting user of the call, and
+// passwords and email addresses never reach the output.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"postgresql-blog/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Redacted replaces the value of a sensitive attribute
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the attribute keys whose values are redacted, wherever
+// they appear in a group
+var sensitiveKeys = map[string]bool{
+	"password": true,
+	"email":    true,
+}
+
+type Config struct {
+	Level  slog.Level
+	Format string
+	Output io.Writer
+}
+
+// ConfigFromEnv reads BLOG_LOG_LEVEL (debug, info, warn or error),
+// BLOG_LOG_FORMAT (text or json) and BLOG_LOG_FILE, the logs go to standard
+// error when no file is given. The level defaults to defaultLevel.
+func ConfigFromEnv(defaultLevel slog.Level) (Config, error) {
+	cfg := Config{Level: defaultLevel, Format: FormatText, Output: os.Stderr}
+	if file := os.Getenv("BLOG_LOG_FILE"); file != "" {
+		output, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return cfg, fmt.Errorf("BLOG_LOG_FILE: %w", err)
+		}
+		cfg.Output = output
+	}
+	if level := os.Getenv("BLOG_LOG_LEVEL"); level != "" {
+		if err := cfg.Level.UnmarshalText([]byte(level)); err != nil {
+			return cfg, fmt.Errorf("BLOG_LOG_LEVEL: %w", err)
+		}
+	}
+	if format := strings.ToLower(os.Getenv("BLOG_LOG_FORMAT")); format != "" {
+		if format != FormatText && format != FormatJSON {
+			return cfg, fmt.Errorf("BLOG_LOG_FORMAT: unknown format %q", format)
+		}
+		cfg.Format = format
+	}
+	return cfg, nil
+}
+
+// New returns the logger described by cfg
+func New(cfg Config) *slog.Logger {
+	output := cfg.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	options := &slog.HandlerOptions{Level: cfg.Level, ReplaceAttr: redact}
+
+	var handler slog.Handler
+	if cfg.Format == FormatJSON {
+		handler = slog.NewJSONHandler(output, options)
+	} else {
+		handler = slog.NewTextHandler(output, options)
+	}
+	return slog.New(&contextHandler{handler})
+}
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, Redacted)
+	}
+	return attr
+}
+
+type operationKey struct{}
+
+// WithOperation starts a new operation, every record logged with the
+// returned context carries its id
+func WithOperation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operationKey{}, newOperationID())
+}
+
+// OperationID returns the id set by WithOperation
+func OperationID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(operationKey{}).(string)
+	return id, ok
+}
+
+func newOperationID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id[:])
+}
+
+// contextHandler adds the operation id, the acting user and the trace of the
+// context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := OperationID(ctx); ok {
+		record.AddAttrs(slog.String("op_id", id))
+	}
+	if userID, ok := repository.ActorFromContext(ctx); ok {
+		record.AddAttrs(slog.Int64("actor_id", userID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
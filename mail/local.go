@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log writes the mails to a writer instead of sending them
+type Log struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewLog(w io.Writer, from string) *Log {
+	return &Log{w: w, from: from}
+}
+
+func (l *Log) Send(_ context.Context, msg Message) error {
+	data, err := format(l.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = fmt.Fprintf(l.w, "--- mail ---\n%s\n------------\n", strings.ReplaceAll(string(data), "\r\n", "\n"))
+	return err
+}
+
+// File writes every mail to its own .eml file in a directory
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (f *File) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(f.from, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.dir, 0o700); err != nil {
+		return err
+	}
+	// the name sorts by time and keeps the address readable
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), strings.Map(safeRune, msg.To))
+	return os.WriteFile(filepath.Join(f.dir, name), data, 0o600)
+}
+
+func safeRune(r rune) rune {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+		return r
+	default:
+		return '_'
+	}
+}
@@ -0,0 +1,110 @@
+// Package mail sends the mails of the blog, like the email verification and
+// the password reset. A Mailer delivers over SMTP, or for local testing
+// writes the mails to files or to the terminal.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// mailers
+const (
+	MailerLog  = "log"
+	MailerFile = "file"
+	MailerSMTP = "smtp"
+)
+
+const (
+	DefaultFrom = "blog@localhost"
+	DefaultDir  = "mail"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	// Mailer is MailerLog, MailerFile or MailerSMTP
+	Mailer string
+	From   string
+	// Output is where the log mailer writes the mails
+	Output io.Writer
+	// Dir is where the file mailer writes the mails
+	Dir string
+	// SMTPAddr is the host:port of the SMTP server, the username and the
+	// password are optional
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// ConfigFromEnv reads BLOG_MAILER, BLOG_MAIL_FROM, BLOG_MAIL_DIR,
+// BLOG_SMTP_ADDR, BLOG_SMTP_USERNAME and BLOG_SMTP_PASSWORD. The mails are
+// written to standard error unless BLOG_MAILER says otherwise.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Mailer:       strings.ToLower(os.Getenv("BLOG_MAILER")),
+		From:         os.Getenv("BLOG_MAIL_FROM"),
+		Output:       os.Stderr,
+		Dir:          os.Getenv("BLOG_MAIL_DIR"),
+		SMTPAddr:     os.Getenv("BLOG_SMTP_ADDR"),
+		SMTPUsername: os.Getenv("BLOG_SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("BLOG_SMTP_PASSWORD"),
+	}
+	if cfg.Mailer == "" {
+		cfg.Mailer = MailerLog
+	}
+	if cfg.From == "" {
+		cfg.From = DefaultFrom
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = DefaultDir
+	}
+	return cfg
+}
+
+// New returns the mailer described by cfg
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Mailer {
+	case MailerLog, "":
+		return NewLog(cfg.Output, cfg.From), nil
+	case MailerFile:
+		return NewFile(cfg.Dir, cfg.From), nil
+	case MailerSMTP:
+		if cfg.SMTPAddr == "" {
+			return nil, fmt.Errorf("the %s mailer needs BLOG_SMTP_ADDR", MailerSMTP)
+		}
+		return NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+// format returns msg as an RFC 5322 message
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, fmt.Errorf("mail header %q contains a line break", header)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP sends the mails through an SMTP server, with PLAIN authentication
+// when a username is given
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTP(addr, username, password, from string) *SMTP {
+	s := &SMTP{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := format(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	// smtp.SendMail does not take a context, give up waiting for it when
+	// the context is done
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
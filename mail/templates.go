@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// the templates of the mails, the first line of a template is the subject
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+//go:embed templates/*.txt
+var templateFiles embed.FS
+
+var templates = template.Must(template.ParseFS(templateFiles, "templates/*.txt"))
+
+// Render returns the mail to to made from the template name and data
+func Render(name, to string, data any) (Message, error) {
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, name+".txt", data); err != nil {
+		return Message{}, err
+	}
+	subject, body, ok := strings.Cut(b.String(), "\n")
+	if !ok || !strings.HasPrefix(subject, "Subject: ") {
+		return Message{}, fmt.Errorf("mail template %s does not start with a subject", name)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimPrefix(subject, "Subject: "),
+		Body:    strings.TrimLeft(body, "\n"),
+	}, nil
+}
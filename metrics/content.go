@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"postgresql-blog/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultContentInterval is how often the business gauges are read from the
+// database at most
+const DefaultContentInterval = 30 * time.Second
+
+// contentCollector counts the published posts and the comments waiting for
+// moderation, which are the ones that are neither published nor deleted
+type contentCollector struct {
+	store    repository.Store
+	interval time.Duration
+
+	published *prometheus.Desc
+	pending   *prometheus.Desc
+
+	mu                       sync.Mutex
+	read                     time.Time
+	publishedPosts, comments float64
+}
+
+func newContentCollector(store repository.Store, interval time.Duration) *contentCollector {
+	return &contentCollector{
+		store:    store,
+		interval: interval,
+		published: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "posts_published"),
+			"Posts that are published.", nil, nil),
+		pending: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "comments_pending_moderation"),
+			"Comments waiting to be published.", nil, nil),
+	}
+}
+
+func (c *contentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.published
+	ch <- c.pending
+}
+
+func (c *contentCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.read) >= c.interval {
+		// a failed read keeps the last values, the next scrape tries again
+		if err := c.refresh(); err != nil {
+			slog.Warn("reading the content metrics", slog.Any("error", err))
+		} else {
+			c.read = time.Now()
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.published, prometheus.GaugeValue, c.publishedPosts)
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, c.comments)
+}
+
+func (c *contentCollector) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repos := c.store.Repositories()
+
+	posts, err := repos.Posts.AllPosts(ctx)
+	if err != nil {
+		return err
+	}
+	comments, err := repos.Comments.AllComments(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.publishedPosts, c.comments = 0, 0
+	for _, post := range posts {
+		if post.IsPublished {
+			c.publishedPosts++
+		}
+	}
+	for _, comment := range comments {
+		if !comment.IsPublished && comment.DeletedAt == nil {
+			c.comments++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package metrics exports Prometheus metrics of the repositories and the
+// services. The calls are counted by an interceptor, so the metrics are added
+// by decorating the store and the services instead of touching them.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/intercept"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "blog"
+
+// Metrics owns the registry the metrics of the blog are exported from
+type Metrics struct {
+	registry *prometheus.Registry
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// New returns the call metrics registered next to the Go runtime and
+// process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "calls_total",
+			Help:      "Calls of the repository and service methods.",
+		}, []string{"layer", "method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "call_errors_total",
+			Help:      "Failed calls of the repository and service methods by kind of error.",
+		}, []string{"layer", "method", "kind"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "call_duration_seconds",
+			Help:      "Duration of the repository and service calls.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"layer", "method"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.calls, m.errors, m.latency,
+	)
+	return m
+}
+
+// Registry returns the registry, more collectors can be added to it
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Intercept is the intercept.Interceptor counting and timing the calls
+func (m *Metrics) Intercept(ctx context.Context, op *intercept.Op, call func(ctx context.Context) error) error {
+	start := time.Now()
+	err := call(ctx)
+	method := op.Name()
+	m.latency.WithLabelValues(op.Layer, method).Observe(time.Since(start).Seconds())
+	m.calls.WithLabelValues(op.Layer, method).Inc()
+	if err != nil {
+		m.errors.WithLabelValues(op.Layer, method, errorKind(err)).Inc()
+	}
+	return err
+}
+
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrNotExist):
+		return "not_exist"
+	case errors.Is(err, repository.ErrDuplicate):
+		return "duplicate"
+	case apperr.Is(err, apperr.RateLimited):
+		return "rate_limited"
+	default:
+		return "other"
+	}
+}
+
+// WatchDB exports the stats of a connection pool, name tells the pools apart
+func (m *Metrics) WatchDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// WatchCache exports the stats of a cache layer
+func (m *Metrics) WatchCache(layer *cache.Layer) error {
+	counters := []struct {
+		name, help string
+		value      func(cache.Stats) uint64
+	}{
+		{"cache_hits_total", "Reads served from the cache.", func(s cache.Stats) uint64 { return s.Hits }},
+		{"cache_misses_total", "Reads that missed the cache.", func(s cache.Stats) uint64 { return s.Misses }},
+		{"cache_errors_total", "Failures of the cache itself.", func(s cache.Stats) uint64 { return s.Errors }},
+	}
+	for _, counter := range counters {
+		value := counter.value
+		err := m.registry.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      counter.name,
+			Help:      counter.help,
+		}, func() float64 { return float64(value(layer.Stats())) }))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchContent exports the business gauges read from store, they are
+// refreshed at most once per interval however often they are scraped
+func (m *Metrics) WatchContent(store repository.Store, interval time.Duration) error {
+	return m.registry.Register(newContentCollector(store, interval))
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+func TestHandlerExposesCalls(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	users := repository.Intercept(memory.New(), m.Intercept).Repositories().Users
+	alice := models.User{Name: "Alice", Email: "alice@example.com", Username: "alice", Password: "secret"}
+	if _, err := users.CreateUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.CreateUser(ctx, alice); err == nil {
+		t.Fatal("a second alice was created")
+	}
+	if _, err := users.GetUserByID(ctx, 1000); err == nil {
+		t.Fatal("a missing user was found")
+	}
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+	res, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", res.StatusCode)
+	}
+
+	for _, want := range []string{
+		`blog_calls_total{layer="repository",method="users.CreateUser"} 2`,
+		`blog_calls_total{layer="repository",method="users.GetUserByID"} 1`,
+		`blog_call_errors_total{kind="duplicate",layer="repository",method="users.CreateUser"} 1`,
+		`blog_call_errors_total{kind="not_exist",layer="repository",method="users.GetUserByID"} 1`,
+		`blog_call_duration_seconds_count{layer="repository",method="users.CreateUser"} 2`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("the metrics miss %s", want)
+		}
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{repository.ErrNotExist, "not_exist"},
+		{apperr.Wrap(repository.ErrNotExist, "post"), "not_exist"},
+		{fmt.Errorf("saving: %w", repository.ErrDuplicate), "duplicate"},
+		{&apperr.Error{Kind: apperr.RateLimited}, "rate_limited"},
+		{errors.New("connection reset"), "other"},
+	}
+	for _, tt := range tests {
+		if got := errorKind(tt.err); got != tt.want {
+			t.Errorf("errorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
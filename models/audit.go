@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// AuditEvent records a change made through the services. Events are only
+// ever appended, never changed or deleted.
+type AuditEvent struct {
+	ID int64
+	// ActorID is the logged in user who made the change, nil when nobody
+	// was logged in
+	ActorID    *int64 `gorm:"index"`
+	Action     string
+	EntityType string `gorm:"index:idx_audit_events_entity"`
+	EntityID   int64  `gorm:"index:idx_audit_events_entity"`
+	// Changes is a JSON object of the changed fields, each with the value
+	// before and after the change
+	Changes string `gorm:"type:text"`
+	// RequestID is the operation id of the logs of the change
+	RequestID string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
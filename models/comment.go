@@ -12,18 +12,33 @@ type Comment struct {
 	DeletedAt   *time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// Upvotes and Downvotes count the votes on the comment, Score and
+	// Controversy rank it by them. They change with every vote, so the
+	// ranking never goes through the votes themselves.
+	Upvotes     int
+	Downvotes   int
+	Score       float64
+	Controversy float64
+	// Reactions counts the reactions to the comment by kind, like
+	// Post.Reactions
+	Reactions map[string]int `gorm:"-"`
 }
 
 type GormComment struct {
 	ID          int64 `gorm:"primary_key"`
 	UserID      uint64
-	PostID      uint64
+	PostID      uint64 `gorm:"index:idx_gorm_comments_post_score,priority:1;index:idx_gorm_comments_post_controversy,priority:1"`
 	Content     string `gorm:"type:text"`
 	IsPublished bool   `gorm:"default:false"`
 	PublishedAt time.Time
 	DeletedAt   *time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Upvotes     int            `gorm:"not null;default:0"`
+	Downvotes   int            `gorm:"not null;default:0"`
+	Score       float64        `gorm:"not null;default:0;index:idx_gorm_comments_post_score,priority:2"`
+	Controversy float64        `gorm:"not null;default:0;index:idx_gorm_comments_post_controversy,priority:2"`
+	Reactions   map[string]int `gorm:"-"`
 }
 
 func (Comment) TableName() string {
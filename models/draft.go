@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Draft is unsaved work on a post, a user has at most one per post. It stays
+// apart from the post until it is applied.
+type Draft struct {
+	ID     int64
+	UserID int64 `gorm:"uniqueIndex:idx_drafts_user_post"`
+	// PostID is the post the draft changes, 0 for a new post
+	PostID    int64 `gorm:"uniqueIndex:idx_drafts_user_post;index:idx_drafts_post"`
+	Title     string
+	Content   string `gorm:"type:text"`
+	Thumbnail string `gorm:"type:text"`
+	// BaseRevision is the revision of the post the draft was started from,
+	// the revisions after it were saved meanwhile
+	BaseRevision int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (Draft) TableName() string {
+	return "drafts"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Identity links a user to an account at a single sign-on provider, a user
+// may have one per provider
+type Identity struct {
+	ID       int64
+	UserID   int64  `gorm:"index"`
+	Provider string `gorm:"uniqueIndex:idx_identities_provider_subject"`
+	// Subject is the id of the account at the provider, it never changes
+	Subject string `gorm:"uniqueIndex:idx_identities_provider_subject"`
+	// Email is the address the provider reported at the last sign-in
+	Email      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (Identity) TableName() string {
+	return "identities"
+}
@@ -12,6 +12,9 @@ type Post struct {
 	PublishedAt time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// Reactions counts the reactions to the post by kind, it is filled by
+	// the services that say so and never stored
+	Reactions map[string]int `gorm:"-"`
 }
 
 type GormPost struct {
@@ -24,6 +27,7 @@ type GormPost struct {
 	PublishedAt time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Reactions   map[string]int `gorm:"-"`
 }
 
 func (Post) TableName() string {
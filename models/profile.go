@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Profile is what a user tells about themselves, every field is optional
+type Profile struct {
+	UserID      int64 `gorm:"primaryKey;autoIncrement:false"`
+	DisplayName string
+	// Bio is Markdown
+	Bio     string
+	Website string
+	Links   []Link `gorm:"serializer:json;type:text"`
+	// Timezone is an IANA zone like "Europe/Berlin", Locale a language tag
+	// like "de-DE"
+	Timezone string
+	Locale   string
+	// EmailPublic shows the email address to everybody, it is hidden by
+	// default
+	EmailPublic bool
+	UpdatedAt   time.Time
+}
+
+func (Profile) TableName() string {
+	return "profiles"
+}
+
+// Link is a link to an account elsewhere, like a social network
+type Link struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Avatar is the profile picture of a user
+type Avatar struct {
+	UserID      int64 `gorm:"primaryKey;autoIncrement:false"`
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
+}
+
+func (Avatar) TableName() string {
+	return "avatars"
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RateLimit is the state of a rate limit or a login lockout, kept so the
+// limits survive restarts
+type RateLimit struct {
+	Key         string  `gorm:"primaryKey"`
+	Tokens      float64 `gorm:"type:double precision"`
+	RefilledAt  time.Time
+	Failures    int64
+	LockedUntil time.Time
+	// ExpiresAt is when the state is back to its start, the row can be
+	// deleted after that
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (RateLimit) TableName() string {
+	return "rate_limits"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// the kinds of records users react to
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// ReactionLike is the reaction there always is, the others are emoji
+const ReactionLike = "like"
+
+// Reaction is a like or an emoji of a user on a post or a comment, a user
+// gives every kind at most once to a target
+type Reaction struct {
+	ID         int64
+	UserID     int64  `gorm:"uniqueIndex:idx_reactions_user_target_kind"`
+	TargetType string `gorm:"uniqueIndex:idx_reactions_user_target_kind;index:idx_reactions_target"`
+	TargetID   int64  `gorm:"uniqueIndex:idx_reactions_user_target_kind;index:idx_reactions_target"`
+	Kind       string `gorm:"uniqueIndex:idx_reactions_user_target_kind"`
+	CreatedAt  time.Time
+}
+
+func (Reaction) TableName() string {
+	return "reactions"
+}
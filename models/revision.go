@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// PostRevision is a post as it was after a change, revisions are never
+// changed
+type PostRevision struct {
+	ID     int64
+	PostID int64 `gorm:"uniqueIndex:idx_post_revisions_post_number"`
+	// Number counts the revisions of a post from 1
+	Number    int `gorm:"uniqueIndex:idx_post_revisions_post_number"`
+	Title     string
+	Content   string `gorm:"type:text"`
+	Thumbnail string `gorm:"type:text"`
+	// EditorID is the user who made the change, nil when nobody was logged
+	// in
+	EditorID *int64
+	// RestoredFrom is the number of the revision this one restored, zero
+	// for an edit
+	RestoredFrom int
+	CreatedAt    time.Time
+}
+
+func (PostRevision) TableName() string {
+	return "post_revisions"
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SchemaMigration records a schema version the database was migrated to
+type SchemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// purposes of a UserToken
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// UserToken is a single-use token mailed to a user. Only the hash of the
+// token is stored, the token itself is only in the mail.
+type UserToken struct {
+	ID      int64
+	UserID  int64 `gorm:"index"`
+	Purpose string
+	Hash    string `gorm:"uniqueIndex"`
+	// Email is the address the token was mailed to, confirming it makes it
+	// the address of the user
+	Email     string `gorm:"not null;default:''"`
+	ExpiresAt time.Time
+	// UsedAt is nil until the token is used
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
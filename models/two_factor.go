@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// TwoFactor is the TOTP second factor of a user. It only counts once the
+// user confirmed it with a code, until then EnabledAt is nil.
+type TwoFactor struct {
+	UserID int64 `gorm:"primaryKey;autoIncrement:false"`
+	// Secret is the base32 TOTP secret
+	Secret    string
+	EnabledAt *time.Time
+	// LastStep is the time step of the last code used, codes of that step
+	// and before are rejected
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+func (TwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code, only its
+// hash is stored
+type RecoveryCode struct {
+	ID     int64
+	UserID int64  `gorm:"index"`
+	Hash   string `gorm:"uniqueIndex"`
+	// UsedAt is nil until the code is used
+	UsedAt *time.Time
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
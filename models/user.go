@@ -1,21 +1,67 @@
 package models
 
+import (
+	"log/slog"
+	"time"
+)
+
 type User struct {
 	ID       int64
 	Name     string
 	Email    string
 	Password string
 	Username string
+	// EmailVerifiedAt is nil until the user confirmed the email address
+	EmailVerifiedAt *time.Time
+	// Status is one of the account states, empty for active accounts
+	Status string
+	// SuspendedUntil ends the suspension of a suspended account
+	SuspendedUntil *time.Time
+	// StatusReason tells the user why the account is not active
+	StatusReason string
 }
 
 type GormUser struct {
-	ID       int64 `gorm:"primary_key"`
-	Name     string
-	Email    string `gorm:"unique"`
-	Password string
-	Username string `gorm:"unique"`
+	ID              int64 `gorm:"primary_key"`
+	Name            string
+	Email           string `gorm:"unique"`
+	Password        string
+	Username        string `gorm:"unique"`
+	EmailVerifiedAt *time.Time
+	Status          string `gorm:"not null;default:''"`
+	SuspendedUntil  *time.Time
+	StatusReason    string `gorm:"not null;default:''"`
+}
+
+// the states of an account, only active accounts log in and write
+const (
+	StatusActive = "active"
+	// StatusDeactivated accounts were closed by their users, logging in
+	// opens them again
+	StatusDeactivated = "deactivated"
+	StatusSuspended   = "suspended"
+	StatusBanned      = "banned"
+	// StatusErased accounts had their personal data removed for good
+	StatusErased = "erased"
+)
+
+// State returns the state of the account at now, a suspension that ended
+// makes it active again
+func (u User) State(now time.Time) string {
+	switch {
+	case u.Status == "":
+		return StatusActive
+	case u.Status == StatusSuspended && u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil):
+		return StatusActive
+	}
+	return u.Status
 }
 
 func (User) TableName() string {
 	return "gorm_users"
 }
+
+// LogValue keeps the password and the email of a logged user out of the logs
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int64("id", u.ID), slog.String("username", u.Username))
+}
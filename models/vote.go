@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// the values of a CommentVote
+const (
+	VoteUp   = 1
+	VoteDown = -1
+)
+
+// CommentVote is the up or down vote of a user on a comment, a user votes
+// at most once on every comment
+type CommentVote struct {
+	ID        int64
+	CommentID int64 `gorm:"uniqueIndex:idx_comment_votes_comment_user"`
+	UserID    int64 `gorm:"uniqueIndex:idx_comment_votes_comment_user;index:idx_comment_votes_user"`
+	// Value is VoteUp or VoteDown
+	Value     int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (CommentVote) TableName() string {
+	return "comment_votes"
+}
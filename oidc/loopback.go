@@ -0,0 +1,104 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultRedirectURL is the loopback address the command line waits on for
+// the provider to send the user back, register it with the provider
+const DefaultRedirectURL = "http://127.0.0.1:8085/callback"
+
+// LoginTimeout bounds how long Login waits for the user
+const LoginTimeout = 5 * time.Minute
+
+// Login signs a user in from a terminal, as native apps do: it serves the
+// redirect URL on the loopback interface, has open show the sign-in URL to
+// the user and returns the claims once the provider sent the user back
+func (c *Client) Login(ctx context.Context, open func(url string) error) (*Claims, error) {
+	redirect, err := url.Parse(c.cfg.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("redirect URL: %w", err)
+	}
+	if redirect.Scheme != "http" || !isLoopback(redirect.Hostname()) {
+		return nil, fmt.Errorf("the redirect URL %s is not a loopback address", c.cfg.RedirectURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LoginTimeout)
+	defer cancel()
+	req, err := c.AuthCodeURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for the provider: %w", err)
+	}
+
+	type result struct {
+		claims *Claims
+		err    error
+	}
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		claims, err := c.callback(ctx, req, r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Sign-in failed: %s\n", html.EscapeString(err.Error()))
+		} else {
+			fmt.Fprintln(w, "Signed in, you can close this window.")
+		}
+		select {
+		case results <- result{claims, err}:
+		default:
+		}
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	if err := open(req.URL); err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-results:
+		return res.claims, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for the provider: %w", ctx.Err())
+	}
+}
+
+// callback checks the query the provider redirected with and exchanges its
+// code
+func (c *Client) callback(ctx context.Context, req *AuthRequest, query url.Values) (*Claims, error) {
+	if query.Get("state") != req.State {
+		return nil, errors.New("the state does not match the sign-in")
+	}
+	if e := query.Get("error"); e != "" {
+		return nil, fmt.Errorf("the provider refused: %s %s", e, query.Get("error_description"))
+	}
+	code := query.Get("code")
+	if code == "" {
+		return nil, errors.New("the provider sent no code")
+	}
+	return c.Exchange(ctx, req, code)
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,284 @@
+// Package oidc signs users in with an OpenID Connect provider, like the
+// identity provider of a company. It is the relying party of the
+// authorization code flow with PKCE: it discovers the endpoints of the
+// provider, sends the user there, exchanges the returned code for an ID token
+// and verifies the token against the cached keys of the provider.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProvider names the provider of the identities when the config does
+// not
+const DefaultProvider = "oidc"
+
+// how long the discovery document and the keys are used before they are
+// fetched again
+const (
+	DiscoveryTTL = time.Hour
+	KeysTTL      = time.Hour
+	// keysRefresh is how often an unknown key id may fetch the keys early,
+	// the provider may have rotated its keys
+	keysRefresh = time.Minute
+)
+
+var (
+	// ErrNotConfigured is returned by ConfigFromEnv when BLOG_OIDC_ISSUER is
+	// not set
+	ErrNotConfigured = errors.New("single sign-on is not configured, set BLOG_OIDC_ISSUER and BLOG_OIDC_CLIENT_ID")
+	// ErrInvalidToken is in the chain of every error about an ID token that
+	// does not verify
+	ErrInvalidToken = errors.New("invalid ID token")
+)
+
+type Config struct {
+	// Provider names the provider in the identities of the users, it must
+	// not change once users signed in
+	Provider string
+	// Issuer is the URL the discovery document is under
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the user back to, it has to
+	// be registered with the provider
+	RedirectURL string
+	// Scopes are requested besides "openid"
+	Scopes []string
+}
+
+// ConfigFromEnv reads BLOG_OIDC_ISSUER, BLOG_OIDC_CLIENT_ID,
+// BLOG_OIDC_CLIENT_SECRET, BLOG_OIDC_REDIRECT_URL, BLOG_OIDC_PROVIDER and
+// BLOG_OIDC_SCOPES. The redirect URL defaults to a loopback address for the
+// command line.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Provider:     os.Getenv("BLOG_OIDC_PROVIDER"),
+		Issuer:       os.Getenv("BLOG_OIDC_ISSUER"),
+		ClientID:     os.Getenv("BLOG_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("BLOG_OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("BLOG_OIDC_REDIRECT_URL"),
+		Scopes:       []string{"email", "profile"},
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return cfg, ErrNotConfigured
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = DefaultProvider
+	}
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = DefaultRedirectURL
+	}
+	if scopes := os.Getenv("BLOG_OIDC_SCOPES"); scopes != "" {
+		cfg.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+	}
+	return cfg, nil
+}
+
+// Discovery is the part of the discovery document the client uses
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the claims of a verified ID token that identify the user
+type Claims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// AuthRequest is a started sign-in. State, Nonce and Verifier are secrets
+// of the client, only URL goes to the user.
+type AuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// Client is the relying party of one provider, it is safe for concurrent
+// use
+type Client struct {
+	cfg  Config
+	http *http.Client
+	now  func() time.Time
+
+	mu           sync.Mutex
+	discovery    *Discovery
+	discoveredAt time.Time
+	keys         map[string]any
+	keysAt       time.Time
+}
+
+// New returns a client of the provider in cfg, httpClient may be nil
+func New(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = DefaultProvider
+	}
+	return &Client{cfg: cfg, http: httpClient, now: time.Now}
+}
+
+// Provider returns the name of the provider
+func (c *Client) Provider() string {
+	return c.cfg.Provider
+}
+
+// RedirectURL returns where the provider sends the user back to
+func (c *Client) RedirectURL() string {
+	return c.cfg.RedirectURL
+}
+
+// Discover returns the discovery document of the issuer, it is fetched once
+// per DiscoveryTTL
+func (c *Client) Discover(ctx context.Context) (*Discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery != nil && c.now().Sub(c.discoveredAt) < DiscoveryTTL {
+		return c.discovery, nil
+	}
+
+	var d Discovery
+	wellKnown := strings.TrimSuffix(c.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, wellKnown, &d); err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", c.cfg.Issuer, err)
+	}
+	if d.Issuer != c.cfg.Issuer {
+		return nil, fmt.Errorf("discovering %s: the document is of issuer %q", c.cfg.Issuer, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("discovering %s: the document lacks an endpoint", c.cfg.Issuer)
+	}
+	c.discovery, c.discoveredAt = &d, c.now()
+	return c.discovery, nil
+}
+
+// AuthCodeURL starts a sign-in, the user opens the URL of the returned
+// request and comes back to the redirect URL with a code
+func (c *Client) AuthCodeURL(ctx context.Context) (*AuthRequest, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := &AuthRequest{}
+	for _, secret := range []*string{&req.State, &req.Nonce, &req.Verifier} {
+		if *secret, err = randomString(); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, c.cfg.Scopes...), " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {challenge(req.Verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	req.URL = d.AuthorizationEndpoint + separator + query.Encode()
+	return req, nil
+}
+
+// Exchange trades the code the provider returned for req for an ID token and
+// returns its verified claims
+func (c *Client) Exchange(ctx context.Context, req *AuthRequest, code string) (*Claims, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"client_id":     {c.cfg.ClientID},
+		"code_verifier": {req.Verifier},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		httpReq.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging the code: %w", err)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("exchanging the code: %s: %w", resp.Status, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("exchanging the code: %s: %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return nil, fmt.Errorf("exchanging the code: %s without an ID token", resp.Status)
+	}
+	return c.Verify(ctx, token.IDToken, req.Nonce)
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func randomString() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// challenge is the S256 code challenge of RFC 7636
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,209 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"postgresql-blog/oidc/oidctest"
+)
+
+var alice = oidctest.User{Subject: "alice-at-idp", Email: "alice@example.com", EmailVerified: true, Name: "Alice", PreferredUsername: "alice"}
+
+func newClient(t *testing.T) (*Client, *oidctest.Provider) {
+	t.Helper()
+	provider, err := oidctest.New(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+	c := New(Config{
+		Issuer:       provider.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://127.0.0.1:8085/callback",
+	}, nil)
+	return c, provider
+}
+
+// authorize starts a sign-in and returns the query the provider redirects
+// back with
+func authorize(t *testing.T, c *Client) (*AuthRequest, url.Values) {
+	t.Helper()
+	req, err := c.AuthCodeURL(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirects.Get(req.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(back.String(), c.RedirectURL()) {
+		t.Fatalf("redirected to %s, want %s", back, c.RedirectURL())
+	}
+	return req, back.Query()
+}
+
+// token returns an ID token for claims, on top of the ones a valid token
+// of the provider has
+func token(t *testing.T, provider *oidctest.Provider, claims map[string]any) string {
+	t.Helper()
+	valid := map[string]any{
+		"iss":   provider.URL,
+		"sub":   alice.Subject,
+		"aud":   oidctest.ClientID,
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": "nonce",
+	}
+	for name, value := range claims {
+		valid[name] = value
+	}
+	raw, err := provider.Sign(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDiscover(t *testing.T) {
+	c, provider := newClient(t)
+	d, err := c.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Issuer != provider.URL || d.TokenEndpoint != provider.URL+"/token" || d.JWKSURI != provider.URL+"/keys" {
+		t.Fatalf("got %+v, want the endpoints of %s", d, provider.URL)
+	}
+
+	// the document has to be of the configured issuer
+	other := New(Config{Issuer: provider.URL + "/", ClientID: oidctest.ClientID}, nil)
+	if _, err := other.Discover(context.Background()); err == nil {
+		t.Fatal("a document of another issuer was accepted")
+	}
+}
+
+func TestSignIn(t *testing.T) {
+	c, _ := newClient(t)
+	req, query := authorize(t, c)
+
+	authURL, err := url.Parse(req.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(req.Verifier))
+	params := authURL.Query()
+	if params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Fatalf("got challenge %q with %q, want the S256 challenge of the verifier", params.Get("code_challenge"), params.Get("code_challenge_method"))
+	}
+	if strings.Contains(req.URL, req.Verifier) {
+		t.Fatal("the verifier was sent to the provider")
+	}
+
+	claims, err := c.callback(context.Background(), req, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Claims{Issuer: c.cfg.Issuer, Subject: alice.Subject, Email: alice.Email, EmailVerified: true, Name: alice.Name, PreferredUsername: alice.PreferredUsername}
+	if *claims != want {
+		t.Fatalf("got %+v, want %+v", *claims, want)
+	}
+	// the code works once
+	if _, err := c.callback(context.Background(), req, query); err == nil {
+		t.Fatal("a code was exchanged twice")
+	}
+}
+
+func TestWrongVerifier(t *testing.T) {
+	c, _ := newClient(t)
+	req, query := authorize(t, c)
+	forged := *req
+	forged.Verifier = strings.Repeat("x", len(req.Verifier))
+	if _, err := c.callback(context.Background(), &forged, query); err == nil {
+		t.Fatal("the code was exchanged without its verifier")
+	}
+}
+
+func TestStateMismatch(t *testing.T) {
+	c, _ := newClient(t)
+	req, query := authorize(t, c)
+	// the redirect of another sign-in
+	_, other := authorize(t, c)
+	if _, err := c.callback(context.Background(), req, other); err == nil {
+		t.Fatal("a redirect with another state was accepted")
+	}
+	query.Set("state", "")
+	if _, err := c.callback(context.Background(), req, query); err == nil {
+		t.Fatal("a redirect without a state was accepted")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	c, provider := newClient(t)
+	ctx := context.Background()
+	if _, err := c.Verify(ctx, token(t, provider, nil), "nonce"); err != nil {
+		t.Fatalf("a valid token: %v", err)
+	}
+
+	valid := token(t, provider, nil)
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+provider.URL+`","sub":"mallory","aud":"blog","exp":9999999999}`)) + "." + parts[2]
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+
+	tests := map[string]struct {
+		raw   string
+		nonce string
+	}{
+		"bad signature":   {tampered, ""},
+		"no signature":    {unsigned, ""},
+		"other nonce":     {valid, "another nonce"},
+		"other issuer":    {token(t, provider, map[string]any{"iss": "https://evil.example"}), "nonce"},
+		"other audience":  {token(t, provider, map[string]any{"aud": "another-client"}), "nonce"},
+		"expired":         {token(t, provider, map[string]any{"exp": time.Now().Add(-2 * leeway).Unix()}), "nonce"},
+		"issued later":    {token(t, provider, map[string]any{"iat": time.Now().Add(2 * leeway).Unix()}), "nonce"},
+		"without subject": {token(t, provider, map[string]any{"sub": ""}), "nonce"},
+	}
+	for name, test := range tests {
+		if _, err := c.Verify(ctx, test.raw, test.nonce); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: got %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	c, provider := newClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	if _, err := c.Verify(ctx, token(t, provider, nil), "nonce"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	rotated := token(t, provider, nil)
+
+	// the keys were fetched just now, an unknown key does not fetch them
+	// again yet
+	now = now.Add(keysRefresh / 2)
+	if _, err := c.Verify(ctx, rotated, "nonce"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("got %v right after the fetch, want the unknown key rejected", err)
+	}
+	now = now.Add(keysRefresh)
+	if _, err := c.Verify(ctx, rotated, "nonce"); err != nil {
+		t.Fatalf("got %v after the refresh interval, want the new key fetched", err)
+	}
+}
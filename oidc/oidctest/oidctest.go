@@ -0,0 +1,223 @@
+// Package oidctest runs a minimal OpenID Connect provider on a loopback
+// address, for trying the single sign-on without a real identity provider.
+// It signs in the configured user right away, without asking for a
+// password.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	ClientID     = "blog"
+	ClientSecret = "secret"
+)
+
+// User is who the provider signs in
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type Provider struct {
+	// URL is the issuer
+	URL string
+	// User is signed in by every authorization request, it can be changed
+	// between sign-ins
+	User User
+
+	server *httptest.Server
+
+	mu sync.Mutex
+	// key signs the tokens, keyID names it in the published keys
+	key   *rsa.PrivateKey
+	keyID string
+	codes map[string]grant
+}
+
+// grant is what an issued code stands for
+type grant struct {
+	user        User
+	redirectURI string
+	nonce       string
+	challenge   string
+}
+
+// New starts a provider, Close stops it
+func New(user User) (*Provider, error) {
+	p := &Provider{User: user, codes: map[string]grant{}}
+	if err := p.RotateKey(); err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	p.URL = p.server.URL
+	return p, nil
+}
+
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// RotateKey replaces the signing key by a new one with a new key id, the old
+// key is not published any more
+func (p *Provider) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key, p.keyID = key, "key-"+random()
+	return nil
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	pub, kid := p.key.PublicKey, p.keyID
+	p.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+// authorize signs the user in and redirects back with a code
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("client_id") != ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	back := redirectURI.Query()
+	back.Set("state", q.Get("state"))
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		back.Set("error", "invalid_request")
+		back.Set("error_description", "PKCE with S256 is required")
+	} else {
+		code := random()
+		p.mu.Lock()
+		p.codes[code] = grant{user: p.User, redirectURI: q.Get("redirect_uri"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+		p.mu.Unlock()
+		back.Set("code", code)
+	}
+	redirectURI.RawQuery = back.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// token exchanges a code once for an ID token
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != ClientID || secret != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	p.mu.Lock()
+	g, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.Sign(map[string]any{
+		"iss":                p.URL,
+		"sub":                g.user.Subject,
+		"aud":                ClientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              g.nonce,
+		"email":              g.user.Email,
+		"email_verified":     g.user.EmailVerified,
+		"name":               g.user.Name,
+		"preferred_username": g.user.PreferredUsername,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": random(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// Sign returns claims as a compact RS256 JWT signed with the current key,
+// tests use it for tokens the provider would not issue
+func (p *Provider) Sign(claims map[string]any) (string, error) {
+	p.mu.Lock()
+	key, kid := p.key, p.keyID
+	p.mu.Unlock()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func random() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,293 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// leeway is the clock difference allowed to the provider
+const leeway = time.Minute
+
+// algorithms are the signatures the client verifies, "none" and the
+// symmetric ones are never accepted
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type idToken struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     verified `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+// audience is a string or a list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// verified is a boolean that some providers send as a string
+type verified bool
+
+func (v *verified) UnmarshalJSON(data []byte) error {
+	*v = verified(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// Verify checks the signature, the issuer, the audience, the expiry and the
+// nonce of a compact ID token and returns its claims
+func (c *Client) Verify(ctx context.Context, raw, nonce string) (*Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a signed JWT", ErrInvalidToken)
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	hash, ok := algorithms[h.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, h.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	key, err := c.key(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(key, h.Alg, hash, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var t idToken
+	if err := decodeSegment(parts[1], &t); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := c.check(t, nonce); err != nil {
+		return nil, err
+	}
+	return &Claims{
+		Issuer:            t.Issuer,
+		Subject:           t.Subject,
+		Email:             t.Email,
+		EmailVerified:     bool(t.EmailVerified),
+		Name:              t.Name,
+		PreferredUsername: t.PreferredUsername,
+	}, nil
+}
+
+func (c *Client) check(t idToken, nonce string) error {
+	now := c.now()
+	switch {
+	case t.Issuer != c.cfg.Issuer:
+		return fmt.Errorf("%w: issued by %q", ErrInvalidToken, t.Issuer)
+	case t.Subject == "":
+		return fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case !contains(t.Audience, c.cfg.ClientID):
+		return fmt.Errorf("%w: not issued for this client", ErrInvalidToken)
+	case len(t.Audience) > 1 && t.AuthorizedParty != c.cfg.ClientID:
+		return fmt.Errorf("%w: authorized party %q", ErrInvalidToken, t.AuthorizedParty)
+	case now.After(time.Unix(t.Expiry, 0).Add(leeway)):
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	case t.IssuedAt != 0 && time.Unix(t.IssuedAt, 0).After(now.Add(leeway)):
+		return fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	case nonce != "" && t.Nonce != nonce:
+		return fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+	return nil
+}
+
+func verifySignature(key any, alg string, hash crypto.Hash, signed string, signature []byte) error {
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg[:2] != "RS" {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(signature) != 2*size {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: the key does not fit algorithm %s", ErrInvalidToken, alg)
+}
+
+// key returns the key with id kid. An unknown id fetches the keys again, at
+// most once per keysRefresh.
+func (c *Client) key(ctx context.Context, kid string) (any, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	age := c.now().Sub(c.keysAt)
+	if key, ok := c.cachedKey(kid); ok && age < KeysTTL {
+		return key, nil
+	}
+	if c.keys != nil && age < keysRefresh {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+
+	var set jwks
+	if err := c.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching the keys of %s: %w", c.cfg.Issuer, err)
+	}
+	c.keys, c.keysAt = set.publicKeys(), c.now()
+	if key, ok := c.cachedKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// cachedKey finds kid in the cached keys, a token without a key id takes
+// the only key there is
+func (c *Client) cachedKey(kid string) (any, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// publicKeys returns the signing keys of the set by id, keys that do not
+// parse are skipped
+func (set jwks) publicKeys() map[string]any {
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("bad exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("point not on curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
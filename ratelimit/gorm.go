@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Gorm keeps the states in the rate_limits table of a gorm database
+type Gorm struct {
+	db *gorm.DB
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+func NewGorm(db *gorm.DB) *Gorm {
+	return &Gorm{db: db}
+}
+
+func (g *Gorm) Get(ctx context.Context, key string) (State, error) {
+	var row models.RateLimit
+	err := g.db.WithContext(ctx).Where("key = ? AND expires_at > ?", key, time.Now()).Limit(1).Find(&row).Error
+	if err != nil {
+		return State{}, repository.TranslateError(err)
+	}
+	return stateOf(row), nil
+}
+
+func (g *Gorm) Update(ctx context.Context, key string, fn func(State) State) error {
+	now := time.Now()
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the row exists before it is locked, so concurrent updates of a new
+		// key wait for each other too
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RateLimit{Key: key}).Error
+		if err != nil {
+			return err
+		}
+		var row models.RateLimit
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).Take(&row).Error
+		if err != nil {
+			return err
+		}
+		s := State{}
+		if now.Before(row.ExpiresAt) {
+			s = stateOf(row)
+		}
+		return tx.Save(rowOf(key, fn(s))).Error
+	})
+	if err != nil {
+		return repository.TranslateError(err)
+	}
+	g.prune(ctx, now)
+	return nil
+}
+
+func (g *Gorm) Delete(ctx context.Context, key string) error {
+	err := g.db.WithContext(ctx).Where("key = ?", key).Delete(&models.RateLimit{}).Error
+	return repository.TranslateError(err)
+}
+
+// prune deletes the expired rows at most every pruneInterval, a failure is
+// left to the next time
+func (g *Gorm) prune(ctx context.Context, now time.Time) {
+	g.mu.Lock()
+	if now.Sub(g.pruned) < pruneInterval {
+		g.mu.Unlock()
+		return
+	}
+	g.pruned = now
+	g.mu.Unlock()
+	g.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.RateLimit{})
+}
+
+func stateOf(row models.RateLimit) State {
+	return State{
+		Tokens:      row.Tokens,
+		RefilledAt:  row.RefilledAt,
+		Failures:    row.Failures,
+		LockedUntil: row.LockedUntil,
+		ExpiresAt:   row.ExpiresAt,
+	}
+}
+
+func rowOf(key string, s State) *models.RateLimit {
+	return &models.RateLimit{
+		Key:         key,
+		Tokens:      s.Tokens,
+		RefilledAt:  s.RefilledAt,
+		Failures:    s.Failures,
+		LockedUntil: s.LockedUntil,
+		ExpiresAt:   s.ExpiresAt,
+	}
+}
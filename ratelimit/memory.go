@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the stores drop the expired states
+const pruneInterval = time.Minute
+
+// Memory keeps the states in the process, they are lost on a restart
+type Memory struct {
+	mu     sync.Mutex
+	states map[string]State
+	pruned time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{states: map[string]State{}}
+}
+
+func (m *Memory) Get(_ context.Context, key string) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.get(key, time.Now()), nil
+}
+
+func (m *Memory) Update(_ context.Context, key string, fn func(State) State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.states[key] = fn(m.get(key, now))
+	if now.Sub(m.pruned) > pruneInterval {
+		m.prune(now)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, key)
+	return nil
+}
+
+func (m *Memory) get(key string, now time.Time) State {
+	s := m.states[key]
+	if now.After(s.ExpiresAt) {
+		return State{}
+	}
+	return s
+}
+
+func (m *Memory) prune(now time.Time) {
+	for key, s := range m.states {
+		if now.After(s.ExpiresAt) {
+			delete(m.states, key)
+		}
+	}
+	m.pruned = now
+}
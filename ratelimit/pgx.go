@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	insertRateLimit = `INSERT INTO rate_limits (key, tokens, refilled_at, failures, locked_until, expires_at)
+VALUES ($1, 0, $2, 0, $2, $2) ON CONFLICT (key) DO NOTHING`
+	rateLimitColumns       = `tokens, refilled_at, failures, locked_until, expires_at`
+	selectRateLimit        = `SELECT ` + rateLimitColumns + ` FROM rate_limits WHERE key = $1 AND expires_at > $2`
+	selectRateLimitForLock = `SELECT ` + rateLimitColumns + ` FROM rate_limits WHERE key = $1 FOR UPDATE`
+	updateRateLimit        = `UPDATE rate_limits SET tokens = $2, refilled_at = $3, failures = $4, locked_until = $5, expires_at = $6 WHERE key = $1`
+	deleteRateLimit        = `DELETE FROM rate_limits WHERE key = $1`
+	pruneRateLimits        = `DELETE FROM rate_limits WHERE expires_at <= $1`
+)
+
+// Pgx keeps the states in the rate_limits table through a pgx pool
+type Pgx struct {
+	pool *pgxpool.Pool
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+func NewPgx(pool *pgxpool.Pool) *Pgx {
+	return &Pgx{pool: pool}
+}
+
+func scanState(row pgx.Row) (State, error) {
+	var s State
+	err := row.Scan(&s.Tokens, &s.RefilledAt, &s.Failures, &s.LockedUntil, &s.ExpiresAt)
+	return s, err
+}
+
+func (p *Pgx) Get(ctx context.Context, key string) (State, error) {
+	s, err := scanState(p.pool.QueryRow(ctx, selectRateLimit, key, time.Now()))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return State{}, nil
+	case err != nil:
+		return State{}, repository.TranslateError(err)
+	}
+	return s, nil
+}
+
+func (p *Pgx) Update(ctx context.Context, key string, fn func(State) State) error {
+	now := time.Now()
+	err := pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, insertRateLimit, key, time.Time{}); err != nil {
+			return err
+		}
+		s, err := scanState(tx.QueryRow(ctx, selectRateLimitForLock, key))
+		if err != nil {
+			return err
+		}
+		if !now.Before(s.ExpiresAt) {
+			s = State{}
+		}
+		s = fn(s)
+		_, err = tx.Exec(ctx, updateRateLimit, key, s.Tokens, s.RefilledAt, s.Failures, s.LockedUntil, s.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		return repository.TranslateError(err)
+	}
+	p.prune(ctx, now)
+	return nil
+}
+
+func (p *Pgx) Delete(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, deleteRateLimit, key)
+	return repository.TranslateError(err)
+}
+
+// prune deletes the expired rows at most every pruneInterval, a failure is
+// left to the next time
+func (p *Pgx) prune(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	if now.Sub(p.pruned) < pruneInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.pruned = now
+	p.mu.Unlock()
+	p.pool.Exec(ctx, pruneRateLimits, now)
+}
@@ -0,0 +1,228 @@
+// Package ratelimit protects the writes, the logins and the mails from abuse.
+// Posts and comments are limited by token buckets per user and per IP
+// address, logins by a bucket per IP address and a lockout per username and
+// IP address that grows with every failed attempt, mails by a bucket per
+// recipient. The state is kept in a Store, in memory or in the database so
+// the limits survive restarts.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+)
+
+// ErrRateLimited is in the chain of every error a Limiter returns, the
+// outermost *apperr.Error carries the RetryAfter of the caller
+var ErrRateLimited error = &apperr.Error{Kind: apperr.RateLimited, Code: "rate_limited", Message: "too many requests"}
+
+// State is what a Store keeps per key, the zero State is a full bucket
+// without failures
+type State struct {
+	Tokens      float64
+	RefilledAt  time.Time
+	Failures    int64
+	LockedUntil time.Time
+	// ExpiresAt is when the state is back to the zero State, the store may
+	// drop it after that
+	ExpiresAt time.Time
+}
+
+// Store keeps the states by key. An expired state is reported as the zero
+// State.
+type Store interface {
+	Get(ctx context.Context, key string) (State, error)
+	// Update replaces the state of key by what fn returns, no other update
+	// of key runs in between
+	Update(ctx context.Context, key string, fn func(State) State) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Limit is a token bucket of Burst tokens that gets a token back Every
+// interval. The zero Limit allows everything.
+type Limit struct {
+	Every time.Duration
+	Burst int
+}
+
+func (l Limit) disabled() bool {
+	return l.Every <= 0 || l.Burst <= 0
+}
+
+// take takes a token from the bucket in s, when there is none it returns how
+// long it takes until there is one
+func (l Limit) take(s State, now time.Time) (State, time.Duration) {
+	burst := float64(l.Burst)
+	tokens := burst
+	if !s.RefilledAt.IsZero() {
+		tokens = math.Min(burst, s.Tokens+float64(now.Sub(s.RefilledAt))/float64(l.Every))
+	}
+	if tokens < 1 {
+		return s, time.Duration((1 - tokens) * float64(l.Every))
+	}
+	s.Tokens, s.RefilledAt = tokens-1, now
+	s.ExpiresAt = now.Add(time.Duration((burst - s.Tokens) * float64(l.Every)))
+	return s, 0
+}
+
+// Lockout locks a key out after Threshold failures in a row. The first lock
+// lasts Base, every further failure doubles it up to Max. The failures are
+// forgotten after a success or Reset without failure. The zero Lockout
+// never locks.
+type Lockout struct {
+	Threshold int64
+	Base      time.Duration
+	Max       time.Duration
+	Reset     time.Duration
+}
+
+func (l Lockout) disabled() bool {
+	return l.Threshold <= 0 || l.Base <= 0
+}
+
+func (l Lockout) fail(s State, now time.Time) State {
+	s.Failures++
+	if s.Failures >= l.Threshold {
+		lock := l.Base
+		for i := l.Threshold; i < s.Failures && lock < l.Max; i++ {
+			lock *= 2
+		}
+		if lock > l.Max {
+			lock = l.Max
+		}
+		s.LockedUntil = now.Add(lock)
+	}
+	s.ExpiresAt = now.Add(l.Reset)
+	if s.LockedUntil.After(s.ExpiresAt) {
+		s.ExpiresAt = s.LockedUntil
+	}
+	return s
+}
+
+type Config struct {
+	// Posts and Comments limit the writes of every user and, on their own,
+	// of every IP address
+	Posts    Limit
+	Comments Limit
+	// Logins limits the login attempts of every IP address
+	Logins  Limit
+	Lockout Lockout
+	// Mails limits the verification and password reset mails to every
+	// address
+	Mails Limit
+}
+
+var DefaultConfig = Config{
+	Posts:    Limit{Every: time.Minute, Burst: 5},
+	Comments: Limit{Every: 10 * time.Second, Burst: 10},
+	Logins:   Limit{Every: 6 * time.Second, Burst: 20},
+	Lockout:  Lockout{Threshold: 5, Base: time.Minute, Max: time.Hour, Reset: time.Hour},
+	Mails:    Limit{Every: 10 * time.Minute, Burst: 3},
+}
+
+// ConfigFromEnv returns DefaultConfig, or a config without limits when
+// BLOG_RATE_LIMIT is "off"
+func ConfigFromEnv() Config {
+	switch strings.ToLower(os.Getenv("BLOG_RATE_LIMIT")) {
+	case "off", "0", "false", "no":
+		return Config{}
+	}
+	return DefaultConfig
+}
+
+// Limiter checks the limits of cfg against the states in a Store
+type Limiter struct {
+	store Store
+	cfg   Config
+	now   func() time.Time
+}
+
+func New(store Store, cfg Config) *Limiter {
+	return &Limiter{store: store, cfg: cfg, now: time.Now}
+}
+
+// Allow takes a token from the bucket of key, it returns an error wrapping
+// ErrRateLimited about entity when the bucket is empty
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit, entity string) error {
+	if limit.disabled() {
+		return nil
+	}
+	var wait time.Duration
+	err := l.store.Update(ctx, key, func(s State) State {
+		s, wait = limit.take(s, l.now())
+		return s
+	})
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return limited("rate_limited", entity, fmt.Sprintf("too many %ss", entity), wait)
+	}
+	return nil
+}
+
+// Locked returns an error wrapping ErrRateLimited while key is locked out
+func (l *Limiter) Locked(ctx context.Context, key, entity string) error {
+	if l.cfg.Lockout.disabled() {
+		return nil
+	}
+	s, err := l.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if wait := s.LockedUntil.Sub(l.now()); wait > 0 {
+		return limited("locked_out", entity, "too many failed logins", wait)
+	}
+	return nil
+}
+
+// Failed counts a failed attempt of key and locks it out when there were
+// too many
+func (l *Limiter) Failed(ctx context.Context, key string) error {
+	if l.cfg.Lockout.disabled() {
+		return nil
+	}
+	return l.store.Update(ctx, key, func(s State) State {
+		return l.cfg.Lockout.fail(s, l.now())
+	})
+}
+
+// Succeeded forgets the failed attempts of key
+func (l *Limiter) Succeeded(ctx context.Context, key string) error {
+	if l.cfg.Lockout.disabled() {
+		return nil
+	}
+	return l.store.Delete(ctx, key)
+}
+
+func limited(code, entity, message string, wait time.Duration) error {
+	// round up, waiting for less than the rounded delay would fail again
+	wait = (wait + time.Second - 1).Truncate(time.Second)
+	return &apperr.Error{
+		Kind:       apperr.RateLimited,
+		Code:       code,
+		Entity:     entity,
+		Message:    fmt.Sprintf("%s, try again in %s", message, wait),
+		RetryAfter: wait,
+		Err:        ErrRateLimited,
+	}
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context for the calls made for a client at ip, the
+// limits per IP address only apply to calls with one
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP returns the address set by WithClientIP
+func ClientIP(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok && ip != ""
+}
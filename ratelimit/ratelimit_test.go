@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/service"
+)
+
+func TestLimitTake(t *testing.T) {
+	limit := Limit{Every: 10 * time.Second, Burst: 2}
+	start := time.Now()
+	steps := []struct {
+		after time.Duration
+		wait  time.Duration
+	}{
+		// the bucket starts full
+		{0, 0},
+		{0, 0},
+		{0, 10 * time.Second},
+		{5 * time.Second, 5 * time.Second},
+		{10 * time.Second, 0},
+		{10 * time.Second, 10 * time.Second},
+		// it refills up to the burst only
+		{time.Hour, 0},
+		{time.Hour, 0},
+		{time.Hour, 10 * time.Second},
+	}
+	var s State
+	for i, step := range steps {
+		var wait time.Duration
+		s, wait = limit.take(s, start.Add(step.after))
+		if wait != step.wait {
+			t.Fatalf("take %d after %v waits %v, want %v", i, step.after, wait, step.wait)
+		}
+	}
+	if want := start.Add(time.Hour + 20*time.Second); !s.ExpiresAt.Equal(want) {
+		t.Fatalf("the empty bucket expires at %v, want when it is full again at %v", s.ExpiresAt, want)
+	}
+}
+
+func TestLockoutFail(t *testing.T) {
+	lockout := Lockout{Threshold: 3, Base: time.Minute, Max: 4 * time.Minute, Reset: time.Hour}
+	now := time.Now()
+	// the lock starts at the threshold and doubles up to the max
+	locks := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	var s State
+	for i, lock := range locks {
+		s = lockout.fail(s, now)
+		if got := s.LockedUntil.Sub(now); lock > 0 && got != lock || lock == 0 && !s.LockedUntil.IsZero() {
+			t.Fatalf("failure %d locks for %v, want %v", i+1, got, lock)
+		}
+		if !s.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Fatalf("failure %d expires at %v, want after the reset", i+1, s.ExpiresAt)
+		}
+	}
+
+	long := Lockout{Threshold: 1, Base: 2 * time.Hour, Max: 2 * time.Hour, Reset: time.Hour}
+	s = long.fail(State{}, now)
+	if !s.ExpiresAt.Equal(s.LockedUntil) {
+		t.Fatalf("a lock longer than the reset expires at %v, want with the lock at %v", s.ExpiresAt, s.LockedUntil)
+	}
+}
+
+// passwords logs in alice with the password "secret"
+type passwords struct {
+	service.Users
+}
+
+func (passwords) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	if username != "alice" || password != "secret" {
+		return nil, &apperr.Error{Kind: apperr.NotFound, Entity: "user"}
+	}
+	return &models.User{ID: 1, Username: "alice"}, nil
+}
+
+func TestLoginLockoutPerAddress(t *testing.T) {
+	now := time.Now()
+	l := New(NewMemory(), Config{Lockout: Lockout{Threshold: 2, Base: time.Minute, Max: time.Hour, Reset: time.Hour}})
+	l.now = func() time.Time { return now }
+	users := l.Services(service.Services{Users: passwords{}}).Users
+	attacker := WithClientIP(context.Background(), "192.0.2.1")
+	owner := WithClientIP(context.Background(), "198.51.100.1")
+
+	for i := 0; i < 2; i++ {
+		if _, err := users.GetUserByUsernameAndPassword(attacker, "alice", "guess"); !apperr.Is(err, apperr.NotFound) {
+			t.Fatalf("got %v for a wrong password, want NotFound", err)
+		}
+	}
+	_, err := users.GetUserByUsernameAndPassword(attacker, "alice", "secret")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v after too many wrong passwords, want the lockout", err)
+	}
+	if _, err := users.GetUserByUsernameAndPassword(owner, "alice", "secret"); err != nil {
+		t.Fatalf("the lockout of another address kept the owner out: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := users.GetUserByUsernameAndPassword(attacker, "alice", "secret"); err != nil {
+		t.Fatalf("got %v once the lock ran out", err)
+	}
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/service"
+)
+
+// Services returns services whose new posts, new comments, logins and mails
+// are limited, the other calls go straight to s
+func (l *Limiter) Services(s service.Services) service.Services {
+	return service.Services{
+		Users:     &users{Users: s.Users, l: l},
+		Posts:     &posts{Posts: s.Posts, l: l},
+		Comments:  &comments{Comments: s.Comments, l: l},
+		Profiles:  s.Profiles,
+		Reactions: s.Reactions,
+		Audit:     s.Audit,
+	}
+}
+
+// allowWrite takes a token of the user and one of the client address
+func (l *Limiter) allowWrite(ctx context.Context, entity string, userID uint64, limit Limit) error {
+	if err := l.Allow(ctx, entity+":user:"+strconv.FormatUint(userID, 10), limit, entity); err != nil {
+		return err
+	}
+	if ip, ok := ClientIP(ctx); ok {
+		return l.Allow(ctx, entity+":ip:"+ip, limit, entity)
+	}
+	return nil
+}
+
+type posts struct {
+	service.Posts
+	l *Limiter
+}
+
+func (p *posts) CreatePost(ctx context.Context, post models.Post) (*models.Post, error) {
+	if err := p.l.allowWrite(ctx, "post", post.UserID, p.l.cfg.Posts); err != nil {
+		return nil, apperr.Wrap(err, "post")
+	}
+	return p.Posts.CreatePost(ctx, post)
+}
+
+// ApplyDraft of a new post creates it, it takes from the limit of the posts
+func (p *posts) ApplyDraft(ctx context.Context, userID, postID int64, opts service.ApplyDraftOptions) (*models.Post, error) {
+	if postID == 0 {
+		if err := p.l.allowWrite(ctx, "post", uint64(userID), p.l.cfg.Posts); err != nil {
+			return nil, apperr.Wrap(err, "post")
+		}
+	}
+	return p.Posts.ApplyDraft(ctx, userID, postID, opts)
+}
+
+type comments struct {
+	service.Comments
+	l *Limiter
+}
+
+func (c *comments) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	if err := c.l.allowWrite(ctx, "comment", comment.UserID, c.l.cfg.Comments); err != nil {
+		return nil, apperr.Wrap(err, "comment")
+	}
+	return c.Comments.CreateComment(ctx, comment)
+}
+
+type users struct {
+	service.Users
+	l *Limiter
+}
+
+// GetUserByUsernameAndPassword is the login. A username is locked out of the
+// client IP address that tried too many wrong passwords, the other
+// addresses still log in.
+func (u *users) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	return u.login(ctx, userKey(ctx, username), func(ctx context.Context) (*models.User, error) {
+		return u.Users.GetUserByUsernameAndPassword(ctx, username, password)
+	})
+}
+
+// LoginWithCode shares the lockout of the login, wrong codes count like
+// wrong passwords
+func (u *users) LoginWithCode(ctx context.Context, username, password, code string) (*models.User, error) {
+	return u.login(ctx, userKey(ctx, username), func(ctx context.Context) (*models.User, error) {
+		return u.Users.LoginWithCode(ctx, username, password, code)
+	})
+}
+
+// LoginWithIdentity locks out the identity after too many wrong codes, the
+// provider checked everything else
+func (u *users) LoginWithIdentity(ctx context.Context, identity service.ExternalIdentity, code string) (*models.User, error) {
+	key := "login:identity:" + identity.Provider + ":" + identity.Subject
+	return u.login(ctx, key, func(ctx context.Context) (*models.User, error) {
+		return u.Users.LoginWithIdentity(ctx, identity, code)
+	})
+}
+
+// userKey is the lockout key of username at the client IP address, so
+// nobody keeps the owner out by failing from somewhere else
+func userKey(ctx context.Context, username string) string {
+	key := "login:user:" + strings.ToLower(username)
+	if ip, ok := ClientIP(ctx); ok {
+		key += ":ip:" + ip
+	}
+	return key
+}
+
+// login runs a login under the limit of the client IP address and the
+// lockout of key
+func (u *users) login(ctx context.Context, key string, login func(ctx context.Context) (*models.User, error)) (*models.User, error) {
+	if ip, ok := ClientIP(ctx); ok {
+		if err := u.l.Allow(ctx, "login:ip:"+ip, u.l.cfg.Logins, "login"); err != nil {
+			return nil, apperr.Wrap(err, "user")
+		}
+	}
+	if err := u.l.Locked(ctx, key, "user"); err != nil {
+		return nil, apperr.Wrap(err, "user")
+	}
+
+	user, err := login(ctx)
+	switch {
+	case err == nil:
+		err = u.l.Succeeded(ctx, key)
+	case apperr.Is(err, apperr.NotFound), errors.Is(err, service.ErrInvalidCode):
+		if failErr := u.l.Failed(ctx, key); failErr != nil {
+			err = failErr
+		}
+	}
+	if err != nil {
+		return nil, apperr.Wrap(err, "user")
+	}
+	return user, nil
+}
+
+func (u *users) RequestEmailVerification(ctx context.Context, userID int64) error {
+	if err := u.l.Allow(ctx, "mail:user:"+strconv.FormatInt(userID, 10), u.l.cfg.Mails, "mail"); err != nil {
+		return apperr.Wrap(err, "user")
+	}
+	return u.Users.RequestEmailVerification(ctx, userID)
+}
+
+func (u *users) RequestPasswordReset(ctx context.Context, email string) error {
+	if err := u.l.Allow(ctx, "mail:email:"+strings.ToLower(strings.TrimSpace(email)), u.l.cfg.Mails, "mail"); err != nil {
+		return apperr.Wrap(err, "user")
+	}
+	return u.Users.RequestPasswordReset(ctx, email)
+}
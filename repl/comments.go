@@ -0,0 +1,181 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (r *REPL) listComments(string) error {
+	all, err := r.commentService.GetAllComments(r.ctx)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, comment := range all {
+		r.printf("ID: %d, User ID: %d, Post ID: %d, Content: %s\n", comment.ID, comment.UserID, comment.PostID, comment.Content)
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) myComments(string) error {
+	all, err := r.commentService.GetCommentByUserID(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, comment := range all {
+		r.printf("ID: %d, Post ID: %d, Content: %s\n", comment.ID, comment.PostID, comment.Content)
+	}
+	r.println(separator)
+	return nil
+}
+
+// postComments lists the comments of a post, the post id may be followed by
+// the sort: old, new, top or controversial
+func (r *REPL) postComments(arg string) error {
+	idArg, sortArg, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	postID, err := parseID(idArg)
+	if err != nil {
+		return err
+	}
+	if _, err := r.postService.GetPostByID(r.ctx, postID); err != nil {
+		return err
+	}
+	all, err := r.commentService.GetCommentByPostID(r.ctx, postID, repository.CommentSort(strings.TrimSpace(sortArg)))
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, comment := range all {
+		r.printf("ID: %d, User ID: %d, Content: %s", comment.ID, comment.UserID, comment.Content)
+		if comment.Upvotes != 0 || comment.Downvotes != 0 {
+			r.printf(", Votes: +%d -%d", comment.Upvotes, comment.Downvotes)
+		}
+		if counts := formatReactions(comment.Reactions); counts != "" {
+			r.printf(", Reactions: %s", counts)
+		}
+		r.println()
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) addComment(arg string) error {
+	postID, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	post, err := r.postService.GetPostByID(r.ctx, postID)
+	if err != nil {
+		return err
+	}
+	r.printf("Commenting on post %d: %s\n", post.ID, post.Title)
+
+	content, err := r.askContent("Enter Content", "")
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		return errors.New("the comment can not be empty")
+	}
+
+	created, err := r.commentService.CreateComment(r.ctx, models.Comment{
+		UserID:  uint64(r.user.ID),
+		PostID:  uint64(post.ID),
+		Content: content,
+	})
+	if err != nil {
+		return err
+	}
+	r.printf("Comment created successfully with ID %d!\n", created.ID)
+	return nil
+}
+
+// ownComment returns the comment with the id in arg if it belongs to the
+// logged in user
+func (r *REPL) ownComment(arg string) (*models.Comment, error) {
+	id, err := parseID(arg)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := r.commentService.GetCommentByID(r.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != uint64(r.user.ID) {
+		return nil, apperr.New(apperr.Forbidden, "comment", fmt.Sprintf("comment %d belongs to another user", id))
+	}
+	return comment, nil
+}
+
+func (r *REPL) editComment(arg string) error {
+	comment, err := r.ownComment(arg)
+	if err != nil {
+		return err
+	}
+
+	updated := *comment
+	if updated.Content, err = r.askContent("New Content", comment.Content); err != nil {
+		return err
+	}
+	updated.UpdatedAt = time.Now()
+
+	if _, err := r.commentService.UpdateCommentByID(r.ctx, updated); err != nil {
+		return err
+	}
+	r.println("Comment updated successfully!")
+	return nil
+}
+
+func (r *REPL) deleteComment(arg string) error {
+	comment, err := r.ownComment(arg)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.confirm(fmt.Sprintf("Are you sure you want to delete the comment with ID %d?", comment.ID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("Comment not deleted!")
+		return nil
+	}
+	if err := r.commentService.DeleteCommentByID(r.ctx, comment.ID); err != nil {
+		return err
+	}
+	r.println("Comment deleted successfully!")
+	return nil
+}
+
+func (r *REPL) upvoteComment(arg string) error {
+	return r.voteComment(arg, models.VoteUp)
+}
+
+func (r *REPL) downvoteComment(arg string) error {
+	return r.voteComment(arg, models.VoteDown)
+}
+
+func (r *REPL) unvoteComment(arg string) error {
+	return r.voteComment(arg, 0)
+}
+
+func (r *REPL) voteComment(arg string, value int) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	comment, err := r.commentService.VoteComment(r.ctx, id, r.user.ID, value)
+	if err != nil {
+		return err
+	}
+	r.printf("Comment %d now has %d upvotes and %d downvotes\n", comment.ID, comment.Upvotes, comment.Downvotes)
+	return nil
+}
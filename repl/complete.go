@@ -0,0 +1,101 @@
+package repl
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// complete returns the candidates for the last word of line: command names
+// first, then the ids of the users, posts or comments a command expects.
+func (r *REPL) complete(line string) []string {
+	fields := strings.Fields(line)
+	// the word being completed is empty when the line ends with a space
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(line, " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+	typed := strings.ToLower(strings.Join(fields, " "))
+
+	seen := map[string]bool{}
+	var candidates []string
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(fields) < len(words) {
+			if strings.Join(words[:len(fields)], " ") != typed {
+				continue
+			}
+			next := words[len(fields)]
+			if strings.HasPrefix(next, strings.ToLower(word)) && !seen[next] {
+				seen[next] = true
+				candidates = append(candidates, next)
+			}
+			continue
+		}
+		if len(fields) == len(words) && cmd.name == typed && cmd.ids != "" {
+			return filterPrefix(r.ids(cmd.ids), word)
+		}
+	}
+	return candidates
+}
+
+func (r *REPL) ids(kind string) []string {
+	var ids []int64
+	switch kind {
+	case "users":
+		users, _ := r.userService.GetAllUsers(r.ctx)
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+	case "posts":
+		posts, _ := r.postService.GetAllPosts(r.ctx)
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+	case "comments":
+		if r.user == nil {
+			return nil
+		}
+		// only offer the comments that can be edited
+		comments, _ := r.commentService.GetCommentByUserID(r.ctx, r.user.ID)
+		for _, comment := range comments {
+			ids = append(ids, comment.ID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = strconv.FormatInt(id, 10)
+	}
+	return result
+}
+
+func filterPrefix(values []string, prefix string) []string {
+	var result []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func replaceLastWord(line, word string) string {
+	if line == "" || strings.HasSuffix(line, " ") {
+		return line + word
+	}
+	i := strings.LastIndex(line, " ")
+	return line[:i+1] + word
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
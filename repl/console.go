@@ -0,0 +1,188 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var errNoEditor = errors.New("no editor configured, set $EDITOR")
+
+// Console is where the REPL reads its input from and writes its output to.
+type Console interface {
+	io.Writer
+	// ReadLine prints the prompt and returns the next full line without the
+	// trailing newline. It returns io.EOF once the input is closed.
+	ReadLine(prompt string) (string, error)
+	// ReadPassword is ReadLine without echoing the input back.
+	ReadPassword(prompt string) (string, error)
+	// SetCompleter installs the function used for tab completion, consoles
+	// without a line editor ignore it.
+	SetCompleter(complete func(line string) []string)
+	// Edit opens an external editor with the given text and returns the
+	// edited text.
+	Edit(initial string) (string, error)
+}
+
+// plainConsole reads lines from any reader, it is used when the input is not
+// a terminal and in tests.
+type plainConsole struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func NewPlainConsole(in io.Reader, out io.Writer) Console {
+	return &plainConsole{in: bufio.NewReader(in), out: out}
+}
+
+func (c *plainConsole) Write(p []byte) (int, error) {
+	return c.out.Write(p)
+}
+
+func (c *plainConsole) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(c.out, prompt)
+	line, err := c.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *plainConsole) ReadPassword(prompt string) (string, error) {
+	return c.ReadLine(prompt)
+}
+
+func (c *plainConsole) SetCompleter(func(line string) []string) {}
+
+func (c *plainConsole) Edit(initial string) (string, error) {
+	return runEditor(initial, os.Stdin, c.out)
+}
+
+// terminalConsole puts the terminal in raw mode and uses the line editor of
+// x/term, which gives us history on the arrow keys and tab completion.
+type terminalConsole struct {
+	fd       int
+	state    *term.State
+	terminal *term.Terminal
+	complete func(line string) []string
+}
+
+// NewTerminalConsole returns a console with a line editor for the terminal
+// on stdin, and a restore function that must be called before exiting.
+func NewTerminalConsole(stdin *os.File, stdout io.Writer) (Console, func(), error) {
+	fd := int(stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &terminalConsole{
+		fd:    fd,
+		state: state,
+		terminal: term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{stdin, stdout}, ""),
+	}
+	if width, height, err := term.GetSize(fd); err == nil {
+		c.terminal.SetSize(width, height)
+	}
+	c.terminal.AutoCompleteCallback = c.autoComplete
+
+	restore := func() { term.Restore(fd, state) }
+	return c, restore, nil
+}
+
+// IsTerminal reports whether f is an interactive terminal.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+func (c *terminalConsole) Write(p []byte) (int, error) {
+	return c.terminal.Write(p)
+}
+
+func (c *terminalConsole) ReadLine(prompt string) (string, error) {
+	c.terminal.SetPrompt(prompt)
+	return c.terminal.ReadLine()
+}
+
+func (c *terminalConsole) ReadPassword(prompt string) (string, error) {
+	return c.terminal.ReadPassword(prompt)
+}
+
+func (c *terminalConsole) SetCompleter(complete func(line string) []string) {
+	c.complete = complete
+}
+
+func (c *terminalConsole) Edit(initial string) (string, error) {
+	// the editor needs the terminal in its normal mode
+	if err := term.Restore(c.fd, c.state); err != nil {
+		return "", err
+	}
+	defer term.MakeRaw(c.fd)
+	return runEditor(initial, os.Stdin, os.Stdout)
+}
+
+func (c *terminalConsole) autoComplete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || c.complete == nil || pos != len(line) {
+		return "", 0, false
+	}
+
+	candidates := c.complete(line)
+	switch len(candidates) {
+	case 0:
+		return "", 0, false
+	case 1:
+		completed := replaceLastWord(line, candidates[0]) + " "
+		return completed, len(completed), true
+	}
+
+	// several matches, complete what they have in common and list them
+	completed := replaceLastWord(line, commonPrefix(candidates))
+	fmt.Fprintf(c.terminal, "%s\n", strings.Join(candidates, "  "))
+	return completed, len(completed), true
+}
+
+func runEditor(initial string, stdin io.Reader, stdout io.Writer) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		return "", errNoEditor
+	}
+
+	file, err := os.CreateTemp("", "blog-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(initial); err != nil {
+		file.Close()
+		return "", err
+	}
+	file.Close()
+
+	// $EDITOR may contain arguments, e.g. "code --wait"
+	args := append(strings.Fields(editor), file.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running editor: %w", err)
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
@@ -0,0 +1,128 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+// draftPost parses the optional post id of the draft commands, 0 is the
+// draft of a new post
+func draftPost(arg string) (int64, error) {
+	if strings.TrimSpace(arg) == "" {
+		return 0, nil
+	}
+	return parseID(arg)
+}
+
+func (r *REPL) printWarnings(state *service.DraftState) {
+	for _, warning := range state.Warnings() {
+		r.printf("Warning: %s\n", warning)
+	}
+}
+
+func (r *REPL) listDrafts(string) error {
+	drafts, err := r.postService.GetDrafts(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, draft := range drafts {
+		post := "new post"
+		if draft.PostID != 0 {
+			post = fmt.Sprintf("post %d", draft.PostID)
+		}
+		r.printf("%s, Title: %s, Saved: %s\n", post, draft.Title, draft.UpdatedAt.Format(time.DateTime))
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) editDraft(arg string) error {
+	postID, err := draftPost(arg)
+	if err != nil {
+		return err
+	}
+	draft := models.Draft{UserID: r.user.ID, PostID: postID}
+	if postID != 0 {
+		post, err := r.ownPost(arg)
+		if err != nil {
+			return err
+		}
+		draft.Title, draft.Content, draft.Thumbnail = post.Title, post.Content, post.Thumbnail
+	}
+	state, err := r.postService.GetDraft(r.ctx, r.user.ID, postID)
+	switch {
+	case err == nil:
+		r.printf("Resuming the draft saved at %s\n", state.Draft.UpdatedAt.Format(time.DateTime))
+		r.printWarnings(state)
+		draft = state.Draft
+	case !errors.Is(err, repository.ErrNotExist):
+		return err
+	}
+
+	if draft.Title, err = r.askDefault("Title", draft.Title); err != nil {
+		return err
+	}
+	if draft.Content, err = r.askContent("Content", draft.Content); err != nil {
+		return err
+	}
+	if state, err = r.postService.SaveDraft(r.ctx, draft); err != nil {
+		return err
+	}
+	r.printWarnings(state)
+	r.printf("Draft saved, save it to the post with %q\n", strings.TrimSpace("drafts apply "+arg))
+	return nil
+}
+
+func (r *REPL) applyDraft(arg string) error {
+	postID, err := draftPost(arg)
+	if err != nil {
+		return err
+	}
+	post, err := r.postService.ApplyDraft(r.ctx, r.user.ID, postID, service.ApplyDraftOptions{})
+	if e, ok := apperr.As(err); ok && e.Code == "post_changed" {
+		ok, err := r.confirm(fmt.Sprintf("Post %d was changed since the draft was started, overwrite the changes?", postID))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			r.println("Draft not applied!")
+			return nil
+		}
+		post, err = r.postService.ApplyDraft(r.ctx, r.user.ID, postID, service.ApplyDraftOptions{Force: true})
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	r.printf("Draft saved to the post with ID %d!\n", post.ID)
+	return nil
+}
+
+func (r *REPL) discardDraft(arg string) error {
+	postID, err := draftPost(arg)
+	if err != nil {
+		return err
+	}
+	ok, err := r.confirm("Are you sure you want to discard the draft?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("Draft kept!")
+		return nil
+	}
+	if err := r.postService.DiscardDraft(r.ctx, r.user.ID, postID); err != nil {
+		return err
+	}
+	r.println("Draft discarded!")
+	return nil
+}
@@ -0,0 +1,138 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"postgresql-blog/service"
+)
+
+func (r *REPL) deactivateUser(string) error {
+	ok, err := r.confirm("Deactivate your account? Logging in again opens it")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("Account kept!")
+		return nil
+	}
+	if err := r.userService.DeactivateUser(r.ctx, r.user.ID); err != nil {
+		return err
+	}
+	r.user = nil
+	r.println("Account deactivated, you are logged out")
+	return nil
+}
+
+func (r *REPL) suspendUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	answer, err := r.ask("Suspend until (RFC 3339 time or duration like 72h)")
+	if err != nil {
+		return err
+	}
+	until, err := time.Parse(time.RFC3339, answer)
+	if err != nil {
+		d, durationErr := time.ParseDuration(answer)
+		if durationErr != nil {
+			return fmt.Errorf("%q is neither an RFC 3339 time nor a duration", answer)
+		}
+		until = time.Now().Add(d)
+	}
+	reason, err := r.ask("Reason")
+	if err != nil {
+		return err
+	}
+	if err := r.userService.SuspendUser(r.ctx, id, until, reason); err != nil {
+		return err
+	}
+	r.printf("User suspended until %s\n", until.Format(time.RFC3339))
+	return nil
+}
+
+func (r *REPL) banUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	reason, err := r.ask("Reason")
+	if err != nil {
+		return err
+	}
+	if err := r.userService.BanUser(r.ctx, id, reason); err != nil {
+		return err
+	}
+	r.println("User banned!")
+	return nil
+}
+
+func (r *REPL) reactivateUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	if err := r.userService.ReactivateUser(r.ctx, id); err != nil {
+		return err
+	}
+	r.println("User reactivated!")
+	return nil
+}
+
+func (r *REPL) eraseUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	user, err := r.userService.GetUserByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	answer, err := r.askDefault("Keep or remove the posts and comments", string(service.KeepContent))
+	if err != nil {
+		return err
+	}
+	policy, err := service.ParseContentPolicy(answer)
+	if err != nil {
+		return err
+	}
+	ok, err := r.confirm(fmt.Sprintf("Erase the personal data of %s with ID %d for good?", user.Username, user.ID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("User not erased!")
+		return nil
+	}
+	if err := r.userService.EraseUser(r.ctx, id, policy); err != nil {
+		return err
+	}
+	if r.user != nil && r.user.ID == id {
+		r.user = nil
+	}
+	r.println("User erased successfully!")
+	return nil
+}
+
+func (r *REPL) exportUser(file string) error {
+	export, err := r.userService.ExportUser(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(strings.TrimSpace(file), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := export.WriteZip(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	r.printf("Your data was written to %s\n", file)
+	return nil
+}
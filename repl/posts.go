@@ -0,0 +1,262 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (r *REPL) printPost(post models.Post) {
+	r.printf("ID: %d, User ID: %d, Title: %s\n", post.ID, post.UserID, post.Title)
+	r.println(post.Content)
+	if counts := formatReactions(post.Reactions); counts != "" {
+		r.printf("Reactions: %s\n", counts)
+	}
+	r.println(separator)
+}
+
+func (r *REPL) listPosts(string) error {
+	all, err := r.postService.GetAllPosts(r.ctx)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, post := range all {
+		r.printf("ID: %d, User ID: %d, Title: %s\n", post.ID, post.UserID, post.Title)
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) myPosts(string) error {
+	all, err := r.postService.GetPostByUserID(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, post := range all {
+		r.printf("ID: %d, Title: %s\n", post.ID, post.Title)
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) getPost(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	post, err := r.postService.GetPostByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	r.printPost(*post)
+	return nil
+}
+
+func (r *REPL) findPost(title string) error {
+	post, err := r.postService.GetPostByTitle(r.ctx, title)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotExist) {
+			return fmt.Errorf("post with title '%s' does not exist", title)
+		}
+		return err
+	}
+	r.printPost(*post)
+	return nil
+}
+
+func (r *REPL) addPost(string) error {
+	newPost := models.Post{UserID: uint64(r.user.ID)}
+	var err error
+	if newPost.Title, err = r.ask("Enter Title"); err != nil {
+		return err
+	}
+	if newPost.Title == "" {
+		return errors.New("the title can not be empty")
+	}
+	if newPost.Content, err = r.askContent("Enter Content", ""); err != nil {
+		return err
+	}
+
+	created, err := r.postService.CreatePost(r.ctx, newPost)
+	if err != nil {
+		return err
+	}
+	r.printf("Post created successfully with ID %d!\n", created.ID)
+	return nil
+}
+
+// ownPost returns the post with the id in arg if it belongs to the logged in user
+func (r *REPL) ownPost(arg string) (*models.Post, error) {
+	id, err := parseID(arg)
+	if err != nil {
+		return nil, err
+	}
+	post, err := r.postService.GetPostByID(r.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != uint64(r.user.ID) {
+		return nil, apperr.New(apperr.Forbidden, "post", fmt.Sprintf("post %d belongs to another user", id))
+	}
+	return post, nil
+}
+
+func (r *REPL) editPost(arg string) error {
+	post, err := r.ownPost(arg)
+	if err != nil {
+		return err
+	}
+
+	r.printf("Enter new values for the post with ID %d, leave empty to keep the current value:\n", post.ID)
+	updated := *post
+	if updated.Title, err = r.askDefault("New Title", post.Title); err != nil {
+		return err
+	}
+	if updated.Content, err = r.askContent("New Content", post.Content); err != nil {
+		return err
+	}
+	updated.UpdatedAt = time.Now()
+
+	if _, err := r.postService.UpdatePostByID(r.ctx, updated); err != nil {
+		return err
+	}
+	r.println("Post updated successfully!")
+	return nil
+}
+
+func (r *REPL) deletePost(arg string) error {
+	post, err := r.ownPost(arg)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.confirm(fmt.Sprintf("Are you sure you want to delete the post with ID %d?", post.ID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("Post not deleted!")
+		return nil
+	}
+	if err := r.postService.DeletePostByID(r.ctx, post.ID); err != nil {
+		return err
+	}
+	r.println("Post deleted successfully!")
+	return nil
+}
+
+// checkReadable returns a NotFound error unless the revisions of post id may
+// be read, like the API hides the drafts the revisions of an unpublished
+// post are for its author only
+func (r *REPL) checkReadable(id int64) error {
+	post, err := r.postService.GetPostByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	if !post.IsPublished && (r.user == nil || post.UserID != uint64(r.user.ID)) {
+		return apperr.New(apperr.NotFound, "post", "")
+	}
+	return nil
+}
+
+func (r *REPL) listRevisions(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	if err := r.checkReadable(id); err != nil {
+		return err
+	}
+	revisions, err := r.postService.GetPostRevisions(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, revision := range revisions {
+		editor := "unknown"
+		if revision.EditorID != nil {
+			editor = strconv.FormatInt(*revision.EditorID, 10)
+		}
+		r.printf("Revision: %d, Editor ID: %s, At: %s, Title: %s", revision.Number, editor,
+			revision.CreatedAt.Format(time.DateTime), revision.Title)
+		if revision.RestoredFrom != 0 {
+			r.printf(", restored from revision %d", revision.RestoredFrom)
+		}
+		r.println()
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) diffRevisions(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	if err := r.checkReadable(id); err != nil {
+		return err
+	}
+	from, err := r.askRevision("From Revision")
+	if err != nil {
+		return err
+	}
+	to, err := r.askRevision("To Revision")
+	if err != nil {
+		return err
+	}
+	words, err := r.confirm("Compare word by word?")
+	if err != nil {
+		return err
+	}
+	d, err := r.postService.DiffPostRevisions(r.ctx, id, from, to, words)
+	if err != nil {
+		return err
+	}
+	r.printf("%s", d)
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) restoreRevision(arg string) error {
+	post, err := r.ownPost(arg)
+	if err != nil {
+		return err
+	}
+	number, err := r.askRevision("Revision")
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.confirm(fmt.Sprintf("Are you sure you want to restore revision %d of the post with ID %d?", number, post.ID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("Revision not restored!")
+		return nil
+	}
+	if _, err := r.postService.RestorePostRevision(r.ctx, post.ID, number); err != nil {
+		return err
+	}
+	r.println("Revision restored successfully!")
+	return nil
+}
+
+func (r *REPL) askRevision(prompt string) (int, error) {
+	answer, err := r.ask(prompt)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(answer)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid revision number %q", answer)
+	}
+	return number, nil
+}
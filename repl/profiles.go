@@ -0,0 +1,137 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"postgresql-blog/models"
+)
+
+func formatLinks(links []models.Link) string {
+	parts := make([]string, len(links))
+	for i, link := range links {
+		parts[i] = link.Label + "=" + link.URL
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseLinks reads links written like formatLinks does, "-" removes them
+func parseLinks(text string) ([]models.Link, error) {
+	if strings.TrimSpace(text) == "-" {
+		return nil, nil
+	}
+	var links []models.Link
+	for _, part := range strings.Split(text, ",") {
+		label, url, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected label=url, got %q", part)
+		}
+		links = append(links, models.Link{Label: label, URL: url})
+	}
+	return links, nil
+}
+
+func (r *REPL) showProfile(username string) error {
+	profile, err := r.profileService.GetPublicProfile(r.ctx, strings.TrimSpace(username))
+	if err != nil {
+		return err
+	}
+
+	r.println(separator)
+	name := profile.Username
+	if profile.DisplayName != "" {
+		name = fmt.Sprintf("%s (%s)", profile.DisplayName, profile.Username)
+	}
+	r.printf("%s, user %d\n", name, profile.UserID)
+	if profile.Email != "" {
+		r.printf("Email: %s\n", profile.Email)
+	}
+	if profile.Website != "" {
+		r.printf("Website: %s\n", profile.Website)
+	}
+	for _, link := range profile.Links {
+		r.printf("%s: %s\n", link.Label, link.URL)
+	}
+	if profile.Timezone != "" || profile.Locale != "" {
+		r.printf("Timezone: %s, Locale: %s\n", profile.Timezone, profile.Locale)
+	}
+	if profile.Bio != "" {
+		r.println()
+		r.println(profile.Bio)
+		r.println()
+	}
+	r.printf("%d published posts, %d comments\n", len(profile.Posts), profile.CommentCount)
+	for _, post := range profile.Posts {
+		r.printf("  %d: %s (%s)\n", post.ID, post.Title, post.PublishedAt.Format("2006-01-02"))
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) editProfile(string) error {
+	profile, err := r.profileService.GetProfile(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+
+	r.println("Enter new values for your profile, leave empty to keep the current value:")
+	updated := *profile
+	if updated.DisplayName, err = r.askDefault("Display Name", profile.DisplayName); err != nil {
+		return err
+	}
+	if ok, err := r.confirm("Edit the bio?"); err != nil {
+		return err
+	} else if ok {
+		if updated.Bio, err = r.askContent("Bio (Markdown)", profile.Bio); err != nil {
+			return err
+		}
+	}
+	if updated.Website, err = r.askDefault("Website", profile.Website); err != nil {
+		return err
+	}
+	links, err := r.askDefault("Links as label=url, separated by commas, \"-\" for none", formatLinks(profile.Links))
+	if err != nil {
+		return err
+	}
+	if links != formatLinks(profile.Links) {
+		if updated.Links, err = parseLinks(links); err != nil {
+			return err
+		}
+	}
+	if updated.Timezone, err = r.askDefault("Timezone", profile.Timezone); err != nil {
+		return err
+	}
+	if updated.Locale, err = r.askDefault("Locale", profile.Locale); err != nil {
+		return err
+	}
+	if updated.EmailPublic, err = r.confirm("Show your email address to everybody?"); err != nil {
+		return err
+	}
+
+	if _, err := r.profileService.UpdateProfile(r.ctx, updated); err != nil {
+		return err
+	}
+	r.println("Profile updated successfully!")
+	return nil
+}
+
+func (r *REPL) setAvatar(file string) error {
+	data, err := os.ReadFile(strings.TrimSpace(file))
+	if err != nil {
+		return err
+	}
+	if err := r.profileService.SetAvatar(r.ctx, r.user.ID, data); err != nil {
+		return err
+	}
+	r.println("Avatar updated successfully!")
+	return nil
+}
+
+func (r *REPL) removeAvatar(string) error {
+	if err := r.profileService.DeleteAvatar(r.ctx, r.user.ID); err != nil {
+		return err
+	}
+	r.println("Avatar removed.")
+	return nil
+}
@@ -0,0 +1,102 @@
+package repl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// formatReactions prints counts like "like 2, 👍 1", empty without any
+func formatReactions(counts map[string]int) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s %d", kind, counts[kind])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *REPL) reactToPost(arg string) error {
+	return r.react(models.TargetPost, arg)
+}
+
+func (r *REPL) reactToComment(arg string) error {
+	return r.react(models.TargetComment, arg)
+}
+
+// react toggles a reaction of the logged in user, giving it again takes it
+// back
+func (r *REPL) react(targetType, arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	kinds := r.reactionService.ReactionKinds()
+	kind, err := r.askDefault("Reaction, one of "+strings.Join(kinds, " "), models.ReactionLike)
+	if err != nil {
+		return err
+	}
+	toggle, err := r.reactionService.ToggleReaction(r.ctx, models.Reaction{
+		UserID:     r.user.ID,
+		TargetType: targetType,
+		TargetID:   id,
+		Kind:       kind,
+	})
+	if err != nil {
+		return err
+	}
+	if toggle.Added {
+		r.printf("Reacted with %s\n", toggle.Reaction.Kind)
+	} else {
+		r.printf("Took back your %s\n", toggle.Reaction.Kind)
+	}
+	if counts := formatReactions(toggle.Counts); counts != "" {
+		r.printf("Reactions: %s\n", counts)
+	}
+	return nil
+}
+
+func (r *REPL) postReactions(arg string) error {
+	return r.listReactions(models.TargetPost, arg)
+}
+
+func (r *REPL) commentReactions(arg string) error {
+	return r.listReactions(models.TargetComment, arg)
+}
+
+// listReactions prints who reacted a page at a time
+func (r *REPL) listReactions(targetType, arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	filter := repository.ReactionFilter{TargetType: targetType, TargetID: id}
+	r.println(separator)
+	for {
+		page, err := r.reactionService.GetReactions(r.ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, reactor := range page.Reactors {
+			r.printf("%s %s, %s\n", reactor.Kind, reactor.Username, reactor.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		if page.Next == 0 {
+			break
+		}
+		more, err := r.confirm("Show more?")
+		if err != nil || !more {
+			r.println(separator)
+			return err
+		}
+		filter.After = page.Next
+	}
+	r.println(separator)
+	return nil
+}
@@ -0,0 +1,360 @@
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"postgresql-blog/logging"
+	"postgresql-blog/models"
+	"postgresql-blog/oidc"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+const separator = "---------------------------------------------------------------------------"
+
+// contentEnd ends multi-line content, contentEditor opens $EDITOR instead
+const (
+	contentEnd    = "."
+	contentEditor = ":edit"
+)
+
+var (
+	errExit        = errors.New("exit")
+	errNotLoggedIn = errors.New("please log in first with \"login\"")
+)
+
+// REPL is the interactive mode of the blog. It reads one command per line
+// until "exit" or the end of the input.
+type REPL struct {
+	console        Console
+	userService    service.Users
+	postService    service.Posts
+	commentService service.Comments
+	profileService service.Profiles
+	// reactionService gives and lists the reactions to posts and comments
+	reactionService service.Reactions
+	// sso signs users in through a provider, nil when none is configured
+	sso *oidc.Client
+
+	ctx  context.Context
+	user *models.User
+}
+
+type command struct {
+	name  string
+	args  string
+	help  string
+	ids   string // kind of id the argument completes to: "users", "posts" or "comments"
+	login bool
+	run   func(r *REPL, arg string) error
+}
+
+// commands lists every command in the order "help" prints them, the name of
+// a command may have two words
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "help", help: "show this help", run: (*REPL).help},
+		{name: "login sso", help: "log in through the single sign-on provider", run: (*REPL).loginSSO},
+		{name: "login", help: "log in to manage posts and comments", run: (*REPL).login},
+		{name: "logout", help: "log out", run: (*REPL).logout},
+		{name: "whoami", help: "show the logged in user", run: (*REPL).whoami},
+		{name: "exit", help: "leave the program", run: func(*REPL, string) error { return errExit }},
+
+		{name: "users list", help: "list all users", run: (*REPL).listUsers},
+		{name: "users get", args: "<id>", ids: "users", help: "show a user", run: (*REPL).getUser},
+		{name: "users find", args: "<email>", help: "find a user by email", run: (*REPL).findUser},
+		{name: "users add", help: "create a user", run: (*REPL).addUser},
+		{name: "users edit", args: "<id>", ids: "users", login: true, help: "update your user, operators anybody's", run: (*REPL).editUser},
+		{name: "users delete", args: "<id>", ids: "users", login: true, help: "delete your user, operators anybody's", run: (*REPL).deleteUser},
+		{name: "users verify", args: "<token>", help: "confirm an email address", run: (*REPL).verifyEmail},
+		{name: "users resend", login: true, help: "mail your verification token again", run: (*REPL).resendVerification},
+		{name: "users reset-password", args: "<email>", help: "mail a password reset token", run: (*REPL).requestPasswordReset},
+		{name: "users set-password", args: "<token>", help: "choose a new password with a reset token", run: (*REPL).resetPassword},
+		{name: "users 2fa enroll", login: true, help: "set up an authenticator app", run: (*REPL).enrollTOTP},
+		{name: "users 2fa confirm", login: true, help: "turn two-factor authentication on", run: (*REPL).confirmTOTP},
+		{name: "users 2fa disable", login: true, help: "turn two-factor authentication off", run: (*REPL).disableTOTP},
+		{name: "users 2fa reset", args: "<id>", ids: "users", login: true, help: "remove the second factor of a user (operators)", run: (*REPL).resetTOTP},
+		{name: "users identities", login: true, help: "list your single sign-on accounts", run: (*REPL).listIdentities},
+		{name: "users sso link", login: true, help: "sign in with the single sign-on provider too", run: (*REPL).linkIdentity},
+		{name: "users sso unlink", args: "<provider>", login: true, help: "stop signing in with a provider", run: (*REPL).unlinkIdentity},
+		{name: "users deactivate", login: true, help: "close your account, logging in opens it again", run: (*REPL).deactivateUser},
+		{name: "users suspend", args: "<id>", ids: "users", login: true, help: "keep a user from logging in until a time (operators)", run: (*REPL).suspendUser},
+		{name: "users ban", args: "<id>", ids: "users", login: true, help: "keep a user from logging in (operators)", run: (*REPL).banUser},
+		{name: "users reactivate", args: "<id>", ids: "users", login: true, help: "let a suspended or banned user in again (operators)", run: (*REPL).reactivateUser},
+		{name: "users erase", args: "<id>", ids: "users", login: true, help: "erase your personal data, operators anybody's", run: (*REPL).eraseUser},
+		{name: "users export", args: "<file>", login: true, help: "write your data to a zip archive", run: (*REPL).exportUser},
+
+		{name: "profile show", args: "<username>", help: "show the public profile of a user", run: (*REPL).showProfile},
+		{name: "profile edit", login: true, help: "update your profile", run: (*REPL).editProfile},
+		{name: "profile avatar remove", login: true, help: "remove your avatar", run: (*REPL).removeAvatar},
+		{name: "profile avatar", args: "<file>", login: true, help: "upload a PNG, JPEG, GIF or WebP avatar", run: (*REPL).setAvatar},
+
+		{name: "posts list", help: "list all posts", run: (*REPL).listPosts},
+		{name: "posts mine", login: true, help: "list your posts", run: (*REPL).myPosts},
+		{name: "posts get", args: "<id>", ids: "posts", help: "show a post", run: (*REPL).getPost},
+		{name: "posts find", args: "<title>", help: "find a post by title", run: (*REPL).findPost},
+		{name: "posts add", login: true, help: "write a new post", run: (*REPL).addPost},
+		{name: "posts edit", args: "<id>", ids: "posts", login: true, help: "update one of your posts", run: (*REPL).editPost},
+		{name: "posts delete", args: "<id>", ids: "posts", login: true, help: "delete one of your posts", run: (*REPL).deletePost},
+		{name: "posts revisions", args: "<id>", ids: "posts", help: "list the revisions of a post", run: (*REPL).listRevisions},
+		{name: "posts diff", args: "<id>", ids: "posts", help: "compare two revisions of a post", run: (*REPL).diffRevisions},
+		{name: "posts restore", args: "<id>", ids: "posts", login: true, help: "restore a revision of one of your posts", run: (*REPL).restoreRevision},
+		{name: "posts react", args: "<id>", ids: "posts", login: true, help: "like or react to a post, again to take it back", run: (*REPL).reactToPost},
+		{name: "posts reactions", args: "<id>", ids: "posts", help: "list who reacted to a post", run: (*REPL).postReactions},
+
+		{name: "drafts list", login: true, help: "list your drafts", run: (*REPL).listDrafts},
+		{name: "drafts edit", args: "[<post id>]", ids: "posts", login: true, help: "write or resume a draft, of a new post without an id", run: (*REPL).editDraft},
+		{name: "drafts apply", args: "[<post id>]", ids: "posts", login: true, help: "save a draft to its post", run: (*REPL).applyDraft},
+		{name: "drafts discard", args: "[<post id>]", ids: "posts", login: true, help: "throw a draft away", run: (*REPL).discardDraft},
+
+		{name: "comments list", help: "list all comments", run: (*REPL).listComments},
+		{name: "comments mine", login: true, help: "list your comments", run: (*REPL).myComments},
+		{name: "comments post", args: "<post id> [old|new|top|controversial]", ids: "posts", help: "list the comments of a post, the oldest first unless sorted", run: (*REPL).postComments},
+		{name: "comments add", args: "<post id>", ids: "posts", login: true, help: "comment on a post", run: (*REPL).addComment},
+		{name: "comments edit", args: "<id>", ids: "comments", login: true, help: "update one of your comments", run: (*REPL).editComment},
+		{name: "comments delete", args: "<id>", ids: "comments", login: true, help: "delete one of your comments", run: (*REPL).deleteComment},
+		{name: "comments react", args: "<id>", ids: "comments", login: true, help: "like or react to a comment, again to take it back", run: (*REPL).reactToComment},
+		{name: "comments reactions", args: "<id>", ids: "comments", help: "list who reacted to a comment", run: (*REPL).commentReactions},
+		{name: "comments upvote", args: "<id>", ids: "comments", login: true, help: "vote a comment up", run: (*REPL).upvoteComment},
+		{name: "comments downvote", args: "<id>", ids: "comments", login: true, help: "vote a comment down", run: (*REPL).downvoteComment},
+		{name: "comments unvote", args: "<id>", ids: "comments", login: true, help: "take your vote on a comment back", run: (*REPL).unvoteComment},
+	}
+}
+
+func New(console Console, userService service.Users, postService service.Posts, commentService service.Comments, profileService service.Profiles, reactionService service.Reactions) *REPL {
+	r := &REPL{
+		console:         console,
+		userService:     userService,
+		postService:     postService,
+		commentService:  commentService,
+		profileService:  profileService,
+		reactionService: reactionService,
+		ctx:             context.Background(),
+	}
+	console.SetCompleter(r.complete)
+	return r
+}
+
+// SetSSO enables the single sign-on through client
+func (r *REPL) SetSSO(client *oidc.Client) {
+	r.sso = client
+}
+
+// Run reads and executes commands until "exit" or the end of the input.
+func (r *REPL) Run() error {
+	r.println("===========================================================================")
+	r.println("Hello! Type \"help\" to see what you can do, \"exit\" to leave.")
+	r.println("===========================================================================")
+
+	for {
+		line, err := r.console.ReadLine(r.prompt())
+		if err == io.EOF {
+			r.println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = r.execute(strings.TrimSpace(line))
+		if errors.Is(err, errExit) {
+			r.println("Exited!")
+			return nil
+		}
+		if err == io.EOF {
+			r.println()
+			return nil
+		}
+		if err != nil {
+			r.printf("Error: %v\n", err)
+		}
+	}
+}
+
+func (r *REPL) prompt() string {
+	if r.user != nil {
+		return fmt.Sprintf("blog(%s)> ", r.user.Username)
+	}
+	return "blog> "
+}
+
+func (r *REPL) execute(line string) error {
+	if line == "" {
+		return nil
+	}
+	if line == "quit" {
+		return errExit
+	}
+
+	cmd, arg, ok := lookup(line)
+	if !ok {
+		return fmt.Errorf("unknown command %q, type \"help\" for the list of commands", line)
+	}
+	if cmd.login && r.user == nil {
+		return errNotLoggedIn
+	}
+	// arguments in brackets are optional
+	if cmd.args != "" && !strings.HasPrefix(cmd.args, "[") && arg == "" {
+		return fmt.Errorf("usage: %s %s", cmd.name, cmd.args)
+	}
+	// every command is an operation of its own in the logs
+	r.ctx = logging.WithOperation(r.session())
+	return cmd.run(r, arg)
+}
+
+// session returns the context of the logged in user, calls made as the user
+// read their own writes back
+func (r *REPL) session() context.Context {
+	if r.user == nil {
+		return context.Background()
+	}
+	return repository.WithActor(context.Background(), r.user.ID)
+}
+
+// lookup finds the command the line starts with and returns the rest of the
+// line as its argument
+func lookup(line string) (command, string, bool) {
+	fields := strings.Fields(line)
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(fields) < len(words) {
+			continue
+		}
+		match := true
+		for i, word := range words {
+			if !strings.EqualFold(fields[i], word) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return cmd, strings.Join(fields[len(words):], " "), true
+		}
+	}
+	return command{}, "", false
+}
+
+func (r *REPL) help(string) error {
+	for _, cmd := range commands {
+		usage := strings.TrimSpace(cmd.name + " " + cmd.args)
+		r.printf("  %-26s %s\n", usage, cmd.help)
+	}
+	return nil
+}
+
+func (r *REPL) login(string) error {
+	username, err := r.ask("Username")
+	if err != nil {
+		return err
+	}
+	password, err := r.console.ReadPassword("Password: ")
+	if err != nil {
+		return err
+	}
+
+	user, err := r.userService.GetUserByUsernameAndPassword(r.ctx, username, password)
+	if errors.Is(err, service.ErrTOTPRequired) {
+		code, askErr := r.ask("Authenticator or recovery code")
+		if askErr != nil {
+			return askErr
+		}
+		user, err = r.userService.LoginWithCode(r.ctx, username, password, code)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotExist) {
+			return errors.New("username or password not found")
+		}
+		return err
+	}
+	r.user = user
+	r.printf("Logged in as %s (ID %d)\n", user.Username, user.ID)
+	return nil
+}
+
+func (r *REPL) logout(string) error {
+	r.user = nil
+	r.println("Logged out")
+	return nil
+}
+
+func (r *REPL) whoami(string) error {
+	if r.user == nil {
+		return errNotLoggedIn
+	}
+	r.printf("ID: %d, Name: %s, Username: %s\n", r.user.ID, r.user.Name, r.user.Username)
+	return nil
+}
+
+// ask prompts for a single line and trims it
+func (r *REPL) ask(label string) (string, error) {
+	line, err := r.console.ReadLine(label + ": ")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askDefault is ask where an empty answer keeps the current value
+func (r *REPL) askDefault(label, current string) (string, error) {
+	answer, err := r.ask(fmt.Sprintf("%s [%s]", label, current))
+	if err != nil || answer == "" {
+		return current, err
+	}
+	return answer, nil
+}
+
+func (r *REPL) askContent(label, current string) (string, error) {
+	r.printf("%s: end with a line containing only %q, or type %q to open $EDITOR\n", label, contentEnd, contentEditor)
+
+	var lines []string
+	for {
+		line, err := r.console.ReadLine("> ")
+		if err != nil {
+			return "", err
+		}
+		if len(lines) == 0 && strings.TrimSpace(line) == contentEditor {
+			return r.console.Edit(current)
+		}
+		if line == contentEnd {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	content := strings.Join(lines, "\n")
+	if content == "" {
+		return current, nil
+	}
+	return content, nil
+}
+
+func (r *REPL) confirm(question string) (bool, error) {
+	answer, err := r.ask(question + " (Y/N)")
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"), nil
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(arg), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid id %q", arg)
+	}
+	return id, nil
+}
+
+func (r *REPL) printf(format string, args ...any) {
+	fmt.Fprintf(r.console, format, args...)
+}
+
+func (r *REPL) println(args ...any) {
+	fmt.Fprintln(r.console, args...)
+}
@@ -0,0 +1,93 @@
+package repl
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"postgresql-blog/mail"
+	"postgresql-blog/repository/memory"
+	"postgresql-blog/service"
+)
+
+func TestSession(t *testing.T) {
+	store := memory.NewDemo()
+	services := service.New(store, service.Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	script := strings.Join([]string{
+		"posts add",
+		"login",
+		"alice",
+		"wrong",
+		"login",
+		"alice",
+		"demo",
+		"whoami",
+		"posts add",
+		"Scripted",
+		"line one",
+		"line two",
+		".",
+		"posts get 4",
+		"posts edit 3",
+		"frobnicate",
+		"logout",
+		"posts revisions 4",
+		"exit",
+		"posts list",
+	}, "\n")
+	var out strings.Builder
+	r := New(NewPlainConsole(strings.NewReader(script), &out), services.Users, services.Posts, services.Comments, services.Profiles, services.Reactions)
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the output answers the lines in order
+	output := out.String()
+	for _, want := range []string{
+		"Error: please log in first",
+		"Error: username or password not found",
+		"Logged in as alice (ID 1)",
+		"blog(alice)> ",
+		"ID: 1, Name: Alice Doe, Username: alice",
+		"Post created successfully with ID 4!",
+		"Scripted",
+		"line one\nline two",
+		"Error: post 3 belongs to another user",
+		`Error: unknown command "frobnicate"`,
+		"Logged out",
+		"Error: post not found",
+		"Exited!",
+	} {
+		i := strings.Index(output, want)
+		if i < 0 {
+			t.Fatalf("the output misses %q after what came before:\n%s", want, out.String())
+		}
+		output = output[i+len(want):]
+	}
+	if strings.Contains(output, "Hello world") {
+		t.Fatal("the lines after exit were run")
+	}
+
+	post, err := store.Repositories().Posts.GetPostByID(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.UserID != 1 || post.Content != "line one\nline two" {
+		t.Fatalf("the session stored %+v", post)
+	}
+}
+
+func TestSessionEndsWithInput(t *testing.T) {
+	store := memory.NewDemo()
+	services := service.New(store, service.Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	var out strings.Builder
+	// the input ends in the middle of a login
+	r := New(NewPlainConsole(strings.NewReader("login\nalice"), &out), services.Users, services.Posts, services.Comments, services.Profiles, services.Reactions)
+	if err := r.Run(); err != nil {
+		t.Fatalf("got %v at the end of the input, want a clean exit", err)
+	}
+	if strings.Contains(out.String(), "Error") {
+		t.Fatalf("the end of the input printed an error:\n%s", out.String())
+	}
+}
@@ -0,0 +1,352 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/oidc"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+)
+
+func (r *REPL) printUser(user models.User) {
+	// the service hides the addresses of other users
+	email := user.Email
+	if email == "" {
+		email = "(hidden)"
+	}
+	r.printf("ID: %d, Name: %s, Email: %s, Username: %s, Status: %s\n", user.ID, user.Name, email, user.Username, user.State(time.Now()))
+}
+
+func (r *REPL) listUsers(string) error {
+	all, err := r.userService.GetAllUsers(r.ctx)
+	if err != nil {
+		return err
+	}
+	r.println(separator)
+	for _, user := range all {
+		r.printUser(user)
+	}
+	r.println(separator)
+	return nil
+}
+
+func (r *REPL) getUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	user, err := r.userService.GetUserByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	r.printUser(*user)
+	return nil
+}
+
+func (r *REPL) findUser(email string) error {
+	user, err := r.userService.GetUserByEmail(r.ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotExist) {
+			return fmt.Errorf("user with email '%s' does not exist", email)
+		}
+		return err
+	}
+	r.printUser(*user)
+	return nil
+}
+
+func (r *REPL) addUser(string) error {
+	var newUser models.User
+	var err error
+	if newUser.Name, err = r.ask("Enter Name"); err != nil {
+		return err
+	}
+	if newUser.Email, err = r.ask("Enter Email"); err != nil {
+		return err
+	}
+	if newUser.Password, err = r.console.ReadPassword("Enter Password: "); err != nil {
+		return err
+	}
+	if newUser.Username, err = r.ask("Enter Username"); err != nil {
+		return err
+	}
+	if newUser.Name == "" || newUser.Email == "" || newUser.Password == "" || newUser.Username == "" {
+		return errors.New("name, email, password and username are required")
+	}
+
+	created, err := r.userService.CreateUser(r.ctx, newUser)
+	if err != nil {
+		return err
+	}
+	r.println("User created successfully!")
+	r.printUser(*created)
+	r.printf("A verification mail was sent to %s, confirm it with \"users verify <token>\"\n", created.Email)
+	return nil
+}
+
+func (r *REPL) editUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	user, err := r.userService.GetUserByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	r.printf("Enter new values for the user with ID %d, leave empty to keep the current value:\n", user.ID)
+	updated := *user
+	if updated.Name, err = r.askDefault("New Name", user.Name); err != nil {
+		return err
+	}
+	if updated.Email, err = r.askDefault("New Email", user.Email); err != nil {
+		return err
+	}
+	password, err := r.console.ReadPassword("New Password []: ")
+	if err != nil {
+		return err
+	}
+	var current string
+	if password != "" {
+		if current, err = r.console.ReadPassword("Current Password: "); err != nil {
+			return err
+		}
+	}
+	if updated.Username, err = r.askDefault("New Username", user.Username); err != nil {
+		return err
+	}
+
+	if password != "" {
+		if err := r.userService.ChangePassword(r.ctx, id, current, password); err != nil {
+			return err
+		}
+	}
+	if _, err := r.userService.UpdateUserByID(r.ctx, updated); err != nil {
+		return err
+	}
+	if updated.Email != user.Email {
+		r.printf("A verification mail was sent to %s, confirm it with \"users verify <token>\"\n", updated.Email)
+	}
+	if r.user.ID == updated.ID {
+		if r.user, err = r.userService.GetUserByID(r.ctx, id); err != nil {
+			return err
+		}
+	}
+	r.println("User updated successfully!")
+	return nil
+}
+
+func (r *REPL) deleteUser(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	user, err := r.userService.GetUserByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	ok, err := r.confirm(fmt.Sprintf("Are you sure you want to delete the user %s with ID %d?", user.Username, user.ID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("User not deleted!")
+		return nil
+	}
+	if err := r.userService.DeleteUserByID(r.ctx, id); err != nil {
+		return err
+	}
+	if r.user != nil && r.user.ID == id {
+		r.user = nil
+	}
+	r.println("User deleted successfully!")
+	return nil
+}
+
+func (r *REPL) verifyEmail(token string) error {
+	user, err := r.userService.VerifyEmail(r.ctx, token)
+	if err != nil {
+		return err
+	}
+	if r.user != nil && r.user.ID == user.ID {
+		r.user = user
+	}
+	r.println("Email address confirmed!")
+	r.printUser(*user)
+	return nil
+}
+
+func (r *REPL) resendVerification(string) error {
+	if err := r.userService.RequestEmailVerification(r.ctx, r.user.ID); err != nil {
+		return err
+	}
+	r.printf("A verification mail was sent to %s\n", r.user.Email)
+	return nil
+}
+
+func (r *REPL) requestPasswordReset(email string) error {
+	if err := r.userService.RequestPasswordReset(r.ctx, email); err != nil {
+		return err
+	}
+	r.printf("If %s belongs to a user, a password reset mail was sent to it\n", email)
+	return nil
+}
+
+func (r *REPL) resetPassword(token string) error {
+	password, err := r.console.ReadPassword("New Password: ")
+	if err != nil {
+		return err
+	}
+	if err := r.userService.ResetPassword(r.ctx, token, password); err != nil {
+		return err
+	}
+	r.println("Password changed, log in with the new one")
+	return nil
+}
+
+func (r *REPL) enrollTOTP(string) error {
+	setup, err := r.userService.EnrollTOTP(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+	r.println("Add this secret to your authenticator app, or turn the URI into a QR code to scan:")
+	r.printf("  Secret: %s\n  URI:    %s\n", setup.Secret, setup.URI)
+	r.println("Then type \"users 2fa confirm\" with the code the app shows")
+	return nil
+}
+
+func (r *REPL) confirmTOTP(string) error {
+	code, err := r.ask("Code")
+	if err != nil {
+		return err
+	}
+	codes, err := r.userService.ConfirmTOTP(r.ctx, r.user.ID, code)
+	if err != nil {
+		return err
+	}
+	r.println("Two-factor authentication is on. Keep these recovery codes safe, each works once:")
+	for _, c := range codes {
+		r.printf("  %s\n", c)
+	}
+	return nil
+}
+
+func (r *REPL) disableTOTP(string) error {
+	code, err := r.ask("Authenticator or recovery code")
+	if err != nil {
+		return err
+	}
+	if err := r.userService.DisableTOTP(r.ctx, r.user.ID, code); err != nil {
+		return err
+	}
+	r.println("Two-factor authentication is off")
+	return nil
+}
+
+func (r *REPL) resetTOTP(arg string) error {
+	id, err := parseID(arg)
+	if err != nil {
+		return err
+	}
+	user, err := r.userService.GetUserByID(r.ctx, id)
+	if err != nil {
+		return err
+	}
+	ok, err := r.confirm(fmt.Sprintf("Remove the second factor of %s with ID %d?", user.Username, user.ID))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		r.println("Second factor kept!")
+		return nil
+	}
+	if err := r.userService.ResetTOTP(r.ctx, id); err != nil {
+		return err
+	}
+	r.println("Second factor removed, the user logs in with the password alone")
+	return nil
+}
+
+// signIn runs the single sign-on, the user opens the printed URL in a
+// browser
+func (r *REPL) signIn() (service.ExternalIdentity, error) {
+	if r.sso == nil {
+		return service.ExternalIdentity{}, oidc.ErrNotConfigured
+	}
+	claims, err := r.sso.Login(r.ctx, func(url string) error {
+		r.printf("Open this URL in a browser to sign in:\n\n  %s\n\nWaiting for the provider ...\n", url)
+		return nil
+	})
+	if err != nil {
+		return service.ExternalIdentity{}, err
+	}
+	return service.ExternalIdentity{
+		Provider:      r.sso.Provider(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
+
+func (r *REPL) loginSSO(string) error {
+	identity, err := r.signIn()
+	if err != nil {
+		return err
+	}
+	user, err := r.userService.LoginWithIdentity(r.ctx, identity, "")
+	if errors.Is(err, service.ErrTOTPRequired) {
+		code, askErr := r.ask("Authenticator or recovery code")
+		if askErr != nil {
+			return askErr
+		}
+		user, err = r.userService.LoginWithIdentity(r.ctx, identity, code)
+	}
+	if err != nil {
+		return err
+	}
+	r.user = user
+	r.printf("Logged in as %s (ID %d)\n", user.Username, user.ID)
+	return nil
+}
+
+func (r *REPL) listIdentities(string) error {
+	identities, err := r.userService.GetUserIdentities(r.ctx, r.user.ID)
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		r.println("No single sign-on accounts linked")
+		return nil
+	}
+	for _, identity := range identities {
+		r.printf("  %s: %s (%s)\n", identity.Provider, identity.Subject, identity.Email)
+	}
+	return nil
+}
+
+func (r *REPL) linkIdentity(string) error {
+	identity, err := r.signIn()
+	if err != nil {
+		return err
+	}
+	linked, err := r.userService.LinkIdentity(r.ctx, r.user.ID, identity)
+	if err != nil {
+		return err
+	}
+	r.printf("Linked your account %s at %s\n", linked.Email, linked.Provider)
+	return nil
+}
+
+func (r *REPL) unlinkIdentity(provider string) error {
+	if err := r.userService.UnlinkIdentity(r.ctx, r.user.ID, provider); err != nil {
+		return err
+	}
+	r.printf("Unlinked %s\n", provider)
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateAudit(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.AuditEvent{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) AppendEvent(ctx context.Context, event models.AuditEvent) (*models.AuditEvent, error) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if err := repo.db.WithContext(ctx).Create(&event).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return &event, nil
+}
+
+func (repo *PostgreSQLGORMRepository) FindEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	query := repo.db.WithContext(ctx).Order("created_at DESC, id DESC")
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []models.AuditEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return events, nil
+}
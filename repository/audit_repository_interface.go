@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+)
+
+// AuditFilter selects audit events, the zero value of a field matches every
+// event
+type AuditFilter struct {
+	ActorID    *int64
+	EntityType string
+	EntityID   int64
+	// Since and Until bound the time of the events, Until is exclusive
+	Since time.Time
+	Until time.Time
+	// Limit is the most events returned, zero for all of them
+	Limit int
+}
+
+// AuditRepository stores the audit log, it can only append to it
+type AuditRepository interface {
+	MigrateAudit(ctx context.Context) error
+	AppendEvent(ctx context.Context, event models.AuditEvent) (*models.AuditEvent, error)
+	// FindEvents returns the events matching filter, the newest first
+	FindEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error)
+}
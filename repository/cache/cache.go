@@ -0,0 +1,18 @@
+// Package cache puts a cache-aside layer in front of the post and comment
+// repositories. Reads of single posts and of the comments of a post are
+// served from a Cache, the writes that change them drop the cached values.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores encoded values by key. A value that is not there, or has
+// expired, is reported with ok false and no error.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value for ttl, a ttl of zero keeps it until it is evicted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
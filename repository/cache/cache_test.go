@@ -0,0 +1,192 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/cache"
+	"postgresql-blog/repository/memory"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// backend is a Cache under test, expire lets ttl pass for it
+type backend struct {
+	name   string
+	cache  cache.Cache
+	expire func(ttl time.Duration)
+}
+
+func backends(t *testing.T) []backend {
+	server := miniredis.RunT(t)
+	redisCache := cache.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	t.Cleanup(func() { redisCache.Close() })
+
+	return []backend{
+		{name: "LRU", cache: cache.NewLRU(100), expire: func(ttl time.Duration) { time.Sleep(ttl) }},
+		{name: "Redis", cache: redisCache, expire: server.FastForward},
+	}
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, b backend)) {
+	for _, b := range backends(t) {
+		b := b
+		t.Run(b.name, func(t *testing.T) { test(t, b) })
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewLRU(2)
+	c.Set(ctx, "a", []byte("a"), 0)
+	c.Set(ctx, "b", []byte("b"), 0)
+	// a is now used more recently than b
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("a is not cached")
+	}
+	c.Set(ctx, "c", []byte("c"), 0)
+
+	if c.Len() != 2 {
+		t.Fatalf("the cache holds %d values, want 2", c.Len())
+	}
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Fatal("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Fatalf("%s was evicted", key)
+		}
+	}
+}
+
+func TestTTL(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		if err := b.cache.Set(ctx, "short", []byte("value"), 20*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.cache.Set(ctx, "forever", []byte("value"), 0); err != nil {
+			t.Fatal(err)
+		}
+		value, ok, err := b.cache.Get(ctx, "short")
+		if err != nil || !ok || string(value) != "value" {
+			t.Fatalf("got %q, %v, %v before the ttl, want the value", value, ok, err)
+		}
+
+		b.expire(30 * time.Millisecond)
+		if _, ok, err := b.cache.Get(ctx, "short"); ok || err != nil {
+			t.Fatalf("got %v, %v after the ttl, want no value", ok, err)
+		}
+		if _, ok, _ := b.cache.Get(ctx, "forever"); !ok {
+			t.Fatal("a value without ttl expired")
+		}
+	})
+}
+
+// slowPosts counts the loads of posts and holds them until release is closed
+type slowPosts struct {
+	repository.PostRepository
+	loads   atomic.Int32
+	release chan struct{}
+}
+
+func (repo *slowPosts) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	repo.loads.Add(1)
+	<-repo.release
+	return &models.Post{ID: id, Title: "post"}, nil
+}
+
+func TestConcurrentMissesLoadOnce(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		const readers = 10
+		layer := cache.NewLayer(b.cache, time.Minute)
+		next := &slowPosts{release: make(chan struct{})}
+		posts := layer.Posts(next)
+
+		var wg sync.WaitGroup
+		for i := 0; i < readers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				post, err := posts.GetPostByID(context.Background(), 1)
+				if err != nil || post.Title != "post" {
+					t.Errorf("got %v, %v, want the post", post, err)
+				}
+			}()
+		}
+		// wait for every reader to miss before the load finishes
+		for layer.Stats().Misses < readers {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(next.release)
+		wg.Wait()
+
+		if loads := next.loads.Load(); loads != 1 {
+			t.Fatalf("the post was loaded %d times, want once", loads)
+		}
+		if _, err := posts.GetPostByID(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		if stats := layer.Stats(); stats.Hits != 1 {
+			t.Fatalf("got %d hits after the load, want 1", stats.Hits)
+		}
+	})
+}
+
+func TestUnitOfWorkInvalidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx := context.Background()
+		layer := cache.NewLayer(b.cache, time.Minute)
+		store := layer.Store(memory.New())
+		posts := store.Repositories().Posts
+
+		post, err := posts.CreatePost(ctx, models.Post{UserID: 1, Title: "first"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		read := func() (string, cache.Stats) {
+			t.Helper()
+			before := layer.Stats()
+			got, err := posts.GetPostByID(ctx, post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after := layer.Stats()
+			return got.Title, cache.Stats{Hits: after.Hits - before.Hits, Misses: after.Misses - before.Misses}
+		}
+		read()
+
+		errRollback := errors.New("roll back")
+		err = store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			if _, err := repos.Posts.UpdatePost(ctx, post.ID, models.Post{UserID: 1, Title: "rolled back"}); err != nil {
+				t.Fatal(err)
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("got %v, want the error of the unit of work", err)
+		}
+		if title, stats := read(); title != "first" || stats.Hits != 1 {
+			t.Fatalf("got %q with %+v after the rollback, want the cached post", title, stats)
+		}
+
+		err = store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			_, err := repos.Posts.UpdatePost(ctx, post.ID, models.Post{UserID: 1, Title: "second"})
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if title, stats := read(); title != "second" || stats.Misses != 1 {
+			t.Fatalf("got %q with %+v after the commit, want the updated post loaded again", title, stats)
+		}
+	})
+}
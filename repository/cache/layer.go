@@ -0,0 +1,274 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats counts how the cached reads were served. Errors are failures of the
+// cache itself, the reads then fall back to the repository.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	Errors uint64
+}
+
+// Layer holds the cache and the state shared by the repositories it
+// decorates: the stampede protection and the stats.
+type Layer struct {
+	cache Cache
+	ttl   time.Duration
+	// group makes concurrent misses of the same key load it only once
+	group singleflight.Group
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	errors atomic.Uint64
+}
+
+// NewLayer caches the values in c for ttl
+func NewLayer(c Cache, ttl time.Duration) *Layer {
+	return &Layer{cache: c, ttl: ttl}
+}
+
+func (l *Layer) Stats() Stats {
+	return Stats{
+		Hits:   l.hits.Load(),
+		Misses: l.misses.Load(),
+		Errors: l.errors.Load(),
+	}
+}
+
+// Posts returns next with cached GetPostByID
+func (l *Layer) Posts(next repository.PostRepository) repository.PostRepository {
+	return &postRepository{PostRepository: next, layer: l}
+}
+
+// Comments returns next with cached GetCommentByPostID, every sort is cached
+// on its own
+func (l *Layer) Comments(next repository.CommentRepository) repository.CommentRepository {
+	return &commentRepository{CommentRepository: next, layer: l}
+}
+
+// Store returns next with cached repositories. Inside a unit of work the
+// reads go to the transaction, the keys it changes are dropped once it has
+// committed. A unit of work that is rolled back leaves the cache alone.
+func (l *Layer) Store(next repository.Store) repository.Store {
+	return &store{Store: next, layer: l}
+}
+
+type store struct {
+	repository.Store
+	layer *Layer
+}
+
+func (s *store) Repositories() repository.Repositories {
+	repos := s.Store.Repositories()
+	repos.Posts = s.layer.Posts(repos.Posts)
+	repos.Comments = s.layer.Comments(repos.Comments)
+	return repos
+}
+
+func (s *store) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	var changed []string
+	err := s.Store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		repos.Posts = &postRepository{PostRepository: repos.Posts, layer: s.layer, changed: &changed}
+		repos.Comments = &commentRepository{CommentRepository: repos.Comments, layer: s.layer, changed: &changed}
+		return fn(ctx, repos)
+	})
+	if err != nil {
+		return err
+	}
+	s.layer.invalidate(ctx, nil, changed...)
+	return nil
+}
+
+func postKey(id int64) string {
+	return "post:" + strconv.FormatInt(id, 10)
+}
+
+func postCommentsKey(postID uint64, sort repository.CommentSort) string {
+	if sort == "" {
+		sort = repository.CommentSortOld
+	}
+	return "comments:post:" + strconv.FormatUint(postID, 10) + ":" + string(sort)
+}
+
+// postCommentsKeys are the keys of the comments of a post in every sort
+func postCommentsKeys(postID uint64) []string {
+	keys := make([]string, 0, len(repository.CommentSorts))
+	for _, sort := range repository.CommentSorts {
+		keys = append(keys, postCommentsKey(postID, sort))
+	}
+	return keys
+}
+
+// load returns the cached value of key or fetches and caches it. The value
+// goes through its encoding even on a miss, so every caller gets its own
+// copy.
+func load[T any](ctx context.Context, l *Layer, key string, fetch func() (T, error)) (T, error) {
+	var value T
+	data, ok, err := l.cache.Get(ctx, key)
+	if err != nil {
+		l.errors.Add(1)
+	}
+	if ok {
+		if err := json.Unmarshal(data, &value); err == nil {
+			l.hits.Add(1)
+			return value, nil
+		}
+		l.errors.Add(1)
+	}
+	l.misses.Add(1)
+
+	shared, err, _ := l.group.Do(key, func() (any, error) {
+		fetched, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(fetched)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.cache.Set(ctx, key, data, l.ttl); err != nil {
+			l.errors.Add(1)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	err = json.Unmarshal(shared.([]byte), &value)
+	return value, err
+}
+
+// invalidate drops keys from the cache, inside a unit of work they are
+// remembered in changed to be dropped after the commit instead
+func (l *Layer) invalidate(ctx context.Context, changed *[]string, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	if changed != nil {
+		*changed = append(*changed, keys...)
+		return
+	}
+	// a failed delete leaves the old value cached until the ttl ends, the
+	// write itself did succeed
+	if err := l.cache.Delete(ctx, keys...); err != nil {
+		l.errors.Add(1)
+	}
+}
+
+type postRepository struct {
+	repository.PostRepository
+	layer *Layer
+	// changed is set inside a unit of work
+	changed *[]string
+}
+
+func (repo *postRepository) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	if repo.changed != nil {
+		return repo.PostRepository.GetPostByID(ctx, id)
+	}
+	post, err := load(ctx, repo.layer, postKey(id), func() (*models.Post, error) {
+		return repo.PostRepository.GetPostByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (repo *postRepository) UpdatePost(ctx context.Context, id int64, updated models.Post) (*models.Post, error) {
+	post, err := repo.PostRepository.UpdatePost(ctx, id, updated)
+	if err != nil {
+		return nil, err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postKey(id))
+	return post, nil
+}
+
+func (repo *postRepository) DeletePost(ctx context.Context, id int64) error {
+	if err := repo.PostRepository.DeletePost(ctx, id); err != nil {
+		return err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postKey(id))
+	return nil
+}
+
+type commentRepository struct {
+	repository.CommentRepository
+	layer   *Layer
+	changed *[]string
+}
+
+func (repo *commentRepository) GetCommentByPostID(ctx context.Context, postid int64, sort repository.CommentSort) ([]models.Comment, error) {
+	if repo.changed != nil {
+		return repo.CommentRepository.GetCommentByPostID(ctx, postid, sort)
+	}
+	return load(ctx, repo.layer, postCommentsKey(uint64(postid), sort), func() ([]models.Comment, error) {
+		return repo.CommentRepository.GetCommentByPostID(ctx, postid, sort)
+	})
+}
+
+func (repo *commentRepository) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	created, err := repo.CommentRepository.CreateComment(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postCommentsKeys(created.PostID)...)
+	return created, nil
+}
+
+func (repo *commentRepository) UpdateComment(ctx context.Context, id int64, updated models.Comment) (*models.Comment, error) {
+	comment, err := repo.CommentRepository.UpdateComment(ctx, id, updated)
+	if err != nil {
+		return nil, err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postCommentsKeys(updated.PostID)...)
+	return comment, nil
+}
+
+func (repo *commentRepository) DeleteComment(ctx context.Context, id int64) error {
+	// the post of the comment is needed to find the cached list
+	comment, err := repo.CommentRepository.GetCommentByID(ctx, id)
+	if err != nil {
+		// let the repository report the missing comment its own way
+		return repo.CommentRepository.DeleteComment(ctx, id)
+	}
+	if err := repo.CommentRepository.DeleteComment(ctx, id); err != nil {
+		return err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postCommentsKeys(comment.PostID)...)
+	return nil
+}
+
+func (repo *commentRepository) AddCommentVotes(ctx context.Context, id int64, up, down int) (*models.Comment, error) {
+	comment, err := repo.CommentRepository.AddCommentVotes(ctx, id, up, down)
+	if err != nil {
+		return nil, err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postCommentsKeys(comment.PostID)...)
+	return comment, nil
+}
+
+func (repo *commentRepository) SetCommentScore(ctx context.Context, id int64, score, controversy float64) error {
+	comment, err := repo.CommentRepository.GetCommentByID(ctx, id)
+	if err != nil {
+		// let the repository report the missing comment its own way
+		return repo.CommentRepository.SetCommentScore(ctx, id, score, controversy)
+	}
+	if err := repo.CommentRepository.SetCommentScore(ctx, id, score, controversy); err != nil {
+		return err
+	}
+	repo.layer.invalidate(ctx, repo.changed, postCommentsKeys(comment.PostID)...)
+	return nil
+}
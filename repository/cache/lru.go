@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Cache holding at most capacity values, the least
+// recently used value is evicted first
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	// order has the most recently used entry at the front
+	order *list.List
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := element.Value.(*entry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(element)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	return e.value, true, nil
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	if element, ok := c.items[key]; ok {
+		e := element.Value.(*entry)
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *LRU) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+// Len returns how many values the cache holds, expired ones included
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry).key)
+}
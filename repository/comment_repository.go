@@ -6,14 +6,13 @@ import (
 
 	"postgresql-blog/models"
 
-	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
 func (repo *PostgreSQLGORMRepository) MigrateComment(ctx context.Context) error {
 	err := repo.db.WithContext(ctx).AutoMigrate(&models.GormComment{})
 	if err != nil {
-		return err
+		return TranslateError(err)
 	}
 	return nil
 }
@@ -34,13 +33,7 @@ func (repo *PostgreSQLGORMRepository) CreateComment(ctx context.Context, comment
 	}
 
 	if err := repo.db.WithContext(ctx).Create(&gormComment).Error; err != nil {
-		var pgxError *pgconn.PgError
-		if errors.As(err, &pgxError) {
-			if pgxError.Code == "23505" {
-				return nil, ErrDuplicate
-			}
-		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.Comment(gormComment)
@@ -50,7 +43,7 @@ func (repo *PostgreSQLGORMRepository) CreateComment(ctx context.Context, comment
 func (repo *PostgreSQLGORMRepository) AllComments(ctx context.Context) ([]models.Comment, error) {
 	var allComments []models.GormComment
 	if err := repo.db.WithContext(ctx).Find(&allComments).Error; err != nil {
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	var result []models.Comment
@@ -67,7 +60,7 @@ func (repo *PostgreSQLGORMRepository) GetCommentByID(ctx context.Context, id int
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.Comment(gormComment)
@@ -80,7 +73,7 @@ func (repo *PostgreSQLGORMRepository) GetCommentByUserID(ctx context.Context, us
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	var result []models.Comment
@@ -91,13 +84,28 @@ func (repo *PostgreSQLGORMRepository) GetCommentByUserID(ctx context.Context, us
 	return result, nil
 }
 
-func (repo *PostgreSQLGORMRepository) GetCommentByPostID(ctx context.Context, postid int64) ([]models.Comment, error) {
+// CommentOrder returns the ORDER BY clause of a sort of the comments, ties
+// go to the older comment
+func CommentOrder(sort CommentSort) string {
+	switch sort {
+	case CommentSortNew:
+		return "created_at DESC, id DESC"
+	case CommentSortTop:
+		return "score DESC, id"
+	case CommentSortControversial:
+		return "controversy DESC, score DESC, id"
+	default:
+		return "id"
+	}
+}
+
+func (repo *PostgreSQLGORMRepository) GetCommentByPostID(ctx context.Context, postid int64, sort CommentSort) ([]models.Comment, error) {
 	var gormComment []models.GormComment
-	if err := repo.db.WithContext(ctx).Where("post_id = ?", postid).Find(&gormComment).Error; err != nil {
+	if err := repo.db.WithContext(ctx).Where("post_id = ?", postid).Order(CommentOrder(sort)).Find(&gormComment).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	var result []models.Comment
@@ -114,7 +122,7 @@ func (repo *PostgreSQLGORMRepository) GetCommentByUserIDPostID(ctx context.Conte
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.Comment(gormComment)
@@ -123,15 +131,9 @@ func (repo *PostgreSQLGORMRepository) GetCommentByUserIDPostID(ctx context.Conte
 
 func (repo *PostgreSQLGORMRepository) UpdateComment(ctx context.Context, id int64, updated models.Comment) (*models.Comment, error) {
 	gormComment := models.Comment(updated)
-	updateRes := repo.db.WithContext(ctx).Where("id = ?", id).Save(&gormComment)
+	updateRes := repo.db.WithContext(ctx).Where("id = ?", id).Omit(voteColumns...).Save(&gormComment)
 	if err := updateRes.Error; err != nil {
-		var pgxError *pgconn.PgError
-		if errors.As(err, &pgxError) {
-			if pgxError.Code == "23505" {
-				return nil, ErrDuplicate
-			}
-		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	rowsAffected := updateRes.RowsAffected
@@ -144,7 +146,7 @@ func (repo *PostgreSQLGORMRepository) UpdateComment(ctx context.Context, id int6
 func (repo *PostgreSQLGORMRepository) DeleteComment(ctx context.Context, id int64) error {
 	res := repo.db.WithContext(ctx).Delete(&models.GormComment{}, id)
 	if err := res.Error; err != nil {
-		return err
+		return TranslateError(err)
 	}
 
 	rowsAffected := res.RowsAffected
@@ -154,3 +156,34 @@ func (repo *PostgreSQLGORMRepository) DeleteComment(ctx context.Context, id int6
 
 	return nil
 }
+
+// voteColumns change only with the votes
+var voteColumns = []string{"upvotes", "downvotes", "score", "controversy"}
+
+func (repo *PostgreSQLGORMRepository) AddCommentVotes(ctx context.Context, id int64, up, down int) (*models.Comment, error) {
+	res := repo.db.WithContext(ctx).Model(&models.GormComment{}).Where("id = ?", id).UpdateColumns(map[string]any{
+		"upvotes":   gorm.Expr("upvotes + ?", up),
+		"downvotes": gorm.Expr("downvotes + ?", down),
+	})
+	if err := res.Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+	return repo.GetCommentByID(ctx, id)
+}
+
+func (repo *PostgreSQLGORMRepository) SetCommentScore(ctx context.Context, id int64, score, controversy float64) error {
+	res := repo.db.WithContext(ctx).Model(&models.GormComment{}).Where("id = ?", id).UpdateColumns(map[string]any{
+		"score":       score,
+		"controversy": controversy,
+	})
+	if err := res.Error; err != nil {
+		return TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return ErrUpdateFailed
+	}
+	return nil
+}
@@ -5,6 +5,26 @@ import (
 	"postgresql-blog/models"
 )
 
+// CommentSort is the order of the comments of a post
+type CommentSort string
+
+const (
+	// CommentSortOld lists the oldest comments first, the order they were
+	// written in
+	CommentSortOld CommentSort = "old"
+	// CommentSortNew lists the newest comments first
+	CommentSortNew CommentSort = "new"
+	// CommentSortTop lists the comments by their score, the lower bound of
+	// the share of upvotes
+	CommentSortTop CommentSort = "top"
+	// CommentSortControversial lists the comments with many votes split
+	// evenly between up and down first
+	CommentSortControversial CommentSort = "controversial"
+)
+
+// CommentSorts are all the orders of the comments
+var CommentSorts = []CommentSort{CommentSortOld, CommentSortNew, CommentSortTop, CommentSortControversial}
+
 // Repository provides access to the website storage.
 type CommentRepository interface {
 	MigrateComment(ctx context.Context) error
@@ -12,8 +32,14 @@ type CommentRepository interface {
 	AllComments(ctx context.Context) ([]models.Comment, error)
 	GetCommentByID(ctx context.Context, id int64) (*models.Comment, error)
 	GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error)
-	GetCommentByPostID(ctx context.Context, postid int64) ([]models.Comment, error)
+	GetCommentByPostID(ctx context.Context, postid int64, sort CommentSort) ([]models.Comment, error)
 	GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error)
+	// UpdateComment leaves the votes and the ranking of the comment as they
+	// are
 	UpdateComment(ctx context.Context, id int64, updated models.Comment) (*models.Comment, error)
 	DeleteComment(ctx context.Context, id int64) error
+	// AddCommentVotes adds to the vote counts of a comment, negative numbers
+	// take votes away, and returns the comment with the new counts
+	AddCommentVotes(ctx context.Context, id int64, up, down int) (*models.Comment, error)
+	SetCommentScore(ctx context.Context, id int64, score, controversy float64) error
 }
@@ -0,0 +1,17 @@
+package repository
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor returns a context for the calls made on behalf of the user with
+// the given id, storage backends use it to give users their own writes back
+func WithActor(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFromContext returns the user set by WithActor
+func ActorFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(actorKey{}).(int64)
+	return userID, ok
+}
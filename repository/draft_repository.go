@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateDraft(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.Draft{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewDraftRepository(db *gorm.DB) DraftRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) SaveDraft(ctx context.Context, draft models.Draft) (*models.Draft, error) {
+	db := repo.db.WithContext(ctx)
+	if draft.ID == 0 {
+		if err := db.Create(&draft).Error; err != nil {
+			return nil, TranslateError(err)
+		}
+		return &draft, nil
+	}
+
+	// the selection keeps Save from inserting a draft that is gone
+	res := db.Where("id = ?", draft.ID).Select("*").Save(&draft)
+	if err := res.Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+	return &draft, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetDraft(ctx context.Context, userID, postID int64) (*models.Draft, error) {
+	var draft models.Draft
+	err := repo.db.WithContext(ctx).Where("user_id = ? AND post_id = ?", userID, postID).First(&draft).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &draft, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetUserDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	var drafts []models.Draft
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC, id DESC").Find(&drafts).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return drafts, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteDraft(ctx context.Context, userID, postID int64) error {
+	res := repo.db.WithContext(ctx).Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Draft{})
+	if err := res.Error; err != nil {
+		return TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteUserDrafts(ctx context.Context, userID int64) error {
+	return TranslateError(repo.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Draft{}).Error)
+}
+
+func (repo *PostgreSQLGORMRepository) DeletePostDrafts(ctx context.Context, postID int64) error {
+	return TranslateError(repo.db.WithContext(ctx).Where("post_id = ?", postID).Delete(&models.Draft{}).Error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+)
+
+// DraftRepository stores the drafts of the posts
+type DraftRepository interface {
+	MigrateDraft(ctx context.Context) error
+	// SaveDraft creates a draft without an ID and updates the one with it
+	SaveDraft(ctx context.Context, draft models.Draft) (*models.Draft, error)
+	// GetDraft returns the draft of a user for a post, postID 0 for the one
+	// of a new post
+	GetDraft(ctx context.Context, userID, postID int64) (*models.Draft, error)
+	// GetUserDrafts returns the drafts of a user, the last saved first
+	GetUserDrafts(ctx context.Context, userID int64) ([]models.Draft, error)
+	DeleteDraft(ctx context.Context, userID, postID int64) error
+	DeleteUserDrafts(ctx context.Context, userID int64) error
+	DeletePostDrafts(ctx context.Context, postID int64) error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"postgresql-blog/apperr"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// TranslateError turns a constraint violation of any dialect into
+// ErrDuplicate or ErrForeignKey and a failure to reach the database into
+// ErrUnavailable. The dialectors map their own codes to the gorm errors when
+// the handle is opened with TranslateError, the PostgreSQL codes are checked
+// as well for handles opened without it and for pgx.
+func TranslateError(err error) error {
+	if _, ok := apperr.As(err); ok || err == nil {
+		// translated already
+		return err
+	}
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrForeignKey
+	case isConnectionError(err):
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	var pgxError *pgconn.PgError
+	if errors.As(err, &pgxError) {
+		switch pgxError.Code {
+		case "23505":
+			return ErrDuplicate
+		case "23503":
+			return ErrForeignKey
+		}
+	}
+	return err
+}
+
+func isConnectionError(err error) bool {
+	var netError net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netError)
+}
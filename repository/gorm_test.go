@@ -0,0 +1,16 @@
+package repository_test
+
+import (
+	"testing"
+
+	"postgresql-blog/database"
+	"postgresql-blog/repository/repotest"
+)
+
+func TestGORM(t *testing.T) {
+	repotest.Run(t, repotest.Postgres(database.DriverPostgres))
+}
+
+func BenchmarkGORM(b *testing.B) {
+	repotest.Bench(b, repotest.Postgres(database.DriverPostgres))
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateIdentity(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.Identity{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) CreateIdentity(ctx context.Context, identity models.Identity) (*models.Identity, error) {
+	if err := repo.db.WithContext(ctx).Create(&identity).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return &identity, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	if err := repo.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &identity, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error) {
+	var identities []models.Identity
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return identities, nil
+}
+
+func (repo *PostgreSQLGORMRepository) TouchIdentity(ctx context.Context, id int64, email string, now time.Time) error {
+	res := repo.db.WithContext(ctx).Model(&models.Identity{}).Where("id = ?", id).
+		Updates(map[string]any{"email": email, "last_used_at": now})
+	if err := res.Error; err != nil {
+		return TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return ErrUpdateFailed
+	}
+	return nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteUserIdentities(ctx context.Context, userID int64, provider string) error {
+	db := repo.db.WithContext(ctx).Where("user_id = ?", userID)
+	if provider != "" {
+		db = db.Where("provider = ?", provider)
+	}
+	return TranslateError(db.Delete(&models.Identity{}).Error)
+}
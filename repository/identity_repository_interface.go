@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+)
+
+// IdentityRepository stores the links of the users to single sign-on
+// providers
+type IdentityRepository interface {
+	MigrateIdentity(ctx context.Context) error
+	CreateIdentity(ctx context.Context, identity models.Identity) (*models.Identity, error)
+	// GetIdentity returns the identity of subject at provider, ErrNotExist
+	// when no user is linked to it
+	GetIdentity(ctx context.Context, provider, subject string) (*models.Identity, error)
+	GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error)
+	// TouchIdentity records a sign-in with the identity and the email the
+	// provider reported
+	TouchIdentity(ctx context.Context, id int64, email string, now time.Time) error
+	// DeleteUserIdentities deletes the identities of a user, of every
+	// provider when provider is empty
+	DeleteUserIdentities(ctx context.Context, userID int64, provider string) error
+}
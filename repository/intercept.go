@@ -0,0 +1,632 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/intercept"
+	"postgresql-blog/models"
+)
+
+// Intercept returns store with its units of work and every repository call
+// going through interceptors, the first one is the outermost
+func Intercept(store Store, interceptors ...intercept.Interceptor) Store {
+	return &interceptedStore{next: store, interceptor: intercept.Chain(interceptors...)}
+}
+
+type interceptedStore struct {
+	next        Store
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedStore) Repositories() Repositories {
+	return interceptRepositories(s.next.Repositories(), s.interceptor)
+}
+
+func (s *interceptedStore) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	op := intercept.Op{Layer: intercept.LayerRepository, Entity: "store", Type: "Store", Method: "Do"}
+	return intercept.Exec(ctx, s.interceptor, op, func(ctx context.Context) error {
+		return s.next.Do(ctx, func(ctx context.Context, repos Repositories) error {
+			return fn(ctx, interceptRepositories(repos, s.interceptor))
+		})
+	})
+}
+
+func interceptRepositories(repos Repositories, interceptor intercept.Interceptor) Repositories {
+	return Repositories{
+		Users:      &interceptedUsers{next: repos.Users, interceptor: interceptor},
+		Posts:      &interceptedPosts{next: repos.Posts, interceptor: interceptor},
+		Comments:   &interceptedComments{next: repos.Comments, interceptor: interceptor},
+		Tokens:     &interceptedTokens{next: repos.Tokens, interceptor: interceptor},
+		TwoFactors: &interceptedTwoFactors{next: repos.TwoFactors, interceptor: interceptor},
+		Identities: &interceptedIdentities{next: repos.Identities, interceptor: interceptor},
+		Profiles:   &interceptedProfiles{next: repos.Profiles, interceptor: interceptor},
+		Audit:      &interceptedAudit{next: repos.Audit, interceptor: interceptor},
+		Revisions:  &interceptedRevisions{next: repos.Revisions, interceptor: interceptor},
+		Drafts:     &interceptedDrafts{next: repos.Drafts, interceptor: interceptor},
+		Reactions:  &interceptedReactions{next: repos.Reactions, interceptor: interceptor},
+		Votes:      &interceptedVotes{next: repos.Votes, interceptor: interceptor},
+	}
+}
+
+func repositoryOp(entity, typ, method string, id int64) intercept.Op {
+	return intercept.Op{Layer: intercept.LayerRepository, Entity: entity, Type: typ, Method: method, ID: id}
+}
+
+type interceptedUsers struct {
+	next        UserRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedUsers) op(method string, id int64) intercept.Op {
+	return repositoryOp("users", "UserRepository", method, id)
+}
+
+func (repo *interceptedUsers) MigrateUser(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateUser", 0), repo.next.MigrateUser)
+}
+
+func (repo *interceptedUsers) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("CreateUser", 0), func(ctx context.Context) (*models.User, error) {
+		return repo.next.CreateUser(ctx, user)
+	})
+}
+
+func (repo *interceptedUsers) AllUsers(ctx context.Context) ([]models.User, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("AllUsers", 0), repo.next.AllUsers)
+}
+
+func (repo *interceptedUsers) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetUserByID", id), func(ctx context.Context) (*models.User, error) {
+		return repo.next.GetUserByID(ctx, id)
+	})
+}
+
+func (repo *interceptedUsers) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetUserByEmail", 0), func(ctx context.Context) (*models.User, error) {
+		return repo.next.GetUserByEmail(ctx, email)
+	})
+}
+
+func (repo *interceptedUsers) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetUserByUsername", 0), func(ctx context.Context) (*models.User, error) {
+		return repo.next.GetUserByUsername(ctx, username)
+	})
+}
+
+func (repo *interceptedUsers) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetUserByUsernameAndPassword", 0), func(ctx context.Context) (*models.User, error) {
+		return repo.next.GetUserByUsernameAndPassword(ctx, username, password)
+	})
+}
+
+func (repo *interceptedUsers) UpdateUser(ctx context.Context, id int64, updated models.User) (*models.User, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("UpdateUser", id), func(ctx context.Context) (*models.User, error) {
+		return repo.next.UpdateUser(ctx, id, updated)
+	})
+}
+
+func (repo *interceptedUsers) DeleteUser(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteUser", id), func(ctx context.Context) error {
+		return repo.next.DeleteUser(ctx, id)
+	})
+}
+
+type interceptedPosts struct {
+	next        PostRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedPosts) op(method string, id int64) intercept.Op {
+	return repositoryOp("posts", "PostRepository", method, id)
+}
+
+func (repo *interceptedPosts) MigratePost(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigratePost", 0), repo.next.MigratePost)
+}
+
+func (repo *interceptedPosts) CreatePost(ctx context.Context, post models.Post) (*models.Post, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("CreatePost", 0), func(ctx context.Context) (*models.Post, error) {
+		return repo.next.CreatePost(ctx, post)
+	})
+}
+
+func (repo *interceptedPosts) AllPosts(ctx context.Context) ([]models.Post, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("AllPosts", 0), repo.next.AllPosts)
+}
+
+func (repo *interceptedPosts) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetPostByID", id), func(ctx context.Context) (*models.Post, error) {
+		return repo.next.GetPostByID(ctx, id)
+	})
+}
+
+func (repo *interceptedPosts) GetPostByTitle(ctx context.Context, title string) (*models.Post, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetPostByTitle", 0), func(ctx context.Context) (*models.Post, error) {
+		return repo.next.GetPostByTitle(ctx, title)
+	})
+}
+
+func (repo *interceptedPosts) GetPostByUserID(ctx context.Context, userid int64) ([]models.Post, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetPostByUserID", userid), func(ctx context.Context) ([]models.Post, error) {
+		return repo.next.GetPostByUserID(ctx, userid)
+	})
+}
+
+func (repo *interceptedPosts) UpdatePost(ctx context.Context, id int64, updated models.Post) (*models.Post, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("UpdatePost", id), func(ctx context.Context) (*models.Post, error) {
+		return repo.next.UpdatePost(ctx, id, updated)
+	})
+}
+
+func (repo *interceptedPosts) DeletePost(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeletePost", id), func(ctx context.Context) error {
+		return repo.next.DeletePost(ctx, id)
+	})
+}
+
+type interceptedComments struct {
+	next        CommentRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedComments) op(method string, id int64) intercept.Op {
+	return repositoryOp("comments", "CommentRepository", method, id)
+}
+
+func (repo *interceptedComments) MigrateComment(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateComment", 0), repo.next.MigrateComment)
+}
+
+func (repo *interceptedComments) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("CreateComment", 0), func(ctx context.Context) (*models.Comment, error) {
+		return repo.next.CreateComment(ctx, comment)
+	})
+}
+
+func (repo *interceptedComments) AllComments(ctx context.Context) ([]models.Comment, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("AllComments", 0), repo.next.AllComments)
+}
+
+func (repo *interceptedComments) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetCommentByID", id), func(ctx context.Context) (*models.Comment, error) {
+		return repo.next.GetCommentByID(ctx, id)
+	})
+}
+
+func (repo *interceptedComments) GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetCommentByUserID", userid), func(ctx context.Context) ([]models.Comment, error) {
+		return repo.next.GetCommentByUserID(ctx, userid)
+	})
+}
+
+func (repo *interceptedComments) GetCommentByPostID(ctx context.Context, postid int64, sort CommentSort) ([]models.Comment, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetCommentByPostID", postid), func(ctx context.Context) ([]models.Comment, error) {
+		return repo.next.GetCommentByPostID(ctx, postid, sort)
+	})
+}
+
+func (repo *interceptedComments) GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetCommentByUserIDPostID", postid), func(ctx context.Context) (*models.Comment, error) {
+		return repo.next.GetCommentByUserIDPostID(ctx, userid, postid)
+	})
+}
+
+func (repo *interceptedComments) UpdateComment(ctx context.Context, id int64, updated models.Comment) (*models.Comment, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("UpdateComment", id), func(ctx context.Context) (*models.Comment, error) {
+		return repo.next.UpdateComment(ctx, id, updated)
+	})
+}
+
+func (repo *interceptedComments) DeleteComment(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteComment", id), func(ctx context.Context) error {
+		return repo.next.DeleteComment(ctx, id)
+	})
+}
+
+func (repo *interceptedComments) AddCommentVotes(ctx context.Context, id int64, up, down int) (*models.Comment, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("AddCommentVotes", id), func(ctx context.Context) (*models.Comment, error) {
+		return repo.next.AddCommentVotes(ctx, id, up, down)
+	})
+}
+
+func (repo *interceptedComments) SetCommentScore(ctx context.Context, id int64, score, controversy float64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("SetCommentScore", id), func(ctx context.Context) error {
+		return repo.next.SetCommentScore(ctx, id, score, controversy)
+	})
+}
+
+type interceptedTokens struct {
+	next        TokenRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedTokens) op(method string, id int64) intercept.Op {
+	return repositoryOp("tokens", "TokenRepository", method, id)
+}
+
+func (repo *interceptedTokens) MigrateToken(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateToken", 0), repo.next.MigrateToken)
+}
+
+func (repo *interceptedTokens) CreateToken(ctx context.Context, token models.UserToken) (*models.UserToken, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("CreateToken", token.UserID), func(ctx context.Context) (*models.UserToken, error) {
+		return repo.next.CreateToken(ctx, token)
+	})
+}
+
+func (repo *interceptedTokens) UseToken(ctx context.Context, purpose, hash string, now time.Time) (*models.UserToken, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("UseToken", 0), func(ctx context.Context) (*models.UserToken, error) {
+		return repo.next.UseToken(ctx, purpose, hash, now)
+	})
+}
+
+func (repo *interceptedTokens) DeleteUserTokens(ctx context.Context, userID int64, purpose string) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteUserTokens", userID), func(ctx context.Context) error {
+		return repo.next.DeleteUserTokens(ctx, userID, purpose)
+	})
+}
+
+type interceptedTwoFactors struct {
+	next        TwoFactorRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedTwoFactors) op(method string, id int64) intercept.Op {
+	return repositoryOp("two_factors", "TwoFactorRepository", method, id)
+}
+
+func (repo *interceptedTwoFactors) MigrateTwoFactor(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateTwoFactor", 0), repo.next.MigrateTwoFactor)
+}
+
+func (repo *interceptedTwoFactors) GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetTwoFactor", userID), func(ctx context.Context) (*models.TwoFactor, error) {
+		return repo.next.GetTwoFactor(ctx, userID)
+	})
+}
+
+func (repo *interceptedTwoFactors) SaveTwoFactor(ctx context.Context, twoFactor models.TwoFactor) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("SaveTwoFactor", twoFactor.UserID), func(ctx context.Context) error {
+		return repo.next.SaveTwoFactor(ctx, twoFactor)
+	})
+}
+
+func (repo *interceptedTwoFactors) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteTwoFactor", userID), func(ctx context.Context) error {
+		return repo.next.DeleteTwoFactor(ctx, userID)
+	})
+}
+
+func (repo *interceptedTwoFactors) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("ReplaceRecoveryCodes", userID), func(ctx context.Context) error {
+		return repo.next.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+}
+
+func (repo *interceptedTwoFactors) UseRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("UseRecoveryCode", userID), func(ctx context.Context) error {
+		return repo.next.UseRecoveryCode(ctx, userID, hash, now)
+	})
+}
+
+type interceptedIdentities struct {
+	next        IdentityRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedIdentities) op(method string, id int64) intercept.Op {
+	return repositoryOp("identities", "IdentityRepository", method, id)
+}
+
+func (repo *interceptedIdentities) MigrateIdentity(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateIdentity", 0), repo.next.MigrateIdentity)
+}
+
+func (repo *interceptedIdentities) CreateIdentity(ctx context.Context, identity models.Identity) (*models.Identity, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("CreateIdentity", identity.UserID), func(ctx context.Context) (*models.Identity, error) {
+		return repo.next.CreateIdentity(ctx, identity)
+	})
+}
+
+func (repo *interceptedIdentities) GetIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetIdentity", 0), func(ctx context.Context) (*models.Identity, error) {
+		return repo.next.GetIdentity(ctx, provider, subject)
+	})
+}
+
+func (repo *interceptedIdentities) GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetUserIdentities", userID), func(ctx context.Context) ([]models.Identity, error) {
+		return repo.next.GetUserIdentities(ctx, userID)
+	})
+}
+
+func (repo *interceptedIdentities) TouchIdentity(ctx context.Context, id int64, email string, now time.Time) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("TouchIdentity", id), func(ctx context.Context) error {
+		return repo.next.TouchIdentity(ctx, id, email, now)
+	})
+}
+
+func (repo *interceptedIdentities) DeleteUserIdentities(ctx context.Context, userID int64, provider string) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteUserIdentities", userID), func(ctx context.Context) error {
+		return repo.next.DeleteUserIdentities(ctx, userID, provider)
+	})
+}
+
+type interceptedProfiles struct {
+	next        ProfileRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedProfiles) op(method string, id int64) intercept.Op {
+	return repositoryOp("profiles", "ProfileRepository", method, id)
+}
+
+func (repo *interceptedProfiles) MigrateProfile(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateProfile", 0), repo.next.MigrateProfile)
+}
+
+func (repo *interceptedProfiles) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetProfile", userID), func(ctx context.Context) (*models.Profile, error) {
+		return repo.next.GetProfile(ctx, userID)
+	})
+}
+
+func (repo *interceptedProfiles) AllProfiles(ctx context.Context) ([]models.Profile, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("AllProfiles", 0), repo.next.AllProfiles)
+}
+
+func (repo *interceptedProfiles) SaveProfile(ctx context.Context, profile models.Profile) (*models.Profile, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("SaveProfile", profile.UserID), func(ctx context.Context) (*models.Profile, error) {
+		return repo.next.SaveProfile(ctx, profile)
+	})
+}
+
+func (repo *interceptedProfiles) DeleteProfile(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteProfile", userID), func(ctx context.Context) error {
+		return repo.next.DeleteProfile(ctx, userID)
+	})
+}
+
+func (repo *interceptedProfiles) GetAvatar(ctx context.Context, userID int64) (*models.Avatar, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetAvatar", userID), func(ctx context.Context) (*models.Avatar, error) {
+		return repo.next.GetAvatar(ctx, userID)
+	})
+}
+
+func (repo *interceptedProfiles) SaveAvatar(ctx context.Context, avatar models.Avatar) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("SaveAvatar", avatar.UserID), func(ctx context.Context) error {
+		return repo.next.SaveAvatar(ctx, avatar)
+	})
+}
+
+func (repo *interceptedProfiles) DeleteAvatar(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteAvatar", userID), func(ctx context.Context) error {
+		return repo.next.DeleteAvatar(ctx, userID)
+	})
+}
+
+type interceptedAudit struct {
+	next        AuditRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedAudit) op(method string, id int64) intercept.Op {
+	return repositoryOp("audit_events", "AuditRepository", method, id)
+}
+
+func (repo *interceptedAudit) MigrateAudit(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateAudit", 0), repo.next.MigrateAudit)
+}
+
+func (repo *interceptedAudit) AppendEvent(ctx context.Context, event models.AuditEvent) (*models.AuditEvent, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("AppendEvent", event.EntityID), func(ctx context.Context) (*models.AuditEvent, error) {
+		return repo.next.AppendEvent(ctx, event)
+	})
+}
+
+func (repo *interceptedAudit) FindEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("FindEvents", filter.EntityID), func(ctx context.Context) ([]models.AuditEvent, error) {
+		return repo.next.FindEvents(ctx, filter)
+	})
+}
+
+type interceptedRevisions struct {
+	next        RevisionRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedRevisions) op(method string, id int64) intercept.Op {
+	return repositoryOp("post_revisions", "RevisionRepository", method, id)
+}
+
+func (repo *interceptedRevisions) MigrateRevision(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateRevision", 0), repo.next.MigrateRevision)
+}
+
+func (repo *interceptedRevisions) CreateRevision(ctx context.Context, revision models.PostRevision) (*models.PostRevision, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("CreateRevision", revision.PostID), func(ctx context.Context) (*models.PostRevision, error) {
+		return repo.next.CreateRevision(ctx, revision)
+	})
+}
+
+func (repo *interceptedRevisions) GetRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetRevisions", postID), func(ctx context.Context) ([]models.PostRevision, error) {
+		return repo.next.GetRevisions(ctx, postID)
+	})
+}
+
+func (repo *interceptedRevisions) GetRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetRevision", postID), func(ctx context.Context) (*models.PostRevision, error) {
+		return repo.next.GetRevision(ctx, postID, number)
+	})
+}
+
+func (repo *interceptedRevisions) PruneRevisions(ctx context.Context, postID int64, keep int) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("PruneRevisions", postID), func(ctx context.Context) error {
+		return repo.next.PruneRevisions(ctx, postID, keep)
+	})
+}
+
+func (repo *interceptedRevisions) DeleteRevisions(ctx context.Context, postID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteRevisions", postID), func(ctx context.Context) error {
+		return repo.next.DeleteRevisions(ctx, postID)
+	})
+}
+
+type interceptedDrafts struct {
+	next        DraftRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedDrafts) op(method string, id int64) intercept.Op {
+	return repositoryOp("drafts", "DraftRepository", method, id)
+}
+
+func (repo *interceptedDrafts) MigrateDraft(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateDraft", 0), repo.next.MigrateDraft)
+}
+
+func (repo *interceptedDrafts) SaveDraft(ctx context.Context, draft models.Draft) (*models.Draft, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("SaveDraft", draft.ID), func(ctx context.Context) (*models.Draft, error) {
+		return repo.next.SaveDraft(ctx, draft)
+	})
+}
+
+func (repo *interceptedDrafts) GetDraft(ctx context.Context, userID, postID int64) (*models.Draft, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetDraft", postID), func(ctx context.Context) (*models.Draft, error) {
+		return repo.next.GetDraft(ctx, userID, postID)
+	})
+}
+
+func (repo *interceptedDrafts) GetUserDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetUserDrafts", userID), func(ctx context.Context) ([]models.Draft, error) {
+		return repo.next.GetUserDrafts(ctx, userID)
+	})
+}
+
+func (repo *interceptedDrafts) DeleteDraft(ctx context.Context, userID, postID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteDraft", postID), func(ctx context.Context) error {
+		return repo.next.DeleteDraft(ctx, userID, postID)
+	})
+}
+
+func (repo *interceptedDrafts) DeleteUserDrafts(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteUserDrafts", userID), func(ctx context.Context) error {
+		return repo.next.DeleteUserDrafts(ctx, userID)
+	})
+}
+
+func (repo *interceptedDrafts) DeletePostDrafts(ctx context.Context, postID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeletePostDrafts", postID), func(ctx context.Context) error {
+		return repo.next.DeletePostDrafts(ctx, postID)
+	})
+}
+
+type interceptedReactions struct {
+	next        ReactionRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedReactions) op(method string, id int64) intercept.Op {
+	return repositoryOp("reactions", "ReactionRepository", method, id)
+}
+
+func (repo *interceptedReactions) MigrateReaction(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateReaction", 0), repo.next.MigrateReaction)
+}
+
+func (repo *interceptedReactions) AddReaction(ctx context.Context, reaction models.Reaction) (*models.Reaction, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("AddReaction", reaction.TargetID), func(ctx context.Context) (*models.Reaction, error) {
+		return repo.next.AddReaction(ctx, reaction)
+	})
+}
+
+func (repo *interceptedReactions) GetReaction(ctx context.Context, userID int64, targetType string, targetID int64, kind string) (*models.Reaction, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetReaction", targetID), func(ctx context.Context) (*models.Reaction, error) {
+		return repo.next.GetReaction(ctx, userID, targetType, targetID, kind)
+	})
+}
+
+func (repo *interceptedReactions) DeleteReaction(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteReaction", id), func(ctx context.Context) error {
+		return repo.next.DeleteReaction(ctx, id)
+	})
+}
+
+func (repo *interceptedReactions) CountReactions(ctx context.Context, targetType string, targetIDs []int64) (map[int64]map[string]int, error) {
+	var counts map[int64]map[string]int
+	err := intercept.Exec(ctx, repo.interceptor, repo.op("CountReactions", 0), func(ctx context.Context) error {
+		var err error
+		counts, err = repo.next.CountReactions(ctx, targetType, targetIDs)
+		return err
+	})
+	return counts, err
+}
+
+func (repo *interceptedReactions) FindReactions(ctx context.Context, filter ReactionFilter) ([]Reactor, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("FindReactions", filter.TargetID), func(ctx context.Context) ([]Reactor, error) {
+		return repo.next.FindReactions(ctx, filter)
+	})
+}
+
+func (repo *interceptedReactions) GetUserReactions(ctx context.Context, userID int64) ([]models.Reaction, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetUserReactions", userID), func(ctx context.Context) ([]models.Reaction, error) {
+		return repo.next.GetUserReactions(ctx, userID)
+	})
+}
+
+func (repo *interceptedReactions) DeleteUserReactions(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteUserReactions", userID), func(ctx context.Context) error {
+		return repo.next.DeleteUserReactions(ctx, userID)
+	})
+}
+
+func (repo *interceptedReactions) DeleteTargetReactions(ctx context.Context, targetType string, targetID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteTargetReactions", targetID), func(ctx context.Context) error {
+		return repo.next.DeleteTargetReactions(ctx, targetType, targetID)
+	})
+}
+
+type interceptedVotes struct {
+	next        VoteRepository
+	interceptor intercept.Interceptor
+}
+
+func (repo *interceptedVotes) op(method string, id int64) intercept.Op {
+	return repositoryOp("votes", "VoteRepository", method, id)
+}
+
+func (repo *interceptedVotes) MigrateVote(ctx context.Context) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("MigrateVote", 0), repo.next.MigrateVote)
+}
+
+func (repo *interceptedVotes) GetVote(ctx context.Context, commentID, userID int64) (*models.CommentVote, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("GetVote", commentID), func(ctx context.Context) (*models.CommentVote, error) {
+		return repo.next.GetVote(ctx, commentID, userID)
+	})
+}
+
+func (repo *interceptedVotes) SaveVote(ctx context.Context, vote models.CommentVote) (*models.CommentVote, error) {
+	return intercept.One(ctx, repo.interceptor, repo.op("SaveVote", vote.ID), func(ctx context.Context) (*models.CommentVote, error) {
+		return repo.next.SaveVote(ctx, vote)
+	})
+}
+
+func (repo *interceptedVotes) DeleteVote(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteVote", id), func(ctx context.Context) error {
+		return repo.next.DeleteVote(ctx, id)
+	})
+}
+
+func (repo *interceptedVotes) GetUserVotes(ctx context.Context, userID int64) ([]models.CommentVote, error) {
+	return intercept.Many(ctx, repo.interceptor, repo.op("GetUserVotes", userID), func(ctx context.Context) ([]models.CommentVote, error) {
+		return repo.next.GetUserVotes(ctx, userID)
+	})
+}
+
+func (repo *interceptedVotes) DeleteCommentVotes(ctx context.Context, commentID int64) error {
+	return intercept.Exec(ctx, repo.interceptor, repo.op("DeleteCommentVotes", commentID), func(ctx context.Context) error {
+		return repo.next.DeleteCommentVotes(ctx, commentID)
+	})
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateAudit(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) AppendEvent(ctx context.Context, event models.AuditEvent) (*models.AuditEvent, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	d.nextAuditID++
+	event.ID = d.nextAuditID
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	d.auditEvents[event.ID] = event
+	return &event, nil
+}
+
+func (repo *memoryRepository) FindEvents(ctx context.Context, match repository.AuditFilter) ([]models.AuditEvent, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	events := filter(d.auditEvents, func(event models.AuditEvent) bool {
+		return (match.ActorID == nil || (event.ActorID != nil && *event.ActorID == *match.ActorID)) &&
+			(match.EntityType == "" || event.EntityType == match.EntityType) &&
+			(match.EntityID == 0 || event.EntityID == match.EntityID) &&
+			(match.Since.IsZero() || !event.CreatedAt.Before(match.Since)) &&
+			(match.Until.IsZero() || event.CreatedAt.Before(match.Until))
+	})
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.After(events[j].CreatedAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+	if match.Limit > 0 && len(events) > match.Limit {
+		events = events[:match.Limit]
+	}
+	return events, nil
+}
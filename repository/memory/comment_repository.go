@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateComment(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	d.nextCommentID++
+	comment.ID = d.nextCommentID
+	timestamps(&comment.CreatedAt, &comment.UpdatedAt)
+	d.comments[comment.ID] = comment
+
+	return &comment, nil
+}
+
+func (repo *memoryRepository) AllComments(ctx context.Context) ([]models.Comment, error) {
+	return repo.comments(ctx, func(models.Comment) bool { return true })
+}
+
+func (repo *memoryRepository) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
+	return repo.findComment(ctx, func(comment models.Comment) bool { return comment.ID == id })
+}
+
+func (repo *memoryRepository) GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error) {
+	return repo.comments(ctx, func(comment models.Comment) bool { return comment.UserID == uint64(userid) })
+}
+
+func (repo *memoryRepository) GetCommentByPostID(ctx context.Context, postid int64, order repository.CommentSort) ([]models.Comment, error) {
+	comments, err := repo.comments(ctx, func(comment models.Comment) bool { return comment.PostID == uint64(postid) })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(comments, commentLess(comments, order))
+	return comments, nil
+}
+
+// commentLess orders the comments like repository.CommentOrder, they come
+// in id order already
+func commentLess(comments []models.Comment, order repository.CommentSort) func(i, j int) bool {
+	switch order {
+	case repository.CommentSortNew:
+		return func(i, j int) bool {
+			if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+				return comments[i].CreatedAt.After(comments[j].CreatedAt)
+			}
+			return comments[i].ID > comments[j].ID
+		}
+	case repository.CommentSortTop:
+		return func(i, j int) bool { return comments[i].Score > comments[j].Score }
+	case repository.CommentSortControversial:
+		return func(i, j int) bool {
+			if comments[i].Controversy != comments[j].Controversy {
+				return comments[i].Controversy > comments[j].Controversy
+			}
+			return comments[i].Score > comments[j].Score
+		}
+	default:
+		return func(i, j int) bool { return false }
+	}
+}
+
+func (repo *memoryRepository) GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error) {
+	return repo.findComment(ctx, func(comment models.Comment) bool {
+		return comment.UserID == uint64(userid) && comment.PostID == uint64(postid)
+	})
+}
+
+func (repo *memoryRepository) comments(ctx context.Context, match func(models.Comment) bool) ([]models.Comment, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return filter(d.comments, match), nil
+}
+
+func (repo *memoryRepository) findComment(ctx context.Context, match func(models.Comment) bool) (*models.Comment, error) {
+	comments, err := repo.comments(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+	if len(comments) == 0 {
+		return nil, repository.ErrNotExist
+	}
+	return &comments[0], nil
+}
+
+func (repo *memoryRepository) UpdateComment(ctx context.Context, id int64, updated models.Comment) (*models.Comment, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	existing, ok := d.comments[id]
+	if !ok {
+		return nil, repository.ErrUpdateFailed
+	}
+	updated.ID = id
+	updated.UpdatedAt = time.Now()
+	updated.Upvotes, updated.Downvotes = existing.Upvotes, existing.Downvotes
+	updated.Score, updated.Controversy = existing.Score, existing.Controversy
+	d.comments[id] = updated
+
+	return &updated, nil
+}
+
+func (repo *memoryRepository) DeleteComment(ctx context.Context, id int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := d.comments[id]; !ok {
+		return repository.ErrDeleteFailed
+	}
+	delete(d.comments, id)
+
+	return nil
+}
+
+func (repo *memoryRepository) AddCommentVotes(ctx context.Context, id int64, up, down int) (*models.Comment, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	comment, ok := d.comments[id]
+	if !ok {
+		return nil, repository.ErrUpdateFailed
+	}
+	comment.Upvotes += up
+	comment.Downvotes += down
+	d.comments[id] = comment
+
+	return &comment, nil
+}
+
+func (repo *memoryRepository) SetCommentScore(ctx context.Context, id int64, score, controversy float64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	comment, ok := d.comments[id]
+	if !ok {
+		return repository.ErrUpdateFailed
+	}
+	comment.Score, comment.Controversy = score, controversy
+	d.comments[id] = comment
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"time"
+
+	"postgresql-blog/models"
+)
+
+// NewDemo returns a store with a few users, posts, comments and likes, used
+// by the demo mode. Every demo user has the password "demo".
+func NewDemo() *Store {
+	s := New()
+	now := time.Now()
+
+	for _, user := range []models.User{
+		{Name: "Alice Doe", Email: "alice@example.com", Username: "alice", Password: "demo"},
+		{Name: "Bob Doe", Email: "bob@example.com", Username: "bob", Password: "demo"},
+	} {
+		s.data.nextUserID++
+		user.ID = s.data.nextUserID
+		user.EmailVerifiedAt = &now
+		s.data.users[user.ID] = user
+	}
+
+	for _, post := range []models.Post{
+		{UserID: 1, Title: "Hello world", Content: "This is the first post of the demo blog.\nNothing here is stored, it is gone when the program exits."},
+		{UserID: 1, Title: "Writing posts", Content: "Log in as alice or bob with the password \"demo\" to write your own posts."},
+		{UserID: 2, Title: "Bob's notes", Content: "Comments, edits and deletes all work like with PostgreSQL."},
+	} {
+		s.data.nextPostID++
+		post.ID = s.data.nextPostID
+		post.IsPublished = true
+		post.PublishedAt, post.CreatedAt, post.UpdatedAt = now, now, now
+		s.data.posts[post.ID] = post
+	}
+
+	for _, comment := range []models.Comment{
+		{UserID: 2, PostID: 1, Content: "Welcome!"},
+		{UserID: 1, PostID: 3, Content: "Nice notes, Bob."},
+	} {
+		s.data.nextCommentID++
+		comment.ID = s.data.nextCommentID
+		comment.IsPublished = true
+		comment.PublishedAt, comment.CreatedAt, comment.UpdatedAt = now, now, now
+		s.data.comments[comment.ID] = comment
+	}
+
+	for _, reaction := range []models.Reaction{
+		{UserID: 2, TargetType: models.TargetPost, TargetID: 1, Kind: models.ReactionLike},
+		{UserID: 1, TargetType: models.TargetComment, TargetID: 1, Kind: models.ReactionLike},
+	} {
+		s.data.nextReactionID++
+		reaction.ID = s.data.nextReactionID
+		reaction.CreatedAt = now
+		s.data.reactions[reaction.ID] = reaction
+	}
+
+	return s
+}
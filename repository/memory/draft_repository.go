@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateDraft(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) SaveDraft(ctx context.Context, draft models.Draft) (*models.Draft, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, other := range d.drafts {
+		if other.ID != draft.ID && other.UserID == draft.UserID && other.PostID == draft.PostID {
+			return nil, repository.ErrDuplicate
+		}
+	}
+	if draft.ID == 0 {
+		d.nextDraftID++
+		draft.ID = d.nextDraftID
+	} else if _, ok := d.drafts[draft.ID]; !ok {
+		return nil, repository.ErrUpdateFailed
+	}
+	d.drafts[draft.ID] = draft
+	return &draft, nil
+}
+
+func (repo *memoryRepository) GetDraft(ctx context.Context, userID, postID int64) (*models.Draft, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, draft := range d.drafts {
+		if draft.UserID == userID && draft.PostID == postID {
+			return &draft, nil
+		}
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (repo *memoryRepository) GetUserDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	drafts := filter(d.drafts, func(draft models.Draft) bool {
+		return draft.UserID == userID
+	})
+	sort.SliceStable(drafts, func(i, j int) bool {
+		if !drafts[i].UpdatedAt.Equal(drafts[j].UpdatedAt) {
+			return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+		}
+		return drafts[i].ID > drafts[j].ID
+	})
+	return drafts, nil
+}
+
+func (repo *memoryRepository) DeleteDraft(ctx context.Context, userID, postID int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, draft := range d.drafts {
+		if draft.UserID == userID && draft.PostID == postID {
+			delete(d.drafts, id)
+			return nil
+		}
+	}
+	return repository.ErrDeleteFailed
+}
+
+func (repo *memoryRepository) DeleteUserDrafts(ctx context.Context, userID int64) error {
+	return repo.deleteDrafts(ctx, func(draft models.Draft) bool { return draft.UserID == userID })
+}
+
+func (repo *memoryRepository) DeletePostDrafts(ctx context.Context, postID int64) error {
+	return repo.deleteDrafts(ctx, func(draft models.Draft) bool { return draft.PostID == postID })
+}
+
+func (repo *memoryRepository) deleteDrafts(ctx context.Context, match func(models.Draft) bool) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, draft := range d.drafts {
+		if match(draft) {
+			delete(d.drafts, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateIdentity(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) CreateIdentity(ctx context.Context, identity models.Identity) (*models.Identity, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, existing := range d.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return nil, repository.ErrDuplicate
+		}
+	}
+	d.nextIdentityID++
+	identity.ID = d.nextIdentityID
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	d.identities[identity.ID] = identity
+
+	return &identity, nil
+}
+
+func (repo *memoryRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, identity := range d.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (repo *memoryRepository) GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var result []models.Identity
+	for _, id := range sortedIDs(d.identities) {
+		if identity := d.identities[id]; identity.UserID == userID {
+			result = append(result, identity)
+		}
+	}
+	return result, nil
+}
+
+func (repo *memoryRepository) TouchIdentity(ctx context.Context, id int64, email string, now time.Time) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	identity, ok := d.identities[id]
+	if !ok {
+		return repository.ErrUpdateFailed
+	}
+	identity.Email, identity.LastUsedAt = email, &now
+	d.identities[id] = identity
+	return nil
+}
+
+func (repo *memoryRepository) DeleteUserIdentities(ctx context.Context, userID int64, provider string) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, identity := range d.identities {
+		if identity.UserID == userID && (provider == "" || identity.Provider == provider) {
+			delete(d.identities, id)
+		}
+	}
+	return nil
+}
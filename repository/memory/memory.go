@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// Store keeps users, posts, comments, tokens, second factors, identities,
+// profiles, the audit log, the post revisions, the drafts, the reactions and
+// the comment votes in memory. It implements the same repositories as
+// PostgreSQLGORMRepository and is safe for concurrent use, which makes it
+// usable for tests and for running the blog without a database.
+type Store struct {
+	mu   sync.Mutex
+	data data
+}
+
+type data struct {
+	users          map[int64]models.User
+	posts          map[int64]models.Post
+	comments       map[int64]models.Comment
+	tokens         map[int64]models.UserToken
+	twoFactors     map[int64]models.TwoFactor
+	recoveryCodes  map[int64]models.RecoveryCode
+	identities     map[int64]models.Identity
+	profiles       map[int64]models.Profile
+	avatars        map[int64]models.Avatar
+	auditEvents    map[int64]models.AuditEvent
+	revisions      map[int64]models.PostRevision
+	drafts         map[int64]models.Draft
+	reactions      map[int64]models.Reaction
+	votes          map[int64]models.CommentVote
+	nextUserID     int64
+	nextPostID     int64
+	nextCommentID  int64
+	nextTokenID    int64
+	nextCodeID     int64
+	nextIdentityID int64
+	nextAuditID    int64
+	nextRevisionID int64
+	nextDraftID    int64
+	nextReactionID int64
+	nextVoteID     int64
+}
+
+func New() *Store {
+	return &Store{data: data{
+		users:         map[int64]models.User{},
+		posts:         map[int64]models.Post{},
+		comments:      map[int64]models.Comment{},
+		tokens:        map[int64]models.UserToken{},
+		twoFactors:    map[int64]models.TwoFactor{},
+		recoveryCodes: map[int64]models.RecoveryCode{},
+		identities:    map[int64]models.Identity{},
+		profiles:      map[int64]models.Profile{},
+		avatars:       map[int64]models.Avatar{},
+		auditEvents:   map[int64]models.AuditEvent{},
+		revisions:     map[int64]models.PostRevision{},
+		drafts:        map[int64]models.Draft{},
+		reactions:     map[int64]models.Reaction{},
+		votes:         map[int64]models.CommentVote{},
+	}}
+}
+
+// Repositories returns repositories that each lock the store per call
+func (s *Store) Repositories() repository.Repositories {
+	r := &memoryRepository{store: s}
+	return repository.Repositories{Users: r, Posts: r, Comments: r, Tokens: r, TwoFactors: r, Identities: r, Profiles: r, Audit: r, Revisions: r, Drafts: r, Reactions: r, Votes: r}
+}
+
+// Do runs fn while holding the store lock, so units of work are serialized.
+// The data is restored when fn returns an error, like a rollback.
+func (s *Store) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.data.clone()
+	r := &memoryRepository{store: s, inTx: true}
+	if err := fn(ctx, repository.Repositories{Users: r, Posts: r, Comments: r, Tokens: r, TwoFactors: r, Identities: r, Profiles: r, Audit: r, Revisions: r, Drafts: r, Reactions: r, Votes: r}); err != nil {
+		s.data = snapshot
+		return err
+	}
+	return nil
+}
+
+func (d data) clone() data {
+	c := d
+	c.users = make(map[int64]models.User, len(d.users))
+	for id, user := range d.users {
+		c.users[id] = user
+	}
+	c.posts = make(map[int64]models.Post, len(d.posts))
+	for id, post := range d.posts {
+		c.posts[id] = post
+	}
+	c.comments = make(map[int64]models.Comment, len(d.comments))
+	for id, comment := range d.comments {
+		c.comments[id] = comment
+	}
+	c.tokens = make(map[int64]models.UserToken, len(d.tokens))
+	for id, token := range d.tokens {
+		c.tokens[id] = token
+	}
+	c.twoFactors = make(map[int64]models.TwoFactor, len(d.twoFactors))
+	for id, twoFactor := range d.twoFactors {
+		c.twoFactors[id] = twoFactor
+	}
+	c.recoveryCodes = make(map[int64]models.RecoveryCode, len(d.recoveryCodes))
+	for id, code := range d.recoveryCodes {
+		c.recoveryCodes[id] = code
+	}
+	c.identities = make(map[int64]models.Identity, len(d.identities))
+	for id, identity := range d.identities {
+		c.identities[id] = identity
+	}
+	c.profiles = make(map[int64]models.Profile, len(d.profiles))
+	for id, profile := range d.profiles {
+		c.profiles[id] = profile
+	}
+	c.avatars = make(map[int64]models.Avatar, len(d.avatars))
+	for id, avatar := range d.avatars {
+		c.avatars[id] = avatar
+	}
+	c.auditEvents = make(map[int64]models.AuditEvent, len(d.auditEvents))
+	for id, event := range d.auditEvents {
+		c.auditEvents[id] = event
+	}
+	c.revisions = make(map[int64]models.PostRevision, len(d.revisions))
+	for id, revision := range d.revisions {
+		c.revisions[id] = revision
+	}
+	c.drafts = make(map[int64]models.Draft, len(d.drafts))
+	for id, draft := range d.drafts {
+		c.drafts[id] = draft
+	}
+	c.reactions = make(map[int64]models.Reaction, len(d.reactions))
+	for id, reaction := range d.reactions {
+		c.reactions[id] = reaction
+	}
+	c.votes = make(map[int64]models.CommentVote, len(d.votes))
+	for id, vote := range d.votes {
+		c.votes[id] = vote
+	}
+	return c
+}
+
+// memoryRepository implements the user, post, comment, token, two factor,
+// identity, profile, audit, revision, draft, reaction and vote repositories
+// on top of a Store. Inside a unit of work the store is already locked.
+type memoryRepository struct {
+	store *Store
+	inTx  bool
+}
+
+func (repo *memoryRepository) lock(ctx context.Context) (*data, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if repo.inTx {
+		return &repo.store.data, func() {}, nil
+	}
+	repo.store.mu.Lock()
+	return &repo.store.data, repo.store.mu.Unlock, nil
+}
+
+// timestamps fills in the fields GORM sets on create
+func timestamps(createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt.IsZero() {
+		*updatedAt = now
+	}
+}
+
+func sortedIDs[T any](rows map[int64]T) []int64 {
+	ids := make([]int64, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// filter returns the rows matching keep in id order, nil when there are none
+// like the GORM repositories do
+func filter[T any](rows map[int64]T, keep func(T) bool) []T {
+	var result []T
+	for _, id := range sortedIDs(rows) {
+		if keep(rows[id]) {
+			result = append(result, rows[id])
+		}
+	}
+	return result
+}
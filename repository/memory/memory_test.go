@@ -0,0 +1,13 @@
+package memory_test
+
+import (
+	"testing"
+
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+	"postgresql-blog/repository/repotest"
+)
+
+func TestMemory(t *testing.T) {
+	repotest.Run(t, func(t testing.TB) repository.Store { return memory.New() })
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigratePost(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) CreatePost(ctx context.Context, post models.Post) (*models.Post, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	d.nextPostID++
+	post.ID = d.nextPostID
+	timestamps(&post.CreatedAt, &post.UpdatedAt)
+	d.posts[post.ID] = post
+
+	return &post, nil
+}
+
+func (repo *memoryRepository) AllPosts(ctx context.Context) ([]models.Post, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return filter(d.posts, func(models.Post) bool { return true }), nil
+}
+
+func (repo *memoryRepository) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	return repo.findPost(ctx, func(post models.Post) bool { return post.ID == id })
+}
+
+func (repo *memoryRepository) GetPostByTitle(ctx context.Context, title string) (*models.Post, error) {
+	return repo.findPost(ctx, func(post models.Post) bool { return post.Title == title })
+}
+
+func (repo *memoryRepository) findPost(ctx context.Context, match func(models.Post) bool) (*models.Post, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	posts := filter(d.posts, match)
+	if len(posts) == 0 {
+		return nil, repository.ErrNotExist
+	}
+	return &posts[0], nil
+}
+
+func (repo *memoryRepository) GetPostByUserID(ctx context.Context, userid int64) ([]models.Post, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return filter(d.posts, func(post models.Post) bool { return post.UserID == uint64(userid) }), nil
+}
+
+func (repo *memoryRepository) UpdatePost(ctx context.Context, id int64, updated models.Post) (*models.Post, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := d.posts[id]; !ok {
+		return nil, repository.ErrUpdateFailed
+	}
+	updated.ID = id
+	updated.UpdatedAt = time.Now()
+	d.posts[id] = updated
+
+	return &updated, nil
+}
+
+func (repo *memoryRepository) DeletePost(ctx context.Context, id int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := d.posts[id]; !ok {
+		return repository.ErrDeleteFailed
+	}
+	delete(d.posts, id)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateProfile(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	profile, ok := d.profiles[userID]
+	if !ok {
+		return nil, repository.ErrNotExist
+	}
+	profile.Links = append([]models.Link(nil), profile.Links...)
+	return &profile, nil
+}
+
+func (repo *memoryRepository) AllProfiles(ctx context.Context) ([]models.Profile, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var result []models.Profile
+	for _, id := range sortedIDs(d.profiles) {
+		profile := d.profiles[id]
+		profile.Links = append([]models.Link(nil), profile.Links...)
+		result = append(result, profile)
+	}
+	return result, nil
+}
+
+func (repo *memoryRepository) SaveProfile(ctx context.Context, profile models.Profile) (*models.Profile, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	profile.UpdatedAt = time.Now()
+	profile.Links = append([]models.Link(nil), profile.Links...)
+	d.profiles[profile.UserID] = profile
+	return &profile, nil
+}
+
+func (repo *memoryRepository) DeleteProfile(ctx context.Context, userID int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	delete(d.avatars, userID)
+	delete(d.profiles, userID)
+	return nil
+}
+
+func (repo *memoryRepository) GetAvatar(ctx context.Context, userID int64) (*models.Avatar, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	avatar, ok := d.avatars[userID]
+	if !ok {
+		return nil, repository.ErrNotExist
+	}
+	return &avatar, nil
+}
+
+func (repo *memoryRepository) SaveAvatar(ctx context.Context, avatar models.Avatar) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	avatar.UpdatedAt = time.Now()
+	avatar.Data = append([]byte(nil), avatar.Data...)
+	d.avatars[avatar.UserID] = avatar
+	return nil
+}
+
+func (repo *memoryRepository) DeleteAvatar(ctx context.Context, userID int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	delete(d.avatars, userID)
+	return nil
+}
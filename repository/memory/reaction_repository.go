@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateReaction(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) AddReaction(ctx context.Context, reaction models.Reaction) (*models.Reaction, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, other := range d.reactions {
+		if other.UserID == reaction.UserID && other.TargetType == reaction.TargetType &&
+			other.TargetID == reaction.TargetID && other.Kind == reaction.Kind {
+			return nil, repository.ErrDuplicate
+		}
+	}
+	d.nextReactionID++
+	reaction.ID = d.nextReactionID
+	d.reactions[reaction.ID] = reaction
+	return &reaction, nil
+}
+
+func (repo *memoryRepository) GetReaction(ctx context.Context, userID int64, targetType string, targetID int64, kind string) (*models.Reaction, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, reaction := range d.reactions {
+		if reaction.UserID == userID && reaction.TargetType == targetType &&
+			reaction.TargetID == targetID && reaction.Kind == kind {
+			return &reaction, nil
+		}
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (repo *memoryRepository) DeleteReaction(ctx context.Context, id int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := d.reactions[id]; !ok {
+		return repository.ErrDeleteFailed
+	}
+	delete(d.reactions, id)
+	return nil
+}
+
+func (repo *memoryRepository) CountReactions(ctx context.Context, targetType string, targetIDs []int64) (map[int64]map[string]int, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	wanted := make(map[int64]bool, len(targetIDs))
+	for _, id := range targetIDs {
+		wanted[id] = true
+	}
+	counts := map[int64]map[string]int{}
+	for _, reaction := range d.reactions {
+		if reaction.TargetType != targetType || !wanted[reaction.TargetID] {
+			continue
+		}
+		if counts[reaction.TargetID] == nil {
+			counts[reaction.TargetID] = map[string]int{}
+		}
+		counts[reaction.TargetID][reaction.Kind]++
+	}
+	return counts, nil
+}
+
+func (repo *memoryRepository) FindReactions(ctx context.Context, f repository.ReactionFilter) ([]repository.Reactor, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	reactions := filter(d.reactions, func(reaction models.Reaction) bool {
+		return reaction.TargetType == f.TargetType && reaction.TargetID == f.TargetID && reaction.ID > f.After &&
+			(f.Kind == "" || reaction.Kind == f.Kind)
+	})
+	if f.Limit > 0 && len(reactions) > f.Limit {
+		reactions = reactions[:f.Limit]
+	}
+	var reactors []repository.Reactor
+	for _, reaction := range reactions {
+		// like the join of the databases, the reactions of deleted users
+		// are left out
+		user, ok := d.users[reaction.UserID]
+		if !ok {
+			continue
+		}
+		reactors = append(reactors, repository.Reactor{Reaction: reaction, Username: user.Username})
+	}
+	return reactors, nil
+}
+
+func (repo *memoryRepository) GetUserReactions(ctx context.Context, userID int64) ([]models.Reaction, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return filter(d.reactions, func(reaction models.Reaction) bool {
+		return reaction.UserID == userID
+	}), nil
+}
+
+func (repo *memoryRepository) DeleteUserReactions(ctx context.Context, userID int64) error {
+	return repo.deleteReactions(ctx, func(reaction models.Reaction) bool { return reaction.UserID == userID })
+}
+
+func (repo *memoryRepository) DeleteTargetReactions(ctx context.Context, targetType string, targetID int64) error {
+	return repo.deleteReactions(ctx, func(reaction models.Reaction) bool {
+		return reaction.TargetType == targetType && reaction.TargetID == targetID
+	})
+}
+
+func (repo *memoryRepository) deleteReactions(ctx context.Context, match func(models.Reaction) bool) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, reaction := range d.reactions {
+		if match(reaction) {
+			delete(d.reactions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateRevision(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) CreateRevision(ctx context.Context, revision models.PostRevision) (*models.PostRevision, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	revision.Number = lastRevision(d, revision.PostID) + 1
+	d.nextRevisionID++
+	revision.ID = d.nextRevisionID
+	if revision.CreatedAt.IsZero() {
+		revision.CreatedAt = time.Now()
+	}
+	d.revisions[revision.ID] = revision
+	return &revision, nil
+}
+
+func (repo *memoryRepository) GetRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// the ids grow with the numbers
+	return filter(d.revisions, func(revision models.PostRevision) bool {
+		return revision.PostID == postID
+	}), nil
+}
+
+func (repo *memoryRepository) GetRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, revision := range d.revisions {
+		if revision.PostID == postID && revision.Number == number {
+			return &revision, nil
+		}
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (repo *memoryRepository) PruneRevisions(ctx context.Context, postID int64, keep int) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	last := lastRevision(d, postID)
+	for id, revision := range d.revisions {
+		if revision.PostID == postID && revision.Number <= last-keep {
+			delete(d.revisions, id)
+		}
+	}
+	return nil
+}
+
+func (repo *memoryRepository) DeleteRevisions(ctx context.Context, postID int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, revision := range d.revisions {
+		if revision.PostID == postID {
+			delete(d.revisions, id)
+		}
+	}
+	return nil
+}
+
+func lastRevision(d *data, postID int64) int {
+	last := 0
+	for _, revision := range d.revisions {
+		if revision.PostID == postID && revision.Number > last {
+			last = revision.Number
+		}
+	}
+	return last
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateToken(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) CreateToken(ctx context.Context, token models.UserToken) (*models.UserToken, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, existing := range d.tokens {
+		if existing.Hash == token.Hash {
+			return nil, repository.ErrDuplicate
+		}
+	}
+	d.nextTokenID++
+	token.ID = d.nextTokenID
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	d.tokens[token.ID] = token
+
+	return &token, nil
+}
+
+func (repo *memoryRepository) UseToken(ctx context.Context, purpose, hash string, now time.Time) (*models.UserToken, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for id, token := range d.tokens {
+		if token.Purpose != purpose || token.Hash != hash || token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			continue
+		}
+		token.UsedAt = &now
+		d.tokens[id] = token
+		return &token, nil
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (repo *memoryRepository) DeleteUserTokens(ctx context.Context, userID int64, purpose string) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, token := range d.tokens {
+		if token.UserID == userID && (purpose == "" || token.Purpose == purpose) {
+			delete(d.tokens, id)
+		}
+	}
+	return nil
+}
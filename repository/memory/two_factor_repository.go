@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateTwoFactor(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	twoFactor, ok := d.twoFactors[userID]
+	if !ok {
+		return nil, repository.ErrNotExist
+	}
+	return &twoFactor, nil
+}
+
+func (repo *memoryRepository) SaveTwoFactor(ctx context.Context, twoFactor models.TwoFactor) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if twoFactor.CreatedAt.IsZero() {
+		twoFactor.CreatedAt = time.Now()
+	}
+	d.twoFactors[twoFactor.UserID] = twoFactor
+	return nil
+}
+
+func (repo *memoryRepository) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	d.deleteRecoveryCodes(userID)
+	delete(d.twoFactors, userID)
+	return nil
+}
+
+func (repo *memoryRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	d.deleteRecoveryCodes(userID)
+	for _, hash := range hashes {
+		for _, code := range d.recoveryCodes {
+			if code.Hash == hash {
+				return repository.ErrDuplicate
+			}
+		}
+		d.nextCodeID++
+		d.recoveryCodes[d.nextCodeID] = models.RecoveryCode{ID: d.nextCodeID, UserID: userID, Hash: hash}
+	}
+	return nil
+}
+
+func (repo *memoryRepository) UseRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, code := range d.recoveryCodes {
+		if code.UserID != userID || code.Hash != hash || code.UsedAt != nil {
+			continue
+		}
+		code.UsedAt = &now
+		d.recoveryCodes[id] = code
+		return nil
+	}
+	return repository.ErrNotExist
+}
+
+func (d *data) deleteRecoveryCodes(userID int64) {
+	for id, code := range d.recoveryCodes {
+		if code.UserID == userID {
+			delete(d.recoveryCodes, id)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateUser(ctx context.Context) error {
+	return nil
+}
+
+// userConflict reports whether another user already has the email or the
+// username, which are unique columns in gorm_users
+func userConflict(d *data, user models.User) bool {
+	for id, existing := range d.users {
+		if id == user.ID {
+			continue
+		}
+		if existing.Email == user.Email || existing.Username == user.Username {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *memoryRepository) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user.ID = 0
+	if userConflict(d, user) {
+		return nil, repository.ErrDuplicate
+	}
+	d.nextUserID++
+	user.ID = d.nextUserID
+	d.users[user.ID] = user
+
+	return &user, nil
+}
+
+func (repo *memoryRepository) AllUsers(ctx context.Context) ([]models.User, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return filter(d.users, func(models.User) bool { return true }), nil
+}
+
+func (repo *memoryRepository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return repo.findUser(ctx, func(user models.User) bool { return user.ID == id })
+}
+
+func (repo *memoryRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return repo.findUser(ctx, func(user models.User) bool { return user.Email == email })
+}
+
+func (repo *memoryRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return repo.findUser(ctx, func(user models.User) bool { return user.Username == username })
+}
+
+func (repo *memoryRepository) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	return repo.findUser(ctx, func(user models.User) bool {
+		return user.Username == username && user.Password == password
+	})
+}
+
+func (repo *memoryRepository) findUser(ctx context.Context, match func(models.User) bool) (*models.User, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	users := filter(d.users, match)
+	if len(users) == 0 {
+		return nil, repository.ErrNotExist
+	}
+	return &users[0], nil
+}
+
+func (repo *memoryRepository) UpdateUser(ctx context.Context, id int64, updated models.User) (*models.User, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := d.users[id]; !ok {
+		return nil, repository.ErrUpdateFailed
+	}
+	updated.ID = id
+	if userConflict(d, updated) {
+		return nil, repository.ErrDuplicate
+	}
+	d.users[id] = updated
+
+	return &updated, nil
+}
+
+func (repo *memoryRepository) DeleteUser(ctx context.Context, id int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := d.users[id]; !ok {
+		return repository.ErrDeleteFailed
+	}
+	delete(d.users, id)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func (repo *memoryRepository) MigrateVote(ctx context.Context) error {
+	return nil
+}
+
+func (repo *memoryRepository) GetVote(ctx context.Context, commentID, userID int64) (*models.CommentVote, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, vote := range d.votes {
+		if vote.CommentID == commentID && vote.UserID == userID {
+			return &vote, nil
+		}
+	}
+	return nil, repository.ErrNotExist
+}
+
+func (repo *memoryRepository) SaveVote(ctx context.Context, vote models.CommentVote) (*models.CommentVote, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, other := range d.votes {
+		if other.ID != vote.ID && other.CommentID == vote.CommentID && other.UserID == vote.UserID {
+			return nil, repository.ErrDuplicate
+		}
+	}
+	if vote.ID == 0 {
+		d.nextVoteID++
+		vote.ID = d.nextVoteID
+	} else if _, ok := d.votes[vote.ID]; !ok {
+		return nil, repository.ErrUpdateFailed
+	}
+	now := time.Now()
+	if vote.CreatedAt.IsZero() {
+		vote.CreatedAt = now
+	}
+	vote.UpdatedAt = now
+	d.votes[vote.ID] = vote
+	return &vote, nil
+}
+
+func (repo *memoryRepository) DeleteVote(ctx context.Context, id int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := d.votes[id]; !ok {
+		return repository.ErrDeleteFailed
+	}
+	delete(d.votes, id)
+	return nil
+}
+
+func (repo *memoryRepository) GetUserVotes(ctx context.Context, userID int64) ([]models.CommentVote, error) {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return filter(d.votes, func(vote models.CommentVote) bool { return vote.UserID == userID }), nil
+}
+
+func (repo *memoryRepository) DeleteCommentVotes(ctx context.Context, commentID int64) error {
+	d, unlock, err := repo.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for id, vote := range d.votes {
+		if vote.CommentID == commentID {
+			delete(d.votes, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package pgxrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type auditRepository struct {
+	q querier
+}
+
+const (
+	migrateAuditEvents = `CREATE TABLE IF NOT EXISTS audit_events (
+	id bigserial PRIMARY KEY,
+	actor_id bigint,
+	action text,
+	entity_type text,
+	entity_id bigint,
+	changes text,
+	request_id text,
+	created_at timestamptz
+)`
+	indexAuditActor   = `CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id)`
+	indexAuditEntity  = `CREATE INDEX IF NOT EXISTS idx_audit_events_entity ON audit_events (entity_type, entity_id)`
+	indexAuditCreated = `CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at)`
+
+	auditColumns     = `id, actor_id, action, entity_type, entity_id, changes, request_id, created_at`
+	insertAuditEvent = `INSERT INTO audit_events (actor_id, action, entity_type, entity_id, changes, request_id, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	selectAuditEvents = `SELECT ` + auditColumns + ` FROM audit_events`
+)
+
+func scanAuditEvent(row pgx.Row) (models.AuditEvent, error) {
+	var event models.AuditEvent
+	err := row.Scan(&event.ID, &event.ActorID, &event.Action, &event.EntityType, &event.EntityID,
+		&event.Changes, &event.RequestID, &event.CreatedAt)
+	return event, err
+}
+
+func (repo *auditRepository) MigrateAudit(ctx context.Context) error {
+	for _, statement := range []string{migrateAuditEvents, indexAuditActor, indexAuditEntity, indexAuditCreated} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *auditRepository) AppendEvent(ctx context.Context, event models.AuditEvent) (*models.AuditEvent, error) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	err := repo.q.QueryRow(ctx, insertAuditEvent, event.ActorID, event.Action, event.EntityType, event.EntityID,
+		event.Changes, event.RequestID, event.CreatedAt).Scan(&event.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &event, nil
+}
+
+func (repo *auditRepository) FindEvents(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != nil {
+		where("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.EntityType != "" {
+		where("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if !filter.Since.IsZero() {
+		where("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("created_at < $%d", filter.Until)
+	}
+
+	query := selectAuditEvents
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := repo.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		events = append(events, event)
+	}
+	return events, translateError(rows.Err())
+}
@@ -0,0 +1,182 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type commentRepository struct {
+	q querier
+}
+
+const (
+	migrateComments = `CREATE TABLE IF NOT EXISTS gorm_comments (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	post_id bigint,
+	content text,
+	is_published boolean DEFAULT false,
+	published_at timestamptz,
+	deleted_at timestamptz,
+	created_at timestamptz,
+	updated_at timestamptz,
+	upvotes bigint NOT NULL DEFAULT 0,
+	downvotes bigint NOT NULL DEFAULT 0,
+	score double precision NOT NULL DEFAULT 0,
+	controversy double precision NOT NULL DEFAULT 0
+)`
+	addCommentVotes = `ALTER TABLE gorm_comments ADD COLUMN IF NOT EXISTS upvotes bigint NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS downvotes bigint NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS score double precision NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS controversy double precision NOT NULL DEFAULT 0`
+	indexCommentsScore       = `CREATE INDEX IF NOT EXISTS idx_gorm_comments_post_score ON gorm_comments (post_id, score)`
+	indexCommentsControversy = `CREATE INDEX IF NOT EXISTS idx_gorm_comments_post_controversy ON gorm_comments (post_id, controversy)`
+
+	commentColumns = `id, user_id, post_id, content, is_published, published_at, deleted_at, created_at, updated_at,
+	upvotes, downvotes, score, controversy`
+	insertComment = `INSERT INTO gorm_comments (user_id, post_id, content, is_published, published_at, deleted_at, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	selectComments              = `SELECT ` + commentColumns + ` FROM gorm_comments ORDER BY id`
+	selectCommentByID           = `SELECT ` + commentColumns + ` FROM gorm_comments WHERE id = $1`
+	selectCommentsByUserID      = `SELECT ` + commentColumns + ` FROM gorm_comments WHERE user_id = $1 ORDER BY id`
+	selectCommentsByPostID      = `SELECT ` + commentColumns + ` FROM gorm_comments WHERE post_id = $1 ORDER BY `
+	selectCommentByUserIDPostID = `SELECT ` + commentColumns + ` FROM gorm_comments WHERE user_id = $1 AND post_id = $2 ORDER BY id LIMIT 1`
+	updateComment               = `UPDATE gorm_comments SET user_id = $2, post_id = $3, content = $4, is_published = $5,
+	published_at = $6, deleted_at = $7, created_at = $8, updated_at = $9 WHERE id = $1`
+	addVotes = `UPDATE gorm_comments SET upvotes = upvotes + $2, downvotes = downvotes + $3 WHERE id = $1
+	RETURNING ` + commentColumns
+	updateScore   = `UPDATE gorm_comments SET score = $2, controversy = $3 WHERE id = $1`
+	deleteComment = `DELETE FROM gorm_comments WHERE id = $1`
+)
+
+func scanComment(row pgx.Row) (models.Comment, error) {
+	var comment models.Comment
+	err := row.Scan(&comment.ID, &comment.UserID, &comment.PostID, &comment.Content, &comment.IsPublished,
+		&comment.PublishedAt, &comment.DeletedAt, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.Upvotes, &comment.Downvotes, &comment.Score, &comment.Controversy)
+	return comment, err
+}
+
+func (repo *commentRepository) MigrateComment(ctx context.Context) error {
+	for _, statement := range []string{migrateComments, addCommentVotes, indexCommentsScore, indexCommentsControversy} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *commentRepository) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	created := comment
+	created.ID = 0
+	now := time.Now()
+	if created.CreatedAt.IsZero() {
+		created.CreatedAt = now
+	}
+	if created.UpdatedAt.IsZero() {
+		created.UpdatedAt = now
+	}
+
+	err := repo.q.QueryRow(ctx, insertComment, created.UserID, created.PostID, created.Content, created.IsPublished,
+		created.PublishedAt, created.DeletedAt, created.CreatedAt, created.UpdatedAt).Scan(&created.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &created, nil
+}
+
+func (repo *commentRepository) AllComments(ctx context.Context) ([]models.Comment, error) {
+	return repo.list(ctx, selectComments)
+}
+
+func (repo *commentRepository) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
+	return repo.get(ctx, selectCommentByID, id)
+}
+
+func (repo *commentRepository) GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error) {
+	return repo.list(ctx, selectCommentsByUserID, userid)
+}
+
+func (repo *commentRepository) GetCommentByPostID(ctx context.Context, postid int64, sort repository.CommentSort) ([]models.Comment, error) {
+	return repo.list(ctx, selectCommentsByPostID+repository.CommentOrder(sort), postid)
+}
+
+func (repo *commentRepository) GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error) {
+	return repo.get(ctx, selectCommentByUserIDPostID, userid, postid)
+}
+
+func (repo *commentRepository) UpdateComment(ctx context.Context, id int64, updated models.Comment) (*models.Comment, error) {
+	updated.UpdatedAt = time.Now()
+	// the votes and the ranking only change with AddCommentVotes and SetCommentScore
+	tag, err := repo.q.Exec(ctx, updateComment, id, updated.UserID, updated.PostID, updated.Content, updated.IsPublished,
+		updated.PublishedAt, updated.DeletedAt, updated.CreatedAt, updated.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repository.ErrUpdateFailed
+	}
+	return &updated, nil
+}
+
+func (repo *commentRepository) DeleteComment(ctx context.Context, id int64) error {
+	tag, err := repo.q.Exec(ctx, deleteComment, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *commentRepository) AddCommentVotes(ctx context.Context, id int64, up, down int) (*models.Comment, error) {
+	comment, err := repo.get(ctx, addVotes, id, up, down)
+	if errors.Is(err, repository.ErrNotExist) {
+		return nil, repository.ErrUpdateFailed
+	}
+	return comment, err
+}
+
+func (repo *commentRepository) SetCommentScore(ctx context.Context, id int64, score, controversy float64) error {
+	tag, err := repo.q.Exec(ctx, updateScore, id, score, controversy)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrUpdateFailed
+	}
+	return nil
+}
+
+func (repo *commentRepository) get(ctx context.Context, query string, args ...any) (*models.Comment, error) {
+	comment, err := scanComment(repo.q.QueryRow(ctx, query, args...))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &comment, nil
+}
+
+func (repo *commentRepository) list(ctx context.Context, query string, args ...any) ([]models.Comment, error) {
+	rows, err := repo.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var result []models.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		result = append(result, comment)
+	}
+	return result, translateError(rows.Err())
+}
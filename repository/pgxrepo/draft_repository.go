@@ -0,0 +1,129 @@
+package pgxrepo
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type draftRepository struct {
+	q querier
+}
+
+const (
+	migrateDrafts = `CREATE TABLE IF NOT EXISTS drafts (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	post_id bigint,
+	title text,
+	content text,
+	thumbnail text,
+	base_revision bigint,
+	created_at timestamptz,
+	updated_at timestamptz
+)`
+	indexDraftsUserPost = `CREATE UNIQUE INDEX IF NOT EXISTS idx_drafts_user_post ON drafts (user_id, post_id)`
+	indexDraftsPost     = `CREATE INDEX IF NOT EXISTS idx_drafts_post ON drafts (post_id)`
+
+	draftColumns = `id, user_id, post_id, title, content, thumbnail, base_revision, created_at, updated_at`
+	insertDraft  = `INSERT INTO drafts (user_id, post_id, title, content, thumbnail, base_revision, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	updateDraft = `UPDATE drafts SET user_id = $2, post_id = $3, title = $4, content = $5, thumbnail = $6,
+	base_revision = $7, created_at = $8, updated_at = $9 WHERE id = $1`
+	selectDraft      = `SELECT ` + draftColumns + ` FROM drafts WHERE user_id = $1 AND post_id = $2`
+	selectUserDrafts = `SELECT ` + draftColumns + ` FROM drafts WHERE user_id = $1 ORDER BY updated_at DESC, id DESC`
+	deleteDraft      = `DELETE FROM drafts WHERE user_id = $1 AND post_id = $2`
+	deleteUserDrafts = `DELETE FROM drafts WHERE user_id = $1`
+	deletePostDrafts = `DELETE FROM drafts WHERE post_id = $1`
+)
+
+func scanDraft(row pgx.Row) (models.Draft, error) {
+	var draft models.Draft
+	err := row.Scan(&draft.ID, &draft.UserID, &draft.PostID, &draft.Title, &draft.Content, &draft.Thumbnail,
+		&draft.BaseRevision, &draft.CreatedAt, &draft.UpdatedAt)
+	return draft, err
+}
+
+func (repo *draftRepository) MigrateDraft(ctx context.Context) error {
+	for _, statement := range []string{migrateDrafts, indexDraftsUserPost, indexDraftsPost} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *draftRepository) SaveDraft(ctx context.Context, draft models.Draft) (*models.Draft, error) {
+	if draft.ID == 0 {
+		err := repo.q.QueryRow(ctx, insertDraft, draft.UserID, draft.PostID, draft.Title, draft.Content, draft.Thumbnail,
+			draft.BaseRevision, draft.CreatedAt, draft.UpdatedAt).Scan(&draft.ID)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		return &draft, nil
+	}
+
+	tag, err := repo.q.Exec(ctx, updateDraft, draft.ID, draft.UserID, draft.PostID, draft.Title, draft.Content,
+		draft.Thumbnail, draft.BaseRevision, draft.CreatedAt, draft.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repository.ErrUpdateFailed
+	}
+	return &draft, nil
+}
+
+func (repo *draftRepository) GetDraft(ctx context.Context, userID, postID int64) (*models.Draft, error) {
+	draft, err := scanDraft(repo.q.QueryRow(ctx, selectDraft, userID, postID))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &draft, nil
+}
+
+func (repo *draftRepository) GetUserDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	return repo.queryDrafts(ctx, selectUserDrafts, userID)
+}
+
+func (repo *draftRepository) queryDrafts(ctx context.Context, query string, id int64) ([]models.Draft, error) {
+	rows, err := repo.q.Query(ctx, query, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var drafts []models.Draft
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, translateError(rows.Err())
+}
+
+func (repo *draftRepository) DeleteDraft(ctx context.Context, userID, postID int64) error {
+	tag, err := repo.q.Exec(ctx, deleteDraft, userID, postID)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *draftRepository) DeleteUserDrafts(ctx context.Context, userID int64) error {
+	_, err := repo.q.Exec(ctx, deleteUserDrafts, userID)
+	return translateError(err)
+}
+
+func (repo *draftRepository) DeletePostDrafts(ctx context.Context, postID int64) error {
+	_, err := repo.q.Exec(ctx, deletePostDrafts, postID)
+	return translateError(err)
+}
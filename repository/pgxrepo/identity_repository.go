@@ -0,0 +1,110 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type identityRepository struct {
+	q querier
+}
+
+const (
+	migrateIdentities = `CREATE TABLE IF NOT EXISTS identities (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	provider text,
+	subject text,
+	email text,
+	created_at timestamptz,
+	last_used_at timestamptz
+)`
+	indexIdentitiesUserID          = `CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities (user_id)`
+	indexIdentitiesProviderSubject = `CREATE UNIQUE INDEX IF NOT EXISTS idx_identities_provider_subject ON identities (provider, subject)`
+	identityColumns                = `id, user_id, provider, subject, email, created_at, last_used_at`
+	insertIdentity                 = `INSERT INTO identities (user_id, provider, subject, email, created_at, last_used_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	selectIdentity               = `SELECT ` + identityColumns + ` FROM identities WHERE provider = $1 AND subject = $2`
+	selectUserIdentities         = `SELECT ` + identityColumns + ` FROM identities WHERE user_id = $1 ORDER BY id`
+	touchIdentity                = `UPDATE identities SET email = $2, last_used_at = $3 WHERE id = $1`
+	deleteUserIdentities         = `DELETE FROM identities WHERE user_id = $1`
+	deleteUserIdentitiesProvider = `DELETE FROM identities WHERE user_id = $1 AND provider = $2`
+)
+
+func scanIdentity(row pgx.Row) (models.Identity, error) {
+	var identity models.Identity
+	err := row.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastUsedAt)
+	return identity, err
+}
+
+func (repo *identityRepository) MigrateIdentity(ctx context.Context) error {
+	for _, statement := range []string{migrateIdentities, indexIdentitiesUserID, indexIdentitiesProviderSubject} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *identityRepository) CreateIdentity(ctx context.Context, identity models.Identity) (*models.Identity, error) {
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	err := repo.q.QueryRow(ctx, insertIdentity, identity.UserID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, identity.LastUsedAt).Scan(&identity.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &identity, nil
+}
+
+func (repo *identityRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	identity, err := scanIdentity(repo.q.QueryRow(ctx, selectIdentity, provider, subject))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &identity, nil
+}
+
+func (repo *identityRepository) GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error) {
+	rows, err := repo.q.Query(ctx, selectUserIdentities, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var identities []models.Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, translateError(rows.Err())
+}
+
+func (repo *identityRepository) TouchIdentity(ctx context.Context, id int64, email string, now time.Time) error {
+	tag, err := repo.q.Exec(ctx, touchIdentity, id, email, now)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrUpdateFailed
+	}
+	return nil
+}
+
+func (repo *identityRepository) DeleteUserIdentities(ctx context.Context, userID int64, provider string) error {
+	var err error
+	if provider == "" {
+		_, err = repo.q.Exec(ctx, deleteUserIdentities, userID)
+	} else {
+		_, err = repo.q.Exec(ctx, deleteUserIdentitiesProvider, userID, provider)
+	}
+	return translateError(err)
+}
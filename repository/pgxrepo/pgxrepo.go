@@ -0,0 +1,219 @@
+// Package pgxrepo implements the repositories with hand-written SQL on a
+// pgx connection pool. It uses the same tables as the GORM repositories, so
+// both can run against the same database.
+package pgxrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// querier is implemented by the pool and by a transaction, so the
+// repositories run the same statements inside and outside a unit of work
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Store is the pgx backed repository.Store
+type Store struct {
+	pool *pgxpool.Pool
+	opts repository.TxOptions
+}
+
+// Open connects a pool to dsn. Every statement is prepared once per
+// connection the first time it runs and the prepared statement is reused
+// after that. The configure functions may change the pool config before it
+// connects.
+func Open(ctx context.Context, dsn string, opts repository.TxOptions, configure ...func(*pgxpool.Config)) (*Store, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	for _, fn := range configure {
+		fn(config)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	// the pool connects lazily, fail now like gorm.Open does
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return New(pool, opts), nil
+}
+
+// New returns a store using an existing pool
+func New(pool *pgxpool.Pool, opts repository.TxOptions) *Store {
+	return &Store{pool: pool, opts: opts}
+}
+
+// Pool returns the connection pool of the store
+func (s *Store) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// Close closes the pool
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+func newRepositories(q querier) repository.Repositories {
+	return repository.Repositories{
+		Users:      &userRepository{q},
+		Posts:      &postRepository{q},
+		Comments:   &commentRepository{q},
+		Tokens:     &tokenRepository{q},
+		TwoFactors: &twoFactorRepository{q},
+		Identities: &identityRepository{q},
+		Profiles:   &profileRepository{q},
+		Audit:      &auditRepository{q},
+		Revisions:  &revisionRepository{q},
+		Drafts:     &draftRepository{q},
+		Reactions:  &reactionRepository{q},
+		Votes:      &voteRepository{q},
+	}
+}
+
+func (s *Store) Repositories() repository.Repositories {
+	return newRepositories(s.pool)
+}
+
+func (s *Store) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	txOptions := pgx.TxOptions{IsoLevel: isoLevel(s.opts.Isolation)}
+	err := repository.Retry(ctx, s.opts, func() error {
+		return pgx.BeginTxFunc(ctx, s.pool, txOptions, func(tx pgx.Tx) error {
+			return fn(ctx, newRepositories(tx))
+		})
+	})
+	return translateError(err)
+}
+
+func isoLevel(level sql.IsolationLevel) pgx.TxIsoLevel {
+	switch level {
+	case sql.LevelSerializable:
+		return pgx.Serializable
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		return pgx.RepeatableRead
+	case sql.LevelReadCommitted:
+		return pgx.ReadCommitted
+	case sql.LevelReadUncommitted:
+		return pgx.ReadUncommitted
+	default:
+		return ""
+	}
+}
+
+const (
+	migrateSchema = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	applied_at timestamptz
+)`
+	insertSchemaVersion = `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, now()) ON CONFLICT DO NOTHING`
+	selectSchemaVersion = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	schemaTableExists   = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+
+	migrateRateLimits = `CREATE TABLE IF NOT EXISTS rate_limits (
+	key text PRIMARY KEY,
+	tokens double precision,
+	refilled_at timestamptz,
+	failures bigint,
+	locked_until timestamptz,
+	expires_at timestamptz
+)`
+	indexRateLimits = `CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits (expires_at)`
+)
+
+// Migrate creates the tables the same way the GORM auto migration does
+func (s *Store) Migrate(ctx context.Context) error {
+	repos := s.Repositories()
+	if err := repos.Users.MigrateUser(ctx); err != nil {
+		return err
+	}
+	if err := repos.Posts.MigratePost(ctx); err != nil {
+		return err
+	}
+	if err := repos.Comments.MigrateComment(ctx); err != nil {
+		return err
+	}
+	if err := repos.Tokens.MigrateToken(ctx); err != nil {
+		return err
+	}
+	if err := repos.TwoFactors.MigrateTwoFactor(ctx); err != nil {
+		return err
+	}
+	if err := repos.Identities.MigrateIdentity(ctx); err != nil {
+		return err
+	}
+	if err := repos.Profiles.MigrateProfile(ctx); err != nil {
+		return err
+	}
+	if err := repos.Audit.MigrateAudit(ctx); err != nil {
+		return err
+	}
+	if err := repos.Revisions.MigrateRevision(ctx); err != nil {
+		return err
+	}
+	if err := repos.Drafts.MigrateDraft(ctx); err != nil {
+		return err
+	}
+	if err := repos.Reactions.MigrateReaction(ctx); err != nil {
+		return err
+	}
+	if err := repos.Votes.MigrateVote(ctx); err != nil {
+		return err
+	}
+	for _, statement := range []string{migrateRateLimits, indexRateLimits} {
+		if _, err := s.pool.Exec(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if _, err := s.pool.Exec(ctx, migrateSchema); err != nil {
+		return err
+	}
+	_, err := s.pool.Exec(ctx, insertSchemaVersion, repository.SchemaVersion)
+	return err
+}
+
+// SchemaVersion returns the newest version the database was migrated to,
+// zero when it was never migrated
+func (s *Store) SchemaVersion(ctx context.Context) (int64, error) {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, schemaTableExists).Scan(&exists); err != nil || !exists {
+		return 0, err
+	}
+	var version int64
+	err := s.pool.QueryRow(ctx, selectSchemaVersion).Scan(&version)
+	return version, err
+}
+
+// Ping checks that the database answers
+func (s *Store) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// translateError turns constraint violations and connection failures into
+// the repository errors
+func translateError(err error) error {
+	return repository.TranslateError(err)
+}
+
+// notExist turns a query without rows into repository.ErrNotExist
+func notExist(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotExist
+	}
+	return translateError(err)
+}
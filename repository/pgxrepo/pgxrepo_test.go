@@ -0,0 +1,16 @@
+package pgxrepo_test
+
+import (
+	"testing"
+
+	"postgresql-blog/database"
+	"postgresql-blog/repository/repotest"
+)
+
+func TestPgx(t *testing.T) {
+	repotest.Run(t, repotest.Postgres(database.DriverPgx))
+}
+
+func BenchmarkPgx(b *testing.B) {
+	repotest.Bench(b, repotest.Postgres(database.DriverPgx))
+}
@@ -0,0 +1,137 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type postRepository struct {
+	q querier
+}
+
+const (
+	migratePosts = `CREATE TABLE IF NOT EXISTS gorm_posts (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	title text,
+	content text,
+	thumbnail text,
+	is_published boolean DEFAULT false,
+	published_at timestamptz,
+	created_at timestamptz,
+	updated_at timestamptz
+)`
+	postColumns = `id, user_id, title, content, thumbnail, is_published, published_at, created_at, updated_at`
+	insertPost  = `INSERT INTO gorm_posts (user_id, title, content, thumbnail, is_published, published_at, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	selectPosts         = `SELECT ` + postColumns + ` FROM gorm_posts ORDER BY id`
+	selectPostByID      = `SELECT ` + postColumns + ` FROM gorm_posts WHERE id = $1`
+	selectPostByTitle   = `SELECT ` + postColumns + ` FROM gorm_posts WHERE title = $1 ORDER BY id LIMIT 1`
+	selectPostsByUserID = `SELECT ` + postColumns + ` FROM gorm_posts WHERE user_id = $1 ORDER BY id`
+	updatePost          = `UPDATE gorm_posts SET user_id = $2, title = $3, content = $4, thumbnail = $5, is_published = $6,
+	published_at = $7, created_at = $8, updated_at = $9 WHERE id = $1`
+	deletePost = `DELETE FROM gorm_posts WHERE id = $1`
+)
+
+func scanPost(row pgx.Row) (models.Post, error) {
+	var post models.Post
+	err := row.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.Thumbnail,
+		&post.IsPublished, &post.PublishedAt, &post.CreatedAt, &post.UpdatedAt)
+	return post, err
+}
+
+func (repo *postRepository) MigratePost(ctx context.Context) error {
+	_, err := repo.q.Exec(ctx, migratePosts)
+	return translateError(err)
+}
+
+func (repo *postRepository) CreatePost(ctx context.Context, post models.Post) (*models.Post, error) {
+	created := post
+	created.ID = 0
+	// like gorm, fill in the timestamps that were not given
+	now := time.Now()
+	if created.CreatedAt.IsZero() {
+		created.CreatedAt = now
+	}
+	if created.UpdatedAt.IsZero() {
+		created.UpdatedAt = now
+	}
+
+	err := repo.q.QueryRow(ctx, insertPost, created.UserID, created.Title, created.Content, created.Thumbnail,
+		created.IsPublished, created.PublishedAt, created.CreatedAt, created.UpdatedAt).Scan(&created.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &created, nil
+}
+
+func (repo *postRepository) AllPosts(ctx context.Context) ([]models.Post, error) {
+	return repo.list(ctx, selectPosts)
+}
+
+func (repo *postRepository) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	return repo.get(ctx, selectPostByID, id)
+}
+
+func (repo *postRepository) GetPostByTitle(ctx context.Context, title string) (*models.Post, error) {
+	return repo.get(ctx, selectPostByTitle, title)
+}
+
+func (repo *postRepository) GetPostByUserID(ctx context.Context, userid int64) ([]models.Post, error) {
+	return repo.list(ctx, selectPostsByUserID, userid)
+}
+
+func (repo *postRepository) UpdatePost(ctx context.Context, id int64, updated models.Post) (*models.Post, error) {
+	updated.UpdatedAt = time.Now()
+	tag, err := repo.q.Exec(ctx, updatePost, id, updated.UserID, updated.Title, updated.Content, updated.Thumbnail,
+		updated.IsPublished, updated.PublishedAt, updated.CreatedAt, updated.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repository.ErrUpdateFailed
+	}
+	return &updated, nil
+}
+
+func (repo *postRepository) DeletePost(ctx context.Context, id int64) error {
+	tag, err := repo.q.Exec(ctx, deletePost, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *postRepository) get(ctx context.Context, query string, args ...any) (*models.Post, error) {
+	post, err := scanPost(repo.q.QueryRow(ctx, query, args...))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &post, nil
+}
+
+func (repo *postRepository) list(ctx context.Context, query string, args ...any) ([]models.Post, error) {
+	rows, err := repo.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var result []models.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		result = append(result, post)
+	}
+	return result, translateError(rows.Err())
+}
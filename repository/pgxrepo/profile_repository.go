@@ -0,0 +1,136 @@
+package pgxrepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"postgresql-blog/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type profileRepository struct {
+	q querier
+}
+
+const (
+	migrateProfiles = `CREATE TABLE IF NOT EXISTS profiles (
+	user_id bigint PRIMARY KEY,
+	display_name text,
+	bio text,
+	website text,
+	links text,
+	timezone text,
+	locale text,
+	email_public boolean,
+	updated_at timestamptz
+)`
+	migrateAvatars = `CREATE TABLE IF NOT EXISTS avatars (
+	user_id bigint PRIMARY KEY,
+	content_type text,
+	data bytea,
+	updated_at timestamptz
+)`
+	profileColumns = `user_id, display_name, bio, website, links, timezone, locale, email_public, updated_at`
+	selectProfile  = `SELECT ` + profileColumns + ` FROM profiles WHERE user_id = $1`
+	selectProfiles = `SELECT ` + profileColumns + ` FROM profiles ORDER BY user_id`
+	upsertProfile  = `INSERT INTO profiles (` + profileColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (user_id) DO UPDATE SET display_name = excluded.display_name, bio = excluded.bio,
+	website = excluded.website, links = excluded.links, timezone = excluded.timezone, locale = excluded.locale,
+	email_public = excluded.email_public, updated_at = excluded.updated_at`
+	deleteProfile = `DELETE FROM profiles WHERE user_id = $1`
+	selectAvatar  = `SELECT user_id, content_type, data, updated_at FROM avatars WHERE user_id = $1`
+	upsertAvatar  = `INSERT INTO avatars (user_id, content_type, data, updated_at) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE SET content_type = excluded.content_type, data = excluded.data, updated_at = excluded.updated_at`
+	deleteAvatar = `DELETE FROM avatars WHERE user_id = $1`
+)
+
+// the links are stored as JSON like the GORM serializer does
+func scanProfile(row pgx.Row) (models.Profile, error) {
+	var profile models.Profile
+	var links *string
+	err := row.Scan(&profile.UserID, &profile.DisplayName, &profile.Bio, &profile.Website, &links,
+		&profile.Timezone, &profile.Locale, &profile.EmailPublic, &profile.UpdatedAt)
+	if err == nil && links != nil && *links != "" {
+		err = json.Unmarshal([]byte(*links), &profile.Links)
+	}
+	return profile, err
+}
+
+func (repo *profileRepository) MigrateProfile(ctx context.Context) error {
+	for _, statement := range []string{migrateProfiles, migrateAvatars} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *profileRepository) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	profile, err := scanProfile(repo.q.QueryRow(ctx, selectProfile, userID))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &profile, nil
+}
+
+func (repo *profileRepository) AllProfiles(ctx context.Context) ([]models.Profile, error) {
+	rows, err := repo.q.Query(ctx, selectProfiles)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var profiles []models.Profile
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, translateError(rows.Err())
+}
+
+func (repo *profileRepository) SaveProfile(ctx context.Context, profile models.Profile) (*models.Profile, error) {
+	profile.UpdatedAt = time.Now()
+	links, err := json.Marshal(profile.Links)
+	if err != nil {
+		return nil, err
+	}
+	_, err = repo.q.Exec(ctx, upsertProfile, profile.UserID, profile.DisplayName, profile.Bio, profile.Website,
+		string(links), profile.Timezone, profile.Locale, profile.EmailPublic, profile.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &profile, nil
+}
+
+func (repo *profileRepository) DeleteProfile(ctx context.Context, userID int64) error {
+	if _, err := repo.q.Exec(ctx, deleteAvatar, userID); err != nil {
+		return translateError(err)
+	}
+	_, err := repo.q.Exec(ctx, deleteProfile, userID)
+	return translateError(err)
+}
+
+func (repo *profileRepository) GetAvatar(ctx context.Context, userID int64) (*models.Avatar, error) {
+	var avatar models.Avatar
+	err := repo.q.QueryRow(ctx, selectAvatar, userID).Scan(&avatar.UserID, &avatar.ContentType, &avatar.Data, &avatar.UpdatedAt)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &avatar, nil
+}
+
+func (repo *profileRepository) SaveAvatar(ctx context.Context, avatar models.Avatar) error {
+	_, err := repo.q.Exec(ctx, upsertAvatar, avatar.UserID, avatar.ContentType, avatar.Data, time.Now())
+	return translateError(err)
+}
+
+func (repo *profileRepository) DeleteAvatar(ctx context.Context, userID int64) error {
+	_, err := repo.q.Exec(ctx, deleteAvatar, userID)
+	return translateError(err)
+}
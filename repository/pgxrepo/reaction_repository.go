@@ -0,0 +1,171 @@
+package pgxrepo
+
+import (
+	"context"
+	"fmt"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type reactionRepository struct {
+	q querier
+}
+
+const (
+	migrateReactions = `CREATE TABLE IF NOT EXISTS reactions (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	target_type text,
+	target_id bigint,
+	kind text,
+	created_at timestamptz
+)`
+	indexReactionsUnique = `CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_user_target_kind ON reactions (user_id, target_type, target_id, kind)`
+	indexReactionsTarget = `CREATE INDEX IF NOT EXISTS idx_reactions_target ON reactions (target_type, target_id)`
+
+	reactionColumns = `id, user_id, target_type, target_id, kind, created_at`
+	insertReaction  = `INSERT INTO reactions (user_id, target_type, target_id, kind, created_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	selectReaction = `SELECT ` + reactionColumns + ` FROM reactions
+	WHERE user_id = $1 AND target_type = $2 AND target_id = $3 AND kind = $4`
+	deleteReaction = `DELETE FROM reactions WHERE id = $1`
+	countReactions = `SELECT target_id, kind, COUNT(*) FROM reactions WHERE target_type = $1 AND target_id = ANY($2) GROUP BY target_id, kind`
+	selectReactors = `SELECT r.id, r.user_id, r.target_type, r.target_id, r.kind, r.created_at, u.username
+	FROM reactions r JOIN gorm_users u ON u.id = r.user_id
+	WHERE r.target_type = $1 AND r.target_id = $2 AND r.id > $3`
+	selectUserReactions   = `SELECT ` + reactionColumns + ` FROM reactions WHERE user_id = $1 ORDER BY id`
+	deleteUserReactions   = `DELETE FROM reactions WHERE user_id = $1`
+	deleteTargetReactions = `DELETE FROM reactions WHERE target_type = $1 AND target_id = $2`
+)
+
+func scanReaction(row pgx.Row) (models.Reaction, error) {
+	var reaction models.Reaction
+	err := row.Scan(&reaction.ID, &reaction.UserID, &reaction.TargetType, &reaction.TargetID, &reaction.Kind, &reaction.CreatedAt)
+	return reaction, err
+}
+
+func (repo *reactionRepository) MigrateReaction(ctx context.Context) error {
+	for _, statement := range []string{migrateReactions, indexReactionsUnique, indexReactionsTarget} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *reactionRepository) AddReaction(ctx context.Context, reaction models.Reaction) (*models.Reaction, error) {
+	err := repo.q.QueryRow(ctx, insertReaction, reaction.UserID, reaction.TargetType, reaction.TargetID,
+		reaction.Kind, reaction.CreatedAt).Scan(&reaction.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &reaction, nil
+}
+
+func (repo *reactionRepository) GetReaction(ctx context.Context, userID int64, targetType string, targetID int64, kind string) (*models.Reaction, error) {
+	reaction, err := scanReaction(repo.q.QueryRow(ctx, selectReaction, userID, targetType, targetID, kind))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &reaction, nil
+}
+
+func (repo *reactionRepository) DeleteReaction(ctx context.Context, id int64) error {
+	tag, err := repo.q.Exec(ctx, deleteReaction, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *reactionRepository) CountReactions(ctx context.Context, targetType string, targetIDs []int64) (map[int64]map[string]int, error) {
+	counts := map[int64]map[string]int{}
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+	rows, err := repo.q.Query(ctx, countReactions, targetType, targetIDs)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID int64
+		var kind string
+		var count int
+		if err := rows.Scan(&targetID, &kind, &count); err != nil {
+			return nil, translateError(err)
+		}
+		if counts[targetID] == nil {
+			counts[targetID] = map[string]int{}
+		}
+		counts[targetID][kind] = count
+	}
+	return counts, translateError(rows.Err())
+}
+
+func (repo *reactionRepository) FindReactions(ctx context.Context, filter repository.ReactionFilter) ([]repository.Reactor, error) {
+	query := selectReactors
+	args := []any{filter.TargetType, filter.TargetID, filter.After}
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		query += fmt.Sprintf(` AND r.kind = $%d`, len(args))
+	}
+	query += ` ORDER BY r.id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := repo.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var reactors []repository.Reactor
+	for rows.Next() {
+		var reactor repository.Reactor
+		err := rows.Scan(&reactor.ID, &reactor.UserID, &reactor.TargetType, &reactor.TargetID, &reactor.Kind,
+			&reactor.CreatedAt, &reactor.Username)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		reactors = append(reactors, reactor)
+	}
+	return reactors, translateError(rows.Err())
+}
+
+func (repo *reactionRepository) GetUserReactions(ctx context.Context, userID int64) ([]models.Reaction, error) {
+	rows, err := repo.q.Query(ctx, selectUserReactions, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var reactions []models.Reaction
+	for rows.Next() {
+		reaction, err := scanReaction(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, translateError(rows.Err())
+}
+
+func (repo *reactionRepository) DeleteUserReactions(ctx context.Context, userID int64) error {
+	_, err := repo.q.Exec(ctx, deleteUserReactions, userID)
+	return translateError(err)
+}
+
+func (repo *reactionRepository) DeleteTargetReactions(ctx context.Context, targetType string, targetID int64) error {
+	_, err := repo.q.Exec(ctx, deleteTargetReactions, targetType, targetID)
+	return translateError(err)
+}
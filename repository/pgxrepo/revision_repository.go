@@ -0,0 +1,103 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type revisionRepository struct {
+	q querier
+}
+
+const (
+	migrateRevisions = `CREATE TABLE IF NOT EXISTS post_revisions (
+	id bigserial PRIMARY KEY,
+	post_id bigint,
+	number bigint,
+	title text,
+	content text,
+	thumbnail text,
+	editor_id bigint,
+	restored_from bigint,
+	created_at timestamptz
+)`
+	indexRevisions = `CREATE UNIQUE INDEX IF NOT EXISTS idx_post_revisions_post_number ON post_revisions (post_id, number)`
+
+	revisionColumns = `id, post_id, number, title, content, thumbnail, editor_id, restored_from, created_at`
+	insertRevision  = `INSERT INTO post_revisions (post_id, number, title, content, thumbnail, editor_id, restored_from, created_at)
+	VALUES ($1, (SELECT COALESCE(MAX(number), 0) + 1 FROM post_revisions WHERE post_id = $1), $2, $3, $4, $5, $6, $7)
+	RETURNING id, number`
+	selectRevisions = `SELECT ` + revisionColumns + ` FROM post_revisions WHERE post_id = $1 ORDER BY number`
+	selectRevision  = `SELECT ` + revisionColumns + ` FROM post_revisions WHERE post_id = $1 AND number = $2`
+	pruneRevisions  = `DELETE FROM post_revisions WHERE post_id = $1
+	AND number <= (SELECT MAX(number) FROM post_revisions WHERE post_id = $1) - $2`
+	deleteRevisions = `DELETE FROM post_revisions WHERE post_id = $1`
+)
+
+func scanRevision(row pgx.Row) (models.PostRevision, error) {
+	var revision models.PostRevision
+	err := row.Scan(&revision.ID, &revision.PostID, &revision.Number, &revision.Title, &revision.Content,
+		&revision.Thumbnail, &revision.EditorID, &revision.RestoredFrom, &revision.CreatedAt)
+	return revision, err
+}
+
+func (repo *revisionRepository) MigrateRevision(ctx context.Context) error {
+	for _, statement := range []string{migrateRevisions, indexRevisions} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *revisionRepository) CreateRevision(ctx context.Context, revision models.PostRevision) (*models.PostRevision, error) {
+	if revision.CreatedAt.IsZero() {
+		revision.CreatedAt = time.Now()
+	}
+	err := repo.q.QueryRow(ctx, insertRevision, revision.PostID, revision.Title, revision.Content, revision.Thumbnail,
+		revision.EditorID, revision.RestoredFrom, revision.CreatedAt).Scan(&revision.ID, &revision.Number)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &revision, nil
+}
+
+func (repo *revisionRepository) GetRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	rows, err := repo.q.Query(ctx, selectRevisions, postID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var revisions []models.PostRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, translateError(rows.Err())
+}
+
+func (repo *revisionRepository) GetRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error) {
+	revision, err := scanRevision(repo.q.QueryRow(ctx, selectRevision, postID, number))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &revision, nil
+}
+
+func (repo *revisionRepository) PruneRevisions(ctx context.Context, postID int64, keep int) error {
+	_, err := repo.q.Exec(ctx, pruneRevisions, postID, keep)
+	return translateError(err)
+}
+
+func (repo *revisionRepository) DeleteRevisions(ctx context.Context, postID int64) error {
+	_, err := repo.q.Exec(ctx, deleteRevisions, postID)
+	return translateError(err)
+}
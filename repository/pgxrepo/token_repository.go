@@ -0,0 +1,81 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type tokenRepository struct {
+	q querier
+}
+
+const (
+	migrateTokens = `CREATE TABLE IF NOT EXISTS user_tokens (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	purpose text,
+	hash text,
+	email text NOT NULL DEFAULT '',
+	expires_at timestamptz,
+	used_at timestamptz,
+	created_at timestamptz
+)`
+	addTokenEmail     = `ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS email text NOT NULL DEFAULT ''`
+	indexTokensUserID = `CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens (user_id)`
+	indexTokensHash   = `CREATE UNIQUE INDEX IF NOT EXISTS idx_user_tokens_hash ON user_tokens (hash)`
+	tokenColumns      = `id, user_id, purpose, hash, email, expires_at, used_at, created_at`
+	insertToken       = `INSERT INTO user_tokens (user_id, purpose, hash, email, expires_at, used_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	useToken = `UPDATE user_tokens SET used_at = $3
+	WHERE purpose = $1 AND hash = $2 AND used_at IS NULL AND expires_at > $3 RETURNING ` + tokenColumns
+	deleteUserTokens        = `DELETE FROM user_tokens WHERE user_id = $1`
+	deleteUserTokensPurpose = `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`
+)
+
+func scanToken(row pgx.Row) (models.UserToken, error) {
+	var token models.UserToken
+	err := row.Scan(&token.ID, &token.UserID, &token.Purpose, &token.Hash, &token.Email, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	return token, err
+}
+
+func (repo *tokenRepository) MigrateToken(ctx context.Context) error {
+	for _, statement := range []string{migrateTokens, addTokenEmail, indexTokensUserID, indexTokensHash} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *tokenRepository) CreateToken(ctx context.Context, token models.UserToken) (*models.UserToken, error) {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	err := repo.q.QueryRow(ctx, insertToken, token.UserID, token.Purpose, token.Hash, token.Email, token.ExpiresAt, token.UsedAt, token.CreatedAt).Scan(&token.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+func (repo *tokenRepository) UseToken(ctx context.Context, purpose, hash string, now time.Time) (*models.UserToken, error) {
+	token, err := scanToken(repo.q.QueryRow(ctx, useToken, purpose, hash, now))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &token, nil
+}
+
+func (repo *tokenRepository) DeleteUserTokens(ctx context.Context, userID int64, purpose string) error {
+	var err error
+	if purpose == "" {
+		_, err = repo.q.Exec(ctx, deleteUserTokens, userID)
+	} else {
+		_, err = repo.q.Exec(ctx, deleteUserTokensPurpose, userID, purpose)
+	}
+	return translateError(err)
+}
@@ -0,0 +1,97 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+type twoFactorRepository struct {
+	q querier
+}
+
+const (
+	migrateTwoFactors = `CREATE TABLE IF NOT EXISTS user_two_factors (
+	user_id bigint PRIMARY KEY,
+	secret text,
+	enabled_at timestamptz,
+	last_step bigint,
+	created_at timestamptz
+)`
+	migrateRecoveryCodes = `CREATE TABLE IF NOT EXISTS recovery_codes (
+	id bigserial PRIMARY KEY,
+	user_id bigint,
+	hash text,
+	used_at timestamptz
+)`
+	indexRecoveryCodesUserID = `CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id)`
+	indexRecoveryCodesHash   = `CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_hash ON recovery_codes (hash)`
+	selectTwoFactor          = `SELECT user_id, secret, enabled_at, last_step, created_at FROM user_two_factors WHERE user_id = $1`
+	upsertTwoFactor          = `INSERT INTO user_two_factors (user_id, secret, enabled_at, last_step, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled_at = excluded.enabled_at,
+	last_step = excluded.last_step, created_at = excluded.created_at`
+	deleteTwoFactor     = `DELETE FROM user_two_factors WHERE user_id = $1`
+	deleteRecoveryCodes = `DELETE FROM recovery_codes WHERE user_id = $1`
+	insertRecoveryCode  = `INSERT INTO recovery_codes (user_id, hash) VALUES ($1, $2)`
+	useRecoveryCode     = `UPDATE recovery_codes SET used_at = $3 WHERE user_id = $1 AND hash = $2 AND used_at IS NULL`
+)
+
+func (repo *twoFactorRepository) MigrateTwoFactor(ctx context.Context) error {
+	for _, statement := range []string{migrateTwoFactors, migrateRecoveryCodes, indexRecoveryCodesUserID, indexRecoveryCodesHash} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *twoFactorRepository) GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	err := repo.q.QueryRow(ctx, selectTwoFactor, userID).Scan(&twoFactor.UserID, &twoFactor.Secret, &twoFactor.EnabledAt, &twoFactor.LastStep, &twoFactor.CreatedAt)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &twoFactor, nil
+}
+
+func (repo *twoFactorRepository) SaveTwoFactor(ctx context.Context, twoFactor models.TwoFactor) error {
+	if twoFactor.CreatedAt.IsZero() {
+		twoFactor.CreatedAt = time.Now()
+	}
+	_, err := repo.q.Exec(ctx, upsertTwoFactor, twoFactor.UserID, twoFactor.Secret, twoFactor.EnabledAt, twoFactor.LastStep, twoFactor.CreatedAt)
+	return translateError(err)
+}
+
+func (repo *twoFactorRepository) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	if _, err := repo.q.Exec(ctx, deleteRecoveryCodes, userID); err != nil {
+		return translateError(err)
+	}
+	_, err := repo.q.Exec(ctx, deleteTwoFactor, userID)
+	return translateError(err)
+}
+
+func (repo *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	if _, err := repo.q.Exec(ctx, deleteRecoveryCodes, userID); err != nil {
+		return translateError(err)
+	}
+	for _, hash := range hashes {
+		if _, err := repo.q.Exec(ctx, insertRecoveryCode, userID, hash); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time) error {
+	tag, err := repo.q.Exec(ctx, useRecoveryCode, userID, hash, now)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotExist
+	}
+	return nil
+}
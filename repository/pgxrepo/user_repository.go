@@ -0,0 +1,164 @@
+package pgxrepo
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type userRepository struct {
+	q querier
+}
+
+const (
+	migrateUsers = `CREATE TABLE IF NOT EXISTS gorm_users (
+	id bigserial PRIMARY KEY,
+	name text,
+	email text UNIQUE,
+	password text,
+	username text UNIQUE,
+	email_verified_at timestamptz,
+	status text NOT NULL DEFAULT '',
+	suspended_until timestamptz,
+	status_reason text NOT NULL DEFAULT ''
+)`
+	userTableExists  = `SELECT to_regclass('gorm_users') IS NOT NULL`
+	userColumnExists = `SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'gorm_users' AND column_name = $1)`
+	addUserVerified  = `ALTER TABLE gorm_users ADD COLUMN IF NOT EXISTS email_verified_at timestamptz`
+	addUserStatus    = `ALTER TABLE gorm_users ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS suspended_until timestamptz,
+	ADD COLUMN IF NOT EXISTS status_reason text NOT NULL DEFAULT ''`
+	verifyUsers = `UPDATE gorm_users SET email_verified_at = now() WHERE email_verified_at IS NULL`
+
+	userColumns = `id, name, email, password, username, email_verified_at, status, suspended_until, status_reason`
+	insertUser  = `INSERT INTO gorm_users (name, email, password, username, email_verified_at, status, suspended_until, status_reason)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	selectUsers          = `SELECT ` + userColumns + ` FROM gorm_users ORDER BY id`
+	selectUserByID       = `SELECT ` + userColumns + ` FROM gorm_users WHERE id = $1`
+	selectUserByEmail    = `SELECT ` + userColumns + ` FROM gorm_users WHERE email = $1 ORDER BY id LIMIT 1`
+	selectUserByUsername = `SELECT ` + userColumns + ` FROM gorm_users WHERE username = $1 ORDER BY id LIMIT 1`
+	selectUserByLogin    = `SELECT ` + userColumns + ` FROM gorm_users WHERE username = $1 AND password = $2 ORDER BY id LIMIT 1`
+	updateUser           = `UPDATE gorm_users SET name = $2, email = $3, password = $4, username = $5, email_verified_at = $6,
+	status = $7, suspended_until = $8, status_reason = $9 WHERE id = $1`
+	deleteUser = `DELETE FROM gorm_users WHERE id = $1`
+)
+
+func scanUser(row pgx.Row) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Username, &user.EmailVerifiedAt,
+		&user.Status, &user.SuspendedUntil, &user.StatusReason)
+	return user, err
+}
+
+func (repo *userRepository) MigrateUser(ctx context.Context) error {
+	// the users from before email verification keep their accounts
+	var hasTable, hasColumn bool
+	err := repo.q.QueryRow(ctx, userTableExists).Scan(&hasTable)
+	if err == nil {
+		err = repo.q.QueryRow(ctx, userColumnExists, "email_verified_at").Scan(&hasColumn)
+	}
+	if err != nil {
+		return translateError(err)
+	}
+
+	for _, statement := range []string{migrateUsers, addUserVerified, addUserStatus} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	if hasTable && !hasColumn {
+		_, err = repo.q.Exec(ctx, verifyUsers)
+	}
+	return translateError(err)
+}
+
+func (repo *userRepository) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	created := models.User{
+		Name:            user.Name,
+		Email:           user.Email,
+		Password:        user.Password,
+		Username:        user.Username,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Status:          user.Status,
+		SuspendedUntil:  user.SuspendedUntil,
+		StatusReason:    user.StatusReason,
+	}
+	err := repo.q.QueryRow(ctx, insertUser, created.Name, created.Email, created.Password, created.Username, created.EmailVerifiedAt,
+		created.Status, created.SuspendedUntil, created.StatusReason).Scan(&created.ID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &created, nil
+}
+
+func (repo *userRepository) AllUsers(ctx context.Context) ([]models.User, error) {
+	return repo.list(ctx, selectUsers)
+}
+
+func (repo *userRepository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return repo.get(ctx, selectUserByID, id)
+}
+
+func (repo *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return repo.get(ctx, selectUserByEmail, email)
+}
+
+func (repo *userRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return repo.get(ctx, selectUserByUsername, username)
+}
+
+func (repo *userRepository) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	return repo.get(ctx, selectUserByLogin, username, password)
+}
+
+func (repo *userRepository) UpdateUser(ctx context.Context, id int64, updated models.User) (*models.User, error) {
+	tag, err := repo.q.Exec(ctx, updateUser, id, updated.Name, updated.Email, updated.Password, updated.Username, updated.EmailVerifiedAt,
+		updated.Status, updated.SuspendedUntil, updated.StatusReason)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repository.ErrUpdateFailed
+	}
+	return &updated, nil
+}
+
+func (repo *userRepository) DeleteUser(ctx context.Context, id int64) error {
+	tag, err := repo.q.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *userRepository) get(ctx context.Context, query string, args ...any) (*models.User, error) {
+	user, err := scanUser(repo.q.QueryRow(ctx, query, args...))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &user, nil
+}
+
+func (repo *userRepository) list(ctx context.Context, query string, args ...any) ([]models.User, error) {
+	rows, err := repo.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var result []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		result = append(result, user)
+	}
+	return result, translateError(rows.Err())
+}
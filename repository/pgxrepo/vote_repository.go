@@ -0,0 +1,119 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type voteRepository struct {
+	q querier
+}
+
+const (
+	migrateVotes = `CREATE TABLE IF NOT EXISTS comment_votes (
+	id bigserial PRIMARY KEY,
+	comment_id bigint NOT NULL,
+	user_id bigint NOT NULL,
+	value bigint NOT NULL,
+	created_at timestamptz,
+	updated_at timestamptz
+)`
+	indexVotesCommentUser = `CREATE UNIQUE INDEX IF NOT EXISTS idx_comment_votes_comment_user ON comment_votes (comment_id, user_id)`
+	indexVotesUser        = `CREATE INDEX IF NOT EXISTS idx_comment_votes_user ON comment_votes (user_id)`
+
+	voteColumns = `id, comment_id, user_id, value, created_at, updated_at`
+	insertVote  = `INSERT INTO comment_votes (comment_id, user_id, value, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	updateVote         = `UPDATE comment_votes SET comment_id = $2, user_id = $3, value = $4, created_at = $5, updated_at = $6 WHERE id = $1`
+	selectVote         = `SELECT ` + voteColumns + ` FROM comment_votes WHERE comment_id = $1 AND user_id = $2`
+	selectUserVotes    = `SELECT ` + voteColumns + ` FROM comment_votes WHERE user_id = $1 ORDER BY id`
+	deleteVote         = `DELETE FROM comment_votes WHERE id = $1`
+	deleteCommentVotes = `DELETE FROM comment_votes WHERE comment_id = $1`
+)
+
+func scanVote(row pgx.Row) (models.CommentVote, error) {
+	var vote models.CommentVote
+	err := row.Scan(&vote.ID, &vote.CommentID, &vote.UserID, &vote.Value, &vote.CreatedAt, &vote.UpdatedAt)
+	return vote, err
+}
+
+func (repo *voteRepository) MigrateVote(ctx context.Context) error {
+	for _, statement := range []string{migrateVotes, indexVotesCommentUser, indexVotesUser} {
+		if _, err := repo.q.Exec(ctx, statement); err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (repo *voteRepository) GetVote(ctx context.Context, commentID, userID int64) (*models.CommentVote, error) {
+	vote, err := scanVote(repo.q.QueryRow(ctx, selectVote, commentID, userID))
+	if err != nil {
+		return nil, notExist(err)
+	}
+	return &vote, nil
+}
+
+func (repo *voteRepository) SaveVote(ctx context.Context, vote models.CommentVote) (*models.CommentVote, error) {
+	now := time.Now()
+	if vote.CreatedAt.IsZero() {
+		vote.CreatedAt = now
+	}
+	vote.UpdatedAt = now
+
+	if vote.ID == 0 {
+		err := repo.q.QueryRow(ctx, insertVote, vote.CommentID, vote.UserID, vote.Value, vote.CreatedAt, vote.UpdatedAt).Scan(&vote.ID)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		return &vote, nil
+	}
+
+	tag, err := repo.q.Exec(ctx, updateVote, vote.ID, vote.CommentID, vote.UserID, vote.Value, vote.CreatedAt, vote.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repository.ErrUpdateFailed
+	}
+	return &vote, nil
+}
+
+func (repo *voteRepository) DeleteVote(ctx context.Context, id int64) error {
+	tag, err := repo.q.Exec(ctx, deleteVote, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *voteRepository) GetUserVotes(ctx context.Context, userID int64) ([]models.CommentVote, error) {
+	rows, err := repo.q.Query(ctx, selectUserVotes, userID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var votes []models.CommentVote
+	for rows.Next() {
+		vote, err := scanVote(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		votes = append(votes, vote)
+	}
+	return votes, translateError(rows.Err())
+}
+
+func (repo *voteRepository) DeleteCommentVotes(ctx context.Context, commentID int64) error {
+	_, err := repo.q.Exec(ctx, deleteCommentVotes, commentID)
+	return translateError(err)
+}
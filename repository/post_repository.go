@@ -6,14 +6,13 @@ import (
 
 	"postgresql-blog/models"
 
-	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
 func (repo *PostgreSQLGORMRepository) MigratePost(ctx context.Context) error {
 	err := repo.db.WithContext(ctx).AutoMigrate(&models.GormPost{})
 	if err != nil {
-		return err
+		return TranslateError(err)
 	}
 	return nil
 }
@@ -35,13 +34,7 @@ func (repo *PostgreSQLGORMRepository) CreatePost(ctx context.Context, post model
 	}
 
 	if err := repo.db.WithContext(ctx).Create(&gormPost).Error; err != nil {
-		var pgxError *pgconn.PgError
-		if errors.As(err, &pgxError) {
-			if pgxError.Code == "23505" {
-				return nil, ErrDuplicate
-			}
-		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.Post(gormPost)
@@ -51,7 +44,7 @@ func (repo *PostgreSQLGORMRepository) CreatePost(ctx context.Context, post model
 func (repo *PostgreSQLGORMRepository) AllPosts(ctx context.Context) ([]models.Post, error) {
 	var allPosts []models.GormPost
 	if err := repo.db.WithContext(ctx).Find(&allPosts).Error; err != nil {
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	var result []models.Post
@@ -68,7 +61,7 @@ func (repo *PostgreSQLGORMRepository) GetPostByID(ctx context.Context, id int64)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.Post(gormPost)
@@ -81,7 +74,7 @@ func (repo *PostgreSQLGORMRepository) GetPostByTitle(ctx context.Context, title
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.Post(gormPost)
@@ -94,7 +87,7 @@ func (repo *PostgreSQLGORMRepository) GetPostByUserID(ctx context.Context, useri
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	var result []models.Post
@@ -109,13 +102,7 @@ func (repo *PostgreSQLGORMRepository) UpdatePost(ctx context.Context, id int64,
 	gormPost := models.Post(updated)
 	updateRes := repo.db.WithContext(ctx).Where("id = ?", id).Save(&gormPost)
 	if err := updateRes.Error; err != nil {
-		var pgxError *pgconn.PgError
-		if errors.As(err, &pgxError) {
-			if pgxError.Code == "23505" {
-				return nil, ErrDuplicate
-			}
-		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	rowsAffected := updateRes.RowsAffected
@@ -128,7 +115,7 @@ func (repo *PostgreSQLGORMRepository) UpdatePost(ctx context.Context, id int64,
 func (repo *PostgreSQLGORMRepository) DeletePost(ctx context.Context, id int64) error {
 	res := repo.db.WithContext(ctx).Delete(&models.GormPost{}, id)
 	if err := res.Error; err != nil {
-		return err
+		return TranslateError(err)
 	}
 
 	rowsAffected := res.RowsAffected
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateProfile(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.Profile{}, &models.Avatar{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewProfileRepository(db *gorm.DB) ProfileRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	var profile models.Profile
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &profile, nil
+}
+
+func (repo *PostgreSQLGORMRepository) AllProfiles(ctx context.Context) ([]models.Profile, error) {
+	var profiles []models.Profile
+	if err := repo.db.WithContext(ctx).Order("user_id").Find(&profiles).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return profiles, nil
+}
+
+func (repo *PostgreSQLGORMRepository) SaveProfile(ctx context.Context, profile models.Profile) (*models.Profile, error) {
+	profile.UpdatedAt = time.Now()
+	err := repo.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(&profile).Error
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+	return &profile, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteProfile(ctx context.Context, userID int64) error {
+	db := repo.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&models.Avatar{}).Error; err != nil {
+		return TranslateError(err)
+	}
+	return TranslateError(db.Where("user_id = ?", userID).Delete(&models.Profile{}).Error)
+}
+
+func (repo *PostgreSQLGORMRepository) GetAvatar(ctx context.Context, userID int64) (*models.Avatar, error) {
+	var avatar models.Avatar
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).First(&avatar).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &avatar, nil
+}
+
+func (repo *PostgreSQLGORMRepository) SaveAvatar(ctx context.Context, avatar models.Avatar) error {
+	avatar.UpdatedAt = time.Now()
+	err := repo.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(&avatar).Error
+	return TranslateError(err)
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteAvatar(ctx context.Context, userID int64) error {
+	return TranslateError(repo.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Avatar{}).Error)
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+)
+
+// ProfileRepository stores the profiles and the avatars of the users
+type ProfileRepository interface {
+	MigrateProfile(ctx context.Context) error
+	// GetProfile returns the profile of a user, ErrNotExist when the user
+	// never saved one
+	GetProfile(ctx context.Context, userID int64) (*models.Profile, error)
+	AllProfiles(ctx context.Context) ([]models.Profile, error)
+	// SaveProfile creates or replaces the profile of its user
+	SaveProfile(ctx context.Context, profile models.Profile) (*models.Profile, error)
+	// DeleteProfile deletes the profile and the avatar of a user
+	DeleteProfile(ctx context.Context, userID int64) error
+	// GetAvatar returns the avatar of a user, ErrNotExist when there is none
+	GetAvatar(ctx context.Context, userID int64) (*models.Avatar, error)
+	// SaveAvatar creates or replaces the avatar of its user
+	SaveAvatar(ctx context.Context, avatar models.Avatar) error
+	DeleteAvatar(ctx context.Context, userID int64) error
+}
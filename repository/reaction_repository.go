@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateReaction(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.Reaction{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewReactionRepository(db *gorm.DB) ReactionRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) AddReaction(ctx context.Context, reaction models.Reaction) (*models.Reaction, error) {
+	if err := repo.db.WithContext(ctx).Create(&reaction).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return &reaction, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetReaction(ctx context.Context, userID int64, targetType string, targetID int64, kind string) (*models.Reaction, error) {
+	var reaction models.Reaction
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ? AND target_type = ? AND target_id = ? AND kind = ?", userID, targetType, targetID, kind).
+		First(&reaction).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &reaction, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteReaction(ctx context.Context, id int64) error {
+	res := repo.db.WithContext(ctx).Delete(&models.Reaction{}, id)
+	if err := res.Error; err != nil {
+		return TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *PostgreSQLGORMRepository) CountReactions(ctx context.Context, targetType string, targetIDs []int64) (map[int64]map[string]int, error) {
+	counts := map[int64]map[string]int{}
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		TargetID int64
+		Kind     string
+		Count    int
+	}
+	err := repo.db.WithContext(ctx).Model(&models.Reaction{}).
+		Select("target_id, kind, COUNT(*) AS count").
+		Where("target_type = ? AND target_id IN ?", targetType, targetIDs).
+		Group("target_id, kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+	for _, row := range rows {
+		if counts[row.TargetID] == nil {
+			counts[row.TargetID] = map[string]int{}
+		}
+		counts[row.TargetID][row.Kind] = row.Count
+	}
+	return counts, nil
+}
+
+func (repo *PostgreSQLGORMRepository) FindReactions(ctx context.Context, filter ReactionFilter) ([]Reactor, error) {
+	query := repo.db.WithContext(ctx).Table("reactions").
+		Select("reactions.*, gorm_users.username").
+		Joins("JOIN gorm_users ON gorm_users.id = reactions.user_id").
+		Where("reactions.target_type = ? AND reactions.target_id = ? AND reactions.id > ?", filter.TargetType, filter.TargetID, filter.After)
+	if filter.Kind != "" {
+		query = query.Where("reactions.kind = ?", filter.Kind)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var reactors []Reactor
+	if err := query.Order("reactions.id").Scan(&reactors).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return reactors, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetUserReactions(ctx context.Context, userID int64) ([]models.Reaction, error) {
+	var reactions []models.Reaction
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&reactions).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return reactions, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteUserReactions(ctx context.Context, userID int64) error {
+	return TranslateError(repo.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Reaction{}).Error)
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteTargetReactions(ctx context.Context, targetType string, targetID int64) error {
+	err := repo.db.WithContext(ctx).Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&models.Reaction{}).Error
+	return TranslateError(err)
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+)
+
+// ReactionFilter selects the reactions to a target a page at a time, the
+// oldest first
+type ReactionFilter struct {
+	TargetType string
+	TargetID   int64
+	// Kind is the reaction to list, empty for all of them
+	Kind string
+	// After is the ID of the last reaction of the page before, 0 for the
+	// first page
+	After int64
+	Limit int
+}
+
+// Reactor is a reaction with the username of the user who reacted
+type Reactor struct {
+	models.Reaction
+	Username string
+}
+
+// ReactionRepository stores the reactions to posts and comments
+type ReactionRepository interface {
+	MigrateReaction(ctx context.Context) error
+	AddReaction(ctx context.Context, reaction models.Reaction) (*models.Reaction, error)
+	// GetReaction returns the reaction of a kind of a user to a target
+	GetReaction(ctx context.Context, userID int64, targetType string, targetID int64, kind string) (*models.Reaction, error)
+	DeleteReaction(ctx context.Context, id int64) error
+	// CountReactions counts the reactions to many targets of a type at
+	// once, by target and kind. Targets without reactions are left out.
+	CountReactions(ctx context.Context, targetType string, targetIDs []int64) (map[int64]map[string]int, error)
+	FindReactions(ctx context.Context, filter ReactionFilter) ([]Reactor, error)
+	GetUserReactions(ctx context.Context, userID int64) ([]models.Reaction, error)
+	DeleteUserReactions(ctx context.Context, userID int64) error
+	DeleteTargetReactions(ctx context.Context, targetType string, targetID int64) error
+}
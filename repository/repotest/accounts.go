@@ -0,0 +1,218 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func testTokens(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	tokens := store.Repositories().Tokens
+	now := time.Now()
+
+	created, err := tokens.CreateToken(ctx, models.UserToken{UserID: 1, Purpose: models.TokenVerifyEmail, Hash: "a", Email: "alice@example.com", ExpiresAt: now.Add(time.Hour)})
+	noErr(t, err)
+	if created.ID == 0 || created.CreatedAt.IsZero() {
+		t.Fatalf("CreateToken returned %+v, want an id and a creation time", created)
+	}
+	_, err = tokens.CreateToken(ctx, models.UserToken{UserID: 2, Purpose: models.TokenVerifyEmail, Hash: "a", ExpiresAt: now.Add(time.Hour)})
+	expectErr(t, err, repository.ErrDuplicate)
+
+	// a token works once, for its purpose, until it expires
+	_, err = tokens.UseToken(ctx, models.TokenResetPassword, "a", now)
+	expectErr(t, err, repository.ErrNotExist)
+	used, err := tokens.UseToken(ctx, models.TokenVerifyEmail, "a", now)
+	noErr(t, err)
+	if used.ID != created.ID || used.UserID != 1 || used.Email != "alice@example.com" || used.UsedAt == nil {
+		t.Fatalf("UseToken returned %+v", used)
+	}
+	_, err = tokens.UseToken(ctx, models.TokenVerifyEmail, "a", now)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = tokens.CreateToken(ctx, models.UserToken{UserID: 1, Purpose: models.TokenResetPassword, Hash: "b", ExpiresAt: now.Add(time.Hour)})
+	noErr(t, err)
+	_, err = tokens.UseToken(ctx, models.TokenResetPassword, "b", now.Add(time.Hour))
+	expectErr(t, err, repository.ErrNotExist)
+
+	_, err = tokens.CreateToken(ctx, models.UserToken{UserID: 1, Purpose: models.TokenVerifyEmail, Hash: "c", ExpiresAt: now.Add(time.Hour)})
+	noErr(t, err)
+	_, err = tokens.CreateToken(ctx, models.UserToken{UserID: 1, Purpose: models.TokenResetPassword, Hash: "d", ExpiresAt: now.Add(time.Hour)})
+	noErr(t, err)
+	noErr(t, tokens.DeleteUserTokens(ctx, 1, models.TokenResetPassword))
+	_, err = tokens.UseToken(ctx, models.TokenResetPassword, "d", now)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = tokens.CreateToken(ctx, models.UserToken{UserID: 1, Purpose: models.TokenResetPassword, Hash: "e", ExpiresAt: now.Add(time.Hour)})
+	noErr(t, err)
+	noErr(t, tokens.DeleteUserTokens(ctx, 1, ""))
+	for _, hash := range []string{"c", "e"} {
+		_, err = tokens.UseToken(ctx, models.TokenVerifyEmail, hash, now)
+		expectErr(t, err, repository.ErrNotExist)
+		_, err = tokens.UseToken(ctx, models.TokenResetPassword, hash, now)
+		expectErr(t, err, repository.ErrNotExist)
+	}
+}
+
+func testTwoFactors(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	twoFactors := store.Repositories().TwoFactors
+	now := time.Now()
+
+	_, err := twoFactors.GetTwoFactor(ctx, 1)
+	expectErr(t, err, repository.ErrNotExist)
+	noErr(t, twoFactors.SaveTwoFactor(ctx, models.TwoFactor{UserID: 1, Secret: "first"}))
+	noErr(t, twoFactors.SaveTwoFactor(ctx, models.TwoFactor{UserID: 1, Secret: "second", EnabledAt: &now, LastStep: 42}))
+	got, err := twoFactors.GetTwoFactor(ctx, 1)
+	noErr(t, err)
+	if got.Secret != "second" || got.EnabledAt == nil || got.LastStep != 42 {
+		t.Fatalf("GetTwoFactor returned %+v, want the second save", got)
+	}
+
+	// recovery codes work once and are replaced as a whole
+	noErr(t, twoFactors.ReplaceRecoveryCodes(ctx, 1, []string{"a", "b"}))
+	noErr(t, twoFactors.UseRecoveryCode(ctx, 1, "a", now))
+	expectErr(t, twoFactors.UseRecoveryCode(ctx, 1, "a", now), repository.ErrNotExist)
+	expectErr(t, twoFactors.UseRecoveryCode(ctx, 2, "b", now), repository.ErrNotExist)
+	noErr(t, twoFactors.ReplaceRecoveryCodes(ctx, 1, []string{"c"}))
+	expectErr(t, twoFactors.UseRecoveryCode(ctx, 1, "b", now), repository.ErrNotExist)
+
+	noErr(t, twoFactors.DeleteTwoFactor(ctx, 1))
+	_, err = twoFactors.GetTwoFactor(ctx, 1)
+	expectErr(t, err, repository.ErrNotExist)
+	expectErr(t, twoFactors.UseRecoveryCode(ctx, 1, "c", now), repository.ErrNotExist)
+}
+
+func testIdentities(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	identities := store.Repositories().Identities
+
+	created, err := identities.CreateIdentity(ctx, models.Identity{UserID: 1, Provider: "https://id.example.com", Subject: "s1"})
+	noErr(t, err)
+	// an account at a provider is linked to one user
+	_, err = identities.CreateIdentity(ctx, models.Identity{UserID: 2, Provider: "https://id.example.com", Subject: "s1"})
+	expectErr(t, err, repository.ErrDuplicate)
+	_, err = identities.CreateIdentity(ctx, models.Identity{UserID: 1, Provider: "https://other.example.com", Subject: "s1"})
+	noErr(t, err)
+
+	got, err := identities.GetIdentity(ctx, "https://id.example.com", "s1")
+	noErr(t, err)
+	if got.ID != created.ID || got.UserID != 1 {
+		t.Fatalf("GetIdentity returned %+v", got)
+	}
+	_, err = identities.GetIdentity(ctx, "https://id.example.com", "s2")
+	expectErr(t, err, repository.ErrNotExist)
+
+	now := time.Now()
+	noErr(t, identities.TouchIdentity(ctx, created.ID, "alice@example.com", now))
+	got, err = identities.GetIdentity(ctx, "https://id.example.com", "s1")
+	noErr(t, err)
+	if got.Email != "alice@example.com" || got.LastUsedAt == nil {
+		t.Fatalf("TouchIdentity stored %+v", got)
+	}
+	expectErr(t, identities.TouchIdentity(ctx, created.ID+1000, "", now), repository.ErrUpdateFailed)
+
+	noErr(t, identities.DeleteUserIdentities(ctx, 1, "https://other.example.com"))
+	linked, err := identities.GetUserIdentities(ctx, 1)
+	noErr(t, err)
+	if len(linked) != 1 || linked[0].ID != created.ID {
+		t.Fatalf("GetUserIdentities returned %+v, want the identity at the first provider", linked)
+	}
+	noErr(t, identities.DeleteUserIdentities(ctx, 1, ""))
+	linked, err = identities.GetUserIdentities(ctx, 1)
+	noErr(t, err)
+	if len(linked) != 0 {
+		t.Fatalf("GetUserIdentities returned %+v after deleting all of them", linked)
+	}
+}
+
+func testProfiles(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	profiles := store.Repositories().Profiles
+
+	_, err := profiles.GetProfile(ctx, 1)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = profiles.SaveProfile(ctx, models.Profile{UserID: 1, DisplayName: "Alice"})
+	noErr(t, err)
+	_, err = profiles.SaveProfile(ctx, models.Profile{UserID: 1, DisplayName: "Alice Doe", Links: []models.Link{{Label: "home", URL: "https://example.com"}}, EmailPublic: true})
+	noErr(t, err)
+	got, err := profiles.GetProfile(ctx, 1)
+	noErr(t, err)
+	if got.DisplayName != "Alice Doe" || len(got.Links) != 1 || got.Links[0].URL != "https://example.com" || !got.EmailPublic {
+		t.Fatalf("GetProfile returned %+v, want the second save", got)
+	}
+	_, err = profiles.SaveProfile(ctx, models.Profile{UserID: 2, DisplayName: "Bob"})
+	noErr(t, err)
+	all, err := profiles.AllProfiles(ctx)
+	noErr(t, err)
+	if len(all) != 2 {
+		t.Fatalf("AllProfiles returned %d profiles, want 2", len(all))
+	}
+
+	_, err = profiles.GetAvatar(ctx, 1)
+	expectErr(t, err, repository.ErrNotExist)
+	noErr(t, profiles.SaveAvatar(ctx, models.Avatar{UserID: 1, ContentType: "image/png", Data: []byte{1}}))
+	noErr(t, profiles.SaveAvatar(ctx, models.Avatar{UserID: 1, ContentType: "image/gif", Data: []byte{2, 3}}))
+	avatar, err := profiles.GetAvatar(ctx, 1)
+	noErr(t, err)
+	if avatar.ContentType != "image/gif" || len(avatar.Data) != 2 {
+		t.Fatalf("GetAvatar returned %+v, want the second save", avatar)
+	}
+	noErr(t, profiles.SaveAvatar(ctx, models.Avatar{UserID: 2, ContentType: "image/png", Data: []byte{1}}))
+	noErr(t, profiles.DeleteAvatar(ctx, 2))
+	_, err = profiles.GetAvatar(ctx, 2)
+	expectErr(t, err, repository.ErrNotExist)
+
+	// the avatar goes with the profile
+	noErr(t, profiles.DeleteProfile(ctx, 1))
+	_, err = profiles.GetProfile(ctx, 1)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = profiles.GetAvatar(ctx, 1)
+	expectErr(t, err, repository.ErrNotExist)
+}
+
+func testAudit(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	audit := store.Repositories().Audit
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	alice, bob := int64(1), int64(2)
+
+	events := []models.AuditEvent{
+		{ActorID: &alice, Action: "create", EntityType: "post", EntityID: 10, Changes: "{}", CreatedAt: start},
+		{ActorID: &alice, Action: "update", EntityType: "post", EntityID: 10, Changes: "{}", CreatedAt: start.Add(time.Minute)},
+		{ActorID: &bob, Action: "create", EntityType: "comment", EntityID: 20, Changes: "{}", CreatedAt: start.Add(2 * time.Minute)},
+		{Action: "create", EntityType: "user", EntityID: 3, Changes: "{}", CreatedAt: start.Add(3 * time.Minute)},
+	}
+	for _, event := range events {
+		appended, err := audit.AppendEvent(ctx, event)
+		noErr(t, err)
+		if appended.ID == 0 {
+			t.Fatal("appended event has no id")
+		}
+	}
+
+	all, err := audit.FindEvents(ctx, repository.AuditFilter{})
+	noErr(t, err)
+	if len(all) != 4 || all[0].EntityType != "user" || all[3].Action != "create" || all[3].EntityType != "post" {
+		t.Fatalf("FindEvents returned %+v, want all of them the newest first", all)
+	}
+	if all[0].ActorID != nil {
+		t.Fatalf("an event without an actor got actor %d", *all[0].ActorID)
+	}
+	byAlice, err := audit.FindEvents(ctx, repository.AuditFilter{ActorID: &alice})
+	noErr(t, err)
+	if len(byAlice) != 2 {
+		t.Fatalf("FindEvents by actor returned %d events, want 2", len(byAlice))
+	}
+	post, err := audit.FindEvents(ctx, repository.AuditFilter{EntityType: "post", EntityID: 10, Limit: 1})
+	noErr(t, err)
+	if len(post) != 1 || post[0].Action != "update" {
+		t.Fatalf("FindEvents of the post returned %+v, want the update only", post)
+	}
+	window, err := audit.FindEvents(ctx, repository.AuditFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)})
+	noErr(t, err)
+	if len(window) != 2 || window[0].EntityType != "comment" || window[1].Action != "update" {
+		t.Fatalf("FindEvents between the times returned %+v, want the update and the comment", window)
+	}
+}
@@ -0,0 +1,58 @@
+package repotest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"postgresql-blog/database"
+	"postgresql-blog/repository"
+)
+
+// EnvDSN names the PostgreSQL database the tests of the postgres backends
+// run on, they are skipped when it is unset. Its tables are emptied, never
+// point it at real data.
+const EnvDSN = "BLOG_TEST_DSN"
+
+// Postgres returns a NewStore opening driver on the database in EnvDSN,
+// migrated and with the tables of the suite emptied
+func Postgres(driver string) NewStore {
+	return func(t testing.TB) repository.Store {
+		t.Helper()
+		dsn := os.Getenv(EnvDSN)
+		if dsn == "" {
+			t.Skipf("%s is not set", EnvDSN)
+		}
+		store := open(t, database.Config{Driver: driver, DSN: dsn, AutoMigrate: true})
+
+		db, err := database.OpenDatabase(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer database.Close(db)
+		if err := db.Exec("TRUNCATE gorm_users, gorm_posts, gorm_comments RESTART IDENTITY").Error; err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+}
+
+// SQLite returns a NewStore opening a migrated sqlite database in a
+// temporary directory
+func SQLite() NewStore {
+	return func(t testing.TB) repository.Store {
+		t.Helper()
+		dsn := filepath.Join(t.TempDir(), "blog.db")
+		return open(t, database.Config{Driver: database.DriverSQLite, DSN: dsn, AutoMigrate: true})
+	}
+}
+
+func open(t testing.TB, cfg database.Config) repository.Store {
+	t.Helper()
+	backend, err := database.OpenBackend(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(backend.Close)
+	return backend.Store
+}
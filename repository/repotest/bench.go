@@ -0,0 +1,123 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// seedPosts is how many posts the read benchmarks start with
+const seedPosts = 100
+
+// bulkSize is how many posts one bulk insert writes in a single transaction
+const bulkSize = 100
+
+// Benchmark is one workload run against a freshly created store
+type Benchmark struct {
+	Name string
+	F    func(b *testing.B, store repository.Store)
+}
+
+// Benchmarks are the workloads Bench runs, they are exported so programs
+// can run them with testing.Benchmark outside of go test
+var Benchmarks = []Benchmark{
+	{"List", benchList},
+	{"GetByID", benchGetByID},
+	{"BulkInsert", benchBulkInsert},
+}
+
+// Bench runs every benchmark against the stores returned by newStore:
+//
+//	func BenchmarkPgx(b *testing.B) {
+//		repotest.Bench(b, repotest.Postgres(database.DriverPgx))
+//	}
+func Bench(b *testing.B, newStore NewStore) {
+	for _, bm := range Benchmarks {
+		bm := bm
+		b.Run(bm.Name, func(b *testing.B) { bm.F(b, newStore(b)) })
+	}
+}
+
+func seed(b *testing.B, store repository.Store) []int64 {
+	b.Helper()
+	ctx := context.Background()
+	ids := make([]int64, 0, seedPosts)
+	err := store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		for i := 0; i < seedPosts; i++ {
+			post, err := repos.Posts.CreatePost(ctx, models.Post{
+				UserID:  uint64(i%10 + 1),
+				Title:   fmt.Sprintf("Seed %d", i),
+				Content: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+			})
+			if err != nil {
+				return err
+			}
+			ids = append(ids, post.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return ids
+}
+
+func benchList(b *testing.B, store repository.Store) {
+	ctx := context.Background()
+	seed(b, store)
+	posts := store.Repositories().Posts
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		all, err := posts.AllPosts(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(all) != seedPosts {
+			b.Fatalf("AllPosts returned %d posts, want %d", len(all), seedPosts)
+		}
+	}
+}
+
+func benchGetByID(b *testing.B, store repository.Store) {
+	ctx := context.Background()
+	ids := seed(b, store)
+	posts := store.Repositories().Posts
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := posts.GetPostByID(ctx, ids[i%len(ids)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchBulkInsert(b *testing.B, store repository.Store) {
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			for j := 0; j < bulkSize; j++ {
+				_, err := repos.Posts.CreatePost(ctx, models.Post{
+					UserID:  1,
+					Title:   fmt.Sprintf("Bulk %d-%d", i, j),
+					Content: "Lorem ipsum dolor sit amet, consectetur adipiscing elit.",
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
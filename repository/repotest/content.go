@@ -0,0 +1,246 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+func testReactions(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	repos := store.Repositories()
+	alice, err := repos.Users.CreateUser(ctx, models.User{Name: "Alice", Email: "alice@example.com", Password: "secret", Username: "alice"})
+	noErr(t, err)
+	bob, err := repos.Users.CreateUser(ctx, models.User{Name: "Bob", Email: "bob@example.com", Password: "secret", Username: "bob"})
+	noErr(t, err)
+	reactions := repos.Reactions
+
+	like, err := reactions.AddReaction(ctx, models.Reaction{UserID: alice.ID, TargetType: models.TargetPost, TargetID: 10, Kind: models.ReactionLike})
+	noErr(t, err)
+	// a user gives every kind once to a target
+	_, err = reactions.AddReaction(ctx, models.Reaction{UserID: alice.ID, TargetType: models.TargetPost, TargetID: 10, Kind: models.ReactionLike})
+	expectErr(t, err, repository.ErrDuplicate)
+	_, err = reactions.AddReaction(ctx, models.Reaction{UserID: alice.ID, TargetType: models.TargetPost, TargetID: 10, Kind: "🎉"})
+	noErr(t, err)
+	_, err = reactions.AddReaction(ctx, models.Reaction{UserID: alice.ID, TargetType: models.TargetComment, TargetID: 10, Kind: models.ReactionLike})
+	noErr(t, err)
+	_, err = reactions.AddReaction(ctx, models.Reaction{UserID: bob.ID, TargetType: models.TargetPost, TargetID: 10, Kind: models.ReactionLike})
+	noErr(t, err)
+
+	got, err := reactions.GetReaction(ctx, alice.ID, models.TargetPost, 10, models.ReactionLike)
+	noErr(t, err)
+	if got.ID != like.ID {
+		t.Fatalf("GetReaction returned %+v, want %+v", got, like)
+	}
+	_, err = reactions.GetReaction(ctx, bob.ID, models.TargetPost, 10, "🎉")
+	expectErr(t, err, repository.ErrNotExist)
+
+	counts, err := reactions.CountReactions(ctx, models.TargetPost, []int64{10, 11})
+	noErr(t, err)
+	if len(counts) != 1 || counts[10][models.ReactionLike] != 2 || counts[10]["🎉"] != 1 {
+		t.Fatalf("CountReactions returned %v, want 2 likes and 1 🎉 on post 10", counts)
+	}
+
+	// pages follow each other by the id of the last reaction
+	filter := repository.ReactionFilter{TargetType: models.TargetPost, TargetID: 10, Limit: 2}
+	first, err := reactions.FindReactions(ctx, filter)
+	noErr(t, err)
+	if len(first) != 2 || first[0].ID != like.ID || first[0].Username != "alice" || first[1].ID <= first[0].ID {
+		t.Fatalf("FindReactions returned %+v as the first page", first)
+	}
+	filter.After = first[1].ID
+	second, err := reactions.FindReactions(ctx, filter)
+	noErr(t, err)
+	if len(second) != 1 || second[0].Username != "bob" {
+		t.Fatalf("FindReactions returned %+v as the second page, want the like of bob", second)
+	}
+	filter.After = second[0].ID
+	last, err := reactions.FindReactions(ctx, filter)
+	noErr(t, err)
+	if len(last) != 0 {
+		t.Fatalf("FindReactions returned %+v after the last page", last)
+	}
+	likes, err := reactions.FindReactions(ctx, repository.ReactionFilter{TargetType: models.TargetPost, TargetID: 10, Kind: models.ReactionLike})
+	noErr(t, err)
+	if len(likes) != 2 {
+		t.Fatalf("FindReactions returned %d likes, want 2", len(likes))
+	}
+
+	mine, err := reactions.GetUserReactions(ctx, alice.ID)
+	noErr(t, err)
+	if len(mine) != 3 {
+		t.Fatalf("GetUserReactions returned %d reactions, want 3", len(mine))
+	}
+
+	noErr(t, reactions.DeleteReaction(ctx, like.ID))
+	expectErr(t, reactions.DeleteReaction(ctx, like.ID), repository.ErrDeleteFailed)
+	// the kind can be given again once it was taken back
+	_, err = reactions.AddReaction(ctx, models.Reaction{UserID: alice.ID, TargetType: models.TargetPost, TargetID: 10, Kind: models.ReactionLike})
+	noErr(t, err)
+
+	noErr(t, reactions.DeleteTargetReactions(ctx, models.TargetPost, 10))
+	counts, err = reactions.CountReactions(ctx, models.TargetPost, []int64{10})
+	noErr(t, err)
+	if len(counts) != 0 {
+		t.Fatalf("CountReactions returned %v after DeleteTargetReactions", counts)
+	}
+	noErr(t, reactions.DeleteUserReactions(ctx, alice.ID))
+	mine, err = reactions.GetUserReactions(ctx, alice.ID)
+	noErr(t, err)
+	if len(mine) != 0 {
+		t.Fatalf("GetUserReactions returned %+v after DeleteUserReactions", mine)
+	}
+}
+
+func testVotes(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	votes := store.Repositories().Votes
+
+	up, err := votes.SaveVote(ctx, models.CommentVote{CommentID: 10, UserID: 1, Value: models.VoteUp})
+	noErr(t, err)
+	if up.ID == 0 || up.CreatedAt.IsZero() {
+		t.Fatalf("SaveVote returned %+v, want an id and a creation time", up)
+	}
+	// a user votes once on a comment, changing the vote updates it
+	_, err = votes.SaveVote(ctx, models.CommentVote{CommentID: 10, UserID: 1, Value: models.VoteDown})
+	expectErr(t, err, repository.ErrDuplicate)
+	changed := *up
+	changed.Value = models.VoteDown
+	_, err = votes.SaveVote(ctx, changed)
+	noErr(t, err)
+	got, err := votes.GetVote(ctx, 10, 1)
+	noErr(t, err)
+	if got.ID != up.ID || got.Value != models.VoteDown {
+		t.Fatalf("GetVote returned %+v after the change, want the down vote %d", got, up.ID)
+	}
+	_, err = votes.SaveVote(ctx, models.CommentVote{ID: up.ID + 1000, CommentID: 11, UserID: 1, Value: models.VoteUp})
+	expectErr(t, err, repository.ErrUpdateFailed)
+
+	_, err = votes.SaveVote(ctx, models.CommentVote{CommentID: 11, UserID: 1, Value: models.VoteUp})
+	noErr(t, err)
+	_, err = votes.SaveVote(ctx, models.CommentVote{CommentID: 10, UserID: 2, Value: models.VoteUp})
+	noErr(t, err)
+	mine, err := votes.GetUserVotes(ctx, 1)
+	noErr(t, err)
+	if len(mine) != 2 {
+		t.Fatalf("GetUserVotes returned %d votes, want 2", len(mine))
+	}
+
+	// a retracted vote is gone, voting again starts a new one
+	noErr(t, votes.DeleteVote(ctx, up.ID))
+	expectErr(t, votes.DeleteVote(ctx, up.ID), repository.ErrDeleteFailed)
+	_, err = votes.GetVote(ctx, 10, 1)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = votes.SaveVote(ctx, models.CommentVote{CommentID: 10, UserID: 1, Value: models.VoteUp})
+	noErr(t, err)
+
+	noErr(t, votes.DeleteCommentVotes(ctx, 10))
+	_, err = votes.GetVote(ctx, 10, 2)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = votes.GetVote(ctx, 11, 1)
+	noErr(t, err)
+}
+
+func testRevisions(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	revisions := store.Repositories().Revisions
+
+	for _, title := range []string{"one", "two", "three"} {
+		_, err := revisions.CreateRevision(ctx, models.PostRevision{PostID: 10, Title: title})
+		noErr(t, err)
+	}
+	other, err := revisions.CreateRevision(ctx, models.PostRevision{PostID: 11, Title: "other"})
+	noErr(t, err)
+	if other.Number != 1 {
+		t.Fatalf("the first revision of a post got number %d", other.Number)
+	}
+
+	all, err := revisions.GetRevisions(ctx, 10)
+	noErr(t, err)
+	if len(all) != 3 || all[0].Number != 1 || all[2].Number != 3 || all[2].Title != "three" {
+		t.Fatalf("GetRevisions returned %+v, want the numbers 1 to 3", all)
+	}
+	got, err := revisions.GetRevision(ctx, 10, 2)
+	noErr(t, err)
+	if got.Title != "two" {
+		t.Fatalf("GetRevision returned %+v", got)
+	}
+	_, err = revisions.GetRevision(ctx, 10, 4)
+	expectErr(t, err, repository.ErrNotExist)
+
+	// pruning keeps the newest, the numbers go on after the last one
+	noErr(t, revisions.PruneRevisions(ctx, 10, 2))
+	all, err = revisions.GetRevisions(ctx, 10)
+	noErr(t, err)
+	if len(all) != 2 || all[0].Number != 2 {
+		t.Fatalf("GetRevisions returned %+v after pruning, want 2 and 3", all)
+	}
+	next, err := revisions.CreateRevision(ctx, models.PostRevision{PostID: 10, Title: "four"})
+	noErr(t, err)
+	if next.Number != 4 {
+		t.Fatalf("the revision after pruning got number %d, want 4", next.Number)
+	}
+
+	noErr(t, revisions.DeleteRevisions(ctx, 10))
+	all, err = revisions.GetRevisions(ctx, 10)
+	noErr(t, err)
+	if len(all) != 0 {
+		t.Fatalf("GetRevisions returned %+v after DeleteRevisions", all)
+	}
+	all, err = revisions.GetRevisions(ctx, 11)
+	noErr(t, err)
+	if len(all) != 1 {
+		t.Fatalf("DeleteRevisions removed the revisions of another post, %d are left", len(all))
+	}
+}
+
+func testDrafts(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	drafts := store.Repositories().Drafts
+
+	saved, err := drafts.SaveDraft(ctx, models.Draft{UserID: 1, PostID: 10, Title: "first"})
+	noErr(t, err)
+	if saved.ID == 0 {
+		t.Fatal("saved draft has no id")
+	}
+	// a user has one draft of a post, saving it again replaces it
+	saved.Title = "second"
+	_, err = drafts.SaveDraft(ctx, *saved)
+	noErr(t, err)
+	got, err := drafts.GetDraft(ctx, 1, 10)
+	noErr(t, err)
+	if got.ID != saved.ID || got.Title != "second" {
+		t.Fatalf("GetDraft returned %+v, want the second save of %d", got, saved.ID)
+	}
+	_, err = drafts.SaveDraft(ctx, models.Draft{UserID: 1, PostID: 10, Title: "another"})
+	expectErr(t, err, repository.ErrDuplicate)
+	_, err = drafts.SaveDraft(ctx, models.Draft{ID: saved.ID + 1000, UserID: 1, PostID: 11, Title: "gone"})
+	expectErr(t, err, repository.ErrUpdateFailed)
+
+	_, err = drafts.SaveDraft(ctx, models.Draft{UserID: 1, Title: "new post"})
+	noErr(t, err)
+	_, err = drafts.SaveDraft(ctx, models.Draft{UserID: 2, PostID: 10, Title: "of bob"})
+	noErr(t, err)
+	mine, err := drafts.GetUserDrafts(ctx, 1)
+	noErr(t, err)
+	if len(mine) != 2 {
+		t.Fatalf("GetUserDrafts returned %d drafts, want 2", len(mine))
+	}
+	_, err = drafts.GetDraft(ctx, 2, 0)
+	expectErr(t, err, repository.ErrNotExist)
+
+	noErr(t, drafts.DeleteDraft(ctx, 1, 0))
+	_, err = drafts.GetDraft(ctx, 1, 0)
+	expectErr(t, err, repository.ErrNotExist)
+	noErr(t, drafts.DeletePostDrafts(ctx, 10))
+	_, err = drafts.GetDraft(ctx, 2, 10)
+	expectErr(t, err, repository.ErrNotExist)
+	noErr(t, drafts.DeleteUserDrafts(ctx, 1))
+	mine, err = drafts.GetUserDrafts(ctx, 1)
+	noErr(t, err)
+	if len(mine) != 0 {
+		t.Fatalf("GetUserDrafts returned %+v after DeleteUserDrafts", mine)
+	}
+}
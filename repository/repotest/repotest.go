@@ -0,0 +1,301 @@
+// Package repotest is the conformance suite for the repository
+// implementations. Every backend must pass it, so the services behave the
+// same whichever one they run on:
+//
+//	func TestMemory(t *testing.T) {
+//		repotest.Run(t, func(t testing.TB) repository.Store { return memory.New() })
+//	}
+//
+// Bench compares the backends on the same workloads.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// NewStore returns an empty store for one test or benchmark, backends with a
+// database migrate it and clean it up with t.Cleanup.
+type NewStore func(t testing.TB) repository.Store
+
+var errRollback = errors.New("rollback")
+
+// Run runs the whole suite against the stores returned by newStore.
+func Run(t *testing.T, newStore NewStore) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newStore(t)) })
+	t.Run("Posts", func(t *testing.T) { testPosts(t, newStore(t)) })
+	t.Run("Comments", func(t *testing.T) { testComments(t, newStore(t)) })
+	t.Run("UnitOfWork", func(t *testing.T) { testUnitOfWork(t, newStore(t)) })
+	t.Run("Tokens", func(t *testing.T) { testTokens(t, newStore(t)) })
+	t.Run("TwoFactors", func(t *testing.T) { testTwoFactors(t, newStore(t)) })
+	t.Run("Identities", func(t *testing.T) { testIdentities(t, newStore(t)) })
+	t.Run("Profiles", func(t *testing.T) { testProfiles(t, newStore(t)) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, newStore(t)) })
+	t.Run("Revisions", func(t *testing.T) { testRevisions(t, newStore(t)) })
+	t.Run("Drafts", func(t *testing.T) { testDrafts(t, newStore(t)) })
+	t.Run("Reactions", func(t *testing.T) { testReactions(t, newStore(t)) })
+	t.Run("Votes", func(t *testing.T) { testVotes(t, newStore(t)) })
+}
+
+func expectErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected %v, got %v", target, err)
+	}
+}
+
+func noErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func testUsers(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	users := store.Repositories().Users
+
+	alice, err := users.CreateUser(ctx, models.User{Name: "Alice", Email: "alice@example.com", Password: "secret", Username: "alice"})
+	noErr(t, err)
+	if alice.ID == 0 {
+		t.Fatal("created user has no id")
+	}
+	bob, err := users.CreateUser(ctx, models.User{Name: "Bob", Email: "bob@example.com", Password: "secret", Username: "bob"})
+	noErr(t, err)
+	if bob.ID == alice.ID {
+		t.Fatal("users got the same id")
+	}
+
+	_, err = users.CreateUser(ctx, models.User{Name: "Other", Email: "alice@example.com", Password: "x", Username: "other"})
+	expectErr(t, err, repository.ErrDuplicate)
+	_, err = users.CreateUser(ctx, models.User{Name: "Other", Email: "other@example.com", Password: "x", Username: "alice"})
+	expectErr(t, err, repository.ErrDuplicate)
+
+	got, err := users.GetUserByID(ctx, alice.ID)
+	noErr(t, err)
+	if got.Email != "alice@example.com" {
+		t.Fatalf("GetUserByID returned %+v", got)
+	}
+	got, err = users.GetUserByEmail(ctx, "bob@example.com")
+	noErr(t, err)
+	if got.ID != bob.ID {
+		t.Fatalf("GetUserByEmail returned %+v", got)
+	}
+	got, err = users.GetUserByUsernameAndPassword(ctx, "alice", "secret")
+	noErr(t, err)
+	if got.ID != alice.ID {
+		t.Fatalf("GetUserByUsernameAndPassword returned %+v", got)
+	}
+	_, err = users.GetUserByUsernameAndPassword(ctx, "alice", "wrong")
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = users.GetUserByID(ctx, bob.ID+1000)
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = users.GetUserByEmail(ctx, "nobody@example.com")
+	expectErr(t, err, repository.ErrNotExist)
+
+	all, err := users.AllUsers(ctx)
+	noErr(t, err)
+	if len(all) != 2 {
+		t.Fatalf("AllUsers returned %d users, want 2", len(all))
+	}
+
+	updated := *alice
+	updated.Name = "Alice Doe"
+	_, err = users.UpdateUser(ctx, alice.ID, updated)
+	noErr(t, err)
+	got, err = users.GetUserByID(ctx, alice.ID)
+	noErr(t, err)
+	if got.Name != "Alice Doe" {
+		t.Fatalf("UpdateUser did not store the name, got %+v", got)
+	}
+	updated.Email = "bob@example.com"
+	_, err = users.UpdateUser(ctx, alice.ID, updated)
+	expectErr(t, err, repository.ErrDuplicate)
+
+	if err := users.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = users.GetUserByID(ctx, bob.ID)
+	expectErr(t, err, repository.ErrNotExist)
+	expectErr(t, users.DeleteUser(ctx, bob.ID), repository.ErrDeleteFailed)
+}
+
+func testPosts(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	posts := store.Repositories().Posts
+
+	first, err := posts.CreatePost(ctx, models.Post{UserID: 1, Title: "First", Content: "one"})
+	noErr(t, err)
+	second, err := posts.CreatePost(ctx, models.Post{UserID: 2, Title: "Second", Content: "two"})
+	noErr(t, err)
+	if first.ID == 0 || first.ID == second.ID {
+		t.Fatalf("bad post ids %d and %d", first.ID, second.ID)
+	}
+	if first.CreatedAt.IsZero() {
+		t.Fatal("CreatedAt was not set")
+	}
+
+	got, err := posts.GetPostByID(ctx, second.ID)
+	noErr(t, err)
+	if got.Title != "Second" {
+		t.Fatalf("GetPostByID returned %+v", got)
+	}
+	got, err = posts.GetPostByTitle(ctx, "First")
+	noErr(t, err)
+	if got.ID != first.ID {
+		t.Fatalf("GetPostByTitle returned %+v", got)
+	}
+	_, err = posts.GetPostByTitle(ctx, "Missing")
+	expectErr(t, err, repository.ErrNotExist)
+	_, err = posts.GetPostByID(ctx, second.ID+1000)
+	expectErr(t, err, repository.ErrNotExist)
+
+	byUser, err := posts.GetPostByUserID(ctx, 1)
+	noErr(t, err)
+	if len(byUser) != 1 || byUser[0].ID != first.ID {
+		t.Fatalf("GetPostByUserID returned %+v", byUser)
+	}
+	none, err := posts.GetPostByUserID(ctx, 99)
+	noErr(t, err)
+	if len(none) != 0 {
+		t.Fatalf("GetPostByUserID for a user without posts returned %+v", none)
+	}
+	all, err := posts.AllPosts(ctx)
+	noErr(t, err)
+	if len(all) != 2 {
+		t.Fatalf("AllPosts returned %d posts, want 2", len(all))
+	}
+
+	updated := *first
+	updated.Content = "changed"
+	_, err = posts.UpdatePost(ctx, first.ID, updated)
+	noErr(t, err)
+	got, err = posts.GetPostByID(ctx, first.ID)
+	noErr(t, err)
+	if got.Content != "changed" || got.Title != "First" {
+		t.Fatalf("UpdatePost stored %+v", got)
+	}
+
+	if err := posts.DeletePost(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = posts.GetPostByID(ctx, first.ID)
+	expectErr(t, err, repository.ErrNotExist)
+	expectErr(t, posts.DeletePost(ctx, first.ID), repository.ErrDeleteFailed)
+}
+
+func testComments(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+	comments := store.Repositories().Comments
+
+	a, err := comments.CreateComment(ctx, models.Comment{UserID: 1, PostID: 10, Content: "a"})
+	noErr(t, err)
+	b, err := comments.CreateComment(ctx, models.Comment{UserID: 2, PostID: 10, Content: "b"})
+	noErr(t, err)
+	c, err := comments.CreateComment(ctx, models.Comment{UserID: 1, PostID: 20, Content: "c"})
+	noErr(t, err)
+	if a.ID == 0 || a.ID == b.ID || b.ID == c.ID {
+		t.Fatal("comments did not get distinct ids")
+	}
+
+	got, err := comments.GetCommentByID(ctx, b.ID)
+	noErr(t, err)
+	if got.Content != "b" {
+		t.Fatalf("GetCommentByID returned %+v", got)
+	}
+	_, err = comments.GetCommentByID(ctx, c.ID+1000)
+	expectErr(t, err, repository.ErrNotExist)
+
+	byPost, err := comments.GetCommentByPostID(ctx, 10, repository.CommentSortOld)
+	noErr(t, err)
+	if len(byPost) != 2 || byPost[0].ID != a.ID {
+		t.Fatalf("GetCommentByPostID returned %+v, want a then b", byPost)
+	}
+
+	voted, err := comments.AddCommentVotes(ctx, b.ID, 2, 1)
+	noErr(t, err)
+	if voted.Upvotes != 2 || voted.Downvotes != 1 {
+		t.Fatalf("AddCommentVotes returned %+v", voted)
+	}
+	noErr(t, comments.SetCommentScore(ctx, b.ID, 0.5, 1.5))
+	top, err := comments.GetCommentByPostID(ctx, 10, repository.CommentSortTop)
+	noErr(t, err)
+	if len(top) != 2 || top[0].ID != b.ID || top[0].Score != 0.5 {
+		t.Fatalf("GetCommentByPostID top returned %+v, want b first", top)
+	}
+	_, err = comments.AddCommentVotes(ctx, c.ID+1000, 1, 0)
+	expectErr(t, err, repository.ErrUpdateFailed)
+	expectErr(t, comments.SetCommentScore(ctx, c.ID+1000, 1, 0), repository.ErrUpdateFailed)
+	byUser, err := comments.GetCommentByUserID(ctx, 1)
+	noErr(t, err)
+	if len(byUser) != 2 {
+		t.Fatalf("GetCommentByUserID returned %d comments, want 2", len(byUser))
+	}
+	got, err = comments.GetCommentByUserIDPostID(ctx, 1, 20)
+	noErr(t, err)
+	if got.ID != c.ID {
+		t.Fatalf("GetCommentByUserIDPostID returned %+v", got)
+	}
+	_, err = comments.GetCommentByUserIDPostID(ctx, 2, 20)
+	expectErr(t, err, repository.ErrNotExist)
+	all, err := comments.AllComments(ctx)
+	noErr(t, err)
+	if len(all) != 3 {
+		t.Fatalf("AllComments returned %d comments, want 3", len(all))
+	}
+
+	updated := *a
+	updated.Content = "changed"
+	_, err = comments.UpdateComment(ctx, a.ID, updated)
+	noErr(t, err)
+	got, err = comments.GetCommentByID(ctx, a.ID)
+	noErr(t, err)
+	if got.Content != "changed" {
+		t.Fatalf("UpdateComment stored %+v", got)
+	}
+
+	// updates leave the votes alone
+	updated = *b
+	updated.Content = "changed"
+	updated.Upvotes = 0
+	_, err = comments.UpdateComment(ctx, b.ID, updated)
+	noErr(t, err)
+	got, err = comments.GetCommentByID(ctx, b.ID)
+	noErr(t, err)
+	if got.Upvotes != 2 || got.Score != 0.5 {
+		t.Fatalf("UpdateComment changed the votes to %+v", got)
+	}
+
+	if err := comments.DeleteComment(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectErr(t, comments.DeleteComment(ctx, a.ID), repository.ErrDeleteFailed)
+}
+
+func testUnitOfWork(t *testing.T, store repository.Store) {
+	ctx := context.Background()
+
+	err := store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if _, err := repos.Posts.CreatePost(ctx, models.Post{UserID: 1, Title: "Rolled back"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	expectErr(t, err, errRollback)
+	_, err = store.Repositories().Posts.GetPostByTitle(ctx, "Rolled back")
+	expectErr(t, err, repository.ErrNotExist)
+
+	err = store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		_, err = repos.Posts.CreatePost(ctx, models.Post{UserID: 1, Title: "Committed"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Repositories().Posts.GetPostByTitle(ctx, "Committed")
+	noErr(t, err)
+}
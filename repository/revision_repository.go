@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateRevision(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.PostRevision{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewRevisionRepository(db *gorm.DB) RevisionRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) CreateRevision(ctx context.Context, revision models.PostRevision) (*models.PostRevision, error) {
+	db := repo.db.WithContext(ctx)
+	var last int
+	err := db.Model(&models.PostRevision{}).Where("post_id = ?", revision.PostID).
+		Select("COALESCE(MAX(number), 0)").Scan(&last).Error
+	if err != nil {
+		return nil, TranslateError(err)
+	}
+	revision.ID, revision.Number = 0, last+1
+	if revision.CreatedAt.IsZero() {
+		revision.CreatedAt = time.Now()
+	}
+	if err := db.Create(&revision).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return &revision, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	if err := repo.db.WithContext(ctx).Where("post_id = ?", postID).Order("number").Find(&revisions).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return revisions, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	err := repo.db.WithContext(ctx).Where("post_id = ? AND number = ?", postID, number).First(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &revision, nil
+}
+
+func (repo *PostgreSQLGORMRepository) PruneRevisions(ctx context.Context, postID int64, keep int) error {
+	db := repo.db.WithContext(ctx)
+	var last int
+	err := db.Model(&models.PostRevision{}).Where("post_id = ?", postID).
+		Select("COALESCE(MAX(number), 0)").Scan(&last).Error
+	if err != nil {
+		return TranslateError(err)
+	}
+	err = db.Where("post_id = ? AND number <= ?", postID, last-keep).Delete(&models.PostRevision{}).Error
+	return TranslateError(err)
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteRevisions(ctx context.Context, postID int64) error {
+	return TranslateError(repo.db.WithContext(ctx).Where("post_id = ?", postID).Delete(&models.PostRevision{}).Error)
+}
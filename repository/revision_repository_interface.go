@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+)
+
+// RevisionRepository stores the revisions of the posts
+type RevisionRepository interface {
+	MigrateRevision(ctx context.Context) error
+	// CreateRevision stores a revision with the number after the last one
+	// of its post
+	CreateRevision(ctx context.Context, revision models.PostRevision) (*models.PostRevision, error)
+	// GetRevisions returns the revisions of a post, the oldest first
+	GetRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error)
+	GetRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error)
+	// PruneRevisions deletes the oldest revisions of a post until keep are
+	// left
+	PruneRevisions(ctx context.Context, postID int64, keep int) error
+	DeleteRevisions(ctx context.Context, postID int64) error
+}
@@ -0,0 +1,7 @@
+package repository
+
+// SchemaVersion is the version of the tables this code works on, it goes up
+// with every change of the tables. Migrating records it in the
+// schema_migrations table, so a server can tell whether its database is
+// ready for it.
+const SchemaVersion = 12
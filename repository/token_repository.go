@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateToken(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.UserToken{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) CreateToken(ctx context.Context, token models.UserToken) (*models.UserToken, error) {
+	token.ID = 0
+	if err := repo.db.WithContext(ctx).Create(&token).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return &token, nil
+}
+
+func (repo *PostgreSQLGORMRepository) UseToken(ctx context.Context, purpose, hash string, now time.Time) (*models.UserToken, error) {
+	// the update is the check, of two concurrent uses only one changes the row
+	res := repo.db.WithContext(ctx).Model(&models.UserToken{}).
+		Where("purpose = ? AND hash = ? AND used_at IS NULL AND expires_at > ?", purpose, hash, now).
+		Update("used_at", now)
+	if err := res.Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotExist
+	}
+
+	var token models.UserToken
+	if err := repo.db.WithContext(ctx).Where("hash = ?", hash).First(&token).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return &token, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteUserTokens(ctx context.Context, userID int64, purpose string) error {
+	query := repo.db.WithContext(ctx).Where("user_id = ?", userID)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	return TranslateError(query.Delete(&models.UserToken{}).Error)
+}
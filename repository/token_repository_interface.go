@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+)
+
+// TokenRepository stores the tokens mailed to the users
+type TokenRepository interface {
+	MigrateToken(ctx context.Context) error
+	CreateToken(ctx context.Context, token models.UserToken) (*models.UserToken, error)
+	// UseToken marks the token of purpose with hash as used and returns it.
+	// A token that is used or expired at now is ErrNotExist.
+	UseToken(ctx context.Context, purpose, hash string, now time.Time) (*models.UserToken, error)
+	// DeleteUserTokens deletes the tokens of a user, of every purpose when
+	// purpose is empty
+	DeleteUserTokens(ctx context.Context, userID int64, purpose string) error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateTwoFactor(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.TwoFactor{}, &models.RecoveryCode{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &twoFactor, nil
+}
+
+func (repo *PostgreSQLGORMRepository) SaveTwoFactor(ctx context.Context, twoFactor models.TwoFactor) error {
+	err := repo.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_step", "created_at"}),
+	}).Create(&twoFactor).Error
+	return TranslateError(err)
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	db := repo.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return TranslateError(err)
+	}
+	return TranslateError(db.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error)
+}
+
+func (repo *PostgreSQLGORMRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	db := repo.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return TranslateError(err)
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	codes := make([]models.RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = models.RecoveryCode{UserID: userID, Hash: hash}
+	}
+	return TranslateError(db.Create(&codes).Error)
+}
+
+func (repo *PostgreSQLGORMRepository) UseRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time) error {
+	res := repo.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", now)
+	if err := res.Error; err != nil {
+		return TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotExist
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/models"
+)
+
+// TwoFactorRepository stores the TOTP secrets and the recovery codes
+type TwoFactorRepository interface {
+	MigrateTwoFactor(ctx context.Context) error
+	// GetTwoFactor returns the second factor of a user, ErrNotExist when
+	// the user has none
+	GetTwoFactor(ctx context.Context, userID int64) (*models.TwoFactor, error)
+	// SaveTwoFactor creates or replaces the second factor of its user
+	SaveTwoFactor(ctx context.Context, twoFactor models.TwoFactor) error
+	// DeleteTwoFactor deletes the second factor and the recovery codes of a
+	// user
+	DeleteTwoFactor(ctx context.Context, userID int64) error
+	// ReplaceRecoveryCodes replaces the recovery codes of a user by hashes
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	// UseRecoveryCode marks the unused recovery code of a user with hash as
+	// used at now, ErrNotExist when there is none
+	UseRecoveryCode(ctx context.Context, userID int64, hash string, now time.Time) error
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Repositories groups the repositories that work on the same database
+// handle, inside a unit of work they all use the same transaction.
+type Repositories struct {
+	Users    UserRepository
+	Posts    PostRepository
+	Comments CommentRepository
+	Tokens   TokenRepository
+	// TwoFactors are the second factors of the logins
+	TwoFactors TwoFactorRepository
+	// Identities link the users to single sign-on providers
+	Identities IdentityRepository
+	// Profiles are what the users tell about themselves
+	Profiles ProfileRepository
+	// Audit is the log of the changes made through the services
+	Audit AuditRepository
+	// Revisions keep the earlier versions of the posts
+	Revisions RevisionRepository
+	// Drafts keep the unsaved work on the posts
+	Drafts DraftRepository
+	// Reactions are the likes and emoji of the users on posts and comments
+	Reactions ReactionRepository
+	// Votes are the up and down votes of the users on comments
+	Votes VoteRepository
+}
+
+// UnitOfWork runs a function with repositories bound to one transaction. The
+// transaction is committed when fn returns nil and rolled back otherwise.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+// Store is a storage backend, its repositories and a way to run them in a
+// single transaction.
+type Store interface {
+	UnitOfWork
+	Repositories() Repositories
+}
+
+type TxOptions struct {
+	// Isolation is the isolation level of the transactions
+	Isolation sql.IsolationLevel
+	// MaxRetries is how often a transaction is retried after a
+	// serialization failure or a deadlock
+	MaxRetries int
+	// RetryDelay is the base delay before a retry, it doubles with every
+	// attempt and gets some jitter
+	RetryDelay time.Duration
+}
+
+// DefaultTxOptions are used by the services. Serializable lets PostgreSQL
+// detect the check-then-act races, the losing transaction is then retried.
+var DefaultTxOptions = TxOptions{
+	Isolation:  sql.LevelSerializable,
+	MaxRetries: 3,
+	RetryDelay: 10 * time.Millisecond,
+}
+
+type gormUnitOfWork struct {
+	db   *gorm.DB
+	opts TxOptions
+}
+
+func NewUnitOfWork(db *gorm.DB, opts TxOptions) UnitOfWork {
+	return &gormUnitOfWork{db: db, opts: opts}
+}
+
+// NewStore returns the GORM backed store
+func NewStore(db *gorm.DB, opts TxOptions) Store {
+	return &gormUnitOfWork{db: db, opts: opts}
+}
+
+func newRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:      NewUserRepository(db),
+		Posts:      NewPostRepository(db),
+		Comments:   NewCommentRepository(db),
+		Tokens:     NewTokenRepository(db),
+		TwoFactors: NewTwoFactorRepository(db),
+		Identities: NewIdentityRepository(db),
+		Profiles:   NewProfileRepository(db),
+		Audit:      NewAuditRepository(db),
+		Revisions:  NewRevisionRepository(db),
+		Drafts:     NewDraftRepository(db),
+		Reactions:  NewReactionRepository(db),
+		Votes:      NewVoteRepository(db),
+	}
+}
+
+func (uow *gormUnitOfWork) Repositories() Repositories {
+	return newRepositories(uow.db)
+}
+
+func (uow *gormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	err := Retry(ctx, uow.opts, func() error {
+		return uow.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(ctx, newRepositories(tx))
+		}, &sql.TxOptions{Isolation: uow.opts.Isolation})
+	})
+	return TranslateError(err)
+}
+
+// Retry runs the transaction in run until it succeeds, fails with an error
+// that is not retryable or opts.MaxRetries is used up.
+func Retry(ctx context.Context, opts TxOptions, run func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := run()
+		if err == nil || !IsRetryable(err) || attempt >= opts.MaxRetries {
+			return err
+		}
+
+		delay := opts.RetryDelay << attempt
+		if delay > 0 {
+			delay += time.Duration(rand.Int63n(int64(delay)))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// IsRetryable reports whether err is a serialization failure (SQLSTATE 40001)
+// or a deadlock (40P01), after which the whole transaction can be retried.
+func IsRetryable(err error) bool {
+	var pgxError *pgconn.PgError
+	if errors.As(err, &pgxError) {
+		return pgxError.Code == "40001" || pgxError.Code == "40P01"
+	}
+	return false
+}
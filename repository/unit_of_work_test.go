@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"postgresql-blog/repository"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"wrapped", fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"other error", errors.New("connection refused"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repository.IsRetryable(tt.err); got != tt.want {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	opts := repository.TxOptions{MaxRetries: 3, RetryDelay: time.Millisecond}
+
+	// the failures are retried until the transaction goes through
+	failures := []error{&pgconn.PgError{Code: "40001"}, &pgconn.PgError{Code: "40P01"}}
+	calls := 0
+	err := repository.Retry(context.Background(), opts, func() error {
+		calls++
+		if calls <= len(failures) {
+			return failures[calls-1]
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("got %v after %d calls, want success on the third", err, calls)
+	}
+
+	// the delay doubles, 1ms, 2ms and 4ms before the retries at least
+	calls = 0
+	start := time.Now()
+	err = repository.Retry(context.Background(), opts, func() error {
+		calls++
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if elapsed := time.Since(start); elapsed < 7*time.Millisecond {
+		t.Fatalf("the retries took %v, want the backoff of 7ms at least", elapsed)
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "40001" {
+		t.Fatalf("got %v once the attempts ran out, want the serialization failure", err)
+	}
+	if calls != opts.MaxRetries+1 {
+		t.Fatalf("got %d calls, want the first one and %d retries", calls, opts.MaxRetries)
+	}
+
+	calls = 0
+	unique := &pgconn.PgError{Code: "23505"}
+	err = repository.Retry(context.Background(), opts, func() error {
+		calls++
+		return unique
+	})
+	if err != unique || calls != 1 {
+		t.Fatalf("got %v after %d calls, want the unique violation without a retry", err, calls)
+	}
+}
+
+func TestRetryStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := repository.Retry(ctx, repository.TxOptions{MaxRetries: 3, RetryDelay: time.Hour}, func() error {
+		calls++
+		cancel()
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want context.Canceled after the first", err, calls)
+	}
+}
@@ -3,10 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"postgresql-blog/models"
 
-	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
@@ -15,9 +15,17 @@ type PostgreSQLGORMRepository struct {
 }
 
 func (repo *PostgreSQLGORMRepository) MigrateUser(ctx context.Context) error {
+	migrator := repo.db.WithContext(ctx).Migrator()
+	// the users from before email verification keep their accounts
+	verifyExisting := migrator.HasTable(&models.GormUser{}) && !migrator.HasColumn(&models.GormUser{}, "EmailVerifiedAt")
+
 	err := repo.db.WithContext(ctx).AutoMigrate(&models.GormUser{})
 	if err != nil {
-		return err
+		return TranslateError(err)
+	}
+	if verifyExisting {
+		err = repo.db.WithContext(ctx).Model(&models.GormUser{}).Where("email_verified_at IS NULL").Update("email_verified_at", time.Now()).Error
+		return TranslateError(err)
 	}
 	return nil
 }
@@ -28,20 +36,18 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 func (repo *PostgreSQLGORMRepository) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
 	gormUser := models.GormUser{
-		Name:     user.Name,
-		Email:    user.Email,
-		Password: user.Password,
-		Username: user.Username,
+		Name:            user.Name,
+		Email:           user.Email,
+		Password:        user.Password,
+		Username:        user.Username,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Status:          user.Status,
+		SuspendedUntil:  user.SuspendedUntil,
+		StatusReason:    user.StatusReason,
 	}
 
 	if err := repo.db.WithContext(ctx).Create(&gormUser).Error; err != nil {
-		var pgxError *pgconn.PgError
-		if errors.As(err, &pgxError) {
-			if pgxError.Code == "23505" {
-				return nil, ErrDuplicate
-			}
-		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.User(gormUser)
@@ -51,7 +57,7 @@ func (repo *PostgreSQLGORMRepository) CreateUser(ctx context.Context, user model
 func (repo *PostgreSQLGORMRepository) AllUsers(ctx context.Context) ([]models.User, error) {
 	var allUsers []models.GormUser
 	if err := repo.db.WithContext(ctx).Find(&allUsers).Error; err != nil {
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	var result []models.User
@@ -68,7 +74,7 @@ func (repo *PostgreSQLGORMRepository) GetUserByID(ctx context.Context, id int64)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.User(gormUser)
@@ -81,7 +87,20 @@ func (repo *PostgreSQLGORMRepository) GetUserByEmail(ctx context.Context, email
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
+	}
+
+	result := models.User(gormUser)
+	return &result, nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var gormUser models.GormUser
+	if err := repo.db.WithContext(ctx).Where("username = ?", username).First(&gormUser).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
 	}
 
 	result := models.User(gormUser)
@@ -94,7 +113,7 @@ func (repo *PostgreSQLGORMRepository) GetUserByUsernameAndPassword(ctx context.C
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotExist
 		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	result := models.User(gormUser)
@@ -105,13 +124,7 @@ func (repo *PostgreSQLGORMRepository) UpdateUser(ctx context.Context, id int64,
 	gormUser := models.User(updated)
 	updateRes := repo.db.WithContext(ctx).Where("id = ?", id).Save(&gormUser)
 	if err := updateRes.Error; err != nil {
-		var pgxError *pgconn.PgError
-		if errors.As(err, &pgxError) {
-			if pgxError.Code == "23505" {
-				return nil, ErrDuplicate
-			}
-		}
-		return nil, err
+		return nil, TranslateError(err)
 	}
 
 	rowsAffected := updateRes.RowsAffected
@@ -124,7 +137,7 @@ func (repo *PostgreSQLGORMRepository) UpdateUser(ctx context.Context, id int64,
 func (repo *PostgreSQLGORMRepository) DeleteUser(ctx context.Context, id int64) error {
 	res := repo.db.WithContext(ctx).Delete(&models.GormUser{}, id)
 	if err := res.Error; err != nil {
-		return err
+		return TranslateError(err)
 	}
 
 	rowsAffected := res.RowsAffected
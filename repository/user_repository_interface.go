@@ -2,15 +2,19 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"postgresql-blog/apperr"
 	"postgresql-blog/models"
 )
 
+// the errors of the repositories, the services add the entity they are about
 var (
-	ErrDuplicate    = errors.New("record already exists")
-	ErrNotExist     = errors.New("row does not exist")
-	ErrUpdateFailed = errors.New("update failed")
-	ErrDeleteFailed = errors.New("delete failed")
+	ErrDuplicate    error = &apperr.Error{Kind: apperr.Conflict, Code: "duplicate", Message: "record already exists"}
+	ErrNotExist     error = &apperr.Error{Kind: apperr.NotFound, Code: "not_exist", Message: "row does not exist"}
+	ErrUpdateFailed error = &apperr.Error{Kind: apperr.NotFound, Code: "update_failed", Message: "update failed"}
+	ErrDeleteFailed error = &apperr.Error{Kind: apperr.NotFound, Code: "delete_failed", Message: "delete failed"}
+	ErrForeignKey   error = &apperr.Error{Kind: apperr.Validation, Code: "foreign_key", Message: "referenced record does not exist"}
+	// ErrUnavailable wraps the failures to reach the database
+	ErrUnavailable error = &apperr.Error{Kind: apperr.Unavailable, Code: "unavailable", Message: "database unavailable"}
 )
 
 // Repository provides access to the website storage.
@@ -20,6 +24,7 @@ type UserRepository interface {
 	AllUsers(ctx context.Context) ([]models.User, error)
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	GetUserByUsernameAndPassword(ctx context.Context, username string, password string) (*models.User, error)
 	UpdateUser(ctx context.Context, id int64, updated models.User) (*models.User, error)
 	DeleteUser(ctx context.Context, id int64) error
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"postgresql-blog/models"
+
+	"gorm.io/gorm"
+)
+
+func (repo *PostgreSQLGORMRepository) MigrateVote(ctx context.Context) error {
+	err := repo.db.WithContext(ctx).AutoMigrate(&models.CommentVote{})
+	if err != nil {
+		return TranslateError(err)
+	}
+	return nil
+}
+
+func NewVoteRepository(db *gorm.DB) VoteRepository {
+	return &PostgreSQLGORMRepository{db}
+}
+
+func (repo *PostgreSQLGORMRepository) GetVote(ctx context.Context, commentID, userID int64) (*models.CommentVote, error) {
+	var vote models.CommentVote
+	err := repo.db.WithContext(ctx).Where("comment_id = ? AND user_id = ?", commentID, userID).First(&vote).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotExist
+		}
+		return nil, TranslateError(err)
+	}
+	return &vote, nil
+}
+
+func (repo *PostgreSQLGORMRepository) SaveVote(ctx context.Context, vote models.CommentVote) (*models.CommentVote, error) {
+	db := repo.db.WithContext(ctx)
+	if vote.ID == 0 {
+		if err := db.Create(&vote).Error; err != nil {
+			return nil, TranslateError(err)
+		}
+		return &vote, nil
+	}
+
+	// the selection keeps Save from inserting a vote that is gone
+	res := db.Where("id = ?", vote.ID).Select("*").Save(&vote)
+	if err := res.Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrUpdateFailed
+	}
+	return &vote, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteVote(ctx context.Context, id int64) error {
+	res := repo.db.WithContext(ctx).Delete(&models.CommentVote{}, id)
+	if err := res.Error; err != nil {
+		return TranslateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return ErrDeleteFailed
+	}
+	return nil
+}
+
+func (repo *PostgreSQLGORMRepository) GetUserVotes(ctx context.Context, userID int64) ([]models.CommentVote, error) {
+	var votes []models.CommentVote
+	if err := repo.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&votes).Error; err != nil {
+		return nil, TranslateError(err)
+	}
+	return votes, nil
+}
+
+func (repo *PostgreSQLGORMRepository) DeleteCommentVotes(ctx context.Context, commentID int64) error {
+	return TranslateError(repo.db.WithContext(ctx).Where("comment_id = ?", commentID).Delete(&models.CommentVote{}).Error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"postgresql-blog/models"
+)
+
+// VoteRepository stores the votes on the comments, the counts they add up
+// to are kept on the comments
+type VoteRepository interface {
+	MigrateVote(ctx context.Context) error
+	GetVote(ctx context.Context, commentID, userID int64) (*models.CommentVote, error)
+	// SaveVote creates the vote when it has no ID yet and updates it
+	// otherwise
+	SaveVote(ctx context.Context, vote models.CommentVote) (*models.CommentVote, error)
+	DeleteVote(ctx context.Context, id int64) error
+	GetUserVotes(ctx context.Context, userID int64) ([]models.CommentVote, error)
+	DeleteCommentVotes(ctx context.Context, commentID int64) error
+}
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+type postResponse struct {
+	ID          int64          `json:"id"`
+	UserID      uint64         `json:"user_id"`
+	Title       string         `json:"title"`
+	Content     string         `json:"content"`
+	Thumbnail   string         `json:"thumbnail,omitempty"`
+	PublishedAt time.Time      `json:"published_at"`
+	Reactions   map[string]int `json:"reactions"`
+}
+
+type commentResponse struct {
+	ID          int64          `json:"id"`
+	UserID      uint64         `json:"user_id"`
+	PostID      uint64         `json:"post_id"`
+	Content     string         `json:"content"`
+	PublishedAt time.Time      `json:"published_at"`
+	Upvotes     int            `json:"upvotes"`
+	Downvotes   int            `json:"downvotes"`
+	Score       float64        `json:"score"`
+	Reactions   map[string]int `json:"reactions"`
+}
+
+type reactionResponse struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// reactionPageResponse is a page of who reacted, next is the after of the
+// next page and missing on the last one
+type reactionPageResponse struct {
+	Reactions []reactionResponse `json:"reactions"`
+	Next      int64              `json:"next,omitempty"`
+}
+
+type linkResponse struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// profileResponse is a public profile, email is only there when the user
+// made it public
+type profileResponse struct {
+	UserID       int64          `json:"user_id"`
+	Username     string         `json:"username"`
+	DisplayName  string         `json:"display_name,omitempty"`
+	Email        string         `json:"email,omitempty"`
+	Bio          string         `json:"bio,omitempty"`
+	Website      string         `json:"website,omitempty"`
+	Links        []linkResponse `json:"links"`
+	Timezone     string         `json:"timezone,omitempty"`
+	Locale       string         `json:"locale,omitempty"`
+	AvatarURL    string         `json:"avatar_url,omitempty"`
+	Posts        []postResponse `json:"posts"`
+	CommentCount int            `json:"comment_count"`
+}
+
+func newPostResponse(post models.Post) postResponse {
+	return postResponse{
+		ID:          post.ID,
+		UserID:      post.UserID,
+		Title:       post.Title,
+		Content:     post.Content,
+		Thumbnail:   post.Thumbnail,
+		PublishedAt: post.PublishedAt,
+		Reactions:   reactionCounts(post.Reactions),
+	}
+}
+
+func newCommentResponse(comment models.Comment) commentResponse {
+	return commentResponse{
+		ID:          comment.ID,
+		UserID:      comment.UserID,
+		PostID:      comment.PostID,
+		Content:     comment.Content,
+		PublishedAt: comment.PublishedAt,
+		Upvotes:     comment.Upvotes,
+		Downvotes:   comment.Downvotes,
+		Score:       comment.Score,
+		Reactions:   reactionCounts(comment.Reactions),
+	}
+}
+
+// reactionCounts never leaves the counts null, the lists of posts have
+// none
+func reactionCounts(counts map[string]int) map[string]int {
+	if counts == nil {
+		return map[string]int{}
+	}
+	return counts
+}
+
+// listPosts serves GET /api/posts, the published posts
+func (s *Server) listPosts(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	posts, err := s.services.Posts.GetAllPosts(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	result := []postResponse{}
+	for _, post := range posts {
+		if post.IsPublished {
+			result = append(result, newPostResponse(post))
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// postRoutes serves GET /api/posts/{id}, GET /api/posts/{id}/comments and
+// GET /api/posts/{id}/reactions, the comments take sort: old, new, top or
+// controversial
+func (s *Server) postRoutes(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	idText, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/posts/"), "/")
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil || (rest != "" && rest != "comments" && rest != "reactions") {
+		http.NotFound(w, r)
+		return
+	}
+
+	post, err := s.services.Posts.GetPostByID(r.Context(), id)
+	if err == nil && !post.IsPublished {
+		// drafts are not public
+		err = apperr.New(apperr.NotFound, "post", "")
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if rest == "" {
+		writeJSON(w, http.StatusOK, newPostResponse(*post))
+		return
+	}
+	if rest == "reactions" {
+		s.writeReactions(w, r, models.TargetPost, id)
+		return
+	}
+
+	sort := repository.CommentSort(r.URL.Query().Get("sort"))
+	comments, err := s.services.Comments.GetCommentByPostID(r.Context(), id, sort)
+	if err != nil && !errors.Is(err, repository.ErrNotExist) {
+		writeError(w, r, err)
+		return
+	}
+	result := []commentResponse{}
+	for _, comment := range comments {
+		if comment.IsPublished && comment.DeletedAt == nil {
+			result = append(result, newCommentResponse(comment))
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// commentRoutes serves GET /api/comments/{id}/reactions
+func (s *Server) commentRoutes(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	idText, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/comments/"), "/")
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil || rest != "reactions" {
+		http.NotFound(w, r)
+		return
+	}
+
+	comment, err := s.services.Comments.GetCommentByID(r.Context(), id)
+	if err == nil && (!comment.IsPublished || comment.DeletedAt != nil) {
+		err = apperr.New(apperr.NotFound, "comment", "")
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	s.writeReactions(w, r, models.TargetComment, id)
+}
+
+// writeReactions answers with a page of who reacted to a target, the query
+// takes kind, after and limit
+func (s *Server) writeReactions(w http.ResponseWriter, r *http.Request, targetType string, id int64) {
+	query := r.URL.Query()
+	filter := repository.ReactionFilter{TargetType: targetType, TargetID: id, Kind: query.Get("kind")}
+	var err error
+	if text := query.Get("after"); text != "" {
+		if filter.After, err = strconv.ParseInt(text, 10, 64); err != nil {
+			writeError(w, r, apperr.Invalid("reaction", "after", "after has to be a reaction id"))
+			return
+		}
+	}
+	if text := query.Get("limit"); text != "" {
+		if filter.Limit, err = strconv.Atoi(text); err != nil {
+			writeError(w, r, apperr.Invalid("reaction", "limit", "limit has to be a number"))
+			return
+		}
+	}
+
+	page, err := s.services.Reactions.GetReactions(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	result := reactionPageResponse{Reactions: []reactionResponse{}, Next: page.Next}
+	for _, reactor := range page.Reactors {
+		result.Reactions = append(result.Reactions, reactionResponse{
+			ID:        reactor.ID,
+			UserID:    reactor.UserID,
+			Username:  reactor.Username,
+			Kind:      reactor.Kind,
+			CreatedAt: reactor.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// userRoutes serves GET /api/users/{username}, the public profile, and GET
+// /api/users/{username}/avatar
+func (s *Server) userRoutes(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	username, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+	if username == "" || (rest != "" && rest != "avatar") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if rest == "avatar" {
+		avatar, err := s.services.Profiles.GetAvatar(r.Context(), username)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", avatar.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, "", avatar.UpdatedAt, bytes.NewReader(avatar.Data))
+		return
+	}
+
+	profile, err := s.services.Profiles.GetPublicProfile(r.Context(), username)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	result := profileResponse{
+		UserID:       profile.UserID,
+		Username:     profile.Username,
+		DisplayName:  profile.DisplayName,
+		Email:        profile.Email,
+		Bio:          profile.Bio,
+		Website:      profile.Website,
+		Links:        []linkResponse{},
+		Timezone:     profile.Timezone,
+		Locale:       profile.Locale,
+		Posts:        []postResponse{},
+		CommentCount: profile.CommentCount,
+	}
+	if profile.HasAvatar {
+		result.AvatarURL = "/api/users/" + url.PathEscape(profile.Username) + "/avatar"
+	}
+	for _, link := range profile.Links {
+		result.Links = append(result.Links, linkResponse{Label: link.Label, URL: link.URL})
+	}
+	for _, post := range profile.Posts {
+		result.Posts = append(result.Posts, newPostResponse(post))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+	return false
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// statuses maps the error kinds the client can act on to HTTP statuses
+var statuses = map[apperr.Kind]int{
+	apperr.NotFound:    http.StatusNotFound,
+	apperr.Conflict:    http.StatusConflict,
+	apperr.Validation:  http.StatusUnprocessableEntity,
+	apperr.Forbidden:   http.StatusForbidden,
+	apperr.RateLimited: http.StatusTooManyRequests,
+}
+
+// writeError answers with the status matching the kind of err. The details
+// of Unavailable and Internal errors stay in the logs.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e, _ := apperr.As(err)
+	if status, ok := statuses[apperr.KindOf(err)]; ok {
+		if e.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(e.RetryAfter.Seconds())))
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error(), Code: e.CodeOrKind(), Field: e.Field})
+		return
+	}
+
+	slog.ErrorContext(r.Context(), "serving request", slog.String("path", r.URL.Path), slog.Any("error", err))
+	if apperr.Is(err, apperr.Unavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "service unavailable", Code: apperr.Unavailable.String()})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error", Code: apperr.Internal.String()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
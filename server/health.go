@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"postgresql-blog/repository"
+)
+
+// readyTimeout bounds the checks of a readiness probe
+const readyTimeout = 2 * time.Second
+
+// healthz tells the process is alive. It does not look at the database, a
+// database outage should take the server out of rotation, not restart it.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// readyz tells whether the server can serve requests: the database answers
+// and is migrated to the schema version of the code
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	result := readiness{Status: "ready", Checks: map[string]string{}}
+	if err := s.db.Ping(ctx); err != nil {
+		result.Checks["database"] = err.Error()
+	} else {
+		result.Checks["database"] = "ok"
+	}
+	version, err := s.db.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		result.Checks["schema"] = err.Error()
+	case version < repository.SchemaVersion:
+		result.Checks["schema"] = fmt.Sprintf("version %d, want %d", version, repository.SchemaVersion)
+	default:
+		result.Checks["schema"] = "ok"
+	}
+
+	status := http.StatusOK
+	for _, check := range result.Checks {
+		if check != "ok" {
+			result.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, result)
+}
@@ -0,0 +1,170 @@
+// Package server runs the blog as a long-running HTTP server: a read-only
+// JSON API of the published posts, health and readiness probes and the
+// metrics. It stops on a signal by draining the requests in flight and the
+// background workers within a deadline.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"postgresql-blog/logging"
+	"postgresql-blog/ratelimit"
+	"postgresql-blog/service"
+	"postgresql-blog/tracing"
+)
+
+const (
+	DefaultAddr            = ":8080"
+	DefaultShutdownTimeout = 15 * time.Second
+)
+
+type Config struct {
+	Addr string
+	// ShutdownTimeout bounds how long the requests in flight and the
+	// background workers get to finish once the server is stopped
+	ShutdownTimeout time.Duration
+}
+
+// ConfigFromEnv reads BLOG_HTTP_ADDR and BLOG_SHUTDOWN_TIMEOUT, values that
+// do not parse keep the defaults
+func ConfigFromEnv() Config {
+	cfg := Config{Addr: DefaultAddr, ShutdownTimeout: DefaultShutdownTimeout}
+	if addr := os.Getenv("BLOG_HTTP_ADDR"); addr != "" {
+		cfg.Addr = addr
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("BLOG_SHUTDOWN_TIMEOUT")); err == nil && timeout > 0 {
+		cfg.ShutdownTimeout = timeout
+	}
+	return cfg
+}
+
+// Database is what the readiness probe checks
+type Database interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int64, error)
+}
+
+type Server struct {
+	cfg      Config
+	db       Database
+	services service.Services
+	mux      *http.ServeMux
+
+	// workers run until the server stops
+	workers       sync.WaitGroup
+	workerCtx     context.Context
+	cancelWorkers context.CancelFunc
+}
+
+func New(cfg Config, db Database, services service.Services) *Server {
+	s := &Server{cfg: cfg, db: db, services: services, mux: http.NewServeMux()}
+	s.workerCtx, s.cancelWorkers = context.WithCancel(context.Background())
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/readyz", s.readyz)
+	s.mux.HandleFunc("/api/posts", s.listPosts)
+	s.mux.HandleFunc("/api/posts/", s.postRoutes)
+	s.mux.HandleFunc("/api/comments/", s.commentRoutes)
+	s.mux.HandleFunc("/api/users/", s.userRoutes)
+	return s
+}
+
+// Handle adds a handler, like the one of the metrics
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Go runs worker in the background, its context is cancelled when the server
+// stops and the server waits for it to return
+func (s *Server) Go(worker func(ctx context.Context)) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		worker(s.workerCtx)
+	}()
+}
+
+// Run serves until ctx is done and then shuts down: the listener is closed,
+// the requests in flight and the workers are waited for until the shutdown
+// timeout. Closing the database is left to the caller, it is only safe once
+// Run returned.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{
+		Handler:           tracing.Handler(withOperation(withClientIP(s.mux))),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	slog.Info("serving", slog.String("addr", listener.Addr().String()))
+
+	select {
+	case err := <-serveErr:
+		s.cancelWorkers()
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", slog.Duration("timeout", s.cfg.ShutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("draining the requests: %w", err))
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	s.cancelWorkers()
+	if err := s.waitWorkers(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping the workers: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) waitWorkers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withClientIP lets the rate limits per IP address apply to the requests.
+// The address is the one of the connection, a proxy in front of the server
+// is limited as a single client.
+func withClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		next.ServeHTTP(w, r.WithContext(ratelimit.WithClientIP(r.Context(), ip)))
+	})
+}
+
+// withOperation gives every request its own operation id in the logs
+func withOperation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(logging.WithOperation(r.Context())))
+	})
+}
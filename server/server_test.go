@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"postgresql-blog/service"
+)
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+// get polls url until the server answers
+func get(t *testing.T, url string) *http.Response {
+	t.Helper()
+	for i := 0; ; i++ {
+		res, err := http.Get(url)
+		if err == nil {
+			return res
+		}
+		if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunDrainsRequestsAndStopsWorkers(t *testing.T) {
+	addr := freeAddr(t)
+	s := New(Config{Addr: addr, ShutdownTimeout: 5 * time.Second}, nil, service.Services{})
+	started, release := make(chan struct{}), make(chan struct{})
+	s.Handle("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	}))
+	stopped := make(chan struct{})
+	s.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+	if res := get(t, "http://"+addr+"/healthz"); res.StatusCode != http.StatusOK {
+		t.Fatalf("healthz answered %d", res.StatusCode)
+	}
+
+	type response struct {
+		body string
+		err  error
+	}
+	slow := make(chan response, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- response{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		slow <- response{string(body), err}
+	}()
+	<-started
+
+	// the request in flight keeps the server up after the stop
+	cancel()
+	select {
+	case err := <-runErr:
+		t.Fatalf("Run returned %v before the request in flight was done", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		t.Fatal("the server takes new connections while shutting down")
+	}
+
+	close(release)
+	if res := <-slow; res.err != nil || res.body != "done" {
+		t.Fatalf("the request in flight got %q, %v", res.body, res.err)
+	}
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the drain")
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Run returned before the worker stopped")
+	}
+}
+
+func TestRunGivesUpOnWorkersAfterTimeout(t *testing.T) {
+	s := New(Config{Addr: freeAddr(t), ShutdownTimeout: 50 * time.Millisecond}, nil, service.Services{})
+	release := make(chan struct{})
+	defer close(release)
+	// a worker that does not listen to its context
+	s.Go(func(context.Context) { <-release })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err := s.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stopping the workers") {
+		t.Fatalf("got %v, want the workers that did not stop", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %v with a shutdown timeout of 50ms", elapsed)
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/logging"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// the actions of the audit events
+const (
+	actionCreate         = "create"
+	actionUpdate         = "update"
+	actionDelete         = "delete"
+	actionVerifyEmail    = "verify_email"
+	actionResetPassword  = "reset_password"
+	actionChangePassword = "change_password"
+	actionDeactivate     = "deactivate"
+	actionSuspend        = "suspend"
+	actionBan            = "ban"
+	actionReactivate     = "reactivate"
+	actionErase          = "erase"
+	actionEnableTOTP     = "enable_two_factor"
+	actionDisableTOTP    = "disable_two_factor"
+	actionLinkIdentity   = "link"
+	actionUnlinkIdentity = "unlink"
+	actionRestore        = "restore"
+)
+
+// the audited entities besides the ones of the errors
+const (
+	entityTwoFactor = "two_factor"
+	entityIdentity  = "identity"
+)
+
+// MaxAuditEvents is the most events GetAuditEvents returns at once
+const MaxAuditEvents = 1000
+
+// secretFields never have their values in the audit log, only that they
+// changed. Besides the credentials these are the personal data of the users
+// and their profiles, so the log keeps none of it after EraseUser.
+var secretFields = map[string]bool{
+	"Password": true, "Secret": true, "Subject": true,
+	"Email": true, "Name": true, "Username": true,
+	"DisplayName": true, "Bio": true, "Website": true, "Links": true,
+}
+
+// change is a field in AuditEvent.Changes
+type change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// audit appends the event for a change of an entity to the unit of work of
+// the change, so there is no change without its event. before and after are
+// the entity before and after the change, nil when it did not exist. Every
+// service method that creates, updates or deletes calls it inside its
+// uow.Do. The bookkeeping of sign-ins, like used tokens and codes, is not
+// audited.
+func audit(ctx context.Context, repos repository.Repositories, action, entity string, id int64, before, after any) error {
+	changes, err := changesBetween(before, after)
+	if err != nil {
+		return err
+	}
+	event := models.AuditEvent{
+		Action:     action,
+		EntityType: entity,
+		EntityID:   id,
+		Changes:    changes,
+	}
+	if actor, ok := repository.ActorFromContext(ctx); ok {
+		event.ActorID = &actor
+	}
+	event.RequestID, _ = logging.OperationID(ctx)
+	_, err = repos.Audit.AppendEvent(ctx, event)
+	return err
+}
+
+// changesBetween returns the fields that differ between before and after as a JSON
+// object
+func changesBetween(before, after any) (string, error) {
+	old, err := fields(before)
+	if err != nil {
+		return "", err
+	}
+	updated, err := fields(after)
+	if err != nil {
+		return "", err
+	}
+
+	changes := map[string]change{}
+	for name, value := range old {
+		if !reflect.DeepEqual(value, updated[name]) {
+			changes[name] = change{Before: value, After: updated[name]}
+		}
+	}
+	for name, value := range updated {
+		if _, ok := old[name]; !ok && value != nil {
+			changes[name] = change{Before: nil, After: value}
+		}
+	}
+	for name, c := range changes {
+		if secretFields[name] {
+			changes[name] = change{Before: redact(c.Before), After: redact(c.After)}
+		}
+	}
+	data, err := json.Marshal(changes)
+	return string(data), err
+}
+
+// fields returns the fields of an entity by their names, nil for nil
+func fields(entity any) (map[string]any, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]any
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// redact hides value, an empty one is kept to show it was set or cleared
+func redact(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return v
+	case string:
+		if v == "" {
+			return v
+		}
+	case []any:
+		if len(v) == 0 {
+			return v
+		}
+	}
+	return logging.Redacted
+}
+
+// avatarChange is what the audit log keeps of an avatar, not the image
+type avatarChange struct {
+	ContentType string
+	Size        int
+}
+
+func avatarOf(avatar *models.Avatar) *avatarChange {
+	if avatar == nil {
+		return nil
+	}
+	return &avatarChange{ContentType: avatar.ContentType, Size: len(avatar.Data)}
+}
+
+// AuditService answers questions about the audit log
+type AuditService struct {
+	AuditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) *AuditService {
+	return &AuditService{AuditRepo: auditRepo}
+}
+
+// GetAuditEvents returns the events matching filter, the newest first and
+// at most MaxAuditEvents of them
+func (auditService *AuditService) GetAuditEvents(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEvent, error) {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, apperr.Invalid(entityAudit, "since", "since has to be before until")
+	}
+	if filter.Limit < 0 {
+		return nil, apperr.Invalid(entityAudit, "limit", "the limit cannot be negative")
+	}
+	if filter.Limit == 0 || filter.Limit > MaxAuditEvents {
+		filter.Limit = MaxAuditEvents
+	}
+	events, err := auditService.AuditRepo.FindEvents(ctx, filter)
+	return events, apperr.Wrap(err, entityAudit)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+func TestAuditKeepsNoPersonalData(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+
+	user, err := services.Users.CreateUser(ctx, models.User{Name: "Alice Liddell", Email: "alice@wonderland.example", Username: "alice", Password: "rabbit hole"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = repository.WithActor(ctx, user.ID)
+	_, err = services.Profiles.UpdateProfile(ctx, models.Profile{
+		UserID:      user.ID,
+		DisplayName: "Alice L.",
+		Bio:         "Curiouser and curiouser",
+		Website:     "https://wonderland.example",
+		Links:       []models.Link{{Label: "mastodon", URL: "https://social.example/@alice"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Users.EraseUser(ctx, user.ID, KeepContent); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.Repositories().Audit.FindEvents(ctx, repository.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 {
+		t.Fatal("no audit events")
+	}
+	personal := []string{"Alice", "alice", "wonderland", "rabbit", "Curiouser", "mastodon", "social.example"}
+	for _, event := range events {
+		for _, value := range personal {
+			if strings.Contains(event.Changes, value) {
+				t.Errorf("the %s %s event keeps %q: %s", event.Action, event.EntityType, value, event.Changes)
+			}
+		}
+	}
+}
@@ -3,82 +3,123 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"strings"
 
 	// "fmt"
 	// "log"
+	"postgresql-blog/apperr"
 	"postgresql-blog/models"
 	"postgresql-blog/repository"
-
-	"gorm.io/gorm"
 )
 
 type CommentService struct {
-	CommentRepo repository.CommentRepository
-	db          *gorm.DB
+	CommentRepo  repository.CommentRepository
+	ReactionRepo repository.ReactionRepository
+	uow          repository.UnitOfWork
 }
 
-func NewCommentService(commentRepo repository.CommentRepository, db *gorm.DB) *CommentService {
+func NewCommentService(commentRepo repository.CommentRepository, reactionRepo repository.ReactionRepository, uow repository.UnitOfWork) *CommentService {
 	return &CommentService{
-		CommentRepo: commentRepo,
-		db:          db,
+		CommentRepo:  commentRepo,
+		ReactionRepo: reactionRepo,
+		uow:          uow,
 	}
 }
 
 func (commentService *CommentService) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
-	_, err := commentService.CommentRepo.GetCommentByUserIDPostID(ctx, int64(comment.UserID), int64(comment.PostID))
-	if err == nil || !errors.Is(err, repository.ErrNotExist) {
-		return nil, errors.New("a comment with the post already exists")
+	if strings.TrimSpace(comment.Content) == "" {
+		return nil, apperr.Invalid(entityComment, "content", "a comment needs content")
 	}
 
-	return commentService.CommentRepo.CreateComment(ctx, comment)
-}
+	var created *models.Comment
+	err := commentService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		_, err := repos.Comments.GetCommentByUserIDPostID(ctx, int64(comment.UserID), int64(comment.PostID))
+		if err == nil {
+			return &apperr.Error{
+				Kind:    apperr.Conflict,
+				Code:    "already_commented",
+				Entity:  entityComment,
+				Field:   "post_id",
+				Message: "a comment with the post already exists",
+				Err:     repository.ErrDuplicate,
+			}
+		}
+		if !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
 
-func (commentService *CommentService) GetAllComments(ctx context.Context) ([]models.Comment, error) {
-	var allComments []models.GormComment
-	if err := commentService.db.WithContext(ctx).Find(&allComments).Error; err != nil {
+		if err := checkVerified(ctx, repos, comment.UserID); err != nil {
+			return err
+		}
+		comment.Reactions = nil
+		// a new comment has no votes yet
+		comment.Upvotes, comment.Downvotes, comment.Score, comment.Controversy = 0, 0, 0, 0
+		created, err = repos.Comments.CreateComment(ctx, comment)
+		if err == nil {
+			return audit(ctx, repos, actionCreate, entityComment, created.ID, nil, created)
+		}
+		if errors.Is(err, repository.ErrForeignKey) {
+			return &apperr.Error{
+				Kind:    apperr.Validation,
+				Code:    "unknown_post",
+				Entity:  entityComment,
+				Field:   "post_id",
+				Message: "the post of the comment does not exist",
+				Err:     err,
+			}
+		}
+		return err
+	})
+	if err != nil {
+		err = apperr.Wrap(err, entityComment)
+		logResult(ctx, "create comment", err, slog.Uint64("post_id", comment.PostID))
 		return nil, err
 	}
+	logResult(ctx, "create comment", nil, slog.Int64("comment_id", created.ID), slog.Uint64("post_id", created.PostID))
+	return created, nil
+}
 
-	var result []models.Comment
-	for _, comment := range allComments {
-		result = append(result, models.Comment(comment))
-	}
-
-	return result, nil
+func (commentService *CommentService) GetAllComments(ctx context.Context) ([]models.Comment, error) {
+	comments, err := commentService.CommentRepo.AllComments(ctx)
+	return comments, apperr.Wrap(err, entityComment)
 }
 
+// GetCommentByID returns the comment with its reactions
 func (commentService *CommentService) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
 	comment, err := commentService.CommentRepo.GetCommentByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
-		return nil, err
+		return nil, apperr.Wrap(err, entityComment)
 	}
-
-	return comment, nil
+	comments := []models.Comment{*comment}
+	if err := countComments(ctx, commentService.ReactionRepo, comments); err != nil {
+		return nil, apperr.Wrap(err, entityReaction)
+	}
+	return &comments[0], nil
 }
 
 func (commentService *CommentService) GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error) {
 	comment, err := commentService.CommentRepo.GetCommentByUserID(ctx, userid)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
-		return nil, err
+		return nil, apperr.Wrap(err, entityComment)
 	}
 	return comment, nil
 }
 
-func (commentService *CommentService) GetCommentByPostID(ctx context.Context, postid int64) ([]models.Comment, error) {
-	comment, err := commentService.CommentRepo.GetCommentByPostID(ctx, postid)
+// GetCommentByPostID returns the comments of a post in the order of sort
+// with their reactions, counted for all of them at once
+func (commentService *CommentService) GetCommentByPostID(ctx context.Context, postid int64, sort repository.CommentSort) ([]models.Comment, error) {
+	order, err := checkCommentSort(sort)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
 		return nil, err
 	}
+	comment, err := commentService.CommentRepo.GetCommentByPostID(ctx, postid, order)
+	if err != nil {
+		return nil, apperr.Wrap(err, entityComment)
+	}
+	if err := countComments(ctx, commentService.ReactionRepo, comment); err != nil {
+		return nil, apperr.Wrap(err, entityReaction)
+	}
 
 	return comment, nil
 }
@@ -86,31 +127,61 @@ func (commentService *CommentService) GetCommentByPostID(ctx context.Context, po
 func (commentService *CommentService) GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error) {
 	comment, err := commentService.CommentRepo.GetCommentByUserIDPostID(ctx, userid, postid)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			log.Printf("Post with user id '%d' and post id '%d' does not exist in the repository\n", userid, postid)
-			return nil, err
+		if !errors.Is(err, repository.ErrNotExist) {
+			slog.ErrorContext(ctx, "looking up comment failed", slog.Int64("user_id", userid), slog.Int64("post_id", postid), slog.Any("error", err))
 		}
-		log.Printf("Error getting post by user id '%d' and post id '%d': %v\n", userid, postid, err)
-		return nil, err
+		return nil, apperr.Wrap(err, entityComment)
 	}
-	log.Printf("Post found by user id '%d' and post id '%d': %+v\n", userid, postid, comment)
 	return comment, nil
 }
 
 func (commentService *CommentService) UpdateCommentByID(ctx context.Context, comment models.Comment) (*models.Comment, error) {
-	existingComment, err := commentService.CommentRepo.GetCommentByID(ctx, comment.ID)
+	var existingComment *models.Comment
+	err := commentService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		existingComment, err = repos.Comments.GetCommentByID(ctx, comment.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkActive(ctx, repos, int64(existingComment.UserID)); err != nil {
+			return err
+		}
+		comment.Reactions = nil
+		// the votes only change with VoteComment
+		comment.Upvotes, comment.Downvotes = existingComment.Upvotes, existingComment.Downvotes
+		comment.Score, comment.Controversy = existingComment.Score, existingComment.Controversy
+		updated, err := repos.Comments.UpdateComment(ctx, comment.ID, comment)
+		if err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionUpdate, entityComment, comment.ID, existingComment, updated)
+	})
+	err = apperr.Wrap(err, entityComment)
+	logResult(ctx, "update comment", err, slog.Int64("comment_id", comment.ID))
 	if err != nil {
 		return nil, err
 	}
-	if _, err := commentService.CommentRepo.UpdateComment(ctx, comment.ID, comment); err != nil {
-		return nil, err
-	}
 	return existingComment, nil
 }
 
+// DeleteCommentByID deletes a comment with its votes and reactions, the
+// author has to be active
 func (commentService *CommentService) DeleteCommentByID(ctx context.Context, id int64) error {
-	if err := commentService.CommentRepo.DeleteComment(ctx, id); err != nil {
-		return err
-	}
-	return nil
+	err := commentService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		comment, err := repos.Comments.GetCommentByID(ctx, id)
+		if errors.Is(err, repository.ErrNotExist) {
+			// deleting a missing comment fails like the repository does
+			return repository.ErrDeleteFailed
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkActive(ctx, repos, int64(comment.UserID)); err != nil {
+			return err
+		}
+		return deleteComment(ctx, repos, *comment)
+	})
+	err = apperr.Wrap(err, entityComment)
+	logResult(ctx, "delete comment", err, slog.Int64("comment_id", id))
+	return err
 }
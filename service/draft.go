@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// DraftState is a draft with what happened to its post since the draft was
+// started. Only the author drafts a post, so the edits are from other
+// sessions of the author or restored revisions.
+type DraftState struct {
+	Draft models.Draft
+	// Edits are the revisions of the post saved since the draft was
+	// started, applying the draft overwrites them
+	Edits []models.PostRevision
+}
+
+// Stale reports whether the post changed since the draft was started
+func (s *DraftState) Stale() bool {
+	return len(s.Edits) > 0
+}
+
+// Warnings tells the user about the edits
+func (s *DraftState) Warnings() []string {
+	var warnings []string
+	for _, revision := range s.Edits {
+		editor := "somebody"
+		if revision.EditorID != nil {
+			editor = fmt.Sprintf("user %d", *revision.EditorID)
+		}
+		warnings = append(warnings, fmt.Sprintf("post %d was changed by %s at %s (revision %d) since the draft was started",
+			revision.PostID, editor, revision.CreatedAt.Format(time.DateTime), revision.Number))
+	}
+	return warnings
+}
+
+// ApplyDraftOptions are how ApplyDraft saves a draft
+type ApplyDraftOptions struct {
+	// Force overwrites the edits of the post made since the draft was
+	// started
+	Force bool
+	// Publish publishes the post if it is not published yet
+	Publish bool
+}
+
+// SaveDraft stores the draft of draft.UserID for draft.PostID, 0 for a new
+// post, replacing the one saved before. Only the author of a post drafts
+// it. It is called for every autosave and is not logged.
+func (postService *PostService) SaveDraft(ctx context.Context, draft models.Draft) (*DraftState, error) {
+	var state *DraftState
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := checkActive(ctx, repos, draft.UserID); err != nil {
+			return err
+		}
+		if draft.PostID != 0 {
+			if _, err := ownPost(ctx, repos, draft.UserID, draft.PostID); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		existing, err := repos.Drafts.GetDraft(ctx, draft.UserID, draft.PostID)
+		switch {
+		case err == nil:
+			draft.ID, draft.BaseRevision, draft.CreatedAt = existing.ID, existing.BaseRevision, existing.CreatedAt
+		case errors.Is(err, repository.ErrNotExist):
+			draft.ID, draft.BaseRevision, draft.CreatedAt = 0, 0, now
+			if draft.PostID != 0 {
+				if draft.BaseRevision, err = currentRevision(ctx, repos, draft.PostID); err != nil {
+					return err
+				}
+			}
+		default:
+			return err
+		}
+		draft.UpdatedAt = now
+
+		saved, err := repos.Drafts.SaveDraft(ctx, draft)
+		if err != nil {
+			return err
+		}
+		state, err = draftState(ctx, repos, *saved)
+		return err
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityDraft)
+	}
+	return state, nil
+}
+
+// ownPost returns the post of a draft, Forbidden unless the user wrote it
+func ownPost(ctx context.Context, repos repository.Repositories, userID, postID int64) (*models.Post, error) {
+	post, err := repos.Posts.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, apperr.Wrap(err, entityPost)
+	}
+	if int64(post.UserID) != userID {
+		return nil, apperr.New(apperr.Forbidden, entityPost, fmt.Sprintf("post %d belongs to another user", postID))
+	}
+	return post, nil
+}
+
+// currentRevision returns the number of the newest revision of a post, the
+// one a draft starts from. It is 0 for a post written before there were
+// revisions.
+func currentRevision(ctx context.Context, repos repository.Repositories, postID int64) (int, error) {
+	revisions, err := repos.Revisions.GetRevisions(ctx, postID)
+	if err != nil || len(revisions) == 0 {
+		return 0, err
+	}
+	return revisions[len(revisions)-1].Number, nil
+}
+
+func draftState(ctx context.Context, repos repository.Repositories, draft models.Draft) (*DraftState, error) {
+	state := &DraftState{Draft: draft}
+	if draft.PostID == 0 {
+		return state, nil
+	}
+	revisions, err := repos.Revisions.GetRevisions(ctx, draft.PostID)
+	if err != nil {
+		return nil, err
+	}
+	for _, revision := range revisions {
+		// a post without revisions gets its first one, the post as it was
+		// before, with the first edit after the draft was started
+		if draft.BaseRevision == 0 && revision.Number == 1 && revision.CreatedAt.Before(draft.CreatedAt) {
+			continue
+		}
+		if revision.Number > draft.BaseRevision {
+			state.Edits = append(state.Edits, revision)
+		}
+	}
+	return state, nil
+}
+
+// GetDraft returns the draft of a user for a post to resume it, postID 0
+// for the draft of a new post
+func (postService *PostService) GetDraft(ctx context.Context, userID, postID int64) (*DraftState, error) {
+	var state *DraftState
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		draft, err := repos.Drafts.GetDraft(ctx, userID, postID)
+		if err != nil {
+			return err
+		}
+		state, err = draftState(ctx, repos, *draft)
+		return err
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityDraft)
+	}
+	return state, nil
+}
+
+// GetDrafts returns the drafts of a user, the last saved first
+func (postService *PostService) GetDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	drafts, err := postService.DraftRepo.GetUserDrafts(ctx, userID)
+	return drafts, apperr.Wrap(err, entityDraft)
+}
+
+func (postService *PostService) DiscardDraft(ctx context.Context, userID, postID int64) error {
+	err := apperr.Wrap(postService.DraftRepo.DeleteDraft(ctx, userID, postID), entityDraft)
+	logResult(ctx, "discard draft", err, slog.Int64("user_id", userID), slog.Int64("post_id", postID))
+	return err
+}
+
+// ApplyDraft saves a draft to its post, or creates the post of a new one,
+// and deletes the draft. A post changed since the draft was started is
+// only overwritten with opts.Force.
+func (postService *PostService) ApplyDraft(ctx context.Context, userID, postID int64, opts ApplyDraftOptions) (*models.Post, error) {
+	var saved *models.Post
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		draft, err := repos.Drafts.GetDraft(ctx, userID, postID)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(draft.Title) == "" {
+			return apperr.Invalid(entityPost, "title", "a post needs a title")
+		}
+		now := time.Now()
+
+		if postID == 0 {
+			post := models.Post{
+				UserID:      uint64(userID),
+				Title:       draft.Title,
+				Content:     draft.Content,
+				Thumbnail:   draft.Thumbnail,
+				IsPublished: opts.Publish,
+			}
+			if opts.Publish {
+				post.PublishedAt = now
+			}
+			if saved, err = postService.create(ctx, repos, post); err != nil {
+				return apperr.Wrap(err, entityPost)
+			}
+		} else {
+			post, err := ownPost(ctx, repos, userID, postID)
+			if err != nil {
+				return err
+			}
+			state, err := draftState(ctx, repos, *draft)
+			if err != nil {
+				return err
+			}
+			if state.Stale() && !opts.Force {
+				return &apperr.Error{
+					Kind:    apperr.Conflict,
+					Code:    "post_changed",
+					Entity:  entityDraft,
+					Message: fmt.Sprintf("post %d was changed since the draft was started, compare its revisions or apply the draft with force", postID),
+				}
+			}
+			updated := *post
+			updated.Title, updated.Content, updated.Thumbnail = draft.Title, draft.Content, draft.Thumbnail
+			updated.UpdatedAt = now
+			if opts.Publish && !updated.IsPublished {
+				updated.IsPublished, updated.PublishedAt = true, now
+			}
+			if _, saved, err = postService.update(ctx, repos, updated); err != nil {
+				return apperr.Wrap(err, entityPost)
+			}
+		}
+		return repos.Drafts.DeleteDraft(ctx, userID, postID)
+	})
+	err = apperr.Wrap(err, entityDraft)
+	logResult(ctx, "apply draft", err, slog.Int64("user_id", userID), slog.Int64("post_id", postID))
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
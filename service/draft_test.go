@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+// verifiedUser creates a user who confirmed the email address
+func verifiedUser(t *testing.T, repos repository.Repositories, username string) *models.User {
+	t.Helper()
+	now := time.Now()
+	user, err := repos.Users.CreateUser(context.Background(), models.User{Username: username, Email: username + "@localhost", Password: "secret", EmailVerifiedAt: &now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestDraftsOfOwnPostsOnly(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	repos := store.Repositories()
+	alice, bob := verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob")
+	post, err := services.Posts.CreatePost(repository.WithActor(ctx, alice.ID), models.Post{UserID: uint64(alice.ID), Title: "mine", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = services.Posts.SaveDraft(ctx, models.Draft{UserID: bob.ID, PostID: post.ID, Title: "taken over"})
+	if !apperr.Is(err, apperr.Forbidden) {
+		t.Fatalf("got %v saving a draft of the post of another user, want Forbidden", err)
+	}
+	// a draft that got there some other way is not applied either
+	if _, err := repos.Drafts.SaveDraft(ctx, models.Draft{UserID: bob.ID, PostID: post.ID, Title: "taken over"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.Posts.ApplyDraft(ctx, bob.ID, post.ID, ApplyDraftOptions{Force: true}); !apperr.Is(err, apperr.Forbidden) {
+		t.Fatalf("got %v applying a draft to the post of another user, want Forbidden", err)
+	}
+	unchanged, err := services.Posts.GetPostByID(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.Title != "mine" {
+		t.Fatalf("the post was changed to %q", unchanged.Title)
+	}
+
+	if _, err := services.Posts.SaveDraft(ctx, models.Draft{UserID: alice.ID, PostID: post.ID, Title: "mine, edited"}); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := services.Posts.ApplyDraft(ctx, alice.ID, post.ID, ApplyDraftOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.Title != "mine, edited" {
+		t.Fatalf("got title %q after applying the draft", applied.Title)
+	}
+}
+
+func TestDraftOfPostWithoutRevisions(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	repos := store.Repositories()
+	alice := verifiedUser(t, repos, "alice")
+	// a post written before there were revisions
+	post, err := repos.Posts.CreatePost(ctx, models.Post{UserID: uint64(alice.ID), Title: "old", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := services.Posts.SaveDraft(ctx, models.Draft{UserID: alice.ID, PostID: post.ID, Title: "draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Stale() {
+		t.Fatalf("a new draft is stale: %v", state.Warnings())
+	}
+	revisions, err := services.Posts.GetPostRevisions(ctx, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Fatalf("saving a draft added %d revisions", len(revisions))
+	}
+
+	// the first edit adds the revision of the post as it was and its own
+	edited := *post
+	edited.Title = "edited"
+	if _, err := services.Posts.UpdatePostByID(repository.WithActor(ctx, alice.ID), edited); err != nil {
+		t.Fatal(err)
+	}
+	state, err = services.Posts.GetDraft(ctx, alice.ID, post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Edits) != 1 || state.Edits[0].Title != "edited" {
+		t.Fatalf("got the edits %+v, want the one edit", state.Edits)
+	}
+}
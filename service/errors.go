@@ -0,0 +1,15 @@
+package service
+
+// the entities the errors of the services are about
+const (
+	entityUser     = "user"
+	entityPost     = "post"
+	entityComment  = "comment"
+	entityProfile  = "profile"
+	entityAvatar   = "avatar"
+	entityAudit    = "audit_event"
+	entityRevision = "revision"
+	entityDraft    = "draft"
+	entityReaction = "reaction"
+	entityVote     = "vote"
+)
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// ExportedUser is the user in an export, without the password
+type ExportedUser struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Username        string     `json:"username"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Status          string     `json:"status"`
+	SuspendedUntil  *time.Time `json:"suspended_until,omitempty"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+}
+
+// ExportedSecurity is what the export tells about the second factor, the
+// secrets stay out of it
+type ExportedSecurity struct {
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at"`
+}
+
+// UserExport is everything stored about a user
+type UserExport struct {
+	ExportedAt time.Time
+	User       ExportedUser
+	Profile    models.Profile
+	Avatar     *models.Avatar
+	Posts      []models.Post
+	Revisions  []models.PostRevision
+	Drafts     []models.Draft
+	Comments   []models.Comment
+	Reactions  []models.Reaction
+	Votes      []models.CommentVote
+	Identities []models.Identity
+	Security   ExportedSecurity
+}
+
+// ExportUser collects everything stored about a user, for the user to take
+// away
+func (userService *UserService) ExportUser(ctx context.Context, id int64) (*UserExport, error) {
+	export := &UserExport{ExportedAt: time.Now()}
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		export.User = ExportedUser{
+			ID:              user.ID,
+			Name:            user.Name,
+			Email:           user.Email,
+			Username:        user.Username,
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			Status:          user.State(export.ExportedAt),
+			SuspendedUntil:  user.SuspendedUntil,
+			StatusReason:    user.StatusReason,
+		}
+
+		profile, err := profileOf(ctx, repos, id)
+		if err != nil {
+			return err
+		}
+		export.Profile = *profile
+		if export.Avatar, err = repos.Profiles.GetAvatar(ctx, id); err != nil && !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+		if export.Posts, err = repos.Posts.GetPostByUserID(ctx, id); err != nil && !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+		for _, post := range export.Posts {
+			revisions, err := repos.Revisions.GetRevisions(ctx, post.ID)
+			if err != nil {
+				return err
+			}
+			export.Revisions = append(export.Revisions, revisions...)
+		}
+		if export.Drafts, err = repos.Drafts.GetUserDrafts(ctx, id); err != nil {
+			return err
+		}
+		if export.Comments, err = repos.Comments.GetCommentByUserID(ctx, id); err != nil && !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+		if export.Reactions, err = repos.Reactions.GetUserReactions(ctx, id); err != nil {
+			return err
+		}
+		if export.Votes, err = repos.Votes.GetUserVotes(ctx, id); err != nil {
+			return err
+		}
+		if export.Identities, err = repos.Identities.GetUserIdentities(ctx, id); err != nil {
+			return err
+		}
+		twoFactor, err := enabledTwoFactor(ctx, repos, id)
+		if err != nil {
+			return err
+		}
+		if twoFactor != nil {
+			export.Security.TwoFactorEnabledAt = twoFactor.EnabledAt
+		}
+		return nil
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "export user", err, slog.Int64("user_id", id))
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// WriteZip writes the export as a zip archive of JSON files, with the
+// avatar as an image of its own
+func (e *UserExport) WriteZip(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"user.json", e.User},
+		{"profile.json", e.Profile},
+		{"posts.json", nonNil(e.Posts)},
+		{"revisions.json", nonNil(e.Revisions)},
+		{"drafts.json", nonNil(e.Drafts)},
+		{"comments.json", nonNil(e.Comments)},
+		{"reactions.json", nonNil(e.Reactions)},
+		{"votes.json", nonNil(e.Votes)},
+		{"identities.json", nonNil(e.Identities)},
+		{"security.json", e.Security},
+	}
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: e.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.value); err != nil {
+			return err
+		}
+	}
+	if e.Avatar != nil {
+		name := "avatar." + strings.TrimPrefix(e.Avatar.ContentType, "image/")
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: e.Avatar.UpdatedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(e.Avatar.Data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// nonNil makes empty lists [] instead of null in the JSON
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// ExternalIdentity is a user as a single sign-on provider vouched for it
+type ExternalIdentity struct {
+	Provider string
+	// Subject is the id of the account at the provider
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Username is the username the provider suggests, the email address is
+	// used without one
+	Username string
+}
+
+// LoginWithIdentity signs in the user linked to the identity. An identity
+// nobody is linked to gets a new user, unless its email address belongs to
+// a user already: linking that one takes the password, see LinkIdentity.
+// Users with two factors give a code as for LoginWithCode, without one the
+// sign-in fails with ErrTOTPRequired.
+func (userService *UserService) LoginWithIdentity(ctx context.Context, identity ExternalIdentity, code string) (*models.User, error) {
+	if identity.Provider == "" || identity.Subject == "" {
+		return nil, apperr.Invalid(entityUser, "identity", "the provider sent no subject")
+	}
+
+	var user *models.User
+	var m *tokenMail
+	created := false
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		linked, err := repos.Identities.GetIdentity(ctx, identity.Provider, identity.Subject)
+		if err == nil {
+			user, err = signInLinked(ctx, repos, *linked, identity, code)
+			return err
+		}
+		if !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+
+		user, m, err = createFromIdentity(ctx, repos, identity)
+		created = err == nil
+		return err
+	})
+	err = apperr.Wrap(err, entityUser)
+	if err != nil {
+		logResult(ctx, "login with identity", err, slog.String("provider", identity.Provider))
+		return nil, err
+	}
+	if created {
+		logResult(ctx, "create user from identity", nil, slog.Int64("user_id", user.ID), slog.String("provider", identity.Provider))
+	}
+	userService.send(ctx, m)
+	return user, nil
+}
+
+// signInLinked checks the second factor and records the sign-in. An email
+// address the provider verified counts as confirmed when it is the one of
+// the user.
+func signInLinked(ctx context.Context, repos repository.Repositories, linked models.Identity, identity ExternalIdentity, code string) (*models.User, error) {
+	user, err := repos.Users.GetUserByID(ctx, linked.UserID)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := enabledTwoFactor(ctx, repos, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil {
+		if err := checkCode(ctx, repos, *twoFactor, code); err != nil {
+			return nil, err
+		}
+	}
+	if err := loginState(ctx, repos, user); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := repos.Identities.TouchIdentity(ctx, linked.ID, identity.Email, now); err != nil {
+		return nil, err
+	}
+	if identity.EmailVerified && user.EmailVerifiedAt == nil && strings.EqualFold(identity.Email, user.Email) {
+		before := *user
+		user.EmailVerifiedAt = &now
+		if _, err := repos.Users.UpdateUser(ctx, user.ID, *user); err != nil {
+			return nil, err
+		}
+		if err := audit(ctx, repos, actionVerifyEmail, entityUser, user.ID, before, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+func createFromIdentity(ctx context.Context, repos repository.Repositories, identity ExternalIdentity) (*models.User, *tokenMail, error) {
+	email := strings.TrimSpace(identity.Email)
+	if email == "" {
+		return nil, nil, apperr.Invalid(entityUser, "email", "the provider sent no email address")
+	}
+	_, err := repos.Users.GetUserByEmail(ctx, email)
+	if err == nil {
+		return nil, nil, &apperr.Error{
+			Kind:    apperr.Conflict,
+			Code:    "identity_not_linked",
+			Entity:  entityUser,
+			Field:   "email",
+			Message: "a user with this email already exists, log in with the password and link the provider",
+		}
+	}
+	if !errors.Is(err, repository.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	// the user signs in through the provider, nobody knows the password
+	// until it is reset
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, nil, err
+	}
+	user := models.User{
+		Name:     identity.Name,
+		Email:    email,
+		Username: identity.Username,
+		Password: base64.RawURLEncoding.EncodeToString(raw),
+	}
+	if user.Username == "" {
+		user.Username = email
+	}
+	if user.Name == "" {
+		user.Name = user.Username
+	}
+	now := time.Now()
+	if identity.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+
+	created, err := repos.Users.CreateUser(ctx, user)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, nil, &apperr.Error{
+			Kind:    apperr.Conflict,
+			Code:    "username_taken",
+			Entity:  entityUser,
+			Field:   "username",
+			Message: "the username " + user.Username + " is taken, log in with the password and link the provider",
+			Err:     err,
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := audit(ctx, repos, actionCreate, entityUser, created.ID, nil, created); err != nil {
+		return nil, nil, err
+	}
+	linked, err := repos.Identities.CreateIdentity(ctx, models.Identity{
+		UserID:     created.ID,
+		Provider:   identity.Provider,
+		Subject:    identity.Subject,
+		Email:      email,
+		LastUsedAt: &now,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := audit(ctx, repos, actionLinkIdentity, entityIdentity, linked.ID, nil, linked); err != nil {
+		return nil, nil, err
+	}
+	var m *tokenMail
+	if created.EmailVerifiedAt == nil {
+		if m, err = verificationMail(ctx, repos, *created); err != nil {
+			return nil, nil, err
+		}
+	}
+	return created, m, nil
+}
+
+// LinkIdentity lets a user sign in through a provider too, a user has at most
+// one identity per provider
+func (userService *UserService) LinkIdentity(ctx context.Context, userID int64, identity ExternalIdentity) (*models.Identity, error) {
+	if identity.Provider == "" || identity.Subject == "" {
+		return nil, apperr.Invalid(entityUser, "identity", "the provider sent no subject")
+	}
+
+	var linked *models.Identity
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if _, err := repos.Users.GetUserByID(ctx, userID); err != nil {
+			return err
+		}
+		existing, err := repos.Identities.GetIdentity(ctx, identity.Provider, identity.Subject)
+		if err == nil {
+			if existing.UserID == userID {
+				linked = existing
+				return nil
+			}
+			return &apperr.Error{Kind: apperr.Conflict, Code: "identity_taken", Entity: entityUser, Message: "this account of " + identity.Provider + " is linked to another user"}
+		}
+		if !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+		identities, err := repos.Identities.GetUserIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, other := range identities {
+			if other.Provider == identity.Provider {
+				return &apperr.Error{Kind: apperr.Conflict, Code: "provider_linked", Entity: entityUser, Message: "another account of " + identity.Provider + " is linked already, unlink it first"}
+			}
+		}
+		linked, err = repos.Identities.CreateIdentity(ctx, models.Identity{
+			UserID:   userID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionLinkIdentity, entityIdentity, linked.ID, nil, linked)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "link identity", err, slog.Int64("user_id", userID), slog.String("provider", identity.Provider))
+	if err != nil {
+		return nil, err
+	}
+	return linked, nil
+}
+
+// UnlinkIdentity stops a user from signing in through provider
+func (userService *UserService) UnlinkIdentity(ctx context.Context, userID int64, provider string) error {
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		identities, err := repos.Identities.GetUserIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, identity := range identities {
+			if identity.Provider == provider {
+				if err := repos.Identities.DeleteUserIdentities(ctx, userID, provider); err != nil {
+					return err
+				}
+				return audit(ctx, repos, actionUnlinkIdentity, entityIdentity, identity.ID, identity, nil)
+			}
+		}
+		return &apperr.Error{Kind: apperr.NotFound, Entity: entityUser, Field: "provider", Message: "no account of " + provider + " is linked", Err: repository.ErrNotExist}
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "unlink identity", err, slog.Int64("user_id", userID), slog.String("provider", provider))
+	return err
+}
+
+func (userService *UserService) GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error) {
+	var identities []models.Identity
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		identities, err = repos.Identities.GetUserIdentities(ctx, userID)
+		return err
+	})
+	return identities, apperr.Wrap(err, entityUser)
+}
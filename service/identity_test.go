@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"postgresql-blog/totp"
+)
+
+func TestLoginWithIdentityNeedsSecondFactor(t *testing.T) {
+	users, _, user, secret, codes := enrolledUser(t)
+	ctx := context.Background()
+	identity := ExternalIdentity{Provider: "idp", Subject: "alice-at-idp", Email: user.Email, EmailVerified: true}
+	if _, err := users.LinkIdentity(ctx, user.ID, identity); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.LoginWithIdentity(ctx, identity, ""); !errors.Is(err, ErrTOTPRequired) {
+		t.Fatalf("got %v without a code, want ErrTOTPRequired", err)
+	}
+	// the code of the confirmation was used already
+	if _, err := users.LoginWithIdentity(ctx, identity, totp.DefaultParams.Generate(secret, time.Now())); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v for a used code, want ErrInvalidCode", err)
+	}
+	signedIn, err := users.LoginWithIdentity(ctx, identity, codes[0])
+	if err != nil {
+		t.Fatalf("got %v for a recovery code, want the sign-in", err)
+	}
+	if signedIn.ID != user.ID {
+		t.Fatalf("signed in as user %d, want %d", signedIn.ID, user.ID)
+	}
+
+	// users without two factors need no code
+	other := ExternalIdentity{Provider: "idp", Subject: "bob-at-idp", Email: "bob@localhost", Username: "bob"}
+	for i := 0; i < 2; i++ {
+		if _, err := users.LoginWithIdentity(ctx, other, ""); err != nil {
+			t.Fatalf("sign-in %d of a user without two factors: %v", i+1, err)
+		}
+	}
+}
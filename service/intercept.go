@@ -0,0 +1,447 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/intercept"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// Intercept returns the services with every call going through
+// interceptors, the first one is the outermost
+func Intercept(s Services, interceptors ...intercept.Interceptor) Services {
+	interceptor := intercept.Chain(interceptors...)
+	return Services{
+		Users:     &interceptedUsers{next: s.Users, interceptor: interceptor},
+		Posts:     &interceptedPosts{next: s.Posts, interceptor: interceptor},
+		Comments:  &interceptedComments{next: s.Comments, interceptor: interceptor},
+		Profiles:  &interceptedProfiles{next: s.Profiles, interceptor: interceptor},
+		Reactions: &interceptedReactions{next: s.Reactions, interceptor: interceptor},
+		Audit:     &interceptedAudit{next: s.Audit, interceptor: interceptor},
+	}
+}
+
+func serviceOp(entity, typ, method string, id int64) intercept.Op {
+	return intercept.Op{Layer: intercept.LayerService, Entity: entity, Type: typ, Method: method, ID: id}
+}
+
+type interceptedUsers struct {
+	next        Users
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedUsers) op(method string, id int64) intercept.Op {
+	return serviceOp("users", "UserService", method, id)
+}
+
+func (s *interceptedUsers) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("CreateUser", 0), func(ctx context.Context) (*models.User, error) {
+		return s.next.CreateUser(ctx, user)
+	})
+}
+
+func (s *interceptedUsers) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetAllUsers", 0), s.next.GetAllUsers)
+}
+
+func (s *interceptedUsers) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetUserByID", id), func(ctx context.Context) (*models.User, error) {
+		return s.next.GetUserByID(ctx, id)
+	})
+}
+
+func (s *interceptedUsers) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetUserByEmail", 0), func(ctx context.Context) (*models.User, error) {
+		return s.next.GetUserByEmail(ctx, email)
+	})
+}
+
+func (s *interceptedUsers) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetUserByUsernameAndPassword", 0), func(ctx context.Context) (*models.User, error) {
+		return s.next.GetUserByUsernameAndPassword(ctx, username, password)
+	})
+}
+
+func (s *interceptedUsers) UpdateUserByID(ctx context.Context, user models.User) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("UpdateUserByID", user.ID), func(ctx context.Context) (*models.User, error) {
+		return s.next.UpdateUserByID(ctx, user)
+	})
+}
+
+func (s *interceptedUsers) DeleteUserByID(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DeleteUserByID", id), func(ctx context.Context) error {
+		return s.next.DeleteUserByID(ctx, id)
+	})
+}
+
+func (s *interceptedUsers) RequestEmailVerification(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("RequestEmailVerification", userID), func(ctx context.Context) error {
+		return s.next.RequestEmailVerification(ctx, userID)
+	})
+}
+
+func (s *interceptedUsers) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("VerifyEmail", 0), func(ctx context.Context) (*models.User, error) {
+		return s.next.VerifyEmail(ctx, token)
+	})
+}
+
+func (s *interceptedUsers) RequestPasswordReset(ctx context.Context, email string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("RequestPasswordReset", 0), func(ctx context.Context) error {
+		return s.next.RequestPasswordReset(ctx, email)
+	})
+}
+
+func (s *interceptedUsers) ResetPassword(ctx context.Context, token, password string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("ResetPassword", 0), func(ctx context.Context) error {
+		return s.next.ResetPassword(ctx, token, password)
+	})
+}
+
+func (s *interceptedUsers) ChangePassword(ctx context.Context, userID int64, current, password string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("ChangePassword", userID), func(ctx context.Context) error {
+		return s.next.ChangePassword(ctx, userID, current, password)
+	})
+}
+
+func (s *interceptedUsers) LoginWithCode(ctx context.Context, username, password, code string) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("LoginWithCode", 0), func(ctx context.Context) (*models.User, error) {
+		return s.next.LoginWithCode(ctx, username, password, code)
+	})
+}
+
+func (s *interceptedUsers) EnrollTOTP(ctx context.Context, userID int64) (*TOTPSetup, error) {
+	return intercept.One(ctx, s.interceptor, s.op("EnrollTOTP", userID), func(ctx context.Context) (*TOTPSetup, error) {
+		return s.next.EnrollTOTP(ctx, userID)
+	})
+}
+
+func (s *interceptedUsers) ConfirmTOTP(ctx context.Context, userID int64, code string) ([]string, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("ConfirmTOTP", userID), func(ctx context.Context) ([]string, error) {
+		return s.next.ConfirmTOTP(ctx, userID, code)
+	})
+}
+
+func (s *interceptedUsers) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DisableTOTP", userID), func(ctx context.Context) error {
+		return s.next.DisableTOTP(ctx, userID, code)
+	})
+}
+
+func (s *interceptedUsers) ResetTOTP(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("ResetTOTP", userID), func(ctx context.Context) error {
+		return s.next.ResetTOTP(ctx, userID)
+	})
+}
+
+func (s *interceptedUsers) LoginWithIdentity(ctx context.Context, identity ExternalIdentity, code string) (*models.User, error) {
+	return intercept.One(ctx, s.interceptor, s.op("LoginWithIdentity", 0), func(ctx context.Context) (*models.User, error) {
+		return s.next.LoginWithIdentity(ctx, identity, code)
+	})
+}
+
+func (s *interceptedUsers) LinkIdentity(ctx context.Context, userID int64, identity ExternalIdentity) (*models.Identity, error) {
+	return intercept.One(ctx, s.interceptor, s.op("LinkIdentity", userID), func(ctx context.Context) (*models.Identity, error) {
+		return s.next.LinkIdentity(ctx, userID, identity)
+	})
+}
+
+func (s *interceptedUsers) UnlinkIdentity(ctx context.Context, userID int64, provider string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("UnlinkIdentity", userID), func(ctx context.Context) error {
+		return s.next.UnlinkIdentity(ctx, userID, provider)
+	})
+}
+
+func (s *interceptedUsers) GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetUserIdentities", userID), func(ctx context.Context) ([]models.Identity, error) {
+		return s.next.GetUserIdentities(ctx, userID)
+	})
+}
+
+func (s *interceptedUsers) DeactivateUser(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DeactivateUser", id), func(ctx context.Context) error {
+		return s.next.DeactivateUser(ctx, id)
+	})
+}
+
+func (s *interceptedUsers) SuspendUser(ctx context.Context, id int64, until time.Time, reason string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("SuspendUser", id), func(ctx context.Context) error {
+		return s.next.SuspendUser(ctx, id, until, reason)
+	})
+}
+
+func (s *interceptedUsers) BanUser(ctx context.Context, id int64, reason string) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("BanUser", id), func(ctx context.Context) error {
+		return s.next.BanUser(ctx, id, reason)
+	})
+}
+
+func (s *interceptedUsers) ReactivateUser(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("ReactivateUser", id), func(ctx context.Context) error {
+		return s.next.ReactivateUser(ctx, id)
+	})
+}
+
+func (s *interceptedUsers) EraseUser(ctx context.Context, id int64, policy ContentPolicy) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("EraseUser", id), func(ctx context.Context) error {
+		return s.next.EraseUser(ctx, id, policy)
+	})
+}
+
+func (s *interceptedUsers) ExportUser(ctx context.Context, id int64) (*UserExport, error) {
+	return intercept.One(ctx, s.interceptor, s.op("ExportUser", id), func(ctx context.Context) (*UserExport, error) {
+		return s.next.ExportUser(ctx, id)
+	})
+}
+
+type interceptedPosts struct {
+	next        Posts
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedPosts) op(method string, id int64) intercept.Op {
+	return serviceOp("posts", "PostService", method, id)
+}
+
+func (s *interceptedPosts) CreatePost(ctx context.Context, post models.Post) (*models.Post, error) {
+	return intercept.One(ctx, s.interceptor, s.op("CreatePost", 0), func(ctx context.Context) (*models.Post, error) {
+		return s.next.CreatePost(ctx, post)
+	})
+}
+
+func (s *interceptedPosts) GetAllPosts(ctx context.Context) ([]models.Post, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetAllPosts", 0), s.next.GetAllPosts)
+}
+
+func (s *interceptedPosts) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetPostByID", id), func(ctx context.Context) (*models.Post, error) {
+		return s.next.GetPostByID(ctx, id)
+	})
+}
+
+func (s *interceptedPosts) GetPostByTitle(ctx context.Context, title string) (*models.Post, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetPostByTitle", 0), func(ctx context.Context) (*models.Post, error) {
+		return s.next.GetPostByTitle(ctx, title)
+	})
+}
+
+func (s *interceptedPosts) GetPostByUserID(ctx context.Context, userid int64) ([]models.Post, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetPostByUserID", userid), func(ctx context.Context) ([]models.Post, error) {
+		return s.next.GetPostByUserID(ctx, userid)
+	})
+}
+
+func (s *interceptedPosts) UpdatePostByID(ctx context.Context, post models.Post) (*models.Post, error) {
+	return intercept.One(ctx, s.interceptor, s.op("UpdatePostByID", post.ID), func(ctx context.Context) (*models.Post, error) {
+		return s.next.UpdatePostByID(ctx, post)
+	})
+}
+
+func (s *interceptedPosts) DeletePostByID(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DeletePostByID", id), func(ctx context.Context) error {
+		return s.next.DeletePostByID(ctx, id)
+	})
+}
+
+func (s *interceptedPosts) GetPostRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetPostRevisions", postID), func(ctx context.Context) ([]models.PostRevision, error) {
+		return s.next.GetPostRevisions(ctx, postID)
+	})
+}
+
+func (s *interceptedPosts) GetPostRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetPostRevision", postID), func(ctx context.Context) (*models.PostRevision, error) {
+		return s.next.GetPostRevision(ctx, postID, number)
+	})
+}
+
+func (s *interceptedPosts) DiffPostRevisions(ctx context.Context, postID int64, from, to int, words bool) (*RevisionDiff, error) {
+	return intercept.One(ctx, s.interceptor, s.op("DiffPostRevisions", postID), func(ctx context.Context) (*RevisionDiff, error) {
+		return s.next.DiffPostRevisions(ctx, postID, from, to, words)
+	})
+}
+
+func (s *interceptedPosts) RestorePostRevision(ctx context.Context, postID int64, number int) (*models.Post, error) {
+	return intercept.One(ctx, s.interceptor, s.op("RestorePostRevision", postID), func(ctx context.Context) (*models.Post, error) {
+		return s.next.RestorePostRevision(ctx, postID, number)
+	})
+}
+
+func (s *interceptedPosts) SaveDraft(ctx context.Context, draft models.Draft) (*DraftState, error) {
+	return intercept.One(ctx, s.interceptor, s.op("SaveDraft", draft.PostID), func(ctx context.Context) (*DraftState, error) {
+		return s.next.SaveDraft(ctx, draft)
+	})
+}
+
+func (s *interceptedPosts) GetDraft(ctx context.Context, userID, postID int64) (*DraftState, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetDraft", postID), func(ctx context.Context) (*DraftState, error) {
+		return s.next.GetDraft(ctx, userID, postID)
+	})
+}
+
+func (s *interceptedPosts) GetDrafts(ctx context.Context, userID int64) ([]models.Draft, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetDrafts", userID), func(ctx context.Context) ([]models.Draft, error) {
+		return s.next.GetDrafts(ctx, userID)
+	})
+}
+
+func (s *interceptedPosts) DiscardDraft(ctx context.Context, userID, postID int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DiscardDraft", postID), func(ctx context.Context) error {
+		return s.next.DiscardDraft(ctx, userID, postID)
+	})
+}
+
+func (s *interceptedPosts) ApplyDraft(ctx context.Context, userID, postID int64, opts ApplyDraftOptions) (*models.Post, error) {
+	return intercept.One(ctx, s.interceptor, s.op("ApplyDraft", postID), func(ctx context.Context) (*models.Post, error) {
+		return s.next.ApplyDraft(ctx, userID, postID, opts)
+	})
+}
+
+type interceptedComments struct {
+	next        Comments
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedComments) op(method string, id int64) intercept.Op {
+	return serviceOp("comments", "CommentService", method, id)
+}
+
+func (s *interceptedComments) CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	return intercept.One(ctx, s.interceptor, s.op("CreateComment", 0), func(ctx context.Context) (*models.Comment, error) {
+		return s.next.CreateComment(ctx, comment)
+	})
+}
+
+func (s *interceptedComments) GetAllComments(ctx context.Context) ([]models.Comment, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetAllComments", 0), s.next.GetAllComments)
+}
+
+func (s *interceptedComments) GetCommentByID(ctx context.Context, id int64) (*models.Comment, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetCommentByID", id), func(ctx context.Context) (*models.Comment, error) {
+		return s.next.GetCommentByID(ctx, id)
+	})
+}
+
+func (s *interceptedComments) GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetCommentByUserID", userid), func(ctx context.Context) ([]models.Comment, error) {
+		return s.next.GetCommentByUserID(ctx, userid)
+	})
+}
+
+func (s *interceptedComments) GetCommentByPostID(ctx context.Context, postid int64, sort repository.CommentSort) ([]models.Comment, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetCommentByPostID", postid), func(ctx context.Context) ([]models.Comment, error) {
+		return s.next.GetCommentByPostID(ctx, postid, sort)
+	})
+}
+
+func (s *interceptedComments) GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetCommentByUserIDPostID", postid), func(ctx context.Context) (*models.Comment, error) {
+		return s.next.GetCommentByUserIDPostID(ctx, userid, postid)
+	})
+}
+
+func (s *interceptedComments) UpdateCommentByID(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	return intercept.One(ctx, s.interceptor, s.op("UpdateCommentByID", comment.ID), func(ctx context.Context) (*models.Comment, error) {
+		return s.next.UpdateCommentByID(ctx, comment)
+	})
+}
+
+func (s *interceptedComments) DeleteCommentByID(ctx context.Context, id int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DeleteCommentByID", id), func(ctx context.Context) error {
+		return s.next.DeleteCommentByID(ctx, id)
+	})
+}
+
+func (s *interceptedComments) VoteComment(ctx context.Context, commentID, userID int64, value int) (*models.Comment, error) {
+	return intercept.One(ctx, s.interceptor, s.op("VoteComment", commentID), func(ctx context.Context) (*models.Comment, error) {
+		return s.next.VoteComment(ctx, commentID, userID, value)
+	})
+}
+
+type interceptedProfiles struct {
+	next        Profiles
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedProfiles) op(method string, id int64) intercept.Op {
+	return serviceOp("profiles", "ProfileService", method, id)
+}
+
+func (s *interceptedProfiles) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetProfile", userID), func(ctx context.Context) (*models.Profile, error) {
+		return s.next.GetProfile(ctx, userID)
+	})
+}
+
+func (s *interceptedProfiles) UpdateProfile(ctx context.Context, profile models.Profile) (*models.Profile, error) {
+	return intercept.One(ctx, s.interceptor, s.op("UpdateProfile", profile.UserID), func(ctx context.Context) (*models.Profile, error) {
+		return s.next.UpdateProfile(ctx, profile)
+	})
+}
+
+func (s *interceptedProfiles) GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetPublicProfile", 0), func(ctx context.Context) (*PublicProfile, error) {
+		return s.next.GetPublicProfile(ctx, username)
+	})
+}
+
+func (s *interceptedProfiles) GetAvatar(ctx context.Context, username string) (*models.Avatar, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetAvatar", 0), func(ctx context.Context) (*models.Avatar, error) {
+		return s.next.GetAvatar(ctx, username)
+	})
+}
+
+func (s *interceptedProfiles) SetAvatar(ctx context.Context, userID int64, data []byte) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("SetAvatar", userID), func(ctx context.Context) error {
+		return s.next.SetAvatar(ctx, userID, data)
+	})
+}
+
+func (s *interceptedProfiles) DeleteAvatar(ctx context.Context, userID int64) error {
+	return intercept.Exec(ctx, s.interceptor, s.op("DeleteAvatar", userID), func(ctx context.Context) error {
+		return s.next.DeleteAvatar(ctx, userID)
+	})
+}
+
+type interceptedReactions struct {
+	next        Reactions
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedReactions) op(method string, id int64) intercept.Op {
+	return serviceOp("reactions", "ReactionService", method, id)
+}
+
+func (s *interceptedReactions) ToggleReaction(ctx context.Context, reaction models.Reaction) (*ReactionToggle, error) {
+	return intercept.One(ctx, s.interceptor, s.op("ToggleReaction", reaction.TargetID), func(ctx context.Context) (*ReactionToggle, error) {
+		return s.next.ToggleReaction(ctx, reaction)
+	})
+}
+
+func (s *interceptedReactions) GetReactions(ctx context.Context, filter repository.ReactionFilter) (*ReactionPage, error) {
+	return intercept.One(ctx, s.interceptor, s.op("GetReactions", filter.TargetID), func(ctx context.Context) (*ReactionPage, error) {
+		return s.next.GetReactions(ctx, filter)
+	})
+}
+
+// ReactionKinds only reads the configuration, it is not intercepted
+func (s *interceptedReactions) ReactionKinds() []string {
+	return s.next.ReactionKinds()
+}
+
+type interceptedAudit struct {
+	next        Audit
+	interceptor intercept.Interceptor
+}
+
+func (s *interceptedAudit) op(method string, id int64) intercept.Op {
+	return serviceOp("audit_events", "AuditService", method, id)
+}
+
+func (s *interceptedAudit) GetAuditEvents(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEvent, error) {
+	return intercept.Many(ctx, s.interceptor, s.op("GetAuditEvents", filter.EntityID), func(ctx context.Context) ([]models.AuditEvent, error) {
+		return s.next.GetAuditEvents(ctx, filter)
+	})
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// ContentPolicy says what an erasure does with the posts and comments of
+// the user
+type ContentPolicy string
+
+const (
+	// KeepContent keeps the posts and comments under the anonymised user
+	KeepContent ContentPolicy = "keep"
+	// RemoveContent deletes them, with the comments others wrote on the
+	// posts
+	RemoveContent ContentPolicy = "remove"
+)
+
+// ParseContentPolicy returns the policy named s
+func ParseContentPolicy(s string) (ContentPolicy, error) {
+	switch policy := ContentPolicy(strings.ToLower(strings.TrimSpace(s))); policy {
+	case KeepContent, RemoveContent:
+		return policy, nil
+	}
+	return "", apperr.Invalid(entityUser, "content", fmt.Sprintf("unknown content policy %q, use keep or remove", s))
+}
+
+// ErasedName is the name of the users whose data was erased
+const ErasedName = "Deleted user"
+
+// accountError returns a Forbidden error unless the account of user is
+// active at now
+func accountError(user models.User, now time.Time) error {
+	state := user.State(now)
+	if state == models.StatusActive {
+		return nil
+	}
+	e := &apperr.Error{Kind: apperr.Forbidden, Code: "account_" + state, Entity: entityUser}
+	switch state {
+	case models.StatusDeactivated:
+		e.Message = "the account is deactivated, log in to open it again"
+	case models.StatusSuspended:
+		e.Message = "the account is suspended"
+		if user.SuspendedUntil != nil {
+			e.Message += " until " + user.SuspendedUntil.Format(time.RFC3339)
+		}
+	case models.StatusBanned:
+		e.Message = "the account is banned"
+	default:
+		e.Message = "the account was erased"
+	}
+	if user.StatusReason != "" && state != models.StatusErased {
+		e.Message += ": " + user.StatusReason
+	}
+	return e
+}
+
+// checkActive returns a Forbidden error unless the user may write
+func checkActive(ctx context.Context, repos repository.Repositories, userID int64) error {
+	user, err := repos.Users.GetUserByID(ctx, userID)
+	if err != nil {
+		return apperr.Wrap(err, entityUser)
+	}
+	return accountError(*user, time.Now())
+}
+
+// loginState lets the user of a login in: an account the user deactivated
+// is opened again, suspended, banned and erased ones are refused
+func loginState(ctx context.Context, repos repository.Repositories, user *models.User) error {
+	if user.State(time.Now()) == models.StatusDeactivated {
+		before := *user
+		user.Status, user.StatusReason = "", ""
+		if _, err := repos.Users.UpdateUser(ctx, user.ID, *user); err != nil {
+			return err
+		}
+		if err := audit(ctx, repos, actionReactivate, entityUser, user.ID, before, user); err != nil {
+			return err
+		}
+		logResult(ctx, "reactivate user", nil, slog.Int64("user_id", user.ID))
+		return nil
+	}
+	return accountError(*user, time.Now())
+}
+
+// DeactivateUser closes the account on request of its user, logging in
+// opens it again
+func (userService *UserService) DeactivateUser(ctx context.Context, id int64) error {
+	return userService.setState(ctx, "deactivate user", actionDeactivate, id, userService.checkSelfOrOperator(id), func(user *models.User) error {
+		if state := user.State(time.Now()); state != models.StatusActive {
+			return accountError(*user, time.Now())
+		}
+		user.Status, user.SuspendedUntil, user.StatusReason = models.StatusDeactivated, nil, ""
+		return nil
+	})
+}
+
+// SuspendUser keeps the user from logging in and writing until the time,
+// only an operator acting in ctx may
+func (userService *UserService) SuspendUser(ctx context.Context, id int64, until time.Time, reason string) error {
+	if !until.After(time.Now()) {
+		return apperr.Invalid(entityUser, "until", "a suspension has to end in the future")
+	}
+	return userService.setState(ctx, "suspend user", actionSuspend, id, userService.checkOperator, func(user *models.User) error {
+		user.Status, user.SuspendedUntil, user.StatusReason = models.StatusSuspended, &until, strings.TrimSpace(reason)
+		return nil
+	})
+}
+
+// BanUser keeps the user from logging in and writing for good, until
+// ReactivateUser. Only an operator acting in ctx may.
+func (userService *UserService) BanUser(ctx context.Context, id int64, reason string) error {
+	return userService.setState(ctx, "ban user", actionBan, id, userService.checkOperator, func(user *models.User) error {
+		user.Status, user.SuspendedUntil, user.StatusReason = models.StatusBanned, nil, strings.TrimSpace(reason)
+		return nil
+	})
+}
+
+// ReactivateUser makes a deactivated, suspended or banned account active
+// again, only an operator acting in ctx may
+func (userService *UserService) ReactivateUser(ctx context.Context, id int64) error {
+	return userService.setState(ctx, "reactivate user", actionReactivate, id, userService.checkOperator, func(user *models.User) error {
+		user.Status, user.SuspendedUntil, user.StatusReason = "", nil, ""
+		return nil
+	})
+}
+
+// setState changes the account state of a user with change once allowed
+// says the actor may, erased accounts stay erased
+func (userService *UserService) setState(ctx context.Context, msg, action string, id int64, allowed func(ctx context.Context, repos repository.Repositories) error, change func(user *models.User) error) error {
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := allowed(ctx, repos); err != nil {
+			return err
+		}
+		user, err := repos.Users.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user.Status == models.StatusErased {
+			return accountError(*user, time.Now())
+		}
+		before := *user
+		if err := change(user); err != nil {
+			return err
+		}
+		if _, err := repos.Users.UpdateUser(ctx, id, *user); err != nil {
+			return err
+		}
+		return audit(ctx, repos, action, entityUser, id, before, user)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, msg, err, slog.Int64("user_id", id))
+	return err
+}
+
+// EraseUser removes the personal data of a user for good: the name, the
+// email address, the username and the password are replaced, the profile,
+// the avatar, the tokens, the second factor and the linked identities are
+// deleted. The posts and comments are kept or removed as policy says, the
+// user stays as their author so no content is left without one. Users may
+// erase themselves, the operators anybody.
+func (userService *UserService) EraseUser(ctx context.Context, id int64, policy ContentPolicy) error {
+	if _, err := ParseContentPolicy(string(policy)); err != nil {
+		return err
+	}
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := userService.checkSelfOrOperator(id)(ctx, repos); err != nil {
+			return err
+		}
+		user, err := repos.Users.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user.Status == models.StatusErased {
+			return &apperr.Error{Kind: apperr.Conflict, Code: "already_erased", Entity: entityUser, Message: "the user was erased already"}
+		}
+		if policy == RemoveContent {
+			if err := removeContent(ctx, repos, id); err != nil {
+				return err
+			}
+		}
+		if err := deletePersonalData(ctx, repos, id); err != nil {
+			return err
+		}
+
+		// nobody knows the new password, the account cannot be used again
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return err
+		}
+		erased := models.User{
+			ID:       id,
+			Name:     ErasedName,
+			Email:    fmt.Sprintf("erased-%d@invalid", id),
+			Username: fmt.Sprintf("deleted-%d", id),
+			Password: base64.RawURLEncoding.EncodeToString(raw),
+			Status:   models.StatusErased,
+		}
+		if _, err := repos.Users.UpdateUser(ctx, id, erased); err != nil {
+			return err
+		}
+		// the event keeps none of the erased data
+		type state struct{ Status string }
+		return audit(ctx, repos, actionErase, entityUser, id, state{user.Status}, state{erased.Status})
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "erase user", err, slog.Int64("user_id", id), slog.String("content", string(policy)))
+	return err
+}
+
+// deletePersonalData deletes what belongs to a user besides the user and
+// the content
+func deletePersonalData(ctx context.Context, repos repository.Repositories, id int64) error {
+	if err := repos.Tokens.DeleteUserTokens(ctx, id, ""); err != nil {
+		return err
+	}
+	if err := repos.TwoFactors.DeleteTwoFactor(ctx, id); err != nil {
+		return err
+	}
+	if err := repos.Identities.DeleteUserIdentities(ctx, id, ""); err != nil {
+		return err
+	}
+	if err := repos.Drafts.DeleteUserDrafts(ctx, id); err != nil {
+		return err
+	}
+	if err := repos.Reactions.DeleteUserReactions(ctx, id); err != nil {
+		return err
+	}
+	if err := retractVotes(ctx, repos, id); err != nil {
+		return err
+	}
+	return repos.Profiles.DeleteProfile(ctx, id)
+}
+
+// removeContent deletes the comments of a user, and the posts of the user
+// with all their comments
+func removeContent(ctx context.Context, repos repository.Repositories, id int64) error {
+	comments, err := repos.Comments.GetCommentByUserID(ctx, id)
+	if err != nil && !errors.Is(err, repository.ErrNotExist) {
+		return err
+	}
+	for _, comment := range comments {
+		if err := deleteComment(ctx, repos, comment); err != nil {
+			return err
+		}
+	}
+
+	posts, err := repos.Posts.GetPostByUserID(ctx, id)
+	if err != nil && !errors.Is(err, repository.ErrNotExist) {
+		return err
+	}
+	for _, post := range posts {
+		if err := deletePost(ctx, repos, post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletePost deletes a post with its comments, revisions, drafts and
+// reactions
+func deletePost(ctx context.Context, repos repository.Repositories, post models.Post) error {
+	comments, err := repos.Comments.GetCommentByPostID(ctx, post.ID, repository.CommentSortOld)
+	if err != nil && !errors.Is(err, repository.ErrNotExist) {
+		return err
+	}
+	for _, comment := range comments {
+		if err := deleteComment(ctx, repos, comment); err != nil {
+			return err
+		}
+	}
+	if err := repos.Posts.DeletePost(ctx, post.ID); err != nil {
+		return err
+	}
+	if err := repos.Revisions.DeleteRevisions(ctx, post.ID); err != nil {
+		return err
+	}
+	if err := repos.Drafts.DeletePostDrafts(ctx, post.ID); err != nil {
+		return err
+	}
+	if err := repos.Reactions.DeleteTargetReactions(ctx, models.TargetPost, post.ID); err != nil {
+		return err
+	}
+	return audit(ctx, repos, actionDelete, entityPost, post.ID, post, nil)
+}
+
+func deleteComment(ctx context.Context, repos repository.Repositories, comment models.Comment) error {
+	if err := repos.Comments.DeleteComment(ctx, comment.ID); err != nil {
+		return err
+	}
+	if err := repos.Reactions.DeleteTargetReactions(ctx, models.TargetComment, comment.ID); err != nil {
+		return err
+	}
+	if err := repos.Votes.DeleteCommentVotes(ctx, comment.ID); err != nil {
+		return err
+	}
+	return audit(ctx, repos, actionDelete, entityComment, comment.ID, comment, nil)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+func TestModerationNeedsOperator(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	create := func(username string) *models.User {
+		user, err := store.Repositories().Users.CreateUser(ctx, models.User{Username: username, Email: username + "@localhost", Password: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	root, alice, bob := create("root"), create("alice"), create("bob")
+	users := NewUserService(store.Repositories().Users, store, mail.NewLog(io.Discard, "blog@localhost"), []int64{root.ID})
+	asRoot, asAlice := repository.WithActor(ctx, root.ID), repository.WithActor(ctx, alice.ID)
+
+	moderate := map[string]func(ctx context.Context) error{
+		"suspend": func(ctx context.Context) error {
+			return users.SuspendUser(ctx, bob.ID, time.Now().Add(time.Hour), "spam")
+		},
+		"ban":        func(ctx context.Context) error { return users.BanUser(ctx, bob.ID, "spam") },
+		"reactivate": func(ctx context.Context) error { return users.ReactivateUser(ctx, bob.ID) },
+		"deactivate": func(ctx context.Context) error { return users.DeactivateUser(ctx, bob.ID) },
+		"erase":      func(ctx context.Context) error { return users.EraseUser(ctx, bob.ID, KeepContent) },
+	}
+	for name, fn := range moderate {
+		for actor, ctx := range map[string]context.Context{"nobody": ctx, "another user": asAlice} {
+			if err := fn(ctx); !errors.Is(err, ErrOperatorRequired) {
+				t.Fatalf("%s by %s: got %v, want ErrOperatorRequired", name, actor, err)
+			}
+		}
+	}
+	for _, name := range []string{"suspend", "ban", "reactivate"} {
+		if err := moderate[name](asRoot); err != nil {
+			t.Fatalf("%s by the operator: %v", name, err)
+		}
+	}
+
+	// an operator who is banned is no operator any more
+	if err := users.BanUser(asRoot, root.ID, "leaving"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.BanUser(asRoot, bob.ID, "spam"); !errors.Is(err, ErrOperatorRequired) {
+		t.Fatalf("ban by a banned operator: got %v, want ErrOperatorRequired", err)
+	}
+
+	// users erase themselves
+	if err := users.EraseUser(asAlice, alice.ID, KeepContent); err != nil {
+		t.Fatalf("erase by the user: %v", err)
+	}
+	erased, err := users.GetUserByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erased.Status != models.StatusErased {
+		t.Fatalf("got status %q after the erasure, want %q", erased.Status, models.StatusErased)
+	}
+}
+
+func TestUpdateAndDeleteNeedSelfOrOperator(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	root, alice, bob := verifiedUser(t, repos, "root"), verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob")
+	users := NewUserService(repos.Users, store, mail.NewLog(io.Discard, "blog@localhost"), []int64{root.ID})
+	asRoot, asAlice, asBob := repository.WithActor(ctx, root.ID), repository.WithActor(ctx, alice.ID), repository.WithActor(ctx, bob.ID)
+
+	for actor, ctx := range map[string]context.Context{"nobody": ctx, "another user": asAlice} {
+		if _, err := users.UpdateUserByID(ctx, models.User{ID: bob.ID, Username: "taken", Password: "secret"}); !errors.Is(err, ErrOperatorRequired) {
+			t.Fatalf("update by %s: got %v, want ErrOperatorRequired", actor, err)
+		}
+		if err := users.DeleteUserByID(ctx, bob.ID); !errors.Is(err, ErrOperatorRequired) {
+			t.Fatalf("delete by %s: got %v, want ErrOperatorRequired", actor, err)
+		}
+	}
+	unchanged, err := repos.Users.GetUserByID(ctx, bob.ID)
+	if err != nil {
+		t.Fatalf("bob is gone: %v", err)
+	}
+	if unchanged.Username != "bob" {
+		t.Fatalf("bob was renamed to %q", unchanged.Username)
+	}
+
+	if _, err := users.UpdateUserByID(asBob, models.User{ID: bob.ID, Name: "Bob", Username: "bob", Password: "secret"}); err != nil {
+		t.Fatalf("update by the user: %v", err)
+	}
+	if err := users.DeleteUserByID(asRoot, bob.ID); err != nil {
+		t.Fatalf("delete by the operator: %v", err)
+	}
+	if err := users.DeleteUserByID(asAlice, alice.ID); err != nil {
+		t.Fatalf("delete by the user: %v", err)
+	}
+}
+
+func TestDeletePostRemovesComments(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	root, alice, bob := verifiedUser(t, repos, "root"), verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob")
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost"), Operators: []int64{root.ID}})
+	post, err := services.Posts.CreatePost(ctx, models.Post{UserID: uint64(alice.ID), Title: "post", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment, err := services.Comments.CreateComment(ctx, models.Comment{UserID: uint64(bob.ID), PostID: uint64(post.ID), Content: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.Comments.VoteComment(ctx, comment.ID, alice.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// suspended users keep their content
+	asRoot := repository.WithActor(ctx, root.ID)
+	for _, user := range []*models.User{alice, bob} {
+		if err := services.Users.SuspendUser(asRoot, user.ID, time.Now().Add(time.Hour), "spam"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := services.Comments.DeleteCommentByID(ctx, comment.ID); !apperr.Is(err, apperr.Forbidden) {
+		t.Fatalf("deleting the comment of a suspended user: got %v, want Forbidden", err)
+	}
+	if err := services.Posts.DeletePostByID(ctx, post.ID); !apperr.Is(err, apperr.Forbidden) {
+		t.Fatalf("deleting the post of a suspended user: got %v, want Forbidden", err)
+	}
+
+	if err := services.Users.ReactivateUser(asRoot, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := services.Posts.DeletePostByID(ctx, post.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Comments.GetCommentByID(ctx, comment.ID); !errors.Is(err, repository.ErrNotExist) {
+		t.Fatalf("got %v looking up the comment of the deleted post, want ErrNotExist", err)
+	}
+	if _, err := repos.Votes.GetVote(ctx, comment.ID, alice.ID); !errors.Is(err, repository.ErrNotExist) {
+		t.Fatalf("got %v looking up the vote on the deleted comment, want ErrNotExist", err)
+	}
+}
+
+func TestOperatorsKeepTheirID(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	root, alice, bob := verifiedUser(t, repos, "root"), verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob")
+	users := NewUserService(repos.Users, store, mail.NewLog(io.Discard, "blog@localhost"), []int64{root.ID})
+	asRoot, asAlice := repository.WithActor(ctx, root.ID), repository.WithActor(ctx, alice.ID)
+
+	// the operator gives up the username and another user takes it
+	renamed := *root
+	renamed.Username = "admin"
+	if _, err := users.UpdateUserByID(asRoot, renamed); err != nil {
+		t.Fatal(err)
+	}
+	taken := *alice
+	taken.Username = "root"
+	if _, err := users.UpdateUserByID(asAlice, taken); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.BanUser(asAlice, bob.ID, "spam"); !errors.Is(err, ErrOperatorRequired) {
+		t.Fatalf("ban by the new holder of the username: got %v, want ErrOperatorRequired", err)
+	}
+	if err := users.BanUser(asRoot, bob.ID, "spam"); err != nil {
+		t.Fatalf("ban by the renamed operator: %v", err)
+	}
+}
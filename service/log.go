@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"postgresql-blog/apperr"
+)
+
+// logResult logs the outcome of a write. Errors of a kind other than
+// Internal and Unavailable are answers the callers handle, they are only
+// logged at debug level.
+func logResult(ctx context.Context, msg string, err error, attrs ...any) {
+	switch kind := apperr.KindOf(err); {
+	case err == nil:
+		slog.InfoContext(ctx, msg, attrs...)
+	case kind == apperr.Internal, kind == apperr.Unavailable:
+		slog.ErrorContext(ctx, msg+" failed", append(attrs, slog.Any("error", err))...)
+	default:
+		slog.DebugContext(ctx, msg+" failed", append(attrs, slog.Any("error", err))...)
+	}
+}
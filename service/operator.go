@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/repository"
+)
+
+// ErrOperatorRequired is returned when a user who is not an operator tries
+// what only the operators may do
+var ErrOperatorRequired error = &apperr.Error{
+	Kind:    apperr.Forbidden,
+	Code:    "operator_required",
+	Entity:  entityUser,
+	Message: "only the operators of the blog may do this",
+}
+
+// OperatorsFromEnv reads BLOG_OPERATORS, the comma separated IDs of the
+// operators. They may remove the second factor of users who lost it and
+// suspend, ban, reactivate and erase users, nobody may when the list is
+// empty. IDs stay with their users, unlike usernames which others can
+// take.
+func OperatorsFromEnv() []int64 {
+	var operators []int64
+	for _, field := range strings.Split(os.Getenv("BLOG_OPERATORS"), ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			slog.Warn("ignoring an operator that is no user id", slog.String("operator", field))
+			continue
+		}
+		operators = append(operators, id)
+	}
+	return operators
+}
+
+// checkOperator returns ErrOperatorRequired unless the actor of ctx is an
+// active operator
+func (userService *UserService) checkOperator(ctx context.Context, repos repository.Repositories) error {
+	actor, ok := repository.ActorFromContext(ctx)
+	if !ok {
+		return ErrOperatorRequired
+	}
+	user, err := repos.Users.GetUserByID(ctx, actor)
+	if errors.Is(err, repository.ErrNotExist) {
+		return ErrOperatorRequired
+	}
+	if err != nil {
+		return err
+	}
+	if !userService.operators[user.ID] || accountError(*user, time.Now()) != nil {
+		return ErrOperatorRequired
+	}
+	return nil
+}
+
+// checkSelfOrOperator returns a check that lets the user with id act on
+// their own account and the operators on every account
+func (userService *UserService) checkSelfOrOperator(id int64) func(ctx context.Context, repos repository.Repositories) error {
+	return func(ctx context.Context, repos repository.Repositories) error {
+		if actor, ok := repository.ActorFromContext(ctx); ok && actor == id {
+			return nil
+		}
+		return userService.checkOperator(ctx, repos)
+	}
+}
@@ -3,102 +3,194 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"strings"
 
 	// "fmt"
 	// "log"
+	"postgresql-blog/apperr"
 	"postgresql-blog/models"
 	"postgresql-blog/repository"
-
-	"gorm.io/gorm"
 )
 
 type PostService struct {
-	PostRepo repository.PostRepository
-	db       *gorm.DB
+	PostRepo     repository.PostRepository
+	RevisionRepo repository.RevisionRepository
+	DraftRepo    repository.DraftRepository
+	ReactionRepo repository.ReactionRepository
+	uow          repository.UnitOfWork
+	// maxRevisions is how many revisions of a post are kept, 0 keeps all
+	maxRevisions int
 }
 
-func NewPostService(postRepo repository.PostRepository, db *gorm.DB) *PostService {
+func NewPostService(postRepo repository.PostRepository, revisionRepo repository.RevisionRepository, draftRepo repository.DraftRepository, reactionRepo repository.ReactionRepository, uow repository.UnitOfWork, maxRevisions int) *PostService {
 	return &PostService{
-		PostRepo: postRepo,
-		db:       db,
+		PostRepo:     postRepo,
+		RevisionRepo: revisionRepo,
+		DraftRepo:    draftRepo,
+		ReactionRepo: reactionRepo,
+		uow:          uow,
+		maxRevisions: maxRevisions,
 	}
 }
 
 func (postService *PostService) CreatePost(ctx context.Context, post models.Post) (*models.Post, error) {
-	_, err := postService.PostRepo.GetPostByTitle(ctx, post.Title)
-	if err == nil || !errors.Is(err, repository.ErrNotExist) {
-		return nil, errors.New("a post with this title already exists")
+	if strings.TrimSpace(post.Title) == "" {
+		return nil, apperr.Invalid(entityPost, "title", "a post needs a title")
 	}
 
-	return postService.PostRepo.CreatePost(ctx, post)
+	var created *models.Post
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		created, err = postService.create(ctx, repos, post)
+		return err
+	})
+	if err != nil {
+		err = apperr.Wrap(err, entityPost)
+		logResult(ctx, "create post", err)
+		return nil, err
+	}
+	logResult(ctx, "create post", nil, slog.Int64("post_id", created.ID))
+	return created, nil
 }
 
-func (postService *PostService) GetAllPosts(ctx context.Context) ([]models.Post, error) {
-	var allPosts []models.GormPost
-	if err := postService.db.WithContext(ctx).Find(&allPosts).Error; err != nil {
+// create stores a new post with its first revision
+func (postService *PostService) create(ctx context.Context, repos repository.Repositories, post models.Post) (*models.Post, error) {
+	_, err := repos.Posts.GetPostByTitle(ctx, post.Title)
+	if err == nil {
+		return nil, &apperr.Error{
+			Kind:    apperr.Conflict,
+			Code:    "title_taken",
+			Entity:  entityPost,
+			Field:   "title",
+			Message: "a post with this title already exists",
+			Err:     repository.ErrDuplicate,
+		}
+	}
+	if !errors.Is(err, repository.ErrNotExist) {
 		return nil, err
 	}
 
-	var result []models.Post
-	for _, post := range allPosts {
-		result = append(result, models.Post(post))
+	if err := checkVerified(ctx, repos, post.UserID); err != nil {
+		return nil, err
+	}
+	post.Reactions = nil
+	created, err := repos.Posts.CreatePost(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	if err := audit(ctx, repos, actionCreate, entityPost, created.ID, nil, created); err != nil {
+		return nil, err
 	}
+	return created, postService.saveRevision(ctx, repos, *created, 0)
+}
 
-	return result, nil
+// GetAllPosts returns the posts with their reactions, counted for all of
+// them at once
+func (postService *PostService) GetAllPosts(ctx context.Context) ([]models.Post, error) {
+	posts, err := postService.PostRepo.AllPosts(ctx)
+	if err != nil {
+		return nil, apperr.Wrap(err, entityPost)
+	}
+	return posts, apperr.Wrap(countPosts(ctx, postService.ReactionRepo, posts), entityReaction)
 }
 
+// GetPostByID returns the post with its reactions
 func (postService *PostService) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
 	post, err := postService.PostRepo.GetPostByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
-		return nil, err
+		return nil, apperr.Wrap(err, entityPost)
 	}
-
-	return post, nil
+	posts := []models.Post{*post}
+	if err := countPosts(ctx, postService.ReactionRepo, posts); err != nil {
+		return nil, apperr.Wrap(err, entityReaction)
+	}
+	return &posts[0], nil
 }
 
+// GetPostByTitle returns the post with its reactions
 func (postService *PostService) GetPostByTitle(ctx context.Context, title string) (*models.Post, error) {
 	post, err := postService.PostRepo.GetPostByTitle(ctx, title)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
-		return nil, err
+		return nil, apperr.Wrap(err, entityPost)
 	}
-
-	return post, nil
+	posts := []models.Post{*post}
+	if err := countPosts(ctx, postService.ReactionRepo, posts); err != nil {
+		return nil, apperr.Wrap(err, entityReaction)
+	}
+	return &posts[0], nil
 }
 
+// GetPostByUserID returns the posts of a user with their reactions
 func (postService *PostService) GetPostByUserID(ctx context.Context, userid int64) ([]models.Post, error) {
 	post, err := postService.PostRepo.GetPostByUserID(ctx, userid)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
-		return nil, err
+		return nil, apperr.Wrap(err, entityPost)
+	}
+	if err := countPosts(ctx, postService.ReactionRepo, post); err != nil {
+		return nil, apperr.Wrap(err, entityReaction)
 	}
 	return post, nil
 }
 
 func (postService *PostService) UpdatePostByID(ctx context.Context, post models.Post) (*models.Post, error) {
-	existingPost, err := postService.PostRepo.GetPostByID(ctx, post.ID)
+	var existingPost *models.Post
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		existingPost, _, err = postService.update(ctx, repos, post)
+		return err
+	})
+	err = apperr.Wrap(err, entityPost)
+	logResult(ctx, "update post", err, slog.Int64("post_id", post.ID))
 	if err != nil {
 		return nil, err
 	}
-	if _, err := postService.PostRepo.UpdatePost(ctx, post.ID, post); err != nil {
-		log.Printf("Error updating post with ID %d: %v", post.ID, err)
-		return nil, err
-	}
 	return existingPost, nil
 }
 
-func (postService *PostService) DeletePostByID(ctx context.Context, id int64) error {
-	if err := postService.PostRepo.DeletePost(ctx, id); err != nil {
-		log.Printf("Error deleting post with ID %d: %v", id, err)
-		return err
+// update saves post with a new revision, it returns the post before and
+// after
+func (postService *PostService) update(ctx context.Context, repos repository.Repositories, post models.Post) (*models.Post, *models.Post, error) {
+	existingPost, err := repos.Posts.GetPostByID(ctx, post.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkActive(ctx, repos, int64(existingPost.UserID)); err != nil {
+		return nil, nil, err
 	}
-	return nil
+	if err := saveFirstRevision(ctx, repos, *existingPost); err != nil {
+		return nil, nil, err
+	}
+	// the reactions of a post read before are not part of it
+	post.Reactions = nil
+	updated, err := repos.Posts.UpdatePost(ctx, post.ID, post)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := audit(ctx, repos, actionUpdate, entityPost, post.ID, existingPost, updated); err != nil {
+		return nil, nil, err
+	}
+	return existingPost, updated, postService.saveRevision(ctx, repos, *updated, 0)
+}
+
+// DeletePostByID deletes a post with its comments, the author has to be
+// active
+func (postService *PostService) DeletePostByID(ctx context.Context, id int64) error {
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		post, err := repos.Posts.GetPostByID(ctx, id)
+		if errors.Is(err, repository.ErrNotExist) {
+			// deleting a missing post fails like the repository does
+			return repository.ErrDeleteFailed
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkActive(ctx, repos, int64(post.UserID)); err != nil {
+			return err
+		}
+		return deletePost(ctx, repos, *post)
+	})
+	err = apperr.Wrap(err, entityPost)
+	logResult(ctx, "delete post", err, slog.Int64("post_id", id))
+	return err
 }
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// limits of the profiles
+const (
+	MaxDisplayName = 64
+	MaxBio         = 4000
+	MaxLinks       = 10
+	MaxLinkLabel   = 32
+	MaxAvatarSize  = 256 << 10
+)
+
+// avatarTypes are the image types an avatar may have
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+// PublicProfile is what everybody sees of a user
+type PublicProfile struct {
+	UserID      int64
+	Username    string
+	DisplayName string
+	// Bio is Markdown
+	Bio      string
+	Website  string
+	Links    []models.Link
+	Timezone string
+	Locale   string
+	// Email is empty unless the user made it public or views their own
+	// profile
+	Email     string
+	HasAvatar bool
+	// Posts are the published posts, the newest first
+	Posts        []models.Post
+	CommentCount int
+}
+
+// Profiles is what the frontends use of the ProfileService
+type Profiles interface {
+	GetProfile(ctx context.Context, userID int64) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, profile models.Profile) (*models.Profile, error)
+	GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error)
+	GetAvatar(ctx context.Context, username string) (*models.Avatar, error)
+	SetAvatar(ctx context.Context, userID int64, data []byte) error
+	DeleteAvatar(ctx context.Context, userID int64) error
+}
+
+type ProfileService struct {
+	ProfileRepo repository.ProfileRepository
+	uow         repository.UnitOfWork
+}
+
+func NewProfileService(profileRepo repository.ProfileRepository, uow repository.UnitOfWork) *ProfileService {
+	return &ProfileService{
+		ProfileRepo: profileRepo,
+		uow:         uow,
+	}
+}
+
+// GetProfile returns the profile of a user, an empty one when the user
+// never saved one
+func (profileService *ProfileService) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	var profile *models.Profile
+	err := profileService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		profile, err = profileOf(ctx, repos, userID)
+		return err
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityProfile)
+	}
+	return profile, nil
+}
+
+// UpdateProfile replaces the profile of profile.UserID
+func (profileService *ProfileService) UpdateProfile(ctx context.Context, profile models.Profile) (*models.Profile, error) {
+	if err := checkProfile(&profile); err != nil {
+		return nil, err
+	}
+
+	var saved *models.Profile
+	err := profileService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := checkActive(ctx, repos, profile.UserID); err != nil {
+			return err
+		}
+		action := actionUpdate
+		before, err := repos.Profiles.GetProfile(ctx, profile.UserID)
+		if errors.Is(err, repository.ErrNotExist) {
+			action = actionCreate
+		} else if err != nil {
+			return err
+		}
+		if saved, err = repos.Profiles.SaveProfile(ctx, profile); err != nil {
+			return err
+		}
+		return audit(ctx, repos, action, entityProfile, profile.UserID, before, saved)
+	})
+	err = apperr.Wrap(err, entityProfile)
+	logResult(ctx, "update profile", err, slog.Int64("user_id", profile.UserID))
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// GetPublicProfile returns the profile of the user with the username, with
+// the published posts and the number of comments of the user
+func (profileService *ProfileService) GetPublicProfile(ctx context.Context, username string) (*PublicProfile, error) {
+	var public *PublicProfile
+	err := profileService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.GetUserByUsername(ctx, username)
+		if err != nil {
+			return apperr.Wrap(err, entityUser)
+		}
+		profile, err := profileOf(ctx, repos, user.ID)
+		if err != nil {
+			return err
+		}
+		posts, err := repos.Posts.GetPostByUserID(ctx, user.ID)
+		if err != nil {
+			return apperr.Wrap(err, entityPost)
+		}
+		comments, err := repos.Comments.GetCommentByUserID(ctx, user.ID)
+		if err != nil {
+			return apperr.Wrap(err, entityComment)
+		}
+		_, err = repos.Profiles.GetAvatar(ctx, user.ID)
+		if err != nil && !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+
+		public = &PublicProfile{
+			UserID:       user.ID,
+			Username:     user.Username,
+			DisplayName:  profile.DisplayName,
+			Bio:          profile.Bio,
+			Website:      profile.Website,
+			Links:        profile.Links,
+			Timezone:     profile.Timezone,
+			Locale:       profile.Locale,
+			HasAvatar:    err == nil,
+			CommentCount: len(comments),
+		}
+		if emailVisible(ctx, user.ID, *profile) {
+			public.Email = user.Email
+		}
+		for _, post := range posts {
+			if post.IsPublished {
+				public.Posts = append(public.Posts, post)
+			}
+		}
+		sort.SliceStable(public.Posts, func(i, j int) bool {
+			return public.Posts[i].PublishedAt.After(public.Posts[j].PublishedAt)
+		})
+		return countPosts(ctx, repos.Reactions, public.Posts)
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityProfile)
+	}
+	return public, nil
+}
+
+// GetAvatar returns the avatar of the user with the username
+func (profileService *ProfileService) GetAvatar(ctx context.Context, username string) (*models.Avatar, error) {
+	var avatar *models.Avatar
+	err := profileService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.GetUserByUsername(ctx, username)
+		if err != nil {
+			return apperr.Wrap(err, entityUser)
+		}
+		avatar, err = repos.Profiles.GetAvatar(ctx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityAvatar)
+	}
+	return avatar, nil
+}
+
+// SetAvatar replaces the avatar of a user, data is a PNG, JPEG, GIF or WebP
+// image of at most MaxAvatarSize bytes
+func (profileService *ProfileService) SetAvatar(ctx context.Context, userID int64, data []byte) error {
+	if len(data) == 0 {
+		return apperr.Invalid(entityAvatar, "image", "the image is empty")
+	}
+	if len(data) > MaxAvatarSize {
+		return apperr.Invalid(entityAvatar, "image", fmt.Sprintf("the image is larger than %d KiB", MaxAvatarSize>>10))
+	}
+	contentType := http.DetectContentType(data)
+	if !avatarTypes[contentType] {
+		return apperr.Invalid(entityAvatar, "image", "the image is not a PNG, JPEG, GIF or WebP")
+	}
+
+	err := profileService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := checkActive(ctx, repos, userID); err != nil {
+			return err
+		}
+		before, err := avatarOrNil(ctx, repos, userID)
+		if err != nil {
+			return err
+		}
+		avatar := models.Avatar{UserID: userID, ContentType: contentType, Data: data}
+		if err := repos.Profiles.SaveAvatar(ctx, avatar); err != nil {
+			return err
+		}
+		action := actionUpdate
+		if before == nil {
+			action = actionCreate
+		}
+		return audit(ctx, repos, action, entityAvatar, userID, avatarOf(before), avatarOf(&avatar))
+	})
+	err = apperr.Wrap(err, entityAvatar)
+	logResult(ctx, "set avatar", err, slog.Int64("user_id", userID), slog.String("content_type", contentType))
+	return err
+}
+
+func (profileService *ProfileService) DeleteAvatar(ctx context.Context, userID int64) error {
+	err := profileService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		before, err := avatarOrNil(ctx, repos, userID)
+		if err != nil || before == nil {
+			return err
+		}
+		if err := repos.Profiles.DeleteAvatar(ctx, userID); err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionDelete, entityAvatar, userID, avatarOf(before), nil)
+	})
+	err = apperr.Wrap(err, entityAvatar)
+	logResult(ctx, "delete avatar", err, slog.Int64("user_id", userID))
+	return err
+}
+
+// profileOf returns the profile of a user, an empty one when the user never
+// saved one
+func profileOf(ctx context.Context, repos repository.Repositories, userID int64) (*models.Profile, error) {
+	profile, err := repos.Profiles.GetProfile(ctx, userID)
+	if errors.Is(err, repository.ErrNotExist) {
+		return &models.Profile{UserID: userID}, nil
+	}
+	return profile, err
+}
+
+// emailVisible tells whether the caller may see the email address of a
+// user: the user themselves, or anybody when the profile says so
+func emailVisible(ctx context.Context, userID int64, profile models.Profile) bool {
+	actor, ok := repository.ActorFromContext(ctx)
+	return profile.EmailPublic || (ok && actor == userID)
+}
+
+// checkProfile trims the fields of profile and validates them
+func checkProfile(profile *models.Profile) error {
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	profile.Bio = strings.TrimSpace(profile.Bio)
+	profile.Website = strings.TrimSpace(profile.Website)
+	profile.Timezone = strings.TrimSpace(profile.Timezone)
+	profile.Locale = strings.TrimSpace(profile.Locale)
+
+	if utf8.RuneCountInString(profile.DisplayName) > MaxDisplayName {
+		return apperr.Invalid(entityProfile, "display_name", fmt.Sprintf("the display name is longer than %d characters", MaxDisplayName))
+	}
+	if utf8.RuneCountInString(profile.Bio) > MaxBio {
+		return apperr.Invalid(entityProfile, "bio", fmt.Sprintf("the bio is longer than %d characters", MaxBio))
+	}
+	if profile.Website != "" && !isWebURL(profile.Website) {
+		return apperr.Invalid(entityProfile, "website", "the website is not an http or https URL")
+	}
+	if len(profile.Links) > MaxLinks {
+		return apperr.Invalid(entityProfile, "links", fmt.Sprintf("a profile has at most %d links", MaxLinks))
+	}
+	for i := range profile.Links {
+		link := &profile.Links[i]
+		link.Label, link.URL = strings.TrimSpace(link.Label), strings.TrimSpace(link.URL)
+		if link.Label == "" || utf8.RuneCountInString(link.Label) > MaxLinkLabel {
+			return apperr.Invalid(entityProfile, "links", fmt.Sprintf("a link needs a label of at most %d characters", MaxLinkLabel))
+		}
+		if !isWebURL(link.URL) {
+			return apperr.Invalid(entityProfile, "links", fmt.Sprintf("the link %q is not an http or https URL", link.Label))
+		}
+	}
+	if profile.Timezone != "" {
+		if _, err := time.LoadLocation(profile.Timezone); err != nil || profile.Timezone == "Local" {
+			return apperr.Invalid(entityProfile, "timezone", fmt.Sprintf("unknown timezone %q, use an IANA name like Europe/Berlin", profile.Timezone))
+		}
+	}
+	if profile.Locale != "" && !localePattern.MatchString(profile.Locale) {
+		return apperr.Invalid(entityProfile, "locale", fmt.Sprintf("invalid locale %q, use a language tag like en-US", profile.Locale))
+	}
+	return nil
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// avatarOrNil returns the avatar of a user, nil when there is none
+func avatarOrNil(ctx context.Context, repos repository.Repositories, userID int64) (*models.Avatar, error) {
+	avatar, err := repos.Profiles.GetAvatar(ctx, userID)
+	if errors.Is(err, repository.ErrNotExist) {
+		return nil, nil
+	}
+	return avatar, err
+}
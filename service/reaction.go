@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// DefaultReactions are the emoji the users may react with besides a like,
+// unless BLOG_REACTIONS says otherwise
+var DefaultReactions = []string{"👍", "❤️", "😂", "🎉", "😮", "😢"}
+
+// limits of the reaction listings
+const (
+	DefaultReactionPage = 50
+	MaxReactionPage     = 100
+)
+
+// ReactionsFromEnv reads BLOG_REACTIONS, the comma separated emoji the users
+// may react with besides a like. "none" allows only likes, an empty value
+// keeps DefaultReactions.
+func ReactionsFromEnv() []string {
+	value := strings.TrimSpace(os.Getenv("BLOG_REACTIONS"))
+	switch value {
+	case "":
+		return DefaultReactions
+	case "none":
+		return []string{}
+	}
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// ReactionToggle is the outcome of ToggleReaction
+type ReactionToggle struct {
+	// Added is true when the reaction was added, false when it was taken
+	// back
+	Added    bool
+	Reaction models.Reaction
+	// Counts are the reactions to the target after the toggle
+	Counts map[string]int
+}
+
+// ReactionPage is a page of the users who reacted to a target
+type ReactionPage struct {
+	Reactors []repository.Reactor
+	// Next is the After of the next page, 0 on the last one
+	Next int64
+}
+
+type ReactionService struct {
+	ReactionRepo repository.ReactionRepository
+	uow          repository.UnitOfWork
+	// kinds are the reactions the users may give, likes and the emoji
+	kinds []string
+}
+
+func NewReactionService(reactionRepo repository.ReactionRepository, uow repository.UnitOfWork, emoji []string) *ReactionService {
+	if emoji == nil {
+		emoji = DefaultReactions
+	}
+	kinds := []string{models.ReactionLike}
+	for _, kind := range emoji {
+		if kind != models.ReactionLike {
+			kinds = append(kinds, kind)
+		}
+	}
+	return &ReactionService{
+		ReactionRepo: reactionRepo,
+		uow:          uow,
+		kinds:        kinds,
+	}
+}
+
+// ReactionKinds returns the reactions the users may give, a like first
+func (reactionService *ReactionService) ReactionKinds() []string {
+	return append([]string(nil), reactionService.kinds...)
+}
+
+func (reactionService *ReactionService) allowed(kind string) bool {
+	for _, k := range reactionService.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (reactionService *ReactionService) validate(targetType, kind string) error {
+	if targetType != models.TargetPost && targetType != models.TargetComment {
+		return apperr.Invalid(entityReaction, "target_type", "reactions are given to a post or a comment")
+	}
+	if kind != "" && !reactionService.allowed(kind) {
+		return apperr.Invalid(entityReaction, "kind", "the reaction has to be one of "+strings.Join(reactionService.kinds, " "))
+	}
+	return nil
+}
+
+// ToggleReaction adds the reaction of the user to the target, or takes it
+// back when the user already gave it
+func (reactionService *ReactionService) ToggleReaction(ctx context.Context, reaction models.Reaction) (*ReactionToggle, error) {
+	if reaction.Kind == "" {
+		reaction.Kind = models.ReactionLike
+	}
+	if err := reactionService.validate(reaction.TargetType, reaction.Kind); err != nil {
+		return nil, err
+	}
+
+	var toggle ReactionToggle
+	err := reactionService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := checkTarget(ctx, repos, reaction.TargetType, reaction.TargetID); err != nil {
+			return err
+		}
+		if err := checkVerified(ctx, repos, uint64(reaction.UserID)); err != nil {
+			return err
+		}
+
+		existing, err := repos.Reactions.GetReaction(ctx, reaction.UserID, reaction.TargetType, reaction.TargetID, reaction.Kind)
+		switch {
+		case err == nil:
+			if err := repos.Reactions.DeleteReaction(ctx, existing.ID); err != nil {
+				return err
+			}
+			toggle.Reaction = *existing
+			err = audit(ctx, repos, actionDelete, entityReaction, existing.ID, existing, nil)
+		case errors.Is(err, repository.ErrNotExist):
+			reaction.CreatedAt = time.Now()
+			var added *models.Reaction
+			if added, err = repos.Reactions.AddReaction(ctx, reaction); err != nil {
+				return err
+			}
+			toggle.Added, toggle.Reaction = true, *added
+			err = audit(ctx, repos, actionCreate, entityReaction, added.ID, nil, added)
+		}
+		if err != nil {
+			return err
+		}
+
+		counts, err := repos.Reactions.CountReactions(ctx, reaction.TargetType, []int64{reaction.TargetID})
+		toggle.Counts = counts[reaction.TargetID]
+		return err
+	})
+	err = apperr.Wrap(err, entityReaction)
+	logResult(ctx, "toggle reaction", err, slog.String("target_type", reaction.TargetType),
+		slog.Int64("target_id", reaction.TargetID), slog.Bool("added", toggle.Added))
+	if err != nil {
+		return nil, err
+	}
+	return &toggle, nil
+}
+
+// GetReactions lists who reacted to a target, the earliest first
+func (reactionService *ReactionService) GetReactions(ctx context.Context, filter repository.ReactionFilter) (*ReactionPage, error) {
+	if err := reactionService.validate(filter.TargetType, filter.Kind); err != nil {
+		return nil, err
+	}
+	if filter.Limit < 0 || filter.After < 0 {
+		return nil, apperr.Invalid(entityReaction, "limit", "the limit and the cursor cannot be negative")
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultReactionPage
+	}
+	filter.Limit = min(filter.Limit, MaxReactionPage)
+
+	// one more than asked for tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	reactors, err := reactionService.ReactionRepo.FindReactions(ctx, filter)
+	if err != nil {
+		return nil, apperr.Wrap(err, entityReaction)
+	}
+	page := &ReactionPage{Reactors: reactors}
+	if len(reactors) > limit {
+		page.Reactors = reactors[:limit]
+		page.Next = page.Reactors[limit-1].ID
+	}
+	return page, nil
+}
+
+// checkTarget returns a NotFound error unless the post or comment exists
+func checkTarget(ctx context.Context, repos repository.Repositories, targetType string, targetID int64) error {
+	var err error
+	if targetType == models.TargetPost {
+		_, err = repos.Posts.GetPostByID(ctx, targetID)
+		return apperr.Wrap(err, entityPost)
+	}
+	_, err = repos.Comments.GetCommentByID(ctx, targetID)
+	return apperr.Wrap(err, entityComment)
+}
+
+// countPosts fills the reactions of the posts with one query
+func countPosts(ctx context.Context, repo repository.ReactionRepository, posts []models.Post) error {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	counts, err := repo.CountReactions(ctx, models.TargetPost, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Reactions = counts[posts[i].ID]
+	}
+	return nil
+}
+
+// countComments fills the reactions of the comments with one query
+func countComments(ctx context.Context, repo repository.ReactionRepository, comments []models.Comment) error {
+	ids := make([]int64, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	counts, err := repo.CountReactions(ctx, models.TargetComment, ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Reactions = counts[comments[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+func TestToggleReaction(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	alice, bob := verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob")
+	post, err := services.Posts.CreatePost(ctx, models.Post{UserID: uint64(alice.ID), Title: "post", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name   string
+		userID int64
+		kind   string
+		added  bool
+		counts map[string]int
+	}{
+		{"like", bob.ID, "", true, map[string]int{models.ReactionLike: 1}},
+		{"emoji", bob.ID, "🎉", true, map[string]int{models.ReactionLike: 1, "🎉": 1}},
+		{"another user", alice.ID, models.ReactionLike, true, map[string]int{models.ReactionLike: 2, "🎉": 1}},
+		{"like again takes it back", bob.ID, models.ReactionLike, false, map[string]int{models.ReactionLike: 1, "🎉": 1}},
+		{"like once more", bob.ID, models.ReactionLike, true, map[string]int{models.ReactionLike: 2, "🎉": 1}},
+	}
+	for _, step := range steps {
+		toggle, err := services.Reactions.ToggleReaction(ctx, models.Reaction{UserID: step.userID, TargetType: models.TargetPost, TargetID: post.ID, Kind: step.kind})
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if toggle.Added != step.added {
+			t.Fatalf("%s: added is %v, want %v", step.name, toggle.Added, step.added)
+		}
+		if len(toggle.Counts) != len(step.counts) {
+			t.Fatalf("%s: got the counts %v, want %v", step.name, toggle.Counts, step.counts)
+		}
+		for kind, count := range step.counts {
+			if toggle.Counts[kind] != count {
+				t.Fatalf("%s: got the counts %v, want %v", step.name, toggle.Counts, step.counts)
+			}
+		}
+	}
+	// one reaction per user, target and kind
+	mine, err := repos.Reactions.GetUserReactions(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 2 {
+		t.Fatalf("bob has %d reactions, want the like and the 🎉", len(mine))
+	}
+
+	if _, err := services.Reactions.ToggleReaction(ctx, models.Reaction{UserID: bob.ID, TargetType: models.TargetPost, TargetID: post.ID, Kind: "🦄"}); !apperr.Is(err, apperr.Validation) {
+		t.Fatalf("got %v for a reaction that is not allowed, want a Validation error", err)
+	}
+	if _, err := services.Reactions.ToggleReaction(ctx, models.Reaction{UserID: bob.ID, TargetType: "user", TargetID: alice.ID}); !apperr.Is(err, apperr.Validation) {
+		t.Fatalf("got %v reacting to a user, want a Validation error", err)
+	}
+	if _, err := services.Reactions.ToggleReaction(ctx, models.Reaction{UserID: bob.ID, TargetType: models.TargetComment, TargetID: 1000}); !apperr.Is(err, apperr.NotFound) {
+		t.Fatalf("got %v reacting to a missing comment, want NotFound", err)
+	}
+}
+
+func TestGetReactionsPages(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	alice := verifiedUser(t, repos, "alice")
+	post, err := services.Posts.CreatePost(ctx, models.Post{UserID: uint64(alice.ID), Title: "post", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usernames := []string{"bob", "carol", "dave", "erin", "frank"}
+	for _, username := range usernames {
+		user := verifiedUser(t, repos, username)
+		if _, err := services.Reactions.ToggleReaction(ctx, models.Reaction{UserID: user.ID, TargetType: models.TargetPost, TargetID: post.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the pages follow each other without gaps, the last has no next
+	var got []string
+	filter := repository.ReactionFilter{TargetType: models.TargetPost, TargetID: post.ID, Limit: 2}
+	for pages := 1; ; pages++ {
+		page, err := services.Reactions.GetReactions(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Reactors) > 2 {
+			t.Fatalf("page %d has %d reactors, want 2 at most", pages, len(page.Reactors))
+		}
+		for _, reactor := range page.Reactors {
+			got = append(got, reactor.Username)
+		}
+		if page.Next == 0 {
+			if pages != 3 {
+				t.Fatalf("got %d pages, want 3", pages)
+			}
+			break
+		}
+		filter.After = page.Next
+	}
+	if len(got) != len(usernames) {
+		t.Fatalf("got the reactors %v, want %v", got, usernames)
+	}
+	for i := range usernames {
+		if got[i] != usernames[i] {
+			t.Fatalf("got the reactors %v, want %v the earliest first", got, usernames)
+		}
+	}
+
+	page, err := services.Reactions.GetReactions(ctx, repository.ReactionFilter{TargetType: models.TargetPost, TargetID: post.ID, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Reactors) != 5 || page.Next != 0 {
+		t.Fatalf("a page of exactly all reactors has %d and next %d, want 5 and none", len(page.Reactors), page.Next)
+	}
+	if _, err := services.Reactions.GetReactions(ctx, repository.ReactionFilter{TargetType: models.TargetPost, TargetID: post.ID, Limit: -1}); !apperr.Is(err, apperr.Validation) {
+		t.Fatalf("got %v for a negative limit, want a Validation error", err)
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/diff"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// DefaultMaxRevisions is how many revisions of a post are kept unless
+// BLOG_POST_REVISIONS says otherwise
+const DefaultMaxRevisions = 50
+
+// MaxRevisionsFromEnv reads BLOG_POST_REVISIONS, the number of revisions
+// kept of every post, 0 keeps all of them. Values that do not parse keep
+// DefaultMaxRevisions.
+func MaxRevisionsFromEnv() int {
+	if keep, err := strconv.Atoi(os.Getenv("BLOG_POST_REVISIONS")); err == nil && keep >= 0 {
+		return keep
+	}
+	return DefaultMaxRevisions
+}
+
+// saveRevision stores post as its newest revision and drops the revisions
+// beyond the limit. An edit that changes nothing the revisions keep adds
+// none, a restore always adds one.
+func (postService *PostService) saveRevision(ctx context.Context, repos repository.Repositories, post models.Post, restoredFrom int) error {
+	revisions, err := repos.Revisions.GetRevisions(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	if n := len(revisions); n > 0 && restoredFrom == 0 && sameRevision(revisions[n-1], post) {
+		return nil
+	}
+
+	revision := models.PostRevision{
+		PostID:       post.ID,
+		Title:        post.Title,
+		Content:      post.Content,
+		Thumbnail:    post.Thumbnail,
+		RestoredFrom: restoredFrom,
+	}
+	if actor, ok := repository.ActorFromContext(ctx); ok {
+		revision.EditorID = &actor
+	}
+	if _, err := repos.Revisions.CreateRevision(ctx, revision); err != nil {
+		return err
+	}
+	if postService.maxRevisions > 0 {
+		return repos.Revisions.PruneRevisions(ctx, post.ID, postService.maxRevisions)
+	}
+	return nil
+}
+
+// saveFirstRevision stores the post as it was before the first edit, for
+// the posts written before there were revisions. The author is its editor.
+func saveFirstRevision(ctx context.Context, repos repository.Repositories, post models.Post) error {
+	revisions, err := repos.Revisions.GetRevisions(ctx, post.ID)
+	if err != nil || len(revisions) > 0 {
+		return err
+	}
+	author := int64(post.UserID)
+	_, err = repos.Revisions.CreateRevision(ctx, models.PostRevision{
+		PostID:    post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Thumbnail: post.Thumbnail,
+		EditorID:  &author,
+		CreatedAt: post.UpdatedAt,
+	})
+	return err
+}
+
+func sameRevision(revision models.PostRevision, post models.Post) bool {
+	return revision.Title == post.Title && revision.Content == post.Content && revision.Thumbnail == post.Thumbnail
+}
+
+// GetPostRevisions returns the revisions of a post, the oldest first. Posts
+// not edited since revisions were introduced have none.
+func (postService *PostService) GetPostRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if _, err := repos.Posts.GetPostByID(ctx, postID); err != nil {
+			return apperr.Wrap(err, entityPost)
+		}
+		var err error
+		revisions, err = repos.Revisions.GetRevisions(ctx, postID)
+		return err
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityRevision)
+	}
+	return revisions, nil
+}
+
+func (postService *PostService) GetPostRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error) {
+	revision, err := postService.RevisionRepo.GetRevision(ctx, postID, number)
+	if err != nil {
+		return nil, apperr.Wrap(err, entityRevision)
+	}
+	return revision, nil
+}
+
+// RevisionDiff is what changed from one revision of a post to another
+type RevisionDiff struct {
+	PostID int64
+	From   int
+	To     int
+	// Words tells whether the edits are words or lines
+	Words     bool
+	Title     []diff.Edit
+	Thumbnail []diff.Edit
+	Content   []diff.Edit
+}
+
+// Changed reports whether the revisions differ
+func (d *RevisionDiff) Changed() bool {
+	return diff.Changed(d.Title) || diff.Changed(d.Thumbnail) || diff.Changed(d.Content)
+}
+
+// String prints the fields that changed, by lines like a unified diff or by
+// words like git diff --word-diff
+func (d *RevisionDiff) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- revision %d\n+++ revision %d\n", d.From, d.To)
+	if !d.Changed() {
+		sb.WriteString("no changes\n")
+		return sb.String()
+	}
+	for _, field := range []struct {
+		name  string
+		edits []diff.Edit
+	}{{"title", d.Title}, {"thumbnail", d.Thumbnail}, {"content", d.Content}} {
+		if !diff.Changed(field.edits) {
+			continue
+		}
+		fmt.Fprintf(&sb, "@@ %s @@\n", field.name)
+		if d.Words {
+			text := diff.FormatWords(field.edits)
+			sb.WriteString(text)
+			if !strings.HasSuffix(text, "\n") {
+				sb.WriteByte('\n')
+			}
+		} else {
+			sb.WriteString(diff.FormatLines(field.edits))
+		}
+	}
+	return sb.String()
+}
+
+// DiffPostRevisions compares two revisions of a post, from may be newer
+// than to
+func (postService *PostService) DiffPostRevisions(ctx context.Context, postID int64, from, to int, words bool) (*RevisionDiff, error) {
+	if from <= 0 {
+		return nil, apperr.Invalid(entityRevision, "from", "revisions are numbered from 1")
+	}
+	if to <= 0 {
+		return nil, apperr.Invalid(entityRevision, "to", "revisions are numbered from 1")
+	}
+	var old, updated *models.PostRevision
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if old, err = repos.Revisions.GetRevision(ctx, postID, from); err != nil {
+			return err
+		}
+		updated, err = repos.Revisions.GetRevision(ctx, postID, to)
+		return err
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityRevision)
+	}
+
+	compare := diff.Lines
+	if words {
+		compare = diff.Words
+	}
+	return &RevisionDiff{
+		PostID:    postID,
+		From:      from,
+		To:        to,
+		Words:     words,
+		Title:     compare(old.Title, updated.Title),
+		Thumbnail: compare(old.Thumbnail, updated.Thumbnail),
+		Content:   compare(old.Content, updated.Content),
+	}, nil
+}
+
+// RestorePostRevision puts the title, content and thumbnail of a revision
+// back into the post, as a new revision
+func (postService *PostService) RestorePostRevision(ctx context.Context, postID int64, number int) (*models.Post, error) {
+	var restored *models.Post
+	err := postService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		existingPost, err := repos.Posts.GetPostByID(ctx, postID)
+		if err != nil {
+			return apperr.Wrap(err, entityPost)
+		}
+		if err := checkActive(ctx, repos, int64(existingPost.UserID)); err != nil {
+			return err
+		}
+		revision, err := repos.Revisions.GetRevision(ctx, postID, number)
+		if err != nil {
+			return err
+		}
+
+		post := *existingPost
+		post.Title, post.Content, post.Thumbnail = revision.Title, revision.Content, revision.Thumbnail
+		post.UpdatedAt = time.Now()
+		if restored, err = repos.Posts.UpdatePost(ctx, postID, post); err != nil {
+			return apperr.Wrap(err, entityPost)
+		}
+		if err := audit(ctx, repos, actionRestore, entityPost, postID, existingPost, restored); err != nil {
+			return err
+		}
+		return postService.saveRevision(ctx, repos, *restored, number)
+	})
+	err = apperr.Wrap(err, entityRevision)
+	logResult(ctx, "restore post revision", err, slog.Int64("post_id", postID), slog.Int("revision", number))
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
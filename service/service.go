@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// Users is what the frontends use of the UserService
+type Users interface {
+	CreateUser(ctx context.Context, user models.User) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetUserByID(ctx context.Context, id int64) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error)
+	UpdateUserByID(ctx context.Context, user models.User) (*models.User, error)
+	DeleteUserByID(ctx context.Context, id int64) error
+	RequestEmailVerification(ctx context.Context, userID int64) error
+	VerifyEmail(ctx context.Context, token string) (*models.User, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	ChangePassword(ctx context.Context, userID int64, current, password string) error
+	LoginWithCode(ctx context.Context, username, password, code string) (*models.User, error)
+	EnrollTOTP(ctx context.Context, userID int64) (*TOTPSetup, error)
+	ConfirmTOTP(ctx context.Context, userID int64, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID int64, code string) error
+	ResetTOTP(ctx context.Context, userID int64) error
+	LoginWithIdentity(ctx context.Context, identity ExternalIdentity, code string) (*models.User, error)
+	LinkIdentity(ctx context.Context, userID int64, identity ExternalIdentity) (*models.Identity, error)
+	UnlinkIdentity(ctx context.Context, userID int64, provider string) error
+	GetUserIdentities(ctx context.Context, userID int64) ([]models.Identity, error)
+	DeactivateUser(ctx context.Context, id int64) error
+	SuspendUser(ctx context.Context, id int64, until time.Time, reason string) error
+	BanUser(ctx context.Context, id int64, reason string) error
+	ReactivateUser(ctx context.Context, id int64) error
+	EraseUser(ctx context.Context, id int64, policy ContentPolicy) error
+	ExportUser(ctx context.Context, id int64) (*UserExport, error)
+}
+
+// Posts is what the frontends use of the PostService
+type Posts interface {
+	CreatePost(ctx context.Context, post models.Post) (*models.Post, error)
+	GetAllPosts(ctx context.Context) ([]models.Post, error)
+	GetPostByID(ctx context.Context, id int64) (*models.Post, error)
+	GetPostByTitle(ctx context.Context, title string) (*models.Post, error)
+	GetPostByUserID(ctx context.Context, userid int64) ([]models.Post, error)
+	UpdatePostByID(ctx context.Context, post models.Post) (*models.Post, error)
+	DeletePostByID(ctx context.Context, id int64) error
+	GetPostRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error)
+	GetPostRevision(ctx context.Context, postID int64, number int) (*models.PostRevision, error)
+	DiffPostRevisions(ctx context.Context, postID int64, from, to int, words bool) (*RevisionDiff, error)
+	RestorePostRevision(ctx context.Context, postID int64, number int) (*models.Post, error)
+	SaveDraft(ctx context.Context, draft models.Draft) (*DraftState, error)
+	GetDraft(ctx context.Context, userID, postID int64) (*DraftState, error)
+	GetDrafts(ctx context.Context, userID int64) ([]models.Draft, error)
+	DiscardDraft(ctx context.Context, userID, postID int64) error
+	ApplyDraft(ctx context.Context, userID, postID int64, opts ApplyDraftOptions) (*models.Post, error)
+}
+
+// Comments is what the frontends use of the CommentService
+type Comments interface {
+	CreateComment(ctx context.Context, comment models.Comment) (*models.Comment, error)
+	GetAllComments(ctx context.Context) ([]models.Comment, error)
+	GetCommentByID(ctx context.Context, id int64) (*models.Comment, error)
+	GetCommentByUserID(ctx context.Context, userid int64) ([]models.Comment, error)
+	GetCommentByPostID(ctx context.Context, postid int64, sort repository.CommentSort) ([]models.Comment, error)
+	GetCommentByUserIDPostID(ctx context.Context, userid int64, postid int64) (*models.Comment, error)
+	UpdateCommentByID(ctx context.Context, comment models.Comment) (*models.Comment, error)
+	DeleteCommentByID(ctx context.Context, id int64) error
+	VoteComment(ctx context.Context, commentID, userID int64, value int) (*models.Comment, error)
+}
+
+// Reactions is what the frontends use of the ReactionService
+type Reactions interface {
+	ToggleReaction(ctx context.Context, reaction models.Reaction) (*ReactionToggle, error)
+	GetReactions(ctx context.Context, filter repository.ReactionFilter) (*ReactionPage, error)
+	ReactionKinds() []string
+}
+
+// Audit is what the frontends use of the AuditService
+type Audit interface {
+	GetAuditEvents(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEvent, error)
+}
+
+// Services groups the services working on the same store
+type Services struct {
+	Users     Users
+	Posts     Posts
+	Comments  Comments
+	Profiles  Profiles
+	Reactions Reactions
+	Audit     Audit
+}
+
+// Options are what the services need besides the store
+type Options struct {
+	// Mailer sends the email verification and password reset tokens
+	Mailer mail.Mailer
+	// MaxRevisions is how many revisions of every post are kept, 0 keeps
+	// all of them
+	MaxRevisions int
+	// Reactions are the emoji the users may react with besides a like, nil
+	// for DefaultReactions
+	Reactions []string
+	// Operators are the IDs of the users who may act on the accounts of
+	// others
+	Operators []int64
+}
+
+// New returns the services of store
+func New(store repository.Store, opts Options) Services {
+	repos := store.Repositories()
+	return Services{
+		Users:     NewUserService(repos.Users, store, opts.Mailer, opts.Operators),
+		Posts:     NewPostService(repos.Posts, repos.Revisions, repos.Drafts, repos.Reactions, store, opts.MaxRevisions),
+		Comments:  NewCommentService(repos.Comments, repos.Reactions, store),
+		Profiles:  NewProfileService(repos.Profiles, store),
+		Reactions: NewReactionService(repos.Reactions, store, opts.Reactions),
+		Audit:     NewAuditService(repos.Audit),
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/totp"
+)
+
+// TOTPIssuer names the blog in the authenticator apps
+const TOTPIssuer = "blog"
+
+// RecoveryCodes is how many recovery codes a user gets
+const RecoveryCodes = 10
+
+var (
+	// ErrTOTPRequired is returned by a login with the right password of a
+	// user with two factors, the login has to be repeated with a code
+	ErrTOTPRequired error = &apperr.Error{
+		Kind:    apperr.Forbidden,
+		Code:    "totp_required",
+		Entity:  entityUser,
+		Field:   "code",
+		Message: "enter the code of your authenticator app or a recovery code",
+	}
+	// ErrInvalidCode is returned for a wrong, reused or expired code
+	ErrInvalidCode error = &apperr.Error{
+		Kind:    apperr.Forbidden,
+		Code:    "invalid_code",
+		Entity:  entityUser,
+		Field:   "code",
+		Message: "the code is wrong or was already used",
+	}
+)
+
+// TOTPSetup is what an authenticator app needs, URI is usually shown as a QR
+// code
+type TOTPSetup struct {
+	Secret string
+	URI    string
+}
+
+// recoveryEncoding writes the recovery codes without characters that are
+// easily confused
+var recoveryEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns the plaintext codes and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodes)
+	hashes := make([]string, RecoveryCodes)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := recoveryEncoding.EncodeToString(raw)
+		codes[i] = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode drops the separators people type into codes
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.DefaultParams.Digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// enabledTwoFactor returns the second factor of a user, nil when it is not
+// enabled
+func enabledTwoFactor(ctx context.Context, repos repository.Repositories, userID int64) (*models.TwoFactor, error) {
+	twoFactor, err := repos.TwoFactors.GetTwoFactor(ctx, userID)
+	if errors.Is(err, repository.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.EnabledAt == nil {
+		return nil, nil
+	}
+	return twoFactor, nil
+}
+
+// checkCode accepts a TOTP code once, or an unused recovery code
+func checkCode(ctx context.Context, repos repository.Repositories, twoFactor models.TwoFactor, code string) error {
+	code = normalizeCode(code)
+	if code == "" {
+		return ErrTOTPRequired
+	}
+	now := time.Now()
+	if isTOTPCode(code) {
+		secret, err := totp.DecodeSecret(twoFactor.Secret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.DefaultParams.Validate(secret, code, now, twoFactor.LastStep)
+		if !ok {
+			return ErrInvalidCode
+		}
+		twoFactor.LastStep = step
+		return repos.TwoFactors.SaveTwoFactor(ctx, twoFactor)
+	}
+	err := repos.TwoFactors.UseRecoveryCode(ctx, twoFactor.UserID, hashToken(code), now)
+	if errors.Is(err, repository.ErrNotExist) {
+		return ErrInvalidCode
+	}
+	return err
+}
+
+// login checks the password and, when the user has two factors, the code
+func (userService *UserService) login(ctx context.Context, username, password, code string) (*models.User, error) {
+	var user *models.User
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		user, err = repos.Users.GetUserByUsernameAndPassword(ctx, username, password)
+		if err != nil {
+			return err
+		}
+		twoFactor, err := enabledTwoFactor(ctx, repos, user.ID)
+		if err != nil {
+			return err
+		}
+		if twoFactor != nil {
+			if err := checkCode(ctx, repos, *twoFactor, code); err != nil {
+				return err
+			}
+		}
+		return loginState(ctx, repos, user)
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityUser)
+	}
+	return user, nil
+}
+
+// LoginWithCode is GetUserByUsernameAndPassword for users with two factors,
+// code is a TOTP code or a recovery code. The code is ignored for users
+// without.
+func (userService *UserService) LoginWithCode(ctx context.Context, username, password, code string) (*models.User, error) {
+	return userService.login(ctx, username, password, code)
+}
+
+// EnrollTOTP starts the enrollment of a user with a new secret, it only
+// counts after ConfirmTOTP
+func (userService *UserService) EnrollTOTP(ctx context.Context, userID int64) (*TOTPSetup, error) {
+	var setup *TOTPSetup
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		enabled, err := enabledTwoFactor(ctx, repos, userID)
+		if err != nil {
+			return err
+		}
+		if enabled != nil {
+			return &apperr.Error{Kind: apperr.Conflict, Code: "totp_enabled", Entity: entityUser, Message: "two-factor authentication is already enabled"}
+		}
+		secret, err := totp.NewSecret()
+		if err != nil {
+			return err
+		}
+		encoded := totp.EncodeSecret(secret)
+		if err := repos.TwoFactors.SaveTwoFactor(ctx, models.TwoFactor{UserID: userID, Secret: encoded}); err != nil {
+			return err
+		}
+		setup = &TOTPSetup{Secret: encoded, URI: totp.DefaultParams.URI(TOTPIssuer, user.Username, secret)}
+		return nil
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "enroll totp", err, slog.Int64("user_id", userID))
+	if err != nil {
+		return nil, err
+	}
+	return setup, nil
+}
+
+// ConfirmTOTP enables the enrolled second factor with a first code and
+// returns the recovery codes, they are not shown again
+func (userService *UserService) ConfirmTOTP(ctx context.Context, userID int64, code string) ([]string, error) {
+	var codes []string
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		twoFactor, err := repos.TwoFactors.GetTwoFactor(ctx, userID)
+		if errors.Is(err, repository.ErrNotExist) {
+			return &apperr.Error{Kind: apperr.Conflict, Code: "totp_not_enrolled", Entity: entityUser, Message: "enroll in two-factor authentication first"}
+		}
+		if err != nil {
+			return err
+		}
+		if twoFactor.EnabledAt != nil {
+			return &apperr.Error{Kind: apperr.Conflict, Code: "totp_enabled", Entity: entityUser, Message: "two-factor authentication is already enabled"}
+		}
+		code = normalizeCode(code)
+		if !isTOTPCode(code) {
+			return ErrInvalidCode
+		}
+		secret, err := totp.DecodeSecret(twoFactor.Secret)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		step, ok := totp.DefaultParams.Validate(secret, code, now, 0)
+		if !ok {
+			return ErrInvalidCode
+		}
+		before := *twoFactor
+		twoFactor.EnabledAt, twoFactor.LastStep = &now, step
+		if err := repos.TwoFactors.SaveTwoFactor(ctx, *twoFactor); err != nil {
+			return err
+		}
+		if err := audit(ctx, repos, actionEnableTOTP, entityTwoFactor, userID, before, twoFactor); err != nil {
+			return err
+		}
+		var hashes []string
+		codes, hashes, err = newRecoveryCodes()
+		if err != nil {
+			return err
+		}
+		return repos.TwoFactors.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "confirm totp", err, slog.Int64("user_id", userID))
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns the second factor of a user off, it takes a code so a
+// stolen password alone cannot
+func (userService *UserService) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		twoFactor, err := enabledTwoFactor(ctx, repos, userID)
+		if err != nil {
+			return err
+		}
+		if twoFactor == nil {
+			return &apperr.Error{Kind: apperr.Conflict, Code: "totp_disabled", Entity: entityUser, Message: "two-factor authentication is not enabled"}
+		}
+		if err := checkCode(ctx, repos, *twoFactor, code); err != nil {
+			return err
+		}
+		if err := repos.TwoFactors.DeleteTwoFactor(ctx, userID); err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionDisableTOTP, entityTwoFactor, userID, twoFactor, nil)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "disable totp", err, slog.Int64("user_id", userID))
+	return err
+}
+
+// ResetTOTP removes the second factor of a user who lost it and the recovery
+// codes, only an operator acting in ctx may
+func (userService *UserService) ResetTOTP(ctx context.Context, userID int64) error {
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := userService.checkOperator(ctx, repos); err != nil {
+			return err
+		}
+		if _, err := repos.Users.GetUserByID(ctx, userID); err != nil {
+			return err
+		}
+		twoFactor, err := repos.TwoFactors.GetTwoFactor(ctx, userID)
+		if errors.Is(err, repository.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := repos.TwoFactors.DeleteTwoFactor(ctx, userID); err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionDisableTOTP, entityTwoFactor, userID, twoFactor, nil)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "reset totp", err, slog.Int64("user_id", userID))
+	return err
+}
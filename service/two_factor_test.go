@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+	"postgresql-blog/totp"
+)
+
+// enrolledUser returns a user with confirmed two factors, its secret and
+// recovery codes
+func enrolledUser(t *testing.T) (*UserService, repository.Store, *models.User, []byte, []string) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	users := NewUserService(store.Repositories().Users, store, mail.NewLog(io.Discard, "blog@localhost"), nil)
+	user, err := store.Repositories().Users.CreateUser(ctx, models.User{Username: "alice", Email: "alice@localhost", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setup, err := users.EnrollTOTP(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.DecodeSecret(setup.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := users.ConfirmTOTP(ctx, user.ID, totp.DefaultParams.Generate(secret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodes {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), RecoveryCodes)
+	}
+	return users, store, user, secret, codes
+}
+
+func TestCheckCodeRejectsUsedStep(t *testing.T) {
+	_, store, user, secret, _ := enrolledUser(t)
+	ctx := context.Background()
+	check := func(code string) error {
+		return store.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			twoFactor, err := enabledTwoFactor(ctx, repos, user.ID)
+			if err != nil {
+				return err
+			}
+			return checkCode(ctx, repos, *twoFactor, code)
+		})
+	}
+
+	// the code of the confirmation was used already
+	now := time.Now()
+	if err := check(totp.DefaultParams.Generate(secret, now)); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v for the code of the confirmation, want ErrInvalidCode", err)
+	}
+	next := totp.DefaultParams.Generate(secret, now.Add(totp.DefaultParams.Period))
+	if err := check(next); err != nil {
+		t.Fatalf("got %v for the code of the next step, want it accepted", err)
+	}
+	if err := check(next); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v for the code used twice, want ErrInvalidCode", err)
+	}
+	// an older step than the last one used is not accepted either
+	if err := check(totp.DefaultParams.Generate(secret, now.Add(-totp.DefaultParams.Period))); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v for the code of the step before, want ErrInvalidCode", err)
+	}
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	users, _, user, _, codes := enrolledUser(t)
+	ctx := context.Background()
+
+	if _, err := users.LoginWithCode(ctx, user.Username, user.Password, ""); !errors.Is(err, ErrTOTPRequired) {
+		t.Fatalf("got %v without a code, want ErrTOTPRequired", err)
+	}
+	if _, err := users.LoginWithCode(ctx, user.Username, user.Password, codes[0]); err != nil {
+		t.Fatalf("got %v for an unused recovery code, want the login", err)
+	}
+	if _, err := users.LoginWithCode(ctx, user.Username, user.Password, codes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v for a used recovery code, want ErrInvalidCode", err)
+	}
+	// the dashes of the shown codes are optional
+	if _, err := users.LoginWithCode(ctx, user.Username, user.Password, normalizeCode(codes[1])); err != nil {
+		t.Fatalf("got %v for a recovery code without dashes, want the login", err)
+	}
+	if _, err := users.LoginWithCode(ctx, user.Username, user.Password, "aaaa-bbbb-cccc-dddd"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("got %v for an unknown recovery code, want ErrInvalidCode", err)
+	}
+}
+
+func TestResetTOTPNeedsOperator(t *testing.T) {
+	users, store, user, _, _ := enrolledUser(t)
+	ctx := context.Background()
+	root, err := store.Repositories().Users.CreateUser(ctx, models.User{Username: "root", Email: "root@localhost", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users.operators[root.ID] = true
+
+	for name, ctx := range map[string]context.Context{
+		"nobody":        ctx,
+		"the user self": repository.WithActor(ctx, user.ID),
+	} {
+		if err := users.ResetTOTP(ctx, user.ID); !errors.Is(err, ErrOperatorRequired) {
+			t.Fatalf("got %v for %s, want ErrOperatorRequired", err, name)
+		}
+	}
+	if err := users.ResetTOTP(repository.WithActor(ctx, root.ID), user.ID); err != nil {
+		t.Fatalf("got %v for the operator, want the second factor removed", err)
+	}
+	if _, err := users.LoginWithCode(ctx, user.Username, user.Password, ""); err != nil {
+		t.Fatalf("got %v logging in after the reset, want the password alone to do", err)
+	}
+}
@@ -3,14 +3,15 @@ package service
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
+	"strings"
 
 	// "fmt"
 	// "log"
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
 	"postgresql-blog/models"
 	"postgresql-blog/repository"
-
-	"gorm.io/gorm"
 )
 
 // func CreateUser(ctx context.Context, userRepository repository.UserRepository) {
@@ -35,92 +36,246 @@ import (
 
 type UserService struct {
 	UserRepo repository.UserRepository
-	db       *gorm.DB
+	uow      repository.UnitOfWork
+	// mailer sends the tokens, they are dropped when it is nil
+	mailer mail.Mailer
+	// operators are the IDs of the operators
+	operators map[int64]bool
 }
 
-func NewUserService(userRepo repository.UserRepository, db *gorm.DB) *UserService {
-	return &UserService{
-		UserRepo: userRepo,
-		db:       db,
+func NewUserService(userRepo repository.UserRepository, uow repository.UnitOfWork, mailer mail.Mailer, operators []int64) *UserService {
+	userService := &UserService{
+		UserRepo:  userRepo,
+		uow:       uow,
+		mailer:    mailer,
+		operators: map[int64]bool{},
+	}
+	for _, id := range operators {
+		userService.operators[id] = true
 	}
+	return userService
 }
 
 func (userService *UserService) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
-	_, err := userService.UserRepo.GetUserByEmail(ctx, user.Email)
-	if err == nil || !errors.Is(err, repository.ErrNotExist) {
-		return nil, errors.New("user with this email already exists")
+	if strings.TrimSpace(user.Username) == "" {
+		return nil, apperr.Invalid(entityUser, "username", "a user needs a username")
+	}
+	if strings.TrimSpace(user.Email) == "" {
+		return nil, apperr.Invalid(entityUser, "email", "a user needs an email address")
 	}
 
-	return userService.UserRepo.CreateUser(ctx, user)
-}
+	// the account is usable once the mailed token confirms the address
+	user.EmailVerifiedAt = nil
 
-func (userService *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
-	var allUsers []models.GormUser
-	if err := userService.db.WithContext(ctx).Find(&allUsers).Error; err != nil {
+	var created *models.User
+	var m *tokenMail
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := checkEmailFree(ctx, repos, user.Email); err != nil {
+			return err
+		}
+		var err error
+		created, err = repos.Users.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		if err := audit(ctx, repos, actionCreate, entityUser, created.ID, nil, created); err != nil {
+			return err
+		}
+		m, err = verificationMail(ctx, repos, *created)
+		return err
+	})
+	if err != nil {
+		err = apperr.Wrap(err, entityUser)
+		logResult(ctx, "create user", err)
 		return nil, err
 	}
+	logResult(ctx, "create user", nil, slog.Int64("user_id", created.ID))
+	userService.send(ctx, m)
+	return created, nil
+}
 
-	var result []models.User
-	for _, user := range allUsers {
-		result = append(result, models.User(user))
+// GetAllUsers returns the users without their email addresses, unless the
+// address is public or of the caller
+func (userService *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if users, err = repos.Users.AllUsers(ctx); err != nil {
+			return err
+		}
+		profiles, err := repos.Profiles.AllProfiles(ctx)
+		if err != nil {
+			return err
+		}
+		byUser := make(map[int64]models.Profile, len(profiles))
+		for _, profile := range profiles {
+			byUser[profile.UserID] = profile
+		}
+		for i := range users {
+			if !emailVisible(ctx, users[i].ID, byUser[users[i].ID]) {
+				users[i].Email = ""
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityUser)
 	}
-
-	return result, nil
+	return users, nil
 }
 
+// GetUserByID hides the email address like GetAllUsers
 func (userService *UserService) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
-	user, err := userService.UserRepo.GetUserByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
+	var user *models.User
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if user, err = repos.Users.GetUserByID(ctx, id); err != nil {
+			return err
 		}
-		return nil, err
+		return hideEmail(ctx, repos, user)
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityUser)
 	}
 
 	// log.Printf("User found by id '%d': %+v\n", id, user)
 	return user, nil
 }
 
+// GetUserByEmail hides the email address like GetAllUsers, the caller knows
+// it anyway
 func (userService *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	user, err := userService.UserRepo.GetUserByEmail(ctx, email)
-	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
+	var user *models.User
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if user, err = repos.Users.GetUserByEmail(ctx, email); err != nil {
+			return err
 		}
-		return nil, err
+		return hideEmail(ctx, repos, user)
+	})
+	if err != nil {
+		return nil, apperr.Wrap(err, entityUser)
 	}
 
 	return user, nil
 }
 
-func (userService *UserService) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
-	user, err := userService.UserRepo.GetUserByUsernameAndPassword(ctx, username, password)
+// hideEmail blanks the email address of user unless the caller may see it
+func hideEmail(ctx context.Context, repos repository.Repositories, user *models.User) error {
+	profile, err := profileOf(ctx, repos, user.ID)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotExist) {
-			return nil, err
-		}
-		return nil, err
+		return err
 	}
+	if !emailVisible(ctx, user.ID, *profile) {
+		user.Email = ""
+	}
+	return nil
+}
 
-	return user, nil
+// GetUserByUsernameAndPassword returns ErrTOTPRequired for users with two
+// factors, they log in with LoginWithCode
+func (userService *UserService) GetUserByUsernameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+	return userService.login(ctx, username, password, "")
 }
 
+// UpdateUserByID changes a user, users change themselves and the operators
+// anybody. The password and the account state are kept, see ChangePassword
+// and the account actions. A new email address of a confirmed user is only
+// stored once the mailed token confirms it, until then the old address can
+// still reset the password.
 func (userService *UserService) UpdateUserByID(ctx context.Context, user models.User) (*models.User, error) {
-	existingUser, err := userService.UserRepo.GetUserByID(ctx, user.ID)
+	var existingUser *models.User
+	var m *tokenMail
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := userService.checkSelfOrOperator(user.ID)(ctx, repos); err != nil {
+			return err
+		}
+		var err error
+		existingUser, err = repos.Users.GetUserByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		// an empty address is one the caller was not shown, it is kept
+		email := user.Email
+		if email == "" {
+			email = existingUser.Email
+		}
+		user.Email, user.Password, user.EmailVerifiedAt = existingUser.Email, existingUser.Password, existingUser.EmailVerifiedAt
+		user.Status, user.SuspendedUntil, user.StatusReason = existingUser.Status, existingUser.SuspendedUntil, existingUser.StatusReason
+		changed := email != existingUser.Email
+		if changed {
+			if err := checkEmailFree(ctx, repos, email); err != nil {
+				return err
+			}
+			// an address nobody confirmed yet is not worth keeping
+			if existingUser.EmailVerifiedAt == nil {
+				user.Email = email
+			}
+		}
+		if _, err = repos.Users.UpdateUser(ctx, user.ID, user); err != nil {
+			return err
+		}
+		if err := audit(ctx, repos, actionUpdate, entityUser, user.ID, existingUser, user); err != nil {
+			return err
+		}
+		if changed {
+			pending := user
+			pending.Email = email
+			m, err = verificationMail(ctx, repos, pending)
+		}
+		return err
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "update user", err, slog.Int64("user_id", user.ID))
 	if err != nil {
 		return nil, err
 	}
-	if _, err := userService.UserRepo.UpdateUser(ctx, user.ID, user); err != nil {
-		log.Printf("Error updating user with ID %d: %v", user.ID, err)
-		return nil, err
-	}
+	userService.send(ctx, m)
 	return existingUser, nil
 }
 
-func (userService *UserService) DeleteUserByID(ctx context.Context, id int64) error {
-	if err := userService.UserRepo.DeleteUser(ctx, id); err != nil {
-		log.Printf("Error deleting user with ID %d: %v", id, err)
-		return err
+// checkEmailFree returns a Conflict error when a user has the address
+func checkEmailFree(ctx context.Context, repos repository.Repositories, email string) error {
+	_, err := repos.Users.GetUserByEmail(ctx, email)
+	if err == nil {
+		return &apperr.Error{
+			Kind:    apperr.Conflict,
+			Code:    "email_taken",
+			Entity:  entityUser,
+			Field:   "email",
+			Message: "user with this email already exists",
+			Err:     repository.ErrDuplicate,
+		}
 	}
-	return nil
+	if errors.Is(err, repository.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// DeleteUserByID deletes the user with the posts and comments, see EraseUser
+// to keep them. Users delete themselves, the operators anybody.
+func (userService *UserService) DeleteUserByID(ctx context.Context, id int64) error {
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := userService.checkSelfOrOperator(id)(ctx, repos); err != nil {
+			return err
+		}
+		user, err := repos.Users.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := removeContent(ctx, repos, id); err != nil {
+			return err
+		}
+		if err := deletePersonalData(ctx, repos, id); err != nil {
+			return err
+		}
+		if err := repos.Users.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionDelete, entityUser, id, user, nil)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "delete user", err, slog.Int64("user_id", id))
+	return err
 }
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// how long the mailed tokens can be used
+const (
+	VerifyEmailTTL   = 48 * time.Hour
+	ResetPasswordTTL = time.Hour
+)
+
+var (
+	errInvalidToken = &apperr.Error{
+		Kind:    apperr.Validation,
+		Code:    "invalid_token",
+		Entity:  entityUser,
+		Field:   "token",
+		Message: "the token is unknown, used or expired",
+	}
+	errWrongPassword = &apperr.Error{
+		Kind:    apperr.Forbidden,
+		Code:    "wrong_password",
+		Entity:  entityUser,
+		Field:   "password",
+		Message: "the current password is wrong",
+	}
+)
+
+// tokenMail is a token to mail once the unit of work that issued it is
+// committed
+type tokenMail struct {
+	template string
+	user     models.User
+	token    string
+	ttl      time.Duration
+}
+
+// issueToken replaces the tokens of purpose of user by a new one for the
+// address user.Email, only its hash is stored
+func issueToken(ctx context.Context, repos repository.Repositories, user models.User, purpose string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := repos.Tokens.DeleteUserTokens(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+	_, err := repos.Tokens.CreateToken(ctx, models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Hash:      hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return token, err
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// useToken uses the token of purpose and returns it with its user
+func useToken(ctx context.Context, repos repository.Repositories, purpose, token string) (*models.UserToken, *models.User, error) {
+	stored, err := repos.Tokens.UseToken(ctx, purpose, hashToken(strings.TrimSpace(token)), time.Now())
+	if errors.Is(err, repository.ErrNotExist) {
+		return nil, nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := repos.Users.GetUserByID(ctx, stored.UserID)
+	if errors.Is(err, repository.ErrNotExist) {
+		return nil, nil, errInvalidToken
+	}
+	return stored, user, err
+}
+
+// send mails m. The token is already stored, so a failure is logged and the
+// user can ask for another mail.
+func (userService *UserService) send(ctx context.Context, m *tokenMail) {
+	if userService.mailer == nil || m == nil {
+		return
+	}
+	msg, err := mail.Render(m.template, m.user.Email, map[string]any{
+		"Name":      m.user.Name,
+		"Username":  m.user.Username,
+		"Token":     m.token,
+		"ExpiresIn": hours(m.ttl),
+	})
+	if err == nil {
+		err = userService.mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "sending mail", slog.String("template", m.template), slog.Int64("user_id", m.user.ID), slog.Any("error", err))
+	}
+}
+
+// hours writes whole hours out for the mails
+func hours(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "1 hour"
+	case d > time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	default:
+		return d.String()
+	}
+}
+
+// verificationMail issues the email verification token of user.Email, a
+// new address of a user is only stored once it is confirmed
+func verificationMail(ctx context.Context, repos repository.Repositories, user models.User) (*tokenMail, error) {
+	token, err := issueToken(ctx, repos, user, models.TokenVerifyEmail, VerifyEmailTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenMail{template: mail.TemplateVerifyEmail, user: user, token: token, ttl: VerifyEmailTTL}, nil
+}
+
+// RequestEmailVerification mails a new verification token to a user whose
+// email address is not confirmed yet
+func (userService *UserService) RequestEmailVerification(ctx context.Context, userID int64) error {
+	var m *tokenMail
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return &apperr.Error{Kind: apperr.Conflict, Code: "already_verified", Entity: entityUser, Field: "email", Message: "the email address is already confirmed"}
+		}
+		m, err = verificationMail(ctx, repos, *user)
+		return err
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "request email verification", err, slog.Int64("user_id", userID))
+	if err != nil {
+		return err
+	}
+	userService.send(ctx, m)
+	return nil
+}
+
+// VerifyEmail confirms the email address the token was mailed to, a new
+// address replaces the one of the user
+func (userService *UserService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	var verified *models.User
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		stored, user, err := useToken(ctx, repos, models.TokenVerifyEmail, token)
+		if err != nil {
+			return err
+		}
+		before := *user
+		// tokens issued before they kept the address confirm the current one
+		if stored.Email != "" && stored.Email != user.Email {
+			if err := checkEmailFree(ctx, repos, stored.Email); err != nil {
+				return err
+			}
+			user.Email = stored.Email
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if verified, err = repos.Users.UpdateUser(ctx, user.ID, *user); err != nil {
+			return err
+		}
+		return audit(ctx, repos, actionVerifyEmail, entityUser, user.ID, before, verified)
+	})
+	if err != nil {
+		err = apperr.Wrap(err, entityUser)
+		logResult(ctx, "verify email", err)
+		return nil, err
+	}
+	logResult(ctx, "verify email", nil, slog.Int64("user_id", verified.ID))
+	return verified, nil
+}
+
+// RequestPasswordReset mails a password reset token to the user with email.
+// An unknown email is not an error, so the answer does not tell which
+// addresses have accounts.
+func (userService *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	var m *tokenMail
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		user, err := repos.Users.GetUserByEmail(ctx, strings.TrimSpace(email))
+		if errors.Is(err, repository.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		token, err := issueToken(ctx, repos, *user, models.TokenResetPassword, ResetPasswordTTL)
+		if err != nil {
+			return err
+		}
+		m = &tokenMail{template: mail.TemplateResetPassword, user: *user, token: token, ttl: ResetPasswordTTL}
+		return nil
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "request password reset", err)
+	if err != nil {
+		return err
+	}
+	userService.send(ctx, m)
+	return nil
+}
+
+// ResetPassword sets the password of the user the token was mailed to. The
+// mail reached the user, so the email address counts as confirmed too.
+func (userService *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	if password == "" {
+		return apperr.Invalid(entityUser, "password", "the new password is empty")
+	}
+
+	var userID int64
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		_, user, err := useToken(ctx, repos, models.TokenResetPassword, token)
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+		before := *user
+		user.Password = password
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		if _, err := repos.Users.UpdateUser(ctx, user.ID, *user); err != nil {
+			return err
+		}
+		if err := audit(ctx, repos, actionResetPassword, entityUser, user.ID, before, user); err != nil {
+			return err
+		}
+		return repos.Tokens.DeleteUserTokens(ctx, user.ID, models.TokenResetPassword)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "reset password", err, slog.Int64("user_id", userID))
+	return err
+}
+
+// ChangePassword sets the password of the user acting in ctx, who has to
+// give the current one. Users who forgot it reset it with a mailed token.
+func (userService *UserService) ChangePassword(ctx context.Context, userID int64, current, password string) error {
+	if password == "" {
+		return apperr.Invalid(entityUser, "password", "the new password is empty")
+	}
+	err := userService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if actor, ok := repository.ActorFromContext(ctx); !ok || actor != userID {
+			return &apperr.Error{Kind: apperr.Forbidden, Code: "not_own_password", Entity: entityUser, Message: "users only change their own password, the others reset it"}
+		}
+		user, err := repos.Users.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := repos.Users.GetUserByUsernameAndPassword(ctx, user.Username, current); errors.Is(err, repository.ErrNotExist) {
+			return errWrongPassword
+		} else if err != nil {
+			return err
+		}
+		before := *user
+		user.Password = password
+		if _, err := repos.Users.UpdateUser(ctx, user.ID, *user); err != nil {
+			return err
+		}
+		if err := audit(ctx, repos, actionChangePassword, entityUser, user.ID, before, user); err != nil {
+			return err
+		}
+		return repos.Tokens.DeleteUserTokens(ctx, user.ID, models.TokenResetPassword)
+	})
+	err = apperr.Wrap(err, entityUser)
+	logResult(ctx, "change password", err, slog.Int64("user_id", userID))
+	return err
+}
+
+// checkVerified returns a Forbidden error unless the account is active and
+// the user confirmed the email address
+func checkVerified(ctx context.Context, repos repository.Repositories, userID uint64) error {
+	user, err := repos.Users.GetUserByID(ctx, int64(userID))
+	if err != nil {
+		return apperr.Wrap(err, entityUser)
+	}
+	if err := accountError(*user, time.Now()); err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return &apperr.Error{
+			Kind:    apperr.Forbidden,
+			Code:    "email_unverified",
+			Entity:  entityUser,
+			Field:   "email",
+			Message: "confirm your email address before posting",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+// mailbox keeps the mails sent by the services
+type mailbox struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (b *mailbox) Send(ctx context.Context, msg mail.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+var mailedToken = regexp.MustCompile(`--token (\S+)`)
+
+// token returns the token of the last mail to address
+func (b *mailbox) token(t *testing.T, address string) string {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.messages) - 1; i >= 0; i-- {
+		if b.messages[i].To != address {
+			continue
+		}
+		if match := mailedToken.FindStringSubmatch(b.messages[i].Body); match != nil {
+			return match[1]
+		}
+	}
+	t.Fatalf("no token was mailed to %s", address)
+	return ""
+}
+
+func TestEmailChangeKeepsOldAddressUntilVerified(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	box := &mailbox{}
+	users := NewUserService(store.Repositories().Users, store, box, nil)
+	alice := verifiedUser(t, store.Repositories(), "alice")
+	asAlice := repository.WithActor(ctx, alice.ID)
+
+	changed := *alice
+	changed.Email = "alice@elsewhere"
+	if _, err := users.UpdateUserByID(asAlice, changed); err != nil {
+		t.Fatal(err)
+	}
+	user, err := users.GetUserByID(asAlice, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != alice.Email || user.EmailVerifiedAt == nil {
+		t.Fatalf("got %q, verified %v before the confirmation, want the old confirmed address", user.Email, user.EmailVerifiedAt != nil)
+	}
+
+	// the old address still resets the password
+	if err := users.RequestPasswordReset(ctx, alice.Email); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.ResetPassword(ctx, box.token(t, alice.Email), "new secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.VerifyEmail(ctx, box.token(t, "alice@elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+	if user, err = users.GetUserByID(asAlice, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "alice@elsewhere" {
+		t.Fatalf("got %q after the confirmation, want the new address", user.Email)
+	}
+}
+
+func TestChangePasswordNeedsCurrent(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	root, alice := verifiedUser(t, store.Repositories(), "root"), verifiedUser(t, store.Repositories(), "alice")
+	users := NewUserService(store.Repositories().Users, store, &mailbox{}, []int64{root.ID})
+	asRoot, asAlice := repository.WithActor(ctx, root.ID), repository.WithActor(ctx, alice.ID)
+
+	// the update keeps the password
+	changed := *alice
+	changed.Password = "taken over"
+	if _, err := users.UpdateUserByID(asAlice, changed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetUserByUsernameAndPassword(ctx, "alice", "secret"); err != nil {
+		t.Fatalf("the update changed the password: %v", err)
+	}
+
+	if err := users.ChangePassword(asAlice, alice.ID, "wrong", "new secret"); !apperr.Is(err, apperr.Forbidden) {
+		t.Fatalf("got %v with a wrong current password, want Forbidden", err)
+	}
+	if err := users.ChangePassword(asRoot, alice.ID, "secret", "new secret"); !apperr.Is(err, apperr.Forbidden) {
+		t.Fatalf("got %v changing the password of another user, want Forbidden", err)
+	}
+	if err := users.ChangePassword(asAlice, alice.ID, "secret", "new secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetUserByUsernameAndPassword(ctx, "alice", "new secret"); err != nil {
+		t.Fatalf("logging in with the new password: %v", err)
+	}
+	if _, err := users.GetUserByUsernameAndPassword(ctx, "alice", "secret"); !errors.Is(err, repository.ErrNotExist) {
+		t.Fatalf("got %v logging in with the old password, want ErrNotExist", err)
+	}
+}
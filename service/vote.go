@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+)
+
+// wilsonZ is the z-score of the 95% confidence of the Wilson lower bound
+const wilsonZ = 1.96
+
+// wilson is the lower bound of the Wilson score interval of the share of
+// upvotes, a comment with few votes ranks below one with as good a share of
+// many. It only depends on the counts of the comment, so it is kept up to
+// date with every vote instead of computed on every read.
+func wilson(up, down int) float64 {
+	n := float64(up + down)
+	if n <= 0 {
+		return 0
+	}
+	p := float64(up) / n
+	z2 := wilsonZ * wilsonZ
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+// controversy is high for comments with many votes split evenly between up
+// and down, zero when all votes agree
+func controversy(up, down int) float64 {
+	if up <= 0 || down <= 0 {
+		return 0
+	}
+	balance := float64(min(up, down)) / float64(max(up, down))
+	return math.Pow(float64(up+down), balance)
+}
+
+// checkCommentSort returns sort unless it is unknown, the empty sort is the
+// oldest first
+func checkCommentSort(sort repository.CommentSort) (repository.CommentSort, error) {
+	if sort == "" {
+		return repository.CommentSortOld, nil
+	}
+	for _, s := range repository.CommentSorts {
+		if s == sort {
+			return s, nil
+		}
+	}
+	return "", apperr.Invalid(entityComment, "sort", "the comments are sorted by old, new, top or controversial")
+}
+
+// VoteComment sets the vote of a user on a comment, models.VoteUp or
+// models.VoteDown, 0 takes the vote back. The counts and the ranking of the
+// comment are adjusted by the difference to the earlier vote.
+func (commentService *CommentService) VoteComment(ctx context.Context, commentID, userID int64, value int) (*models.Comment, error) {
+	if value != models.VoteUp && value != models.VoteDown && value != 0 {
+		return nil, apperr.Invalid(entityVote, "value", "a vote is 1 for up, -1 for down or 0 to take it back")
+	}
+
+	var comment *models.Comment
+	err := commentService.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if comment, err = repos.Comments.GetCommentByID(ctx, commentID); err != nil {
+			return apperr.Wrap(err, entityComment)
+		}
+		if err := checkVerified(ctx, repos, uint64(userID)); err != nil {
+			return err
+		}
+
+		existing, err := repos.Votes.GetVote(ctx, commentID, userID)
+		if err != nil && !errors.Is(err, repository.ErrNotExist) {
+			return err
+		}
+		previous := 0
+		if existing != nil {
+			previous = existing.Value
+		}
+		if previous == value {
+			return nil
+		}
+
+		switch {
+		case value == 0:
+			if err := repos.Votes.DeleteVote(ctx, existing.ID); err != nil {
+				return err
+			}
+			err = audit(ctx, repos, actionDelete, entityVote, existing.ID, existing, nil)
+		case existing == nil:
+			var saved *models.CommentVote
+			saved, err = repos.Votes.SaveVote(ctx, models.CommentVote{CommentID: commentID, UserID: userID, Value: value, CreatedAt: time.Now()})
+			if err != nil {
+				return err
+			}
+			err = audit(ctx, repos, actionCreate, entityVote, saved.ID, nil, saved)
+		default:
+			changed := *existing
+			changed.Value = value
+			var saved *models.CommentVote
+			if saved, err = repos.Votes.SaveVote(ctx, changed); err != nil {
+				return err
+			}
+			err = audit(ctx, repos, actionUpdate, entityVote, saved.ID, existing, saved)
+		}
+		if err != nil {
+			return err
+		}
+
+		up, down := tally(value)
+		upBefore, downBefore := tally(previous)
+		comment, err = addVotes(ctx, repos, commentID, up-upBefore, down-downBefore)
+		return err
+	})
+	err = apperr.Wrap(err, entityVote)
+	logResult(ctx, "vote comment", err, slog.Int64("comment_id", commentID), slog.Int("value", value))
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// tally returns the upvotes and downvotes a vote value counts as
+func tally(value int) (up, down int) {
+	switch value {
+	case models.VoteUp:
+		return 1, 0
+	case models.VoteDown:
+		return 0, 1
+	}
+	return 0, 0
+}
+
+// addVotes adds to the counts of a comment and ranks it by the new counts
+func addVotes(ctx context.Context, repos repository.Repositories, commentID int64, up, down int) (*models.Comment, error) {
+	comment, err := repos.Comments.AddCommentVotes(ctx, commentID, up, down)
+	if err != nil {
+		return nil, err
+	}
+	comment.Score, comment.Controversy = wilson(comment.Upvotes, comment.Downvotes), controversy(comment.Upvotes, comment.Downvotes)
+	if err := repos.Comments.SetCommentScore(ctx, commentID, comment.Score, comment.Controversy); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// retractVotes takes back all votes of a user, the comments they were
+// given to are ranked again
+func retractVotes(ctx context.Context, repos repository.Repositories, userID int64) error {
+	votes, err := repos.Votes.GetUserVotes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, vote := range votes {
+		if err := repos.Votes.DeleteVote(ctx, vote.ID); err != nil {
+			return err
+		}
+		up, down := tally(vote.Value)
+		if _, err := addVotes(ctx, repos, vote.CommentID, -up, -down); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"io"
+	"math"
+	"testing"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/mail"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/repository/memory"
+)
+
+func TestWilson(t *testing.T) {
+	tests := []struct {
+		up, down int
+		want     float64
+	}{
+		{0, 0, 0},
+		{0, 1, 0},
+		{1, 0, 0.2065},
+		{10, 0, 0.7225},
+		{5, 5, 0.2366},
+		{90, 10, 0.8256},
+	}
+	for _, tt := range tests {
+		if got := wilson(tt.up, tt.down); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("wilson(%d, %d) = %.4f, want %.4f", tt.up, tt.down, got, tt.want)
+		}
+	}
+	// as good a share of more votes ranks higher
+	if wilson(10, 0) <= wilson(1, 0) || wilson(90, 10) <= wilson(9, 1) {
+		t.Error("more votes of the same share do not rank higher")
+	}
+}
+
+func TestControversy(t *testing.T) {
+	tests := []struct {
+		up, down int
+		want     float64
+	}{
+		{0, 0, 0},
+		{5, 0, 0},
+		{0, 5, 0},
+		{1, 1, 2},
+		{5, 5, 10},
+		{1, 4, 1.4953},
+		{4, 1, 1.4953},
+	}
+	for _, tt := range tests {
+		if got := controversy(tt.up, tt.down); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("controversy(%d, %d) = %.4f, want %.4f", tt.up, tt.down, got, tt.want)
+		}
+	}
+}
+
+// checkVotes fails unless the comment has the counts and the ranking of up
+// and down votes
+func checkVotes(t *testing.T, repos repository.Repositories, commentID int64, up, down int) {
+	t.Helper()
+	comment, err := repos.Comments.GetCommentByID(context.Background(), commentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Upvotes != up || comment.Downvotes != down {
+		t.Fatalf("got %d up and %d down, want %d and %d", comment.Upvotes, comment.Downvotes, up, down)
+	}
+	if comment.Score != wilson(up, down) || comment.Controversy != controversy(up, down) {
+		t.Fatalf("got score %v and controversy %v for %d up and %d down", comment.Score, comment.Controversy, up, down)
+	}
+}
+
+func TestVoteComment(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	alice, bob := verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob")
+	post, err := services.Posts.CreatePost(ctx, models.Post{UserID: uint64(alice.ID), Title: "post", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment, err := services.Comments.CreateComment(ctx, models.Comment{UserID: uint64(alice.ID), PostID: uint64(post.ID), Content: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name     string
+		userID   int64
+		value    int
+		up, down int
+	}{
+		{"up", bob.ID, models.VoteUp, 1, 0},
+		{"up again", bob.ID, models.VoteUp, 1, 0},
+		{"up to down", bob.ID, models.VoteDown, 0, 1},
+		{"another user", alice.ID, models.VoteUp, 1, 1},
+		{"retract", bob.ID, 0, 1, 0},
+		{"retract again", bob.ID, 0, 1, 0},
+		{"down after the retract", bob.ID, models.VoteDown, 1, 1},
+	}
+	for _, step := range steps {
+		voted, err := services.Comments.VoteComment(ctx, comment.ID, step.userID, step.value)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if voted.Upvotes != step.up || voted.Downvotes != step.down {
+			t.Fatalf("%s: returned %d up and %d down, want %d and %d", step.name, voted.Upvotes, voted.Downvotes, step.up, step.down)
+		}
+		checkVotes(t, repos, comment.ID, step.up, step.down)
+	}
+	votes, err := repos.Votes.GetUserVotes(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || votes[0].Value != models.VoteDown {
+		t.Fatalf("bob has the votes %+v, want the one down vote", votes)
+	}
+
+	if _, err := services.Comments.VoteComment(ctx, comment.ID, bob.ID, 2); !apperr.Is(err, apperr.Validation) {
+		t.Fatalf("got %v voting 2, want a Validation error", err)
+	}
+	if _, err := services.Comments.VoteComment(ctx, comment.ID+1000, bob.ID, models.VoteUp); !apperr.Is(err, apperr.NotFound) {
+		t.Fatalf("got %v voting on a missing comment, want NotFound", err)
+	}
+}
+
+func TestCommentOrderByVotes(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	repos := store.Repositories()
+	services := New(store, Options{Mailer: mail.NewLog(io.Discard, "blog@localhost")})
+	alice, bob, carol := verifiedUser(t, repos, "alice"), verifiedUser(t, repos, "bob"), verifiedUser(t, repos, "carol")
+	post, err := services.Posts.CreatePost(ctx, models.Post{UserID: uint64(alice.ID), Title: "post", Content: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a user comments once on a post
+	ids := map[string]int64{}
+	for content, author := range map[string]*models.User{"split": alice, "liked": bob, "loved": carol} {
+		comment, err := services.Comments.CreateComment(ctx, models.Comment{UserID: uint64(author.ID), PostID: uint64(post.ID), Content: content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[content] = comment.ID
+	}
+	votes := []struct {
+		content string
+		userID  int64
+		value   int
+	}{
+		{"split", alice.ID, models.VoteUp},
+		{"split", bob.ID, models.VoteDown},
+		{"liked", bob.ID, models.VoteUp},
+		{"loved", alice.ID, models.VoteUp},
+		{"loved", bob.ID, models.VoteUp},
+	}
+	for _, vote := range votes {
+		if _, err := services.Comments.VoteComment(ctx, ids[vote.content], vote.userID, vote.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	top, err := services.Comments.GetCommentByPostID(ctx, post.ID, repository.CommentSortTop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 3 || top[0].Content != "loved" || top[1].Content != "liked" || top[2].Content != "split" {
+		t.Fatalf("got the top comments %v, want loved, liked and split", contents(top))
+	}
+	controversial, err := services.Comments.GetCommentByPostID(ctx, post.ID, repository.CommentSortControversial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(controversial) != 3 || controversial[0].Content != "split" {
+		t.Fatalf("got the controversial comments %v, want split first", contents(controversial))
+	}
+	if _, err := services.Comments.GetCommentByPostID(ctx, post.ID, "best"); !apperr.Is(err, apperr.Validation) {
+		t.Fatalf("got %v for an unknown sort, want a Validation error", err)
+	}
+}
+
+func contents(comments []models.Comment) []string {
+	var list []string
+	for _, comment := range comments {
+		list = append(list, comment.Content)
+	}
+	return list
+}
@@ -0,0 +1,137 @@
+// Package totp implements the time-based one-time passwords of RFC 6238,
+// the codes of the authenticator apps, on top of the HOTP algorithm of
+// RFC 4226.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Algorithm is the HMAC hash of the codes, SHA1 is what every authenticator
+// app supports
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+func (a Algorithm) hash() func() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
+
+type Params struct {
+	Digits    int
+	Period    time.Duration
+	Algorithm Algorithm
+	// Skew is how many periods before and after the current one are
+	// accepted, for clocks that are a little off
+	Skew int
+}
+
+// DefaultParams are the ones the authenticator apps assume
+var DefaultParams = Params{Digits: 6, Period: 30 * time.Second, Algorithm: SHA1, Skew: 1}
+
+// SecretSize is the size of a new secret, 160 bits as RFC 4226 recommends
+const SecretSize = 20
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random secret
+func NewSecret() ([]byte, error) {
+	secret := make([]byte, SecretSize)
+	_, err := rand.Read(secret)
+	return secret, err
+}
+
+// EncodeSecret returns the base32 form of secret the apps take
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// DecodeSecret parses a base32 secret, spaces and lower case are accepted
+func DecodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	return encoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Step returns the number of the period t is in
+func (p Params) Step(t time.Time) int64 {
+	return t.Unix() / int64(p.Period/time.Second)
+}
+
+// Code returns the code of the given step
+func (p Params) Code(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(p.Algorithm.hash(), secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation of RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < p.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", p.Digits, value%mod)
+}
+
+// Generate returns the code at t
+func (p Params) Generate(secret []byte, t time.Time) string {
+	return p.Code(secret, p.Step(t))
+}
+
+// Validate returns the step code is valid for at t, within the skew. Steps
+// up to after are rejected, so a code that was used once cannot be used
+// again.
+func (p Params) Validate(secret []byte, code string, t time.Time, after int64) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != p.Digits {
+		return 0, false
+	}
+	now := p.Step(t)
+	for step := now - int64(p.Skew); step <= now+int64(p.Skew); step++ {
+		if step <= after {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(p.Code(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI returns the otpauth URI of the secret, the authenticator apps read it
+// from a QR code or take it pasted
+func (p Params) URI(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", string(p.Algorithm))
+	query.Set("digits", fmt.Sprint(p.Digits))
+	query.Set("period", fmt.Sprint(int(p.Period/time.Second)))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
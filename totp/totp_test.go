@@ -0,0 +1,71 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// the test vectors of RFC 6238 Appendix B, every algorithm has its own seed
+func TestRFC6238(t *testing.T) {
+	seeds := map[Algorithm][]byte{
+		SHA1:   []byte("12345678901234567890"),
+		SHA256: []byte("12345678901234567890123456789012"),
+		SHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix  int64
+		codes map[Algorithm]string
+	}{
+		{59, map[Algorithm]string{SHA1: "94287082", SHA256: "46119246", SHA512: "90693936"}},
+		{1111111109, map[Algorithm]string{SHA1: "07081804", SHA256: "68084774", SHA512: "25091201"}},
+		{1111111111, map[Algorithm]string{SHA1: "14050471", SHA256: "67062674", SHA512: "99943326"}},
+		{1234567890, map[Algorithm]string{SHA1: "89005924", SHA256: "91819424", SHA512: "93441116"}},
+		{2000000000, map[Algorithm]string{SHA1: "69279037", SHA256: "90698825", SHA512: "38618901"}},
+		{20000000000, map[Algorithm]string{SHA1: "65353130", SHA256: "77737706", SHA512: "47863826"}},
+	}
+	for _, test := range tests {
+		for algorithm, want := range test.codes {
+			p := Params{Digits: 8, Period: 30 * time.Second, Algorithm: algorithm}
+			if got := p.Generate(seeds[algorithm], time.Unix(test.unix, 0)); got != want {
+				t.Errorf("%s at %d: got %s, want %s", algorithm, test.unix, got, want)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	p := DefaultParams
+	now := time.Unix(1234567890, 0)
+	current := p.Step(now)
+
+	if step, ok := p.Validate(secret, p.Generate(secret, now), now, 0); !ok || step != current {
+		t.Fatalf("got %d, %v for the current code, want %d", step, ok, current)
+	}
+	if _, ok := p.Validate(secret, p.Generate(secret, now.Add(-p.Period)), now, 0); !ok {
+		t.Fatal("the code of the step before was rejected within the skew")
+	}
+	if _, ok := p.Validate(secret, p.Generate(secret, now.Add(-2*p.Period)), now, 0); ok {
+		t.Fatal("the code of two steps before was accepted")
+	}
+	if _, ok := p.Validate(secret, p.Generate(secret, now), now, current); ok {
+		t.Fatal("a code of a used step was accepted")
+	}
+}
+
+func TestSecretEncoding(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := EncodeSecret(secret)
+	// the way people copy a secret from a screen
+	decoded, err := DecodeSecret(" " + encoded[:4] + " " + strings.ToLower(encoded[4:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != string(secret) {
+		t.Fatalf("got %x, want %x", decoded, secret)
+	}
+}
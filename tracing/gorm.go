@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	gormPluginName = "blog:tracing"
+	gormSpanKey    = "tracing:span"
+)
+
+// GormPlugin records a span for every statement run through a *gorm.DB, as a
+// child of the span in the context of the statement. The statement is
+// recorded with its placeholders, the values never reach the spans.
+type GormPlugin struct{}
+
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{}
+}
+
+func (p *GormPlugin) Name() string {
+	return gormPluginName
+}
+
+// Initialize registers the callbacks around every kind of statement
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tracing:start", startSpan("gorm.Create")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tracing:start", startSpan("gorm.Query")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tracing:start", startSpan("gorm.Update")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tracing:start", startSpan("gorm.Delete")); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tracing:start", startSpan("gorm.Row")); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("tracing:start", startSpan("gorm.Raw")); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("tracing:end", endSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("gorm:query").Register("tracing:end", endSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("tracing:end", endSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("tracing:end", endSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("gorm:row").Register("tracing:end", endSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("gorm:raw").Register("tracing:end", endSpan); err != nil {
+		return err
+	}
+	return nil
+}
+
+func startSpan(name string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := tracer().Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemKey.String(db.Dialector.Name())),
+		)
+		if db.Statement.Table != "" {
+			span.SetAttributes(semconv.DBSQLTable(db.Statement.Table))
+		}
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(semconv.DBStatement(db.Statement.SQL.String()))
+	// row statements leave the count unknown
+	if db.Statement.RowsAffected >= 0 {
+		span.SetAttributes(rowsKey.Int64(db.Statement.RowsAffected))
+	}
+	// a lookup finding nothing is an answer, not a failure
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
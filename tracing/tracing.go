@@ -0,0 +1,137 @@
+// Package tracing records OpenTelemetry spans of the service calls and of
+// the SQL statements they run. Without an exporter the spans go to the no-op
+// tracer, so the hooks cost next to nothing when tracing is off.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"postgresql-blog/intercept"
+	"postgresql-blog/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// span exporters
+const (
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const (
+	instrumentationName = "postgresql-blog"
+	serviceName         = "postgresql-blog"
+)
+
+// attributes of the spans
+var (
+	layerKey    = attribute.Key("blog.layer")
+	entityKey   = attribute.Key("blog.entity")
+	entityIDKey = attribute.Key("blog.entity.id")
+	rowsKey     = attribute.Key("blog.rows")
+)
+
+type Config struct {
+	// Exporter is ExporterStdout, ExporterOTLP or empty to record nothing
+	Exporter string
+	// Output is where the stdout exporter writes
+	Output io.Writer
+}
+
+// ConfigFromEnv reads BLOG_TRACE_EXPORTER (stdout or otlp) and
+// BLOG_TRACE_FILE, the stdout exporter writes to standard error when no file
+// is given. The otlp exporter is set up by the OTEL_EXPORTER_OTLP_*
+// variables.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Exporter: strings.ToLower(os.Getenv("BLOG_TRACE_EXPORTER")), Output: os.Stderr}
+	switch cfg.Exporter {
+	case "", ExporterStdout, ExporterOTLP:
+	default:
+		return cfg, fmt.Errorf("BLOG_TRACE_EXPORTER: unknown exporter %q", cfg.Exporter)
+	}
+	if file := os.Getenv("BLOG_TRACE_FILE"); file != "" {
+		output, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return cfg, fmt.Errorf("BLOG_TRACE_FILE: %w", err)
+		}
+		cfg.Output = output
+	}
+	return cfg, nil
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes the spans that are not exported
+// yet and stops the exporter.
+func Setup(ctx context.Context, cfg Config) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		output := cfg.Output
+		if output == nil {
+			output = os.Stderr
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(output))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		err = fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Intercept is the intercept.Interceptor recording a span for every call,
+// named after the method like "PostService.CreatePost"
+func Intercept(ctx context.Context, op *intercept.Op, call func(ctx context.Context) error) error {
+	ctx, span := tracer().Start(ctx, op.Type+"."+op.Method, trace.WithAttributes(
+		layerKey.String(op.Layer),
+		entityKey.String(op.Entity),
+	))
+	defer span.End()
+	if op.ID != 0 {
+		span.SetAttributes(entityIDKey.Int64(op.ID))
+	}
+	if userID, ok := repository.ActorFromContext(ctx); ok {
+		span.SetAttributes(semconv.EnduserID(fmt.Sprint(userID)))
+	}
+
+	err := call(ctx)
+	span.SetAttributes(rowsKey.Int(op.Rows))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
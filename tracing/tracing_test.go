@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"postgresql-blog/intercept"
+	"postgresql-blog/repository"
+
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// record installs a tracer provider keeping the ended spans for the test
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestInterceptWithGormStatements(t *testing.T) {
+	recorder := record(t)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(NewGormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := repository.WithActor(context.Background(), 3)
+	op := &intercept.Op{Layer: intercept.LayerService, Entity: "posts", Type: "PostService", Method: "GetPostByID", ID: 7}
+	err = Intercept(ctx, op, func(ctx context.Context) error {
+		var n int
+		op.Rows = 1
+		return db.WithContext(ctx).Raw("SELECT ?", 1).Scan(&n).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want the statement and the call", len(spans))
+	}
+	statement, call := spans[0], spans[1]
+	if call.Name() != "PostService.GetPostByID" || statement.Name() != "gorm.Row" {
+		t.Fatalf("got the spans %q and %q", statement.Name(), call.Name())
+	}
+	// the statement is a child of the call
+	if statement.Parent().SpanID() != call.SpanContext().SpanID() || statement.SpanContext().TraceID() != call.SpanContext().TraceID() {
+		t.Fatal("the statement span is no child of the call span")
+	}
+	got := attributes(call)
+	if got[entityIDKey].AsInt64() != 7 || got[rowsKey].AsInt64() != 1 || got[layerKey].AsString() != intercept.LayerService || got["enduser.id"].AsString() != "3" {
+		t.Fatalf("the call span has the attributes %v", call.Attributes())
+	}
+	if sql := attributes(statement)["db.statement"].AsString(); sql != "SELECT ?" {
+		t.Fatalf("the statement span has the statement %q, want the one with the placeholder", sql)
+	}
+	if call.Status().Code == codes.Error {
+		t.Fatal("the successful call has an error status")
+	}
+}
+
+func TestInterceptRecordsErrors(t *testing.T) {
+	recorder := record(t)
+	failure := errors.New("boom")
+	op := &intercept.Op{Layer: intercept.LayerRepository, Entity: "users", Type: "UserRepository", Method: "GetUserByID"}
+	if err := Intercept(context.Background(), op, func(context.Context) error { return failure }); err != failure {
+		t.Fatalf("Intercept returned %v, want the error of the call", err)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" {
+		t.Fatalf("got the spans %v, want one with the error status", spans)
+	}
+	if _, ok := attributes(spans[0])[entityIDKey]; ok {
+		t.Fatal("a call without an id has an id attribute")
+	}
+}
+
+func TestHandlerContinuesTrace(t *testing.T) {
+	recorder := record(t)
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want the request", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /readyz" || span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("got the span %q in trace %s, want the one of the caller", span.Name(), span.SpanContext().TraceID())
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("the span has the parent %s, want the caller", span.Parent().SpanID())
+	}
+	if attributes(span)["http.status_code"].AsInt64() != http.StatusServiceUnavailable || span.Status().Code != codes.Error {
+		t.Fatalf("the span of a 503 has the attributes %v and the status %v", span.Attributes(), span.Status())
+	}
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// autosaveInterval is how often the post editor saves a draft while the
+// text changes
+const autosaveInterval = 10 * time.Second
+
+type (
+	// autosaveMsg asks to save the post editor f as the draft of postID
+	autosaveMsg struct {
+		f      *form
+		postID int64
+	}
+	// draftMsg is a draft of the post editor f that was saved, or resumed
+	// when it was opened
+	draftMsg struct {
+		f       *form
+		state   *service.DraftState
+		resumed bool
+	}
+)
+
+// autosave saves the values of the post editor f as the draft of postID
+// every autosaveInterval, until the editor is closed
+func (m *model) autosave(f *form, postID int64) tea.Cmd {
+	return tea.Tick(autosaveInterval, func(time.Time) tea.Msg {
+		return autosaveMsg{f: f, postID: postID}
+	})
+}
+
+// resumeDraft fills the post editor f with the draft saved for postID, if
+// there is one
+func (m *model) resumeDraft(f *form, postID int64) tea.Cmd {
+	userID := m.user.ID
+	return func() tea.Msg {
+		state, err := m.postService.GetDraft(m.context(), userID, postID)
+		if errors.Is(err, repository.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return draftMsg{f: f, state: state, resumed: true}
+	}
+}
+
+// saveDraft stores the values of the post editor f, title, thumbnail and
+// content, as the draft of postID
+func (m *model) saveDraft(f *form, postID int64, values []string) tea.Cmd {
+	f.saved = values
+	draft := models.Draft{UserID: m.user.ID, PostID: postID, Title: values[0], Thumbnail: values[1], Content: values[2]}
+	return func() tea.Msg {
+		state, err := m.postService.SaveDraft(m.context(), draft)
+		if err != nil {
+			return errMsg{err}
+		}
+		return draftMsg{f: f, state: state}
+	}
+}
+
+// discardDraft deletes the draft of postID once the post is saved, there
+// may be none
+func (m *model) discardDraft(postID int64) error {
+	err := m.postService.DiscardDraft(m.context(), m.user.ID, postID)
+	if errors.Is(err, repository.ErrDeleteFailed) {
+		return nil
+	}
+	return err
+}
+
+func (m *model) updateDraft(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case autosaveMsg:
+		if m.form != msg.f {
+			// the editor was closed
+			return nil
+		}
+		next := m.autosave(msg.f, msg.postID)
+		values := msg.f.values()
+		if slices.Equal(values, msg.f.saved) {
+			return next
+		}
+		return tea.Batch(m.saveDraft(msg.f, msg.postID, values), next)
+
+	case draftMsg:
+		if m.form != msg.f {
+			return nil
+		}
+		draft := msg.state.Draft
+		if msg.resumed {
+			msg.f.setValues([]string{draft.Title, draft.Thumbnail, draft.Content})
+			msg.f.saved = msg.f.values()
+			msg.f.note = fmt.Sprintf("Resumed the draft saved at %s", draft.UpdatedAt.Format(time.DateTime))
+		} else {
+			msg.f.note = fmt.Sprintf("Draft saved at %s", draft.UpdatedAt.Format(time.TimeOnly))
+		}
+		msg.f.warnings = msg.state.Warnings()
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// field is one input of a form, either a single line or a multi-line area
+type field struct {
+	label     string
+	multiline bool
+	input     textinput.Model
+	area      textarea.Model
+}
+
+// form edits a set of fields; tab moves between them, ctrl+s submits and
+// esc cancels
+type form struct {
+	title    string
+	fields   []*field
+	focus    int
+	onSubmit func(values []string) tea.Cmd
+	// onCancel runs on esc when it is set
+	onCancel func(values []string) tea.Cmd
+	// note and warnings are shown under the title, like the autosaves of a
+	// draft and the changes of others
+	note     string
+	warnings []string
+	// saved are the values of the last autosave
+	saved []string
+}
+
+func newInput(label, value string, password bool) *field {
+	input := textinput.New()
+	input.Prompt = ""
+	input.CharLimit = 256
+	input.SetValue(value)
+	if password {
+		input.EchoMode = textinput.EchoPassword
+		input.EchoCharacter = '•'
+	}
+	return &field{label: label, input: input}
+}
+
+func newArea(label, value string) *field {
+	area := textarea.New()
+	area.ShowLineNumbers = false
+	area.CharLimit = 0
+	area.SetValue(value)
+	return &field{label: label, multiline: true, area: area}
+}
+
+func newForm(title string, onSubmit func(values []string) tea.Cmd, fields ...*field) *form {
+	f := &form{title: title, fields: fields, onSubmit: onSubmit}
+	f.setFocus(0)
+	return f
+}
+
+func (f *form) setFocus(i int) tea.Cmd {
+	f.focus = (i + len(f.fields)) % len(f.fields)
+	var cmd tea.Cmd
+	for j, fld := range f.fields {
+		switch {
+		case j == f.focus && fld.multiline:
+			cmd = fld.area.Focus()
+		case j == f.focus:
+			cmd = fld.input.Focus()
+		case fld.multiline:
+			fld.area.Blur()
+		default:
+			fld.input.Blur()
+		}
+	}
+	return cmd
+}
+
+func (f *form) values() []string {
+	values := make([]string, len(f.fields))
+	for i, fld := range f.fields {
+		if fld.multiline {
+			values[i] = strings.TrimRight(fld.area.Value(), "\n")
+		} else {
+			values[i] = strings.TrimSpace(fld.input.Value())
+		}
+	}
+	return values
+}
+
+func (f *form) resize(width, height int) {
+	// single line fields take two rows each, the areas share the rest
+	areas, rest := 0, height-4
+	for _, fld := range f.fields {
+		if fld.multiline {
+			areas++
+		} else {
+			rest -= 2
+		}
+	}
+	for _, fld := range f.fields {
+		if fld.multiline {
+			fld.area.SetWidth(width - 2)
+			fld.area.SetHeight(max(3, rest/max(1, areas)-1))
+		} else {
+			fld.input.Width = width - 4
+		}
+	}
+}
+
+// update handles a message and reports whether the form is done, either
+// submitted or cancelled
+func (f *form) update(msg tea.Msg) (tea.Cmd, bool) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "esc":
+			if f.onCancel != nil {
+				return f.onCancel(f.values()), true
+			}
+			return nil, true
+		case "ctrl+s":
+			return f.onSubmit(f.values()), true
+		case "tab":
+			return f.setFocus(f.focus + 1), false
+		case "shift+tab":
+			return f.setFocus(f.focus - 1), false
+		case "enter":
+			// enter submits from the last single line field and moves on
+			// from the others, areas keep it for new lines
+			if !f.fields[f.focus].multiline {
+				if f.focus == len(f.fields)-1 {
+					return f.onSubmit(f.values()), true
+				}
+				return f.setFocus(f.focus + 1), false
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	fld := f.fields[f.focus]
+	if fld.multiline {
+		fld.area, cmd = fld.area.Update(msg)
+	} else {
+		fld.input, cmd = fld.input.Update(msg)
+	}
+	return cmd, false
+}
+
+func (f *form) setValues(values []string) {
+	for i, fld := range f.fields {
+		if i >= len(values) {
+			break
+		}
+		if fld.multiline {
+			fld.area.SetValue(values[i])
+		} else {
+			fld.input.SetValue(values[i])
+		}
+	}
+}
+
+func (f *form) view() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(f.title) + "\n")
+	if f.note != "" {
+		b.WriteString(labelStyle.Render(f.note) + "\n")
+	}
+	for _, warning := range f.warnings {
+		b.WriteString(errorStyle.Render("Warning: "+warning) + "\n")
+	}
+	b.WriteString("\n")
+	for i, fld := range f.fields {
+		label := labelStyle.Render(fld.label)
+		if i == f.focus {
+			label = selectedStyle.Render(fld.label)
+		}
+		b.WriteString(label + "\n")
+		if fld.multiline {
+			b.WriteString(fld.area.View() + "\n")
+		} else {
+			b.WriteString("  " + fld.input.View() + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,451 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"postgresql-blog/apperr"
+	"postgresql-blog/models"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// postList is the paginated and searchable list of posts
+type postList struct {
+	all       []models.Post
+	shown     []models.Post
+	cursor    int
+	mine      bool
+	searching bool
+	search    textinput.Model
+}
+
+func newPostList() postList {
+	search := textinput.New()
+	search.Prompt = "/"
+	search.Placeholder = "search titles and content"
+	return postList{search: search}
+}
+
+func (l *postList) setPosts(posts []models.Post) {
+	l.all = posts
+	l.filter()
+}
+
+func (l *postList) filter() {
+	query := strings.ToLower(strings.TrimSpace(l.search.Value()))
+	l.shown = l.shown[:0]
+	for _, post := range l.all {
+		if query == "" ||
+			strings.Contains(strings.ToLower(post.Title), query) ||
+			strings.Contains(strings.ToLower(post.Content), query) {
+			l.shown = append(l.shown, post)
+		}
+	}
+	l.cursor = min(l.cursor, max(0, len(l.shown)-1))
+}
+
+func (l *postList) selected() *models.Post {
+	if len(l.shown) == 0 {
+		return nil
+	}
+	post := l.shown[l.cursor]
+	return &post
+}
+
+func (m *model) pageSize() int {
+	return max(3, m.height-8)
+}
+
+func (m *model) loadPosts() tea.Cmd {
+	mine := m.postList.mine
+	return func() tea.Msg {
+		var posts []models.Post
+		var err error
+		if mine {
+			posts, err = m.postService.GetPostByUserID(m.context(), m.user.ID)
+		} else {
+			posts, err = m.postService.GetAllPosts(m.context())
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return postsMsg{posts}
+	}
+}
+
+func (m *model) loadComments(postID int64) tea.Cmd {
+	sort := m.commentSort
+	return func() tea.Msg {
+		comments, err := m.commentService.GetCommentByPostID(m.context(), postID, sort)
+		if err != nil {
+			return errMsg{err}
+		}
+		return commentsMsg{comments}
+	}
+}
+
+func (m *model) updatePosts(msg tea.Msg) tea.Cmd {
+	l := &m.postList
+	key, ok := msg.(tea.KeyMsg)
+	if l.searching {
+		if ok && (key.String() == "enter" || key.String() == "esc") {
+			l.searching = false
+			l.search.Blur()
+			if key.String() == "esc" {
+				l.search.SetValue("")
+				l.filter()
+			}
+			return nil
+		}
+		var cmd tea.Cmd
+		l.search, cmd = l.search.Update(msg)
+		l.filter()
+		return cmd
+	}
+	if !ok {
+		return nil
+	}
+
+	page := m.pageSize()
+	switch key.String() {
+	case "q":
+		return tea.Quit
+	case "up", "k":
+		l.cursor = max(0, l.cursor-1)
+	case "down", "j":
+		l.cursor = min(max(0, len(l.shown)-1), l.cursor+1)
+	case "right", "l", "pgdown":
+		l.cursor = min(max(0, len(l.shown)-1), (l.cursor/page+1)*page)
+	case "left", "h", "pgup":
+		l.cursor = max(0, (l.cursor/page-1)*page)
+	case "home", "g":
+		l.cursor = 0
+	case "end", "G":
+		l.cursor = max(0, len(l.shown)-1)
+	case "/":
+		l.searching = true
+		return l.search.Focus()
+	case "m":
+		l.mine = !l.mine
+		l.cursor = 0
+		return m.loadPosts()
+	case "r":
+		return m.loadPosts()
+	case "u":
+		m.screen = screenUsers
+		return m.loadUsers()
+	case "enter":
+		if post := l.selected(); post != nil {
+			return m.openPost(*post)
+		}
+	case "n":
+		return m.editPost(nil)
+	case "e":
+		if post := l.selected(); post != nil {
+			return m.editPost(post)
+		}
+	case "d":
+		if post := l.selected(); post != nil {
+			return m.deletePost(*post)
+		}
+	}
+	return nil
+}
+
+func (m *model) viewPosts() (string, string) {
+	l := &m.postList
+	var b strings.Builder
+
+	title := "All posts"
+	if l.mine {
+		title = "Your posts"
+	}
+	page, pages := 1, 1
+	if size := m.pageSize(); len(l.shown) > 0 {
+		page, pages = l.cursor/size+1, (len(l.shown)-1)/size+1
+	}
+	fmt.Fprintf(&b, "%s  %s\n", titleStyle.Render(title), labelStyle.Render(fmt.Sprintf("page %d/%d, %d posts", page, pages, len(l.shown))))
+	if l.searching || l.search.Value() != "" {
+		b.WriteString(l.search.View())
+	}
+	b.WriteString("\n\n")
+
+	size := m.pageSize()
+	start := l.cursor / size * size
+	end := min(len(l.shown), start+size)
+	for i := start; i < end; i++ {
+		post := l.shown[i]
+		line := fmt.Sprintf("%4d  %-30s  %s", post.ID, truncate(post.Title, 30), truncate(post.Content, max(10, m.width-45)))
+		if i == l.cursor {
+			b.WriteString(selectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if len(l.shown) == 0 {
+		b.WriteString(labelStyle.Render("  no posts") + "\n")
+	}
+
+	return b.String(), "↑/↓ move • ←/→ page • enter open • / search • n new • e edit • d delete • m mine/all • u users • r refresh • q quit"
+}
+
+func (m *model) openPost(post models.Post) tea.Cmd {
+	m.post = &post
+	m.comments = nil
+	m.comment = 0
+	m.scroll = 0
+	m.screen = screenPost
+	return m.loadComments(post.ID)
+}
+
+func (m *model) loadPost(id int64) tea.Cmd {
+	return func() tea.Msg {
+		post, err := m.postService.GetPostByID(m.context(), id)
+		if err != nil {
+			return errMsg{err}
+		}
+		return postMsg{post}
+	}
+}
+
+func (m *model) reloadPost() tea.Cmd {
+	id := m.post.ID
+	return tea.Batch(m.loadPosts(), m.loadComments(id), m.loadPost(id))
+}
+
+func (m *model) updatePost(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	switch key.String() {
+	case "esc", "q", "backspace":
+		m.screen = screenPosts
+		return m.loadPosts()
+	case "up", "k":
+		m.comment = max(0, m.comment-1)
+	case "down", "j":
+		m.comment = min(max(0, len(m.comments)-1), m.comment+1)
+	case "K", "ctrl+u":
+		m.scroll = max(0, m.scroll-1)
+	case "J", "ctrl+d":
+		m.scroll = min(max(0, strings.Count(m.post.Content, "\n")), m.scroll+1)
+	case "r":
+		return m.reloadPost()
+	case "E":
+		return m.editPost(m.post)
+	case "c":
+		return m.editComment(nil)
+	case "e":
+		if len(m.comments) > 0 {
+			return m.editComment(&m.comments[m.comment])
+		}
+	case "d":
+		if len(m.comments) > 0 {
+			return m.deleteComment(m.comments[m.comment])
+		}
+	case "D":
+		return m.deletePost(*m.post)
+	case "l":
+		return m.like(models.TargetPost, m.post.ID)
+	case "L":
+		if len(m.comments) > 0 {
+			return m.like(models.TargetComment, m.comments[m.comment].ID)
+		}
+	case "+":
+		if len(m.comments) > 0 {
+			return m.vote(m.comments[m.comment].ID, models.VoteUp)
+		}
+	case "-":
+		if len(m.comments) > 0 {
+			return m.vote(m.comments[m.comment].ID, models.VoteDown)
+		}
+	case "0":
+		if len(m.comments) > 0 {
+			return m.vote(m.comments[m.comment].ID, 0)
+		}
+	case "s":
+		return m.nextCommentSort()
+	}
+	return nil
+}
+
+func (m *model) viewPost() (string, string) {
+	post := m.post
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", titleStyle.Render(post.Title))
+	header := fmt.Sprintf("post %d by user %d, created %s", post.ID, post.UserID, post.CreatedAt.Format(time.DateTime))
+	if counts := formatReactions(post.Reactions); counts != "" {
+		header += " • " + counts
+	}
+	fmt.Fprintf(&b, "%s\n", labelStyle.Render(header))
+
+	// the post body gets half of the screen, the comments the rest
+	bodyHeight := max(3, m.height/2-4)
+	lines := strings.Split(post.Content, "\n")
+	start := min(m.scroll, max(0, len(lines)-1))
+	end := min(len(lines), start+bodyHeight)
+	b.WriteString(paneStyle.Width(max(20, m.width-4)).Render(strings.Join(lines[start:end], "\n")) + "\n")
+
+	fmt.Fprintf(&b, "%s  %s\n", titleStyle.Render(fmt.Sprintf("Comments (%d)", len(m.comments))), labelStyle.Render(commentSortName(m.commentSort)))
+	size := max(1, m.height-bodyHeight-10)
+	from, to := visible(m.comment, len(m.comments), size)
+	for i := from; i < to; i++ {
+		comment := m.comments[i]
+		counts := formatReactions(comment.Reactions)
+		if counts != "" {
+			counts = "  " + counts
+		}
+		if comment.Upvotes != 0 || comment.Downvotes != 0 {
+			counts = fmt.Sprintf("  +%d -%d", comment.Upvotes, comment.Downvotes) + counts
+		}
+		line := fmt.Sprintf("%4d  user %-4d  %s%s", comment.ID, comment.UserID, truncate(comment.Content, max(10, m.width-22-len([]rune(counts)))), counts)
+		if i == m.comment {
+			b.WriteString(selectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if len(m.comments) == 0 {
+		b.WriteString(labelStyle.Render("  no comments yet") + "\n")
+	}
+
+	return b.String(), "↑/↓ comments • J/K scroll post • l like post • L like comment • +/-/0 vote up/down/back • s sort comments • c comment • e edit comment • d delete comment • E edit post • D delete post • esc back"
+}
+
+// editPost opens the editor for post, or for a new post when post is nil
+func (m *model) editPost(post *models.Post) tea.Cmd {
+	if post != nil && !m.owns(post.UserID) {
+		m.err = apperr.New(apperr.Forbidden, "post", fmt.Sprintf("post %d belongs to another user", post.ID))
+		return nil
+	}
+
+	title, content, thumbnail := "", "", ""
+	heading := "New post"
+	var postID int64
+	if post != nil {
+		title, content, thumbnail = post.Title, post.Content, post.Thumbnail
+		heading = fmt.Sprintf("Edit post %d", post.ID)
+		postID = post.ID
+	}
+
+	existing := post
+	f := newForm(heading, func(values []string) tea.Cmd {
+		if values[0] == "" {
+			return func() tea.Msg { return errMsg{errors.New("the title can not be empty")} }
+		}
+		if existing == nil {
+			newPost := models.Post{UserID: uint64(m.user.ID), Title: values[0], Content: values[2], Thumbnail: values[1]}
+			return m.run("Post created", func() error {
+				if _, err := m.postService.CreatePost(m.context(), newPost); err != nil {
+					return err
+				}
+				return m.discardDraft(0)
+			}, m.loadPosts())
+		}
+
+		updated := *existing
+		updated.Title, updated.Thumbnail, updated.Content = values[0], values[1], values[2]
+		updated.UpdatedAt = time.Now()
+		reload := m.loadPosts()
+		if m.screen == screenPost || m.back == screenPost {
+			m.post = &updated
+			reload = tea.Batch(reload, m.loadComments(updated.ID))
+		}
+		return m.run("Post updated", func() error {
+			if _, err := m.postService.UpdatePostByID(m.context(), updated); err != nil {
+				return err
+			}
+			return m.discardDraft(postID)
+		}, reload)
+	}, newInput("Title", title, false), newInput("Thumbnail", thumbnail, false), newArea("Content", content))
+	f.saved = f.values()
+	// esc keeps the work as a draft, the editor resumes it next time
+	f.onCancel = func(values []string) tea.Cmd {
+		if slices.Equal(values, f.saved) {
+			return nil
+		}
+		save := m.saveDraft(f, postID, values)
+		return func() tea.Msg {
+			if msg, ok := save().(errMsg); ok {
+				return msg
+			}
+			return doneMsg{status: "Draft kept, edit the post again to resume it"}
+		}
+	}
+	return tea.Batch(m.openForm(f), m.resumeDraft(f, postID), m.autosave(f, postID))
+}
+
+func (m *model) deletePost(post models.Post) tea.Cmd {
+	if !m.owns(post.UserID) {
+		m.err = apperr.New(apperr.Forbidden, "post", fmt.Sprintf("post %d belongs to another user", post.ID))
+		return nil
+	}
+	m.confirm = &confirmation{
+		question: fmt.Sprintf("Delete post %d %q?", post.ID, post.Title),
+		action: m.run("Post deleted", func() error {
+			return m.postService.DeletePostByID(m.context(), post.ID)
+		}, m.loadPosts()),
+	}
+	if m.screen == screenPost {
+		m.screen = screenPosts
+	}
+	return nil
+}
+
+// editComment opens the editor for comment, or for a new comment on the open
+// post when comment is nil
+func (m *model) editComment(comment *models.Comment) tea.Cmd {
+	if comment != nil && !m.owns(comment.UserID) {
+		m.err = apperr.New(apperr.Forbidden, "comment", fmt.Sprintf("comment %d belongs to another user", comment.ID))
+		return nil
+	}
+
+	content, heading := "", fmt.Sprintf("Comment on %q", m.post.Title)
+	if comment != nil {
+		content, heading = comment.Content, fmt.Sprintf("Edit comment %d", comment.ID)
+	}
+
+	existing := comment
+	postID := m.post.ID
+	return m.openForm(newForm(heading, func(values []string) tea.Cmd {
+		if strings.TrimSpace(values[0]) == "" {
+			return func() tea.Msg { return errMsg{errors.New("the comment can not be empty")} }
+		}
+		if existing == nil {
+			newComment := models.Comment{UserID: uint64(m.user.ID), PostID: uint64(postID), Content: values[0]}
+			return m.run("Comment created", func() error {
+				_, err := m.commentService.CreateComment(m.context(), newComment)
+				return err
+			}, m.loadComments(postID))
+		}
+
+		updated := *existing
+		updated.Content = values[0]
+		updated.UpdatedAt = time.Now()
+		return m.run("Comment updated", func() error {
+			_, err := m.commentService.UpdateCommentByID(m.context(), updated)
+			return err
+		}, m.loadComments(postID))
+	}, newArea("Content", content)))
+}
+
+func (m *model) deleteComment(comment models.Comment) tea.Cmd {
+	if !m.owns(comment.UserID) {
+		m.err = apperr.New(apperr.Forbidden, "comment", fmt.Sprintf("comment %d belongs to another user", comment.ID))
+		return nil
+	}
+	m.confirm = &confirmation{
+		question: fmt.Sprintf("Delete comment %d?", comment.ID),
+		action: m.run("Comment deleted", func() error {
+			return m.commentService.DeleteCommentByID(m.context(), comment.ID)
+		}, m.loadComments(int64(comment.PostID))),
+	}
+	return nil
+}
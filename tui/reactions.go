@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"postgresql-blog/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// like toggles the like of the user on a post or comment and reloads the
+// post to show the new counts
+func (m *model) like(targetType string, id int64) tea.Cmd {
+	reload := m.reloadPost()
+	reaction := models.Reaction{UserID: m.user.ID, TargetType: targetType, TargetID: id, Kind: models.ReactionLike}
+	return func() tea.Msg {
+		toggle, err := m.reactionService.ToggleReaction(m.context(), reaction)
+		if err != nil {
+			return errMsg{err}
+		}
+		status := fmt.Sprintf("Liked %s %d", targetType, id)
+		if !toggle.Added {
+			status = fmt.Sprintf("Took back the like of %s %d", targetType, id)
+		}
+		return doneMsg{status: status, reload: reload}
+	}
+}
+
+// formatReactions prints counts like "like 2, 👍 1", empty without any
+func formatReactions(counts map[string]int) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		parts[i] = fmt.Sprintf("%s %d", kind, counts[kind])
+	}
+	return strings.Join(parts, ", ")
+}
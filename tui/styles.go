@@ -0,0 +1,13 @@
+package tui
+
+import "github.com/charmbracelet/lipgloss"
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	labelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	selectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("86"))
+	helpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+)
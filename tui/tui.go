@@ -0,0 +1,309 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"postgresql-blog/logging"
+	"postgresql-blog/models"
+	"postgresql-blog/repository"
+	"postgresql-blog/service"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type screen int
+
+const (
+	screenLogin screen = iota
+	screenPosts
+	screenPost
+	screenUsers
+	screenForm
+)
+
+// messages sent back by the commands that talk to the services
+type (
+	loginMsg    struct{ user *models.User }
+	postsMsg    struct{ posts []models.Post }
+	postMsg     struct{ post *models.Post }
+	commentsMsg struct{ comments []models.Comment }
+	usersMsg    struct{ users []models.User }
+	doneMsg     struct {
+		status string
+		reload tea.Cmd
+	}
+	errMsg struct{ err error }
+)
+
+// confirmation is a yes/no question asked before a destructive action
+type confirmation struct {
+	question string
+	action   tea.Cmd
+}
+
+type model struct {
+	userService     service.Users
+	postService     service.Posts
+	commentService  service.Comments
+	reactionService service.Reactions
+
+	user          *models.User
+	width, height int
+	screen        screen
+	back          screen // where a form returns to
+	status        string
+	err           error
+	confirm       *confirmation
+	form          *form
+
+	postList postList
+	post     *models.Post
+	comments []models.Comment
+	comment  int // selected comment
+	scroll   int // first line of the post body shown
+	// commentSort is the order of the comments, the oldest first when empty
+	commentSort repository.CommentSort
+
+	users  []models.User
+	userAt int
+}
+
+// context starts an operation for one action, made as the logged in user
+// so they read their own writes back
+func (m *model) context() context.Context {
+	ctx := context.Background()
+	if m.user != nil {
+		ctx = repository.WithActor(ctx, m.user.ID)
+	}
+	return logging.WithOperation(ctx)
+}
+
+// Run starts the full screen interface and blocks until the user quits.
+func Run(userService service.Users, postService service.Posts, commentService service.Comments, reactionService service.Reactions) error {
+	m := &model{
+		userService:     userService,
+		postService:     postService,
+		commentService:  commentService,
+		reactionService: reactionService,
+		postList:        newPostList(),
+		width:           80,
+		height:          24,
+	}
+	m.showLogin()
+
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		if m.form != nil {
+			m.form.resize(m.width, m.height-2)
+		}
+		return m, nil
+
+	case loginMsg:
+		m.user = msg.user
+		m.form = nil
+		m.screen = screenPosts
+		m.status = fmt.Sprintf("Logged in as %s", m.user.Username)
+		return m, m.loadPosts()
+
+	case postsMsg:
+		m.postList.setPosts(msg.posts)
+		return m, nil
+
+	case postMsg:
+		m.post = msg.post
+		return m, nil
+
+	case commentsMsg:
+		// the selection follows its comment when the order changes
+		var selected int64
+		if m.comment < len(m.comments) {
+			selected = m.comments[m.comment].ID
+		}
+		m.comments = msg.comments
+		m.comment = min(m.comment, max(0, len(m.comments)-1))
+		for i, comment := range m.comments {
+			if comment.ID == selected {
+				m.comment = i
+			}
+		}
+		return m, nil
+
+	case usersMsg:
+		m.users = msg.users
+		m.userAt = min(m.userAt, max(0, len(m.users)-1))
+		return m, nil
+
+	case doneMsg:
+		m.status, m.err = msg.status, nil
+		return m, msg.reload
+
+	case autosaveMsg, draftMsg:
+		return m, m.updateDraft(msg)
+
+	case errMsg:
+		m.err = msg.err
+		if m.screen == screenLogin {
+			// let the user try again
+			m.showLogin()
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.confirm != nil {
+			return m, m.answer(msg.String())
+		}
+		if m.screen != screenForm && m.screen != screenLogin {
+			// a key press dismisses the last message
+			m.status, m.err = "", nil
+		}
+	}
+
+	switch m.screen {
+	case screenLogin, screenForm:
+		return m, m.updateForm(msg)
+	case screenPosts:
+		return m, m.updatePosts(msg)
+	case screenPost:
+		return m, m.updatePost(msg)
+	case screenUsers:
+		return m, m.updateUsers(msg)
+	}
+	return m, nil
+}
+
+func (m *model) View() string {
+	var body string
+	var help string
+	switch m.screen {
+	case screenLogin, screenForm:
+		body = m.form.view()
+		help = "tab next field • ctrl+s save • esc cancel • ctrl+c quit"
+		if m.screen == screenLogin {
+			help = "tab next field • enter log in • ctrl+c quit"
+		}
+	case screenPosts:
+		body, help = m.viewPosts()
+	case screenPost:
+		body, help = m.viewPost()
+	case screenUsers:
+		body, help = m.viewUsers()
+	}
+
+	var footer string
+	switch {
+	case m.confirm != nil:
+		footer = selectedStyle.Render(m.confirm.question + " (y/n)")
+	case m.err != nil:
+		footer = errorStyle.Render("Error: " + m.err.Error())
+	case m.status != "":
+		footer = statusStyle.Render(m.status)
+	}
+
+	// keep the footer at the bottom of the screen
+	lines := strings.Count(body, "\n") + 1
+	padding := max(1, m.height-lines-2)
+	return body + strings.Repeat("\n", padding) + footer + "\n" + helpStyle.Render(help)
+}
+
+func (m *model) answer(key string) tea.Cmd {
+	action := m.confirm.action
+	m.confirm = nil
+	if key == "y" || key == "Y" {
+		return action
+	}
+	m.status = "Cancelled"
+	return nil
+}
+
+func (m *model) updateForm(msg tea.Msg) tea.Cmd {
+	cmd, done := m.form.update(msg)
+	if done && m.screen == screenForm {
+		m.form = nil
+		m.screen = m.back
+	}
+	if done && m.screen == screenLogin {
+		// the login form stays up until the credentials are accepted
+		m.form.setFocus(0)
+	}
+	return cmd
+}
+
+// openForm shows f and returns to the current screen when it is closed
+func (m *model) openForm(f *form) tea.Cmd {
+	m.form = f
+	m.back = m.screen
+	m.screen = screenForm
+	m.form.resize(m.width, m.height-2)
+	return m.form.setFocus(0)
+}
+
+func (m *model) showLogin() {
+	m.screen = screenLogin
+	m.form = newForm("Log in to the blog", func(values []string) tea.Cmd {
+		username, password, code := values[0], values[1], values[2]
+		return func() tea.Msg {
+			var user *models.User
+			var err error
+			if code != "" {
+				user, err = m.userService.LoginWithCode(m.context(), username, password, code)
+			} else {
+				user, err = m.userService.GetUserByUsernameAndPassword(m.context(), username, password)
+			}
+			if errors.Is(err, repository.ErrNotExist) {
+				return errMsg{errors.New("username or password not found")}
+			}
+			if err != nil {
+				return errMsg{err}
+			}
+			return loginMsg{user}
+		}
+	}, newInput("Username", "", false), newInput("Password", "", true), newInput("2FA code (if enabled)", "", false))
+	m.form.resize(m.width, m.height-2)
+}
+
+// run calls fn in the background and reports its outcome as a status line
+func (m *model) run(status string, fn func() error, reload tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		if err := fn(); err != nil {
+			return errMsg{err}
+		}
+		return doneMsg{status: status, reload: reload}
+	}
+}
+
+func (m *model) owns(userID uint64) bool {
+	return m.user != nil && uint64(m.user.ID) == userID
+}
+
+// visible returns the window of at most size items around the cursor
+func visible(cursor, count, size int) (int, int) {
+	start := 0
+	if cursor >= size {
+		start = cursor - size + 1
+	}
+	return start, min(count, start+size)
+}
+
+func truncate(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if width <= 1 || len([]rune(s)) <= width {
+		return s
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
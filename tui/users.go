@@ -0,0 +1,136 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"postgresql-blog/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func (m *model) loadUsers() tea.Cmd {
+	return func() tea.Msg {
+		users, err := m.userService.GetAllUsers(m.context())
+		if err != nil {
+			return errMsg{err}
+		}
+		return usersMsg{users}
+	}
+}
+
+func (m *model) updateUsers(msg tea.Msg) tea.Cmd {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	switch key.String() {
+	case "esc", "q", "backspace":
+		m.screen = screenPosts
+		return m.loadPosts()
+	case "up", "k":
+		m.userAt = max(0, m.userAt-1)
+	case "down", "j":
+		m.userAt = min(max(0, len(m.users)-1), m.userAt+1)
+	case "r":
+		return m.loadUsers()
+	case "n":
+		return m.editUser(nil)
+	case "e", "enter":
+		if len(m.users) > 0 {
+			return m.editUser(&m.users[m.userAt])
+		}
+	case "d":
+		if len(m.users) > 0 {
+			return m.deleteUser(m.users[m.userAt])
+		}
+	}
+	return nil
+}
+
+func (m *model) viewUsers() (string, string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %s\n\n", titleStyle.Render("Users"), labelStyle.Render(fmt.Sprintf("%d users", len(m.users))))
+	fmt.Fprintf(&b, "  %s\n", labelStyle.Render(fmt.Sprintf("%4s  %-20s  %-20s  %s", "ID", "USERNAME", "NAME", "EMAIL")))
+
+	from, to := visible(m.userAt, len(m.users), m.pageSize())
+	for i := from; i < to; i++ {
+		user := m.users[i]
+		line := fmt.Sprintf("%4d  %-20s  %-20s  %s", user.ID, truncate(user.Username, 20), truncate(user.Name, 20), user.Email)
+		if i == m.userAt {
+			b.WriteString(selectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	return b.String(), "↑/↓ move • n new • e/enter edit • d delete • r refresh • esc back"
+}
+
+// editUser opens the editor for user, or for a new user when user is nil
+func (m *model) editUser(user *models.User) tea.Cmd {
+	var current models.User
+	heading := "New user"
+	if user != nil {
+		current = *user
+		heading = fmt.Sprintf("Edit user %d", user.ID)
+	}
+
+	inputs := []*field{newInput("Name", current.Name, false), newInput("Email", current.Email, false),
+		newInput("Username", current.Username, false), newInput("Password", "", true)}
+	if user != nil {
+		inputs[3] = newInput("New password", "", true)
+		inputs = append(inputs, newInput("Current password", "", true))
+	}
+	return m.openForm(newForm(heading, func(values []string) tea.Cmd {
+		updated := current
+		updated.Name, updated.Email, updated.Username = values[0], values[1], values[2]
+		if user == nil {
+			updated.Password = values[3]
+		}
+		// the addresses of other users are hidden, an empty one is kept
+		if updated.Name == "" || (user == nil && updated.Email == "") || updated.Username == "" || (user == nil && updated.Password == "") {
+			return func() tea.Msg { return errMsg{errors.New("name, email, username and password are required")} }
+		}
+
+		if user == nil {
+			return m.run("User created", func() error {
+				_, err := m.userService.CreateUser(m.context(), updated)
+				return err
+			}, m.loadUsers())
+		}
+		// users change themselves and the operators anybody, the service
+		// refuses the others. The password takes the current one.
+		password, currentPassword := values[3], values[4]
+		if m.user.ID == updated.ID {
+			m.user = &updated
+		}
+		return m.run("User updated", func() error {
+			if password != "" {
+				if err := m.userService.ChangePassword(m.context(), updated.ID, currentPassword, password); err != nil {
+					return err
+				}
+			}
+			_, err := m.userService.UpdateUserByID(m.context(), updated)
+			return err
+		}, m.loadUsers())
+	}, inputs...))
+}
+
+// deleteUser asks before deleting another user, only the operators may and
+// the service refuses the others
+func (m *model) deleteUser(user models.User) tea.Cmd {
+	if m.user.ID == user.ID {
+		m.err = errors.New("you can not delete the user you are logged in as")
+		return nil
+	}
+	m.confirm = &confirmation{
+		question: fmt.Sprintf("Delete user %d %q?", user.ID, user.Username),
+		action: m.run("User deleted", func() error {
+			return m.userService.DeleteUserByID(m.context(), user.ID)
+		}, m.loadUsers()),
+	}
+	return nil
+}
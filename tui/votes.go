@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"fmt"
+
+	"postgresql-blog/repository"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// vote sets the vote of the user on a comment, 0 takes it back, and reloads
+// the comments to show them in their new order
+func (m *model) vote(commentID int64, value int) tea.Cmd {
+	reload := m.loadComments(m.post.ID)
+	return func() tea.Msg {
+		comment, err := m.commentService.VoteComment(m.context(), commentID, m.user.ID, value)
+		if err != nil {
+			return errMsg{err}
+		}
+		status := fmt.Sprintf("Comment %d has %d upvotes and %d downvotes", comment.ID, comment.Upvotes, comment.Downvotes)
+		return doneMsg{status: status, reload: reload}
+	}
+}
+
+// nextCommentSort shows the comments in the next order
+func (m *model) nextCommentSort() tea.Cmd {
+	sorts := repository.CommentSorts
+	current := m.commentSort
+	if current == "" {
+		current = repository.CommentSortOld
+	}
+	next := sorts[0]
+	for i, sort := range sorts {
+		if sort == current && i+1 < len(sorts) {
+			next = sorts[i+1]
+		}
+	}
+	m.commentSort = next
+	return m.loadComments(m.post.ID)
+}
+
+func commentSortName(sort repository.CommentSort) string {
+	switch sort {
+	case repository.CommentSortNew:
+		return "newest first"
+	case repository.CommentSortTop:
+		return "top first"
+	case repository.CommentSortControversial:
+		return "most controversial first"
+	default:
+		return "oldest first"
+	}
+}